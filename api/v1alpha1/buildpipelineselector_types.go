@@ -33,6 +33,7 @@ import (
 //
 // which means that language is 'java' AND (project type is 'spring' OR 'quarkus') AND
 // annotation 'builder' is present with value 'gradle' OR 'maven'.
+// For more complex rules, use a CEL expression which is connected with other conditions via AND too.
 type WhenCondition struct {
 	// Defines component language to match, e.g. 'java'.
 	// The value to compare with is taken from devfile.metadata.language field.
@@ -64,6 +65,13 @@ type WhenCondition struct {
 	// The values to compare with are taken from component.metadata.labels field.
 	// +kubebuilder:validation:Optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Defines CEL expression which must evaluate to true for the condition to match.
+	// Available variables are: language, projectType, dockerfile, componentName, application,
+	// annotations, labels, gitUrl, gitHost, gitRevision, contextDir and component (the whole Component object).
+	// Example: componentName.matches('^web-') && !('skip-build' in labels) && language != 'java'
+	// +kubebuilder:validation:Optional
+	Expression string `json:"expression,omitempty"`
 }
 
 // PipelineParam is a type to describe pipeline parameters.
@@ -121,22 +129,102 @@ type PipelineSelector struct {
 	WhenConditions WhenCondition `json:"when,omitempty"`
 }
 
+// BuildPipelineSelectorMergeMode defines how items of a BuildPipelineSelector are merged
+// with the items of the less specific BuildPipelineSelectors.
+// +kubebuilder:validation:Enum=prepend;append;replace
+type BuildPipelineSelectorMergeMode string
+
+const (
+	// The items are evaluated before the items of the less specific selectors.
+	BuildPipelineSelectorMergeModePrepend BuildPipelineSelectorMergeMode = "prepend"
+	// The items are evaluated after the items of the less specific selectors.
+	BuildPipelineSelectorMergeModeAppend BuildPipelineSelectorMergeMode = "append"
+	// The items of the less specific selectors are not evaluated at all.
+	BuildPipelineSelectorMergeModeReplace BuildPipelineSelectorMergeMode = "replace"
+)
+
 // BuildPipelineSelectorSpec defines the desired state of BuildPipelineSelector
 type BuildPipelineSelectorSpec struct {
 	// Defines chain of pipeline selectors.
 	// The first matching item is used.
 	// +kubebuilder:validation:Required
 	Selectors []PipelineSelector `json:"selectors"`
+
+	// Defines order in which application, namespace and global BuildPipelineSelectors are merged.
+	// Selectors are merged from the lowest priority to the highest one, so the selector with the highest priority
+	// is applied last. Selectors with equal priority are merged from the global to the application one.
+	// +kubebuilder:validation:Optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Defines how the selectors are merged with the already merged selectors of lower priority.
+	// Default is prepend.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=prepend
+	Mode BuildPipelineSelectorMergeMode `json:"mode,omitempty"`
+
+	// Defines whether an item with the same name as an already merged item overrides the item in place
+	// instead of being added to the list. Applies to prepend and append modes only.
+	// Unnamed items never override other items.
+	// +kubebuilder:validation:Optional
+	OverrideByName bool `json:"overrideByName,omitempty"`
+
+	// Defines whether bundle tags of the pipelines selected by this selector are resolved to digests
+	// when PipelineRuns are generated, so the builds are reproducible even if the tag moves.
+	// The original bundle reference is recorded in the build.appstudio.redhat.com/bundle_tag annotation.
+	// +kubebuilder:validation:Optional
+	PinBundleDigest bool `json:"pinBundleDigest,omitempty"`
+}
+
+const (
+	// PipelineSelectorValidConditionType is the condition type which reports
+	// whether the pipeline referenced by a selector item (or all items) can be resolved.
+	PipelineSelectorValidConditionType = "Valid"
+)
+
+// PipelineSelectorStatus defines the observed state of a single pipeline selector item.
+type PipelineSelectorStatus struct {
+	// Index of the selector item in the spec selectors list.
+	Index int `json:"index"`
+
+	// Name of the selector item, if set in the spec.
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// Conditions of the selector item, e.g. whether the referenced pipeline is valid.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BuildPipelineSelectorStatus defines the observed state of BuildPipelineSelector
+type BuildPipelineSelectorStatus struct {
+	// The generation of the spec which was validated last time.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions summarize the state of all selector items.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Validation results of every selector item, in the same order as in the spec.
+	// +kubebuilder:validation:Optional
+	// +listType=atomic
+	Selectors []PipelineSelectorStatus `json:"selectors,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 
 // BuildPipelineSelector is the Schema for the BuildPipelineSelectors API
 type BuildPipelineSelector struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec BuildPipelineSelectorSpec `json:"spec,omitempty"`
+	Spec   BuildPipelineSelectorSpec   `json:"spec,omitempty"`
+	Status BuildPipelineSelectorStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BuildErrorInfo describes persistent error of the last build request.
+type BuildErrorInfo struct {
+	// ID of the build-service error.
+	// +kubebuilder:validation:Optional
+	ErrId int `json:"errorId,omitempty"`
+
+	// Short description of the error.
+	// +kubebuilder:validation:Optional
+	ErrMessage string `json:"errorMessage,omitempty"`
+}
+
+// SimpleBuildStatus shows state of the simple (initial) build of the Component.
+type SimpleBuildStatus struct {
+	// The time when last simple build was submitted.
+	// +kubebuilder:validation:Optional
+	BuildStartTime *metav1.Time `json:"buildStartTime,omitempty"`
+
+	BuildErrorInfo `json:",inline"`
+}
+
+// PaCBuildStatus shows state of Pipelines as Code configuration of the Component.
+type PaCBuildStatus struct {
+	// Shows if Pipelines as Code is used.
+	// Values are: enabled, disabled, error.
+	// +kubebuilder:validation:Optional
+	State string `json:"state,omitempty"`
+
+	// Link to Pipelines as Code provision / unprovision merge request.
+	// +kubebuilder:validation:Optional
+	MergeUrl string `json:"mergeUrl,omitempty"`
+
+	// Time of the last successful Pipelines as Code configuration.
+	// +kubebuilder:validation:Optional
+	ConfigurationTime *metav1.Time `json:"configurationTime,omitempty"`
+
+	// Method used to sign the commits of the merge request: gpg, ssh or github-app.
+	// Empty if the commits are not signed.
+	// +kubebuilder:validation:Optional
+	CommitSigningMethod string `json:"commitSigningMethod,omitempty"`
+
+	// State of the Pipelines as Code configuration merge request: pending-merge, merged or rejected.
+	// +kubebuilder:validation:Optional
+	MergeState string `json:"mergeState,omitempty"`
+
+	// Shows if the pending merge request can be merged without conflicts.
+	// Not set if the git provider hasn't computed it yet.
+	// +kubebuilder:validation:Optional
+	Mergeable *bool `json:"mergeable,omitempty"`
+
+	// Time when the Pipelines as Code configuration merge request was merged.
+	// +kubebuilder:validation:Optional
+	MergeTime *metav1.Time `json:"mergeTime,omitempty"`
+
+	// Time when state of the Pipelines as Code configuration merge request was last checked in the git provider.
+	// +kubebuilder:validation:Optional
+	MergeCheckTime *metav1.Time `json:"mergeCheckTime,omitempty"`
+
+	BuildErrorInfo `json:",inline"`
+}
+
+// SelectedPipeline describes the build pipeline selected for the Component by BuildPipelineSelectors.
+type SelectedPipeline struct {
+	// Name of the pipeline.
+	Name string `json:"name"`
+
+	// Bundle the pipeline is taken from, if the pipeline comes from a bundle.
+	// +kubebuilder:validation:Optional
+	Bundle string `json:"bundle,omitempty"`
+
+	// Location the pipeline is taken from, e.g. bundle reference or file in a git repository.
+	// +kubebuilder:validation:Optional
+	Source string `json:"source,omitempty"`
+}
+
+// PipelineSelectorTrace shows how a BuildPipelineSelector was evaluated for the Component.
+type PipelineSelectorTrace struct {
+	// Namespace of the BuildPipelineSelector.
+	Namespace string `json:"namespace"`
+
+	// Name of the BuildPipelineSelector.
+	Name string `json:"name"`
+
+	// Shows that the BuildPipelineSelector doesn't exist, so it was skipped.
+	// +kubebuilder:validation:Optional
+	NotFound bool `json:"notFound,omitempty"`
+
+	// Evaluated items of the selectors list, in order.
+	// +kubebuilder:validation:Optional
+	Items []PipelineSelectorItemTrace `json:"items,omitempty"`
+}
+
+// PipelineSelectorItemTrace shows how a single PipelineSelector item was evaluated for the Component.
+type PipelineSelectorItemTrace struct {
+	// Index of the item in the selectors list.
+	Index int `json:"index"`
+
+	// Name of the item, if set.
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// Shows that all conditions of the item are satisfied.
+	// +kubebuilder:validation:Optional
+	Matched bool `json:"matched,omitempty"`
+
+	// Describes the first condition of the item not satisfied by the Component.
+	// +kubebuilder:validation:Optional
+	FailedCondition string `json:"failedCondition,omitempty"`
+}
+
+// ComponentBuildStatusSpec defines the desired state of ComponentBuildStatus
+type ComponentBuildStatusSpec struct {
+	// Name of the Component in the same namespace the build status belongs to.
+	// +kubebuilder:validation:Required
+	ComponentName string `json:"componentName"`
+}
+
+// ComponentBuildStatusStatus defines the observed state of ComponentBuildStatus
+type ComponentBuildStatusStatus struct {
+	// State of the simple build.
+	// +kubebuilder:validation:Optional
+	Simple *SimpleBuildStatus `json:"simple,omitempty"`
+
+	// State of Pipelines as Code configuration.
+	// +kubebuilder:validation:Optional
+	PaC *PaCBuildStatus `json:"pac,omitempty"`
+
+	// Build pipeline selected for the Component at the time of the last build request.
+	// +kubebuilder:validation:Optional
+	Pipeline *SelectedPipeline `json:"pipeline,omitempty"`
+
+	// Shows how BuildPipelineSelectors were evaluated, if no build pipeline matches the Component.
+	// +kubebuilder:validation:Optional
+	PipelineSelection []PipelineSelectorTrace `json:"pipelineSelection,omitempty"`
+
+	// Build methods agnostic message, e.g. invalid build request.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Component",type=string,JSONPath=`.spec.componentName`
+//+kubebuilder:printcolumn:name="PaC",type=string,JSONPath=`.status.pac.state`
+//+kubebuilder:printcolumn:name="Merge URL",type=string,JSONPath=`.status.pac.mergeUrl`
+
+// ComponentBuildStatus is the Schema for the ComponentBuildStatuses API.
+// It has the same name as the Component it belongs to.
+type ComponentBuildStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComponentBuildStatusSpec   `json:"spec,omitempty"`
+	Status ComponentBuildStatusStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ComponentBuildStatusList contains a list of ComponentBuildStatus
+type ComponentBuildStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComponentBuildStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComponentBuildStatus{}, &ComponentBuildStatusList{})
+}
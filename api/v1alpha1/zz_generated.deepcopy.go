@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -41,12 +42,28 @@ func (in *BackwardsCompatiblePipelineRef) DeepCopy() *BackwardsCompatiblePipelin
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildErrorInfo) DeepCopyInto(out *BuildErrorInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildErrorInfo.
+func (in *BuildErrorInfo) DeepCopy() *BuildErrorInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildErrorInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildPipelineSelector) DeepCopyInto(out *BuildPipelineSelector) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPipelineSelector.
@@ -121,6 +138,179 @@ func (in *BuildPipelineSelectorSpec) DeepCopy() *BuildPipelineSelectorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildPipelineSelectorStatus) DeepCopyInto(out *BuildPipelineSelectorStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]PipelineSelectorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPipelineSelectorStatus.
+func (in *BuildPipelineSelectorStatus) DeepCopy() *BuildPipelineSelectorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildPipelineSelectorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentBuildStatus) DeepCopyInto(out *ComponentBuildStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentBuildStatus.
+func (in *ComponentBuildStatus) DeepCopy() *ComponentBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComponentBuildStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentBuildStatusList) DeepCopyInto(out *ComponentBuildStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ComponentBuildStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentBuildStatusList.
+func (in *ComponentBuildStatusList) DeepCopy() *ComponentBuildStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentBuildStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComponentBuildStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentBuildStatusSpec) DeepCopyInto(out *ComponentBuildStatusSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentBuildStatusSpec.
+func (in *ComponentBuildStatusSpec) DeepCopy() *ComponentBuildStatusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentBuildStatusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentBuildStatusStatus) DeepCopyInto(out *ComponentBuildStatusStatus) {
+	*out = *in
+	if in.Simple != nil {
+		in, out := &in.Simple, &out.Simple
+		*out = new(SimpleBuildStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PaC != nil {
+		in, out := &in.PaC, &out.PaC
+		*out = new(PaCBuildStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pipeline != nil {
+		in, out := &in.Pipeline, &out.Pipeline
+		*out = new(SelectedPipeline)
+		**out = **in
+	}
+	if in.PipelineSelection != nil {
+		in, out := &in.PipelineSelection, &out.PipelineSelection
+		*out = make([]PipelineSelectorTrace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentBuildStatusStatus.
+func (in *ComponentBuildStatusStatus) DeepCopy() *ComponentBuildStatusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentBuildStatusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PaCBuildStatus) DeepCopyInto(out *PaCBuildStatus) {
+	*out = *in
+	if in.ConfigurationTime != nil {
+		in, out := &in.ConfigurationTime, &out.ConfigurationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Mergeable != nil {
+		in, out := &in.Mergeable, &out.Mergeable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MergeTime != nil {
+		in, out := &in.MergeTime, &out.MergeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.MergeCheckTime != nil {
+		in, out := &in.MergeCheckTime, &out.MergeCheckTime
+		*out = (*in).DeepCopy()
+	}
+	out.BuildErrorInfo = in.BuildErrorInfo
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PaCBuildStatus.
+func (in *PaCBuildStatus) DeepCopy() *PaCBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PaCBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineParam) DeepCopyInto(out *PipelineParam) {
 	*out = *in
@@ -158,6 +348,98 @@ func (in *PipelineSelector) DeepCopy() *PipelineSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSelectorItemTrace) DeepCopyInto(out *PipelineSelectorItemTrace) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSelectorItemTrace.
+func (in *PipelineSelectorItemTrace) DeepCopy() *PipelineSelectorItemTrace {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSelectorItemTrace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSelectorStatus) DeepCopyInto(out *PipelineSelectorStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSelectorStatus.
+func (in *PipelineSelectorStatus) DeepCopy() *PipelineSelectorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSelectorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSelectorTrace) DeepCopyInto(out *PipelineSelectorTrace) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PipelineSelectorItemTrace, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSelectorTrace.
+func (in *PipelineSelectorTrace) DeepCopy() *PipelineSelectorTrace {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSelectorTrace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelectedPipeline) DeepCopyInto(out *SelectedPipeline) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelectedPipeline.
+func (in *SelectedPipeline) DeepCopy() *SelectedPipeline {
+	if in == nil {
+		return nil
+	}
+	out := new(SelectedPipeline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SimpleBuildStatus) DeepCopyInto(out *SimpleBuildStatus) {
+	*out = *in
+	if in.BuildStartTime != nil {
+		in, out := &in.BuildStartTime, &out.BuildStartTime
+		*out = (*in).DeepCopy()
+	}
+	out.BuildErrorInfo = in.BuildErrorInfo
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SimpleBuildStatus.
+func (in *SimpleBuildStatus) DeepCopy() *SimpleBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SimpleBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WhenCondition) DeepCopyInto(out *WhenCondition) {
 	*out = *in
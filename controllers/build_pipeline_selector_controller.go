@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	pipelineselector "github.com/redhat-appstudio/build-service/pkg/pipeline-selector"
+)
+
+const (
+	PipelineSelectorReasonValid                  = "PipelineResolved"
+	PipelineSelectorReasonInvalid                = "InvalidSelectors"
+	PipelineSelectorReasonUnsupportedPipelineRef = "UnsupportedPipelineRef"
+	PipelineSelectorReasonMissingBundleParams    = "MissingBundleResolverParams"
+	PipelineSelectorReasonMissingResolverParams  = "MissingResolverParams"
+	PipelineSelectorReasonRetrievalFailed        = "PipelineRetrievalFailed"
+	PipelineSelectorReasonInvalidExpression      = "InvalidExpression"
+
+	// Retrieval of a pipeline could fail due to registry unavailability,
+	// so retry validation of such selectors from time to time.
+	pipelineSelectorRevalidationInterval = 10 * time.Minute
+)
+
+// BuildPipelineSelectorReconciler validates BuildPipelineSelector objects and reports
+// the result in the status, so broken selector items are visible before any Component hits them.
+type BuildPipelineSelectorReconciler struct {
+	Client        client.Client
+	Scheme        *runtime.Scheme
+	EventRecorder record.EventRecorder
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BuildPipelineSelectorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&buildappstudiov1alpha1.BuildPipelineSelector{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=buildpipelineselectors,verbs=get;list;watch
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=buildpipelineselectors/status,verbs=get;update;patch
+
+func (r *BuildPipelineSelectorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx).WithName("BuildPipelineSelector")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	var pipelineSelector buildappstudiov1alpha1.BuildPipelineSelector
+	if err := r.Client.Get(ctx, req.NamespacedName, &pipelineSelector); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get BuildPipelineSelector")
+		return ctrl.Result{}, err
+	}
+
+	var prefetchResults map[int]*pipelinePrefetchResult
+	if isPipelineSpecPrefetchEnabled() && pipelineSelector.Status.ObservedGeneration != pipelineSelector.Generation {
+		// The selector has changed, refresh its pipelines in the cache before Components request them
+		prefetchResults = prefetchPipelineSpecs(ctx, pipelineSelector.Spec.Selectors)
+	}
+
+	selectorsStatus := make([]buildappstudiov1alpha1.PipelineSelectorStatus, 0, len(pipelineSelector.Spec.Selectors))
+	invalidSelectors := []string{}
+	retrievalFailed := false
+	for i := range pipelineSelector.Spec.Selectors {
+		selector := &pipelineSelector.Spec.Selectors[i]
+
+		selectorStatus := buildappstudiov1alpha1.PipelineSelectorStatus{Index: i, Name: selector.Name}
+		// Keep last transition time of unchanged conditions
+		if i < len(pipelineSelector.Status.Selectors) {
+			selectorStatus.Conditions = pipelineSelector.Status.Selectors[i].Conditions
+		}
+
+		condition := metav1.Condition{
+			Type:               buildappstudiov1alpha1.PipelineSelectorValidConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             PipelineSelectorReasonValid,
+			Message:            "Pipeline is resolved",
+			ObservedGeneration: pipelineSelector.Generation,
+		}
+		if err := validatePipelineSelector(ctx, r.Client, pipelineSelector.Namespace, selector, prefetchResults[i]); err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = getPipelineSelectorInvalidReason(err)
+			condition.Message = err.Error()
+			if condition.Reason == PipelineSelectorReasonRetrievalFailed {
+				retrievalFailed = true
+			}
+			invalidSelectors = append(invalidSelectors, getPipelineSelectorDisplayName(i, selector))
+			log.Info("invalid pipeline selector", "Index", i, "Name", selector.Name, "Reason", condition.Reason, "Error", err.Error())
+		}
+		meta.SetStatusCondition(&selectorStatus.Conditions, condition)
+
+		selectorsStatus = append(selectorsStatus, selectorStatus)
+	}
+
+	summaryCondition := metav1.Condition{
+		Type:               buildappstudiov1alpha1.PipelineSelectorValidConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             PipelineSelectorReasonValid,
+		Message:            "All pipeline selectors are valid",
+		ObservedGeneration: pipelineSelector.Generation,
+	}
+	if len(invalidSelectors) > 0 {
+		summaryCondition.Status = metav1.ConditionFalse
+		summaryCondition.Reason = PipelineSelectorReasonInvalid
+		summaryCondition.Message = fmt.Sprintf("Invalid pipeline selectors: %s", strings.Join(invalidSelectors, ", "))
+		r.EventRecorder.Event(&pipelineSelector, corev1.EventTypeWarning, PipelineSelectorReasonInvalid, summaryCondition.Message)
+	}
+
+	pipelineSelector.Status.ObservedGeneration = pipelineSelector.Generation
+	pipelineSelector.Status.Selectors = selectorsStatus
+	meta.SetStatusCondition(&pipelineSelector.Status.Conditions, summaryCondition)
+	if err := r.Client.Status().Update(ctx, &pipelineSelector); err != nil {
+		log.Error(err, "failed to update BuildPipelineSelector status")
+		return ctrl.Result{}, err
+	}
+
+	if retrievalFailed {
+		return ctrl.Result{RequeueAfter: pipelineSelectorRevalidationInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// validatePipelineSelector checks that the when condition expression of the given selector item compiles
+// and the pipeline referenced by the item is supported by build-service and could be retrieved.
+// If the pipeline has just been prefetched, the prefetch result is used instead of retrieving the pipeline again.
+func validatePipelineSelector(ctx context.Context, k8sClient client.Client, namespace string, selector *buildappstudiov1alpha1.PipelineSelector, prefetchResult *pipelinePrefetchResult) error {
+	if selector.WhenConditions.Expression != "" {
+		if _, err := pipelineselector.CompileWhenExpression(selector.WhenConditions.Expression); err != nil {
+			return err
+		}
+	}
+
+	pipelineSource, err := newPipelineSource(selector.PipelineRef.AsPipelineRef(), k8sClient, namespace, namespace == buildServiceNamespaceName)
+	if err != nil {
+		return err
+	}
+
+	if prefetchResult != nil {
+		err = prefetchResult.err
+	} else {
+		_, err = pipelineSource.GetPipelineSpec(ctx)
+	}
+	if err != nil {
+		if _, ok := err.(*boerrors.BuildOpError); ok {
+			return err
+		}
+		return boerrors.NewBuildOpError(
+			boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("failed to retrieve pipeline %s from %s: %w", pipelineSource.GetPipelineName(), pipelineSource.GetLocation(), err),
+		)
+	}
+	return nil
+}
+
+// getPipelineSelectorInvalidReason maps pipeline selector validation error into condition reason.
+func getPipelineSelectorInvalidReason(err error) string {
+	if boErr, ok := err.(*boerrors.BuildOpError); ok {
+		switch boerrors.BOErrorId(boErr.GetErrorId()) {
+		case boerrors.EUnsupportedPipelineRef:
+			return PipelineSelectorReasonUnsupportedPipelineRef
+		case boerrors.EMissingParamsForBundleResolver:
+			return PipelineSelectorReasonMissingBundleParams
+		case boerrors.EMissingParamsForPipelineResolver:
+			return PipelineSelectorReasonMissingResolverParams
+		case boerrors.EInvalidPipelineSelectorExpression:
+			return PipelineSelectorReasonInvalidExpression
+		}
+	}
+	return PipelineSelectorReasonRetrievalFailed
+}
+
+func getPipelineSelectorDisplayName(index int, selector *buildappstudiov1alpha1.PipelineSelector) string {
+	if selector.Name != "" {
+		return selector.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+var _ = Describe("BuildPipelineSelector controller", func() {
+
+	var (
+		selectorKey = types.NamespacedName{Name: "validated-selector", Namespace: HASAppNamespace}
+	)
+
+	Context("Test BuildPipelineSelector validation", func() {
+
+		_ = AfterEach(func() {
+			deleteBuildPipelineRunSelector(selectorKey)
+		})
+
+		waitSelectorValidated := func() *buildappstudiov1alpha1.BuildPipelineSelector {
+			selector := &buildappstudiov1alpha1.BuildPipelineSelector{}
+			Eventually(func() bool {
+				Expect(k8sClient.Get(ctx, selectorKey, selector)).To(Succeed())
+				return selector.Status.ObservedGeneration == selector.Generation
+			}, timeout, interval).Should(BeTrue())
+			return selector
+		}
+
+		It("should report invalid selector items", func() {
+			selector := &buildappstudiov1alpha1.BuildPipelineSelector{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      selectorKey.Name,
+					Namespace: selectorKey.Namespace,
+				},
+				Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+					Selectors: []buildappstudiov1alpha1.PipelineSelector{
+						{
+							Name: "git-resolver",
+							PipelineRef: buildappstudiov1alpha1.BackwardsCompatiblePipelineRef{
+								PipelineRef: tektonapi.PipelineRef{
+									ResolverRef: tektonapi.ResolverRef{
+										Resolver: "git",
+										Params: []tektonapi.Param{
+											{Name: "url", Value: *tektonapi.NewStructuredValues("https://github.com/redhat-appstudio/build-definitions")},
+										},
+									},
+								},
+							},
+						},
+						{
+							PipelineRef: buildappstudiov1alpha1.BackwardsCompatiblePipelineRef{
+								PipelineRef: tektonapi.PipelineRef{
+									ResolverRef: tektonapi.ResolverRef{
+										Resolver: "bundles",
+										Params: []tektonapi.Param{
+											{Name: "kind", Value: *tektonapi.NewStructuredValues("pipeline")},
+											{Name: "name", Value: *tektonapi.NewStructuredValues("docker-build")},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, selector)).To(Succeed())
+
+			selector = waitSelectorValidated()
+
+			validCondition := meta.FindStatusCondition(selector.Status.Conditions, buildappstudiov1alpha1.PipelineSelectorValidConditionType)
+			Expect(validCondition).ToNot(BeNil())
+			Expect(validCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(validCondition.Reason).To(Equal(PipelineSelectorReasonInvalid))
+			Expect(validCondition.Message).To(ContainSubstring("git-resolver"))
+			Expect(validCondition.Message).To(ContainSubstring("#1"))
+
+			Expect(selector.Status.Selectors).To(HaveLen(2))
+
+			Expect(selector.Status.Selectors[0].Name).To(Equal("git-resolver"))
+			gitResolverCondition := meta.FindStatusCondition(selector.Status.Selectors[0].Conditions, buildappstudiov1alpha1.PipelineSelectorValidConditionType)
+			Expect(gitResolverCondition).ToNot(BeNil())
+			Expect(gitResolverCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(gitResolverCondition.Reason).To(Equal(PipelineSelectorReasonUnsupportedPipelineRef))
+
+			Expect(selector.Status.Selectors[1].Index).To(Equal(1))
+			missingBundleCondition := meta.FindStatusCondition(selector.Status.Selectors[1].Conditions, buildappstudiov1alpha1.PipelineSelectorValidConditionType)
+			Expect(missingBundleCondition).ToNot(BeNil())
+			Expect(missingBundleCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(missingBundleCondition.Reason).To(Equal(PipelineSelectorReasonMissingBundleParams))
+		})
+	})
+})
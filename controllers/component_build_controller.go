@@ -39,6 +39,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/github"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
 )
 
@@ -48,6 +49,8 @@ const (
 	BuildRequestTriggerPaCBuildAnnotationValue    = "trigger-pac-build"
 	BuildRequestConfigurePaCAnnotationValue       = "configure-pac"
 	BuildRequestUnconfigurePaCAnnotationValue     = "unconfigure-pac"
+	BuildRequestRefreshPaCAnnotationValue         = "refresh-pac"
+	BuildRequestPreviewAnnotationValue            = "preview"
 
 	BuildStatusAnnotationName = "build.appstudio.openshift.io/status"
 
@@ -103,6 +106,15 @@ func initMetrics() error {
 	if err := metrics.Registry.Register(pipelinesAsCodeComponentProvisionTimeMetric); err != nil {
 		return fmt.Errorf("failed to register the PaC_configuration_time metric: %w", err)
 	}
+	if err := metrics.Registry.Register(pipelineSpecCacheRequestsMetric); err != nil {
+		return fmt.Errorf("failed to register the pipeline_spec_cache_requests_total metric: %w", err)
+	}
+	if err := metrics.Registry.Register(pipelinesAsCodeMergeRequestMergeTimeMetric); err != nil {
+		return fmt.Errorf("failed to register the PaC_merge_request_merge_time metric: %w", err)
+	}
+	if err := metrics.Registry.Register(github.InstallationTokensMintedMetric); err != nil {
+		return fmt.Errorf("failed to register the github_app_installation_tokens_minted_total metric: %w", err)
+	}
 
 	return nil
 }
@@ -139,6 +151,17 @@ type PaCBuildStatus struct {
 	MergeUrl string `json:"merge-url,omitempty"`
 	// Time of the last successful PaC configuration in RFC1123 format
 	ConfigurationTime string `json:"configuration-time,omitempty"`
+	// Method used to sign the commits of the merge request, if any
+	CommitSigningMethod string `json:"commit-signing-method,omitempty"`
+	// Shows if the PaC configuration merge request is merged.
+	// Values are: pending-merge, merged, rejected.
+	MergeState string `json:"merge-state,omitempty"`
+	// Shows if the pending merge request can be merged without conflicts, if known
+	Mergeable *bool `json:"mergeable,omitempty"`
+	// Time when the PaC configuration merge request was merged in RFC1123 format
+	MergeTime string `json:"merge-time,omitempty"`
+	// Time of the last check of the PaC configuration merge request state in RFC1123 format
+	MergeCheckTime string `json:"merge-check-time,omitempty"`
 
 	ErrorInfo
 }
@@ -179,9 +202,12 @@ func (r *ComponentBuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=components,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=components/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=buildpipelineselectors,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=componentbuildstatuses,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=componentbuildstatuses/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=create
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelines,verbs=get
 //+kubebuilder:rbac:groups=pipelinesascode.tekton.dev,resources=repositories,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;update;delete
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -264,7 +290,7 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			log.Info("PaC finalizer removed", l.Action, l.ActionDelete)
 
 			// Try to clean up Pipelines as Code configuration
-			_, _ = r.UndoPaCProvisionForComponent(ctx, &component)
+			_, _, _ = r.UndoPaCProvisionForComponent(ctx, &component)
 		}
 
 		return ctrl.Result{}, nil
@@ -319,14 +345,23 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	requestedAction, requestedActionExists := component.Annotations[BuildRequestAnnotationName]
 	if !requestedActionExists {
 		if _, statusExists := component.Annotations[BuildStatusAnnotationName]; statusExists {
-			// Nothing to do
-			return ctrl.Result{}, nil
+			// Nothing to do, just make sure the build status object exists
+			if err := r.ensureComponentBuildStatus(ctx, &component); err != nil {
+				return ctrl.Result{}, err
+			}
+			// Follow Pipelines as Code configuration merge request, if any
+			return r.checkPaCMergeRequest(ctx, &component)
 		}
 		// Automatically build component after creation
 		log.Info("automatically requesting initial build for the new component")
 		requestedAction = BuildRequestTriggerSimpleBuildAnnotationValue
 	}
 
+	buildStatus, err := r.readComponentBuildStatus(ctx, &component)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	switch requestedAction {
 	case BuildRequestTriggerSimpleBuildAnnotationValue:
 		simpleBuildStatus := &SimpleBuildStatus{}
@@ -338,7 +373,7 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			} else {
 				// transient error, retry
 				log.Error(err, "simple build submition transient error")
-				return ctrl.Result{}, err
+				return getTransientErrorResult(err)
 			}
 		} else {
 			simpleBuildStatus.BuildStartTime = time.Now().Format(time.RFC1123)
@@ -350,13 +385,11 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 
 		// Update build status annotation
-		buildStatus := readBuildStatus((&component))
 		buildStatus.Simple = simpleBuildStatus
 		buildStatus.Message = "done"
 		writeBuildStatus(&component, buildStatus)
 
 	case BuildRequestTriggerPaCBuildAnnotationValue:
-		buildStatus := readBuildStatus(&component)
 		if !(buildStatus.PaC != nil && buildStatus.PaC.State == "enabled") {
 			log.Info("Can't rerun push pipeline because Pipelines as Code isn't provisioned for the Component")
 			return ctrl.Result{}, nil
@@ -367,14 +400,13 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		if err != nil {
 			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
 				log.Error(err, "Failed to rerun push pipeline for the Component")
-				buildStatus := readBuildStatus((&component))
 				buildStatus.PaC.ErrId = boErr.GetErrorId()
 				buildStatus.PaC.ErrMessage = boErr.ShortError()
 				writeBuildStatus(&component, buildStatus)
 			} else {
 				// transient error, retry
 				log.Error(err, "Failed to rerun push pipeline for the Component with transient error")
-				return ctrl.Result{}, err
+				return getTransientErrorResult(err)
 			}
 		} else {
 			if reconcileRequired {
@@ -384,7 +416,7 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	case BuildRequestConfigurePaCAnnotationValue:
 		pacBuildStatus := &PaCBuildStatus{}
-		if mergeUrl, err := r.ProvisionPaCForComponent(ctx, &component); err != nil {
+		if mergeUrl, commitSigningMethod, err := r.ProvisionPaCForComponent(ctx, &component); err != nil {
 			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
 				log.Error(err, "Pipelines as Code provision for the Component failed")
 				pacBuildStatus.State = "error"
@@ -393,12 +425,14 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			} else {
 				// transient error, retry
 				log.Error(err, "Pipelines as Code provision transient error")
-				return ctrl.Result{}, err
+				return getTransientErrorResult(err)
 			}
 		} else {
 			pacBuildStatus.State = "enabled"
 			pacBuildStatus.MergeUrl = mergeUrl
 			pacBuildStatus.ConfigurationTime = time.Now().Format(time.RFC1123)
+			pacBuildStatus.CommitSigningMethod = commitSigningMethod
+			pacBuildStatus.MergeState = getInitialPaCMergeState(mergeUrl)
 			log.Info("Pipelines as Code provision for the Component finished successfully")
 		}
 
@@ -417,7 +451,6 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 
 		// Update build status annotation
-		buildStatus := readBuildStatus((&component))
 		buildStatus.PaC = pacBuildStatus
 		buildStatus.Message = "done"
 		writeBuildStatus(&component, buildStatus)
@@ -427,6 +460,35 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			component.Annotations = make(map[string]string)
 		}
 
+	case BuildRequestRefreshPaCAnnotationValue:
+		if buildStatus.PaC != nil && buildStatus.PaC.State == "enabled" {
+			if mergeUrl, isRefreshed, err := r.RefreshPaCMergeRequestForComponent(ctx, &component); err != nil {
+				if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
+					log.Error(err, "Pipelines as Code merge request refresh for the Component failed")
+					buildStatus.Message = fmt.Sprintf("Pipelines as Code merge request refresh failed: %s", boErr.ShortError())
+				} else {
+					// transient error, retry
+					log.Error(err, "Pipelines as Code merge request refresh transient error")
+					return getTransientErrorResult(err)
+				}
+			} else {
+				if isRefreshed {
+					applyPaCMergeRequestRefresh(buildStatus.PaC, mergeUrl)
+				}
+				buildStatus.Message = "done"
+			}
+		} else {
+			buildStatus.Message = "Pipelines as Code isn't provisioned for the Component, nothing to refresh"
+		}
+
+		if err := r.Client.Get(ctx, req.NamespacedName, &component); err != nil {
+			log.Error(err, "failed to get Component", l.Action, l.ActionView)
+			return ctrl.Result{}, err
+		}
+
+		// Update build status annotation
+		writeBuildStatus(&component, buildStatus)
+
 	case BuildRequestUnconfigurePaCAnnotationValue:
 		// Remove Pipelines as Code configuration finalizer
 		if controllerutil.ContainsFinalizer(&component, PaCProvisionFinalizer) {
@@ -440,7 +502,7 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 
 		pacBuildStatus := &PaCBuildStatus{}
-		if mergeUrl, err := r.UndoPaCProvisionForComponent(ctx, &component); err != nil {
+		if mergeUrl, commitSigningMethod, err := r.UndoPaCProvisionForComponent(ctx, &component); err != nil {
 			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
 				log.Error(err, "Pipelines as Code unprovision for the Component failed")
 				pacBuildStatus.State = "error"
@@ -449,11 +511,12 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			} else {
 				// transient error, retry
 				log.Error(err, "Pipelines as Code unprovision transient error")
-				return ctrl.Result{}, err
+				return getTransientErrorResult(err)
 			}
 		} else {
 			pacBuildStatus.State = "disabled"
 			pacBuildStatus.MergeUrl = mergeUrl
+			pacBuildStatus.CommitSigningMethod = commitSigningMethod
 			log.Info("Pipelines as Code unprovision for the Component finished successfully")
 		}
 
@@ -464,22 +527,47 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 
 		// Update build status annotation
-		buildStatus := readBuildStatus((&component))
 		buildStatus.PaC = pacBuildStatus
 		buildStatus.Message = "done"
 		writeBuildStatus(&component, buildStatus)
 
+	case BuildRequestPreviewAnnotationValue:
+		if err := r.PreviewPaCForComponent(ctx, &component); err != nil {
+			if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.IsPersistent() {
+				log.Error(err, "build preview for the Component failed")
+				buildStatus.Message = fmt.Sprintf("build preview failed: %s", boErr.ShortError())
+			} else {
+				// transient error, retry
+				log.Error(err, "build preview transient error")
+				return getTransientErrorResult(err)
+			}
+		} else {
+			buildStatus.Message = fmt.Sprintf("build preview is saved in %s ConfigMap", getBuildPreviewConfigMapName(&component))
+			log.Info("build preview for the Component finished successfully")
+		}
+
+		if err := r.Client.Get(ctx, req.NamespacedName, &component); err != nil {
+			log.Error(err, "failed to get Component", l.Action, l.ActionView)
+			return ctrl.Result{}, err
+		}
+
+		// Update build status annotation
+		writeBuildStatus(&component, buildStatus)
+
 	default:
 		if requestedAction == "" {
 			// Do not show error for empty annotation, consider it as noop.
 			return ctrl.Result{}, nil
 		}
 
-		buildStatus := readBuildStatus((&component))
 		buildStatus.Message = fmt.Sprintf("unexpected build request: %s", requestedAction)
 		writeBuildStatus(&component, buildStatus)
 	}
 
+	if err := r.writeComponentBuildStatus(ctx, &component, buildStatus); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	delete(component.Annotations, BuildRequestAnnotationName)
 
 	if err := r.Client.Update(ctx, &component); err != nil {
@@ -523,6 +611,16 @@ func (r *ComponentBuildReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	return ctrl.Result{}, nil
 }
 
+// getTransientErrorResult returns reconcile result to retry the operation failed with the given transient error.
+// If the error carries retry delay, e.g. a git provider rate limit is hit, the reconcile is requeued after the delay
+// instead of exponential backoff, which could retry too early.
+func getTransientErrorResult(err error) (ctrl.Result, error) {
+	if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return ctrl.Result{}, err
+}
+
 func readBuildStatus(component *appstudiov1alpha1.Component) *BuildStatus {
 	if component.Annotations == nil {
 		return &BuildStatus{}
@@ -44,6 +44,30 @@ var DevfileSearchForDockerfile = devfile.SearchForDockerfile
 
 // GetPipelineForComponent searches for the build pipeline to use on the component.
 func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*tektonapi.PipelineRef, []tektonapi.Param, error) {
+	pipelineRef, pipelineParams, _, err := r.getPipelineWithMatchForComponent(ctx, component)
+	return pipelineRef, pipelineParams, err
+}
+
+// getPipelineWithMatchForComponent searches for the build pipeline to use on the component
+// and returns the pipeline selector item which matched the component.
+func (r *ComponentBuildReconciler) getPipelineWithMatchForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*tektonapi.PipelineRef, []tektonapi.Param, *pipelineselector.MatchedSelector, error) {
+	selectionResult, err := r.evaluatePipelineSelectorsForComponent(ctx, component)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if selectionResult.PipelineRef == nil {
+		selectionTrace := pipelineselector.FormatSelectionTrace(selectionResult.Trace)
+		r.EventRecorder.Event(component, "Warning", "NoPipelineSelected", truncateEventMessage(selectionTrace))
+		return nil, nil, nil, boerrors.NewBuildOpError(boerrors.ENoPipelineIsSelected,
+			fmt.Errorf("no build pipeline matches the component: %s", selectionTrace))
+	}
+	return selectionResult.PipelineRef, selectionResult.PipelineParams, selectionResult.Matched, nil
+}
+
+// evaluatePipelineSelectorsForComponent evaluates BuildPipelineSelectors applicable to the given Component.
+// The selectors are merged according to their priority and mode, see pipelineselector.GetEffectivePipelineSelectors.
+// The selection trace contains all consulted BuildPipelineSelectors, including not existing ones.
+func (r *ComponentBuildReconciler) evaluatePipelineSelectorsForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (*pipelineselector.SelectionResult, error) {
 	var pipelineSelectors []buildappstudiov1alpha1.BuildPipelineSelector
 	pipelineSelector := &buildappstudiov1alpha1.BuildPipelineSelector{}
 
@@ -56,29 +80,45 @@ func (r *ComponentBuildReconciler) GetPipelineForComponent(ctx context.Context,
 		{Namespace: buildServiceNamespaceName, Name: buildPipelineSelectorResourceName},
 	}
 
+	var selectionTrace []buildappstudiov1alpha1.PipelineSelectorTrace
 	for _, pipelineSelectorKey := range pipelineSelectorKeys {
 		if err := r.Client.Get(ctx, pipelineSelectorKey, pipelineSelector); err != nil {
 			if !errors.IsNotFound(err) {
-				return nil, nil, err
+				return nil, err
 			}
 			// The config is not found, try the next one in the hierarchy
+			selectionTrace = append(selectionTrace, buildappstudiov1alpha1.PipelineSelectorTrace{
+				Namespace: pipelineSelectorKey.Namespace,
+				Name:      pipelineSelectorKey.Name,
+				NotFound:  true,
+			})
 		} else {
 			pipelineSelectors = append(pipelineSelectors, *pipelineSelector)
 		}
 	}
 
-	if len(pipelineSelectors) > 0 {
-		pipelineRef, pipelineParams, err := pipelineselector.SelectPipelineForComponent(component, pipelineSelectors)
-		if err != nil {
-			return nil, nil, err
-		}
-		if pipelineRef == nil {
-			return nil, nil, boerrors.NewBuildOpError(boerrors.ENoPipelineIsSelected, nil)
-		}
-		return pipelineRef, pipelineParams, nil
+	if len(pipelineSelectors) == 0 {
+		return nil, boerrors.NewBuildOpError(boerrors.EBuildPipelineSelectorNotDefined, nil)
+	}
+
+	selectionResult, err := pipelineselector.EvaluatePipelineSelectors(component, pipelineSelectors)
+	if err != nil {
+		return nil, err
 	}
+	// Not found selectors go first, followed by the evaluated items in the evaluation order
+	selectionTrace = append(selectionTrace, selectionResult.Trace...)
+	selectionResult.Trace = selectionTrace
+
+	return selectionResult, nil
+}
 
-	return nil, nil, boerrors.NewBuildOpError(boerrors.EBuildPipelineSelectorNotDefined, nil)
+// truncateEventMessage shortens the given message to fit into an Event.
+func truncateEventMessage(message string) string {
+	const maxEventMessageLength = 1024
+	if len(message) <= maxEventMessageLength {
+		return message
+	}
+	return message[:maxEventMessageLength-3] + "..."
 }
 
 func (r *ComponentBuildReconciler) ensurePipelineServiceAccount(ctx context.Context, namespace string) (*corev1.ServiceAccount, error) {
@@ -36,9 +36,11 @@ import (
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	"github.com/redhat-appstudio/application-service/pkg/devfile"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineselector "github.com/redhat-appstudio/build-service/pkg/pipeline-selector"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonapi_v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	oci "github.com/tektoncd/pipeline/pkg/remote/oci"
@@ -89,28 +91,33 @@ var GetHttpClientFunction = getHttpClient
 // ProvisionPaCForComponent does Pipelines as Code provision for the given component.
 // Mainly, it creates PaC configuration merge request into the component source repositotiry.
 // If GitHub PaC application is not configured, creates a webhook for PaC.
-func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (string, error) {
+// Returns the merge request URL and the method used to sign the merge request commits, if any.
+func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (string, string, error) {
 	log := ctrllog.FromContext(ctx).WithName("PaC-setup")
 	ctx = ctrllog.IntoContext(ctx, log)
 
 	log.Info("Starting Pipelines as Code provision for the Component")
 
-	gitProvider, err := gitops.GetGitProvider(*component)
+	gitProvider, err := getGitProvider(*component)
 	if err != nil {
 		// Do not reconcile, because configuration must be fixed before it is possible to proceed.
-		return "", boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
+		return "", "", boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
 			fmt.Errorf("error detecting git provider: %w", err))
 	}
+	if err := validatePaCGitProvider(gitProvider); err != nil {
+		// Do not reconcile, because the Component cannot be onboarded to Pipelines as Code.
+		return "", "", err
+	}
 
 	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if err := validatePaCConfiguration(gitProvider, pacSecret.Data); err != nil {
 		r.EventRecorder.Event(pacSecret, "Warning", "ErrorValidatingPaCSecret", err.Error())
 		// Do not reconcile, because configuration must be fixed before it is possible to proceed.
-		return "", boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+		return "", "", boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
 			fmt.Errorf("invalid configuration in Pipelines as Code secret: %w", err))
 	}
 
@@ -120,25 +127,25 @@ func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context,
 		// and stores it in the corresponding k8s secret.
 		webhookSecretString, err = r.ensureWebhookSecret(ctx, component)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 
 		// Obtain Pipelines as Code callback URL
 		webhookTargetUrl, err = r.getPaCWebhookTargetUrl(ctx)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 	}
 
 	if err := r.ensurePaCRepository(ctx, component, pacSecret.Data); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// Manage merge request for Pipelines as Code configuration
 	mrUrl, err := r.ConfigureRepositoryForPaC(ctx, component, pacSecret.Data, webhookTargetUrl, webhookSecretString)
 	if err != nil {
 		r.EventRecorder.Event(component, "Warning", "ErrorConfiguringPaCForComponentRepository", err.Error())
-		return "", err
+		return "", "", err
 	}
 	var mrMessage string
 	if mrUrl != "" {
@@ -154,7 +161,7 @@ func (r *ComponentBuildReconciler) ProvisionPaCForComponent(ctx context.Context,
 		pipelinesAsCodeComponentProvisionTimeMetric.Observe(time.Since(component.CreationTimestamp.Time).Seconds())
 	}
 
-	return mrUrl, nil
+	return mrUrl, commitsigning.GetSigningMethod(gitProvider, pacSecret.Data), nil
 }
 
 func getHttpClient() *http.Client { // #nosec G402 // dev instances need insecure, because they have self signed certificates
@@ -235,24 +242,7 @@ func (r *ComponentBuildReconciler) TriggerPaCBuild(ctx context.Context, componen
 	}
 
 	repoUrl := component.Spec.Source.GitSource.URL
-	gitProvider, err := gitops.GetGitProvider(*component)
-	if err != nil {
-		log.Error(err, "error detecting git provider")
-		// There is no point to continue if git provider is not known.
-		return false, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, err)
-	}
-
-	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
-	if err != nil {
-		return false, err
-	}
-
-	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
-		PacSecretData:             pacSecret.Data,
-		GitProvider:               gitProvider,
-		RepoUrl:                   repoUrl,
-		IsAppInstallationExpected: true,
-	})
+	gitClient, err := r.getGitClientForComponent(ctx, component)
 	if err != nil {
 		return false, err
 	}
@@ -260,7 +250,7 @@ func (r *ComponentBuildReconciler) TriggerPaCBuild(ctx context.Context, componen
 	// get target branch for incoming hook
 	targetBranch := component.Spec.Source.GitSource.Revision
 	if targetBranch == "" {
-		targetBranch, err = gitClient.GetDefaultBranch(repoUrl)
+		targetBranch, err = gitClient.GetDefaultBranch(ctx, repoUrl)
 		if err != nil {
 			return false, err
 		}
@@ -308,6 +298,49 @@ func (r *ComponentBuildReconciler) TriggerPaCBuild(ctx context.Context, componen
 	return false, nil
 }
 
+// getGitClientForComponent creates git provider client for the Component repository
+// using the Pipelines as Code secret.
+func (r *ComponentBuildReconciler) getGitClientForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (gp.GitProviderClientWithContext, error) {
+	_, gitClient, err := r.getPaCConfigAndGitClientForComponent(ctx, component)
+	return gitClient, err
+}
+
+// getPaCConfigAndGitClientForComponent returns Pipelines as Code secret data
+// and git provider client for the Component repository created using it.
+func (r *ComponentBuildReconciler) getPaCConfigAndGitClientForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (map[string][]byte, gp.GitProviderClientWithContext, error) {
+	log := ctrllog.FromContext(ctx)
+
+	repoUrl := component.Spec.Source.GitSource.URL
+	gitProvider, err := getGitProvider(*component)
+	if err != nil {
+		log.Error(err, "error detecting git provider")
+		// There is no point to continue if git provider is not known.
+		return nil, nil, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, err)
+	}
+
+	pacSecret, err := r.ensurePaCSecret(ctx, component, gitProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apiBaseUrl, err := getGitProviderApiUrl(repoUrl, pacSecret.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacSecret.Data,
+		GitProvider:               gitProvider,
+		RepoUrl:                   repoUrl,
+		IsAppInstallationExpected: true,
+		ApiBaseUrl:                apiBaseUrl,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pacSecret.Data, gitClient, nil
+}
+
 // cleanupPaCRepositoryIncomingsAndSecret is cleaning up incomings in Repository
 // for unprovisioned component, and also removes incoming secret when no longer required
 func (r *ComponentBuildReconciler) cleanupPaCRepositoryIncomingsAndSecret(ctx context.Context, component *appstudiov1alpha1.Component, baseBranch string) error {
@@ -407,24 +440,25 @@ func (r *ComponentBuildReconciler) cleanupPaCRepositoryIncomingsAndSecret(ctx co
 // UndoPaCProvisionForComponent creates merge request that removes Pipelines as Code configuration from component source repository.
 // Deletes PaC webhook if used.
 // In case of any errors just logs them and does not block Component deletion.
-func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (string, error) {
+// Returns the merge request URL and the method used to sign the merge request commits, if any.
+func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (string, string, error) {
 	log := ctrllog.FromContext(ctx).WithName("PaC-cleanup")
 	ctx = ctrllog.IntoContext(ctx, log)
 
 	log.Info("Starting Pipelines as Code unprovision for the Component")
 
-	gitProvider, err := gitops.GetGitProvider(*component)
+	gitProvider, err := getGitProvider(*component)
 	if err != nil {
 		log.Error(err, "error detecting git provider")
 		// There is no point to continue if git provider is not known.
-		return "", boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, err)
+		return "", "", boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, err)
 	}
 
 	pacSecret := corev1.Secret{}
 	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}, &pacSecret); err != nil {
 		log.Error(err, "error getting git provider credentials secret", l.Action, l.ActionView)
 		// Cannot continue without accessing git provider credentials.
-		return "", boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound, err)
+		return "", "", boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound, err)
 	}
 
 	webhookTargetUrl := ""
@@ -440,13 +474,13 @@ func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Cont
 	baseBranch, mrUrl, action, err := r.UnconfigureRepositoryForPaC(ctx, component, pacSecret.Data, webhookTargetUrl)
 	if err != nil {
 		log.Error(err, "failed to create merge request to remove Pipelines as Code configuration from Component source repository", l.Audit, "true")
-		return "", err
+		return "", "", err
 	}
 
 	err = r.cleanupPaCRepositoryIncomingsAndSecret(ctx, component, baseBranch)
 	if err != nil {
 		log.Error(err, "failed cleanup incomings from repo and incoming secret")
-		return "", err
+		return "", "", err
 	}
 
 	if action == "delete" {
@@ -457,8 +491,10 @@ func (r *ComponentBuildReconciler) UndoPaCProvisionForComponent(ctx context.Cont
 		}
 	} else if action == "close" {
 		log.Info(fmt.Sprintf("Pipelines as Code configuration merge request has been closed: %s", mrUrl))
+	} else if action == "update" {
+		log.Info(fmt.Sprintf("Component has been removed from Pipelines as Code configuration merge request: %s", mrUrl))
 	}
-	return mrUrl, nil
+	return mrUrl, commitsigning.GetSigningMethod(gitProvider, pacSecret.Data), nil
 }
 
 func (r *ComponentBuildReconciler) ensurePaCSecret(ctx context.Context, component *appstudiov1alpha1.Component, gitProvider string) (*corev1.Secret, error) {
@@ -607,6 +643,83 @@ func (r *ComponentBuildReconciler) getPaCRoutePublicUrl(ctx context.Context) (st
 	return "https://" + pacWebhookRoute.Spec.Host, nil
 }
 
+// getGitProvider returns git provider type of the component repository.
+// In addition to the git providers known to gitops, self-hosted Gitea (and compatible Forgejo)
+// is supported if set via the git provider annotation of the component.
+// Azure DevOps is detected by the repository host or set via the annotation for Azure DevOps Server.
+func getGitProvider(component appstudiov1alpha1.Component) (string, error) {
+	gitProvider, err := gitops.GetGitProvider(component)
+	if err == nil {
+		return gitProvider, nil
+	}
+
+	switch component.GetAnnotations()[gitops.GitProviderAnnotationName] {
+	case "gitea", "forgejo":
+		return "gitea", nil
+	case "azure-devops":
+		return "azure-devops", nil
+	}
+	if isAzureDevOpsRepositoryUrl(component.Spec.Source.GitSource.URL) {
+		return "azure-devops", nil
+	}
+	return "", err
+}
+
+// isAzureDevOpsRepositoryUrl checks whether the given repository is hosted by Azure DevOps Services.
+func isAzureDevOpsRepositoryUrl(gitURL string) bool {
+	gitProviderUrl, err := getGitProviderUrl(gitURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(gitProviderUrl, "https://"), "http://"))
+	return host == "dev.azure.com" || host == "ssh.dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com")
+}
+
+// validatePaCGitProvider checks that Pipelines as Code is able to handle repositories of the given git provider.
+// Azure DevOps git client is used for builds without Pipelines as Code only,
+// because the Pipelines as Code version used in the cluster has no Azure DevOps provider.
+func validatePaCGitProvider(gitProvider string) error {
+	if gitProvider == "azure-devops" {
+		return boerrors.NewBuildOpError(boerrors.EPaCGitProviderNotSupported,
+			fmt.Errorf("Pipelines as Code does not support %s git provider", gitProvider))
+	}
+	return nil
+}
+
+// generatePaCRepository creates configuration of Pipelines as Code repository object for the component.
+func generatePaCRepository(component appstudiov1alpha1.Component, config map[string][]byte) (*pacv1alpha1.Repository, error) {
+	gitProvider, err := getGitProvider(component)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePaCGitProvider(gitProvider); err != nil {
+		return nil, err
+	}
+	if gitProvider != "gitea" {
+		return gitops.GeneratePACRepository(component, config)
+	}
+
+	// gitops doesn't know Gitea, so generate the object as for a self-hosted GitLab,
+	// which is also webhook based, and then adjust the git provider settings.
+	gitProviderUrl, err := getGitProviderUrl(component.Spec.Source.GitSource.URL)
+	if err != nil {
+		return nil, err
+	}
+	componentCopy := component.DeepCopy()
+	if componentCopy.Annotations == nil {
+		componentCopy.Annotations = make(map[string]string)
+	}
+	componentCopy.Annotations[gitops.GitProviderAnnotationName] = "gitlab"
+	repository, err := gitops.GeneratePACRepository(*componentCopy, config)
+	if err != nil {
+		return nil, err
+	}
+	repository.Spec.GitProvider.URL = gitProviderUrl
+	repository.Spec.GitProvider.Type = gitProvider
+	repository.Spec.GitProvider.Secret.Key = gitops.GetProviderTokenKey(gitProvider)
+	return repository, nil
+}
+
 // validatePaCConfiguration detects checks that all required fields is set for whatever method is used.
 func validatePaCConfiguration(gitProvider string, config map[string][]byte) error {
 	isApp := gitops.IsPaCApplicationConfigured(gitProvider, config)
@@ -644,6 +757,21 @@ func validatePaCConfiguration(gitProvider string, config map[string][]byte) erro
 	case "gitlab":
 		err = checkMandatoryFieldsNotEmpty(config, expectedPaCWebhookConfigFields)
 
+	case "gitea":
+		err = checkMandatoryFieldsNotEmpty(config, expectedPaCWebhookConfigFields)
+
+	case "azure-devops":
+		err = checkMandatoryFieldsNotEmpty(config, expectedPaCWebhookConfigFields)
+		if err != nil {
+			break
+		}
+
+		// Personal access token is sent as basic authentication password
+		accessToken := strings.TrimSpace(string(config[gitops.GetProviderTokenKey(gitProvider)]))
+		if strings.ContainsAny(accessToken, " \t\r\n:") {
+			err = fmt.Errorf(" Pipelines as Code secret: Azure DevOps personal access token is malformed")
+		}
+
 	case "bitbucket":
 		err = checkMandatoryFieldsNotEmpty(config, []string{gitops.GetProviderTokenKey(gitProvider)})
 		if err != nil {
@@ -657,8 +785,51 @@ func validatePaCConfiguration(gitProvider string, config map[string][]byte) erro
 	default:
 		err = fmt.Errorf("unsupported git provider: %s", gitProvider)
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := validateCommitSigningConfiguration(gitProvider, config); err != nil {
+		return err
+	}
+	return validateForkConfiguration(gitProvider, config)
+}
+
+// validateForkConfiguration checks that merge requests from forks, if requested, are possible with the configuration.
+func validateForkConfiguration(gitProvider string, config map[string][]byte) error {
+	if !gp.IsForkModeEnabled(config) {
+		return nil
+	}
+	switch gitProvider {
+	case "github":
+		if gitops.IsPaCApplicationConfigured(gitProvider, config) {
+			return fmt.Errorf(" Pipelines as Code secret: merge requests from forks require a token, not GitHub application")
+		}
+	case "gitlab":
+		signingMethod := commitsigning.GetSigningMethod(gitProvider, config)
+		if signingMethod == commitsigning.MethodGPG || signingMethod == commitsigning.MethodSSH {
+			return fmt.Errorf(" Pipelines as Code secret: commit signing is not supported for merge requests from forks in GitLab")
+		}
+	default:
+		return fmt.Errorf(" Pipelines as Code secret: merge requests from forks are not supported for %s", gitProvider)
+	}
+	return nil
+}
+
+// validateCommitSigningConfiguration checks that the commit signing key, if any, is usable
+// and the git provider supports signed commits.
+func validateCommitSigningConfiguration(gitProvider string, config map[string][]byte) error {
+	signingMethod := commitsigning.GetSigningMethod(gitProvider, config)
+	if signingMethod != commitsigning.MethodGPG && signingMethod != commitsigning.MethodSSH {
+		return nil
+	}
+	if gitProvider != "github" && gitProvider != "gitlab" {
+		return fmt.Errorf(" Pipelines as Code secret: commit signing is not supported for %s", gitProvider)
+	}
+	if _, err := commitsigning.NewSignerFromSecret(config); err != nil {
+		return fmt.Errorf(" Pipelines as Code secret: invalid commit signing key: %w", err)
+	}
+	return nil
 }
 
 func checkMandatoryFieldsNotEmpty(config map[string][]byte, mandatoryFields []string) error {
@@ -702,7 +873,7 @@ func (r *ComponentBuildReconciler) ensurePaCRepository(ctx context.Context, comp
 	}
 
 	// This is the first Component that does PaC provision for the git repository
-	repository, err = gitops.GeneratePACRepository(*component, pacConfig)
+	repository, err = generatePaCRepository(*component, pacConfig)
 	if err != nil {
 		return err
 	}
@@ -745,7 +916,7 @@ func (r *ComponentBuildReconciler) findPaCRepositoryForComponent(ctx context.Con
 		return nil, err
 	}
 
-	gitUrl := strings.TrimSuffix(strings.TrimSuffix(component.Spec.Source.GitSource.URL, ".git"), "/")
+	gitUrl := getPaCRepositoryUrl(component.Spec.Source.GitSource.URL)
 	for _, pacRepository := range pacRepositoriesList.Items {
 		if pacRepository.Spec.URL == gitUrl {
 			return &pacRepository, nil
@@ -756,43 +927,66 @@ func (r *ComponentBuildReconciler) findPaCRepositoryForComponent(ctx context.Con
 
 // generatePaCPipelineRunConfigs generates PipelineRun YAML configs for given component.
 // The generated PipelineRun Yaml content are returned in byte string and in the order of push and pull request.
-func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClient, pacTargetBranch string) ([]byte, []byte, error) {
-	log := ctrllog.FromContext(ctx)
-
-	pipelineRef, additionalPipelineParams, err := r.GetPipelineForComponent(ctx, component)
+func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, pacTargetBranch string) ([]byte, []byte, error) {
+	pipelineRef, additionalPipelineParams, matchedSelector, err := r.getPipelineWithMatchForComponent(ctx, component)
 	if err != nil {
 		return nil, nil, err
 	}
-	pipelineName, pipelineBundle, err := getPipelineNameAndBundle(pipelineRef)
+	pipelineRef, pipelineBundleTag, err := pinPipelineBundleDigest(ctx, pipelineRef, matchedSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.generatePaCPipelineRunConfigsForPipeline(ctx, component, pipelineRef, matchedSelector, pipelineBundleTag, additionalPipelineParams, gitClient, pacTargetBranch)
+}
+
+// generatePaCPipelineRunConfigsForPipeline generates PipelineRun YAML configs for given component using given pipeline
+// selected by the matched selector. If the pipeline bundle is pinned to digest, the original bundle reference is recorded in the PipelineRuns.
+// The generated PipelineRun Yaml content are returned in byte string and in the order of push and pull request.
+func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigsForPipeline(ctx context.Context, component *appstudiov1alpha1.Component, pipelineRef *tektonapi.PipelineRef, matchedSelector *pipelineselector.MatchedSelector, pipelineBundleTag string, additionalPipelineParams []tektonapi.Param, gitClient gp.GitProviderClientWithContext, pacTargetBranch string) ([]byte, []byte, error) {
+	log := ctrllog.FromContext(ctx)
+
+	pipelineSource, err := newPipelineSource(pipelineRef, r.Client, component.Namespace, isBuildServiceSelector(matchedSelector))
 	if err != nil {
 		return nil, nil, err
 	}
-	log.Info(fmt.Sprintf("Selected %s pipeline from %s bundle for %s component",
-		pipelineName, pipelineBundle, component.Name),
+	log.Info(fmt.Sprintf("Selected %s pipeline from %s for %s component",
+		pipelineSource.GetPipelineName(), pipelineSource.GetLocation(), component.Name),
 		l.Audit, "true")
 
-	// Get pipeline from the bundle to be expanded to the PipelineRun
-	pipelineSpec, err := retrievePipelineSpec(ctx, pipelineBundle, pipelineName)
+	// Get pipeline from its source to be expanded to the PipelineRun
+	pipelineSpec, err := pipelineSource.GetPipelineSpec(ctx)
 	if err != nil {
 		r.EventRecorder.Event(component, "Warning", "ErrorGettingPipelineFromBundle", err.Error())
 		return nil, nil, err
 	}
 
+	pinnedBundleAnnotations := map[string]string{}
+	if pipelineBundleTag != "" {
+		pinnedBundleAnnotations[pipelineBundleAnnotationName] = getPipelineSourceBundle(pipelineSource)
+		pinnedBundleAnnotations[pipelineBundleTagAnnotationName] = pipelineBundleTag
+	}
+
 	pipelineRunOnPush, err := generatePaCPipelineRunForComponent(
-		component, pipelineSpec, additionalPipelineParams, false, pacTargetBranch, gitClient)
+		ctx, component, pipelineSpec, additionalPipelineParams, false, pacTargetBranch, gitClient)
 	if err != nil {
 		return nil, nil, err
 	}
+	for name, value := range pinnedBundleAnnotations {
+		pipelineRunOnPush.Annotations[name] = value
+	}
 	pipelineRunOnPushYaml, err := yaml.Marshal(pipelineRunOnPush)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	pipelineRunOnPR, err := generatePaCPipelineRunForComponent(
-		component, pipelineSpec, additionalPipelineParams, true, pacTargetBranch, gitClient)
+		ctx, component, pipelineSpec, additionalPipelineParams, true, pacTargetBranch, gitClient)
 	if err != nil {
 		return nil, nil, err
 	}
+	for name, value := range pinnedBundleAnnotations {
+		pipelineRunOnPR.Annotations[name] = value
+	}
 	pipelineRunOnPRYaml, err := yaml.Marshal(pipelineRunOnPR)
 	if err != nil {
 		return nil, nil, err
@@ -801,62 +995,138 @@ func (r *ComponentBuildReconciler) generatePaCPipelineRunConfigs(ctx context.Con
 	return pipelineRunOnPushYaml, pipelineRunOnPRYaml, nil
 }
 
-func generateMergeRequestSourceBranch(component *appstudiov1alpha1.Component) string {
-	return fmt.Sprintf("%s%s", pacMergeRequestSourceBranchPrefix, component.Name)
+// getPaCRepositoryUrl returns git repository URL in the form used in PaC Repository objects.
+func getPaCRepositoryUrl(gitUrl string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(gitUrl, ".git"), "/")
+}
+
+func generateMergeRequestSourceBranch(branchPrefix string, component *appstudiov1alpha1.Component) string {
+	return fmt.Sprintf("%s%s", branchPrefix, component.Name)
 }
 
 // ConfigureRepositoryForPaC creates a merge request with initial Pipelines as Code configuration
 // and configures a webhook to notify in-cluster PaC unless application (on the repository side) is used.
+// If direct commit is requested for the Component, the configuration is committed into the base branch instead,
+// unless the branch doesn't accept direct pushes.
 func (r *ComponentBuildReconciler) ConfigureRepositoryForPaC(ctx context.Context, component *appstudiov1alpha1.Component, pacConfig map[string][]byte, webhookTargetUrl, webhookSecret string) (prUrl string, err error) {
 	log := ctrllog.FromContext(ctx).WithValues("repository", component.Spec.Source.GitSource.URL)
 	ctx = ctrllog.IntoContext(ctx, log)
 
-	gitProvider, _ := gitops.GetGitProvider(*component)
+	gitProvider, _ := getGitProvider(*component)
 	repoUrl := component.Spec.Source.GitSource.URL
 
-	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+	apiBaseUrl, err := getGitProviderApiUrl(repoUrl, pacConfig)
+	if err != nil {
+		return "", err
+	}
+
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
 		PacSecretData:             pacConfig,
 		GitProvider:               gitProvider,
 		RepoUrl:                   repoUrl,
 		IsAppInstallationExpected: true,
+		ApiBaseUrl:                apiBaseUrl,
 	})
 	if err != nil {
 		return "", err
 	}
 
-	baseBranch := component.Spec.Source.GitSource.Revision
-	if baseBranch == "" {
-		baseBranch, err = gitClient.GetDefaultBranch(repoUrl)
+	mrData, generatedConfiguration, err := r.generatePaCMergeRequestData(ctx, component, gitClient, pacConfig)
+	if err != nil {
+		return "", err
+	}
+
+	if !gitops.IsPaCApplicationConfigured(gitProvider, pacConfig) {
+		// Webhook
+		if err := gitClient.SetupPaCWebhook(ctx, repoUrl, webhookTargetUrl, webhookSecret); err != nil {
+			log.Error(err, fmt.Sprintf("failed to setup Pipelines as Code webhook %s", webhookTargetUrl), l.Audit, "true")
+			return "", err
+		} else {
+			log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" configured for %s Component in %s namespace",
+				webhookTargetUrl, component.GetName(), component.GetNamespace()),
+				l.Audit, "true")
+		}
+	}
+
+	isDirectCommitAllowed, err := r.isPaCDirectCommitAllowed(ctx, component)
+	if err != nil {
+		return "", err
+	}
+	if isDirectCommitAllowed {
+		isCommitted, err := r.commitPaCConfigurationDirectly(ctx, component, gitClient, mrData, gitClient.CommitPaCConfiguration)
 		if err != nil {
 			return "", err
 		}
+		if isCommitted {
+			// No merge request is needed, the configuration is in the base branch
+			return "", r.saveGeneratedPaCConfigurations(ctx, generatedConfiguration)
+		}
 	}
 
-	pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, component, gitClient, baseBranch)
+	mrUrl, err := gitClient.EnsurePaCMergeRequest(ctx, repoUrl, mrData)
 	if err != nil {
 		return "", err
 	}
+	return mrUrl, r.saveGeneratedPaCConfigurations(ctx, generatedConfiguration)
+}
 
-	mrData := &gp.MergeRequestData{
-		CommitMessage:  "Appstudio update " + component.Name,
-		BranchName:     generateMergeRequestSourceBranch(component),
-		BaseBranchName: baseBranch,
-		Title:          "Appstudio update " + component.Name,
-		Text:           mergeRequestDescription,
-		AuthorName:     "redhat-appstudio",
-		AuthorEmail:    "rhtap@redhat.com",
-		Files: []gp.RepositoryFile{
-			{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename, Content: pipelineRunOnPushYaml},
-			{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename, Content: pipelineRunOnPRYaml},
-		},
+// generatePaCMergeRequestData returns data of the merge request which proposes Pipelines as Code configuration
+// of the given Component, or of all not yet onboarded Components from its git repository in batch mode.
+// Customizations of the configuration made in the repository are kept in the proposed files.
+// Also returns the files as generated, before merge with the customizations, together with the Components they belong to.
+func (r *ComponentBuildReconciler) generatePaCMergeRequestData(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, pacConfig map[string][]byte) (*gp.MergeRequestData, *generatedPaCConfiguration, error) {
+	log := ctrllog.FromContext(ctx)
+
+	gitProvider, _ := getGitProvider(*component)
+	repoUrl := component.Spec.Source.GitSource.URL
+
+	var err error
+	baseBranch := component.Spec.Source.GitSource.Revision
+	if baseBranch == "" {
+		baseBranch, err = gitClient.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	isAppUsed := gitops.IsPaCApplicationConfigured(gitProvider, pacConfig)
-	if isAppUsed {
+	pacRepository, err := r.findPaCRepositoryForComponent(ctx, component)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mrTemplates, err := r.getMergeRequestTemplates(ctx, component.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mrData *gp.MergeRequestData
+	mrComponents := []appstudiov1alpha1.Component{*component}
+	if pacRepository != nil && isPaCBatchModeEnabled(pacRepository) {
+		// Combine configuration of all not yet onboarded Components from the git repository into single merge request
+		batchComponents, err := r.getPaCBatchComponents(ctx, component, gitClient, baseBranch)
+		if err != nil {
+			return nil, nil, err
+		}
+		batchComponents = append(batchComponents, *component)
+		mrData, err = r.generatePaCBatchMergeRequestData(ctx, batchComponents, pacRepository, gitClient, baseBranch, mrTemplates.getBranchPrefix())
+		if err != nil {
+			return nil, nil, err
+		}
+		mrComponents = batchComponents
+	} else {
+		pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, component, gitClient, baseBranch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mrData = newPaCMergeRequestData(component, baseBranch, mrTemplates.getBranchPrefix(), pipelineRunOnPushYaml, pipelineRunOnPRYaml)
+	}
+
+	if gitops.IsPaCApplicationConfigured(gitProvider, pacConfig) {
 		// Customize PR data to reflect git application name
-		if appName, appSlug, err := gitClient.GetConfiguredGitAppName(); err == nil {
-			mrData.CommitMessage = fmt.Sprintf("%s update %s", appName, component.Name)
-			mrData.Title = fmt.Sprintf("%s update %s", appName, component.Name)
+		if appName, appSlug, err := gitClient.GetConfiguredGitAppName(ctx); err == nil {
+			mrData.CommitMessage = strings.Replace(mrData.CommitMessage, "Appstudio", appName, 1)
+			mrData.Title = strings.Replace(mrData.Title, "Appstudio", appName, 1)
 			mrData.AuthorName = appSlug
 		} else {
 			if gitProvider == "github" {
@@ -864,36 +1134,60 @@ func (r *ComponentBuildReconciler) ConfigureRepositoryForPaC(ctx context.Context
 				// Do not fail PaC provision if failed to read GitHub App info
 			}
 		}
-	} else {
-		// Webhook
-		if err := gitClient.SetupPaCWebhook(repoUrl, webhookTargetUrl, webhookSecret); err != nil {
-			log.Error(err, fmt.Sprintf("failed to setup Pipelines as Code webhook %s", webhookTargetUrl), l.Audit, "true")
-			return "", err
-		} else {
-			log.Info(fmt.Sprintf("Pipelines as Code webhook \"%s\" configured for %s Component in %s namespace",
-				webhookTargetUrl, component.GetName(), component.GetNamespace()),
-				l.Audit, "true")
-		}
 	}
 
-	return gitClient.EnsurePaCMergeRequest(repoUrl, mrData)
+	if err := r.customizePaCMergeRequest(ctx, mrTemplates, mrData, component, getComponentNames(mrComponents)); err != nil {
+		return nil, nil, err
+	}
+
+	generatedFiles := make([]gp.RepositoryFile, len(mrData.Files))
+	copy(generatedFiles, mrData.Files)
+	if err := r.mergePaCConfigurationCustomizations(ctx, gitClient, repoUrl, mrComponents, mrData); err != nil {
+		return nil, nil, err
+	}
+	return mrData, &generatedPaCConfiguration{components: mrComponents, files: generatedFiles}, nil
+}
+
+// newPaCMergeRequestData returns data of the merge request which proposes the given Pipelines as Code configuration
+// of the single Component.
+func newPaCMergeRequestData(component *appstudiov1alpha1.Component, baseBranch, branchPrefix string, pipelineRunOnPushYaml, pipelineRunOnPRYaml []byte) *gp.MergeRequestData {
+	return &gp.MergeRequestData{
+		CommitMessage:  "Appstudio update " + component.Name,
+		BranchName:     generateMergeRequestSourceBranch(branchPrefix, component),
+		BaseBranchName: baseBranch,
+		Title:          "Appstudio update " + component.Name,
+		Text:           mergeRequestDescription,
+		AuthorName:     "redhat-appstudio",
+		AuthorEmail:    "rhtap@redhat.com",
+		Files: []gp.RepositoryFile{
+			{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPushFilename, Content: pipelineRunOnPushYaml},
+			{FullPath: ".tekton/" + component.Name + "-" + pipelineRunOnPRFilename, Content: pipelineRunOnPRYaml},
+		},
+	}
 }
 
 // UnconfigureRepositoryForPaC creates a merge request that deletes Pipelines as Code configuration of the diven component in its repository.
+// If direct commit is requested for the Component, the configuration is deleted from the base branch without a merge request when possible.
 // Deletes PaC webhook if it's used.
 // Does not delete PaC GitHub application from the repository as its installation was done manually by the user.
 // Returns merge request web URL or empty string if it's not needed.
 func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Context, component *appstudiov1alpha1.Component, pacConfig map[string][]byte, webhookTargetUrl string) (baseBranch string, prUrl string, action string, err error) {
 	log := ctrllog.FromContext(ctx)
 
-	gitProvider, _ := gitops.GetGitProvider(*component)
+	gitProvider, _ := getGitProvider(*component)
 	repoUrl := component.Spec.Source.GitSource.URL
 
-	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+	apiBaseUrl, err := getGitProviderApiUrl(repoUrl, pacConfig)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
 		PacSecretData:             pacConfig,
 		GitProvider:               gitProvider,
 		RepoUrl:                   repoUrl,
 		IsAppInstallationExpected: true,
+		ApiBaseUrl:                apiBaseUrl,
 	})
 	if err != nil {
 		return "", "", "", err
@@ -902,7 +1196,7 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 	isAppUsed := gitops.IsPaCApplicationConfigured(gitProvider, pacConfig)
 	if !isAppUsed {
 		if webhookTargetUrl != "" {
-			err = gitClient.DeletePaCWebhook(repoUrl, webhookTargetUrl)
+			err = gitClient.DeletePaCWebhook(ctx, repoUrl, webhookTargetUrl)
 			if err != nil {
 				// Just log the error and continue with merge request creation
 				log.Error(err, fmt.Sprintf("failed to delete Pipelines as Code webhook %s", webhookTargetUrl), l.Action, l.ActionDelete, l.Audit, "true")
@@ -914,22 +1208,45 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 		}
 	}
 
-	sourceBranch := generateMergeRequestSourceBranch(component)
 	baseBranch = component.Spec.Source.GitSource.Revision
 	if baseBranch == "" {
-		baseBranch, err = gitClient.GetDefaultBranch(repoUrl)
+		baseBranch, err = gitClient.GetDefaultBranch(ctx, repoUrl)
 		if err != nil {
 			return "", "", "", nil
 		}
 	}
 
+	mrTemplates, err := r.getMergeRequestTemplates(ctx, component.Namespace)
+	if err != nil {
+		return baseBranch, "", "", err
+	}
+	sourceBranch := generateMergeRequestSourceBranch(mrTemplates.getBranchPrefix(), component)
+
+	pacRepository, err := r.findPaCRepositoryForComponent(ctx, component)
+	if err != nil {
+		return baseBranch, "", "", err
+	}
+	if pacRepository != nil && isPaCBatchModeEnabled(pacRepository) {
+		prUrl, isInBatch, err := r.removeComponentFromPaCBatchMergeRequest(ctx, component, pacRepository, gitClient, baseBranch, mrTemplates)
+		if err != nil {
+			return baseBranch, "", "", err
+		}
+		if isInBatch {
+			if prUrl == "" {
+				return baseBranch, "", "close", nil
+			}
+			return baseBranch, prUrl, "update", nil
+		}
+		// The Component configuration is not in the batch merge request, so it might be merged already
+	}
+
 	mrData := &gp.MergeRequestData{
 		BranchName:     sourceBranch,
 		BaseBranchName: baseBranch,
 		AuthorName:     "redhat-appstudio",
 	}
 
-	mergeRequest, err := gitClient.FindUnmergedPaCMergeRequest(repoUrl, mrData)
+	mergeRequest, err := gitClient.FindUnmergedPaCMergeRequest(ctx, repoUrl, mrData)
 	if err != nil {
 		return baseBranch, "", "", err
 	}
@@ -938,7 +1255,7 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 		// Create new PaC configuration clean up merge request
 		mrData = &gp.MergeRequestData{
 			CommitMessage:  "Appstudio purge " + component.Name,
-			BranchName:     "appstudio-purge-" + component.Name,
+			BranchName:     mrTemplates.getBranchPrefix() + "purge-" + component.Name,
 			BaseBranchName: baseBranch,
 			Title:          "Appstudio purge " + component.Name,
 			Text:           "Pipelines as Code configuration removal",
@@ -952,7 +1269,7 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 
 		if isAppUsed {
 			// Customize PR data to reflect git application name
-			if appName, appSlug, err := gitClient.GetConfiguredGitAppName(); err == nil {
+			if appName, appSlug, err := gitClient.GetConfiguredGitAppName(ctx); err == nil {
 				mrData.CommitMessage = fmt.Sprintf("%s purge %s", appName, component.Name)
 				mrData.Title = fmt.Sprintf("%s purge %s", appName, component.Name)
 				mrData.AuthorName = appSlug
@@ -964,7 +1281,21 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 			}
 		}
 
-		prUrl, err = gitClient.UndoPaCMergeRequest(repoUrl, mrData)
+		if err := customizePaCPurgeMergeRequest(mrTemplates, mrData, component); err != nil {
+			return baseBranch, "", "", err
+		}
+
+		if isPaCDirectCommitEnabled(component) {
+			isCommitted, err := r.commitPaCConfigurationDirectly(ctx, component, gitClient, mrData, gitClient.CommitPaCConfigurationRemoval)
+			if err != nil {
+				return baseBranch, "", "", err
+			}
+			if isCommitted {
+				return baseBranch, "", "delete", nil
+			}
+		}
+
+		prUrl, err = gitClient.UndoPaCMergeRequest(ctx, repoUrl, mrData)
 		return baseBranch, prUrl, "delete", err
 	} else {
 		// Close merge request.
@@ -972,7 +1303,7 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 
 		// Non-existing source branch should not be an error, just ignore it,
 		// but other errors should be handled.
-		if _, err := gitClient.DeleteBranch(repoUrl, sourceBranch); err != nil {
+		if _, err := gitClient.DeleteBranch(ctx, repoUrl, sourceBranch); err != nil {
 			return baseBranch, "", "", err
 		}
 		log.Info(fmt.Sprintf("pull request source branch %s is deleted", sourceBranch), l.Action, l.ActionDelete)
@@ -983,17 +1314,18 @@ func (r *ComponentBuildReconciler) UnconfigureRepositoryForPaC(ctx context.Conte
 // generatePaCPipelineRunForComponent returns pipeline run definition to build component source with.
 // Generated pipeline run contains placeholders that are expanded by Pipeline-as-Code.
 func generatePaCPipelineRunForComponent(
+	ctx context.Context,
 	component *appstudiov1alpha1.Component,
 	pipelineSpec *tektonapi.PipelineSpec,
 	additionalPipelineParams []tektonapi.Param,
 	onPull bool,
 	pacTargetBranch string,
-	gitClient gp.GitProviderClient) (*tektonapi.PipelineRun, error) {
+	gitClient gp.GitProviderClientWithContext) (*tektonapi.PipelineRun, error) {
 
 	if pacTargetBranch == "" {
 		return nil, fmt.Errorf("target branch can't be empty for generating PaC PipelineRun for: %v", component)
 	}
-	pipelineCelExpression, err := generateCelExpressionForPipeline(component, gitClient, pacTargetBranch, onPull)
+	pipelineCelExpression, err := generateCelExpressionForPipeline(ctx, component, gitClient, pacTargetBranch, onPull)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate cel expression for pipeline: %w", err)
 	}
@@ -1082,7 +1414,7 @@ func generatePaCPipelineRunForComponent(
 // Examples of returned values:
 // event == "push" && target_branch == "main"
 // event == "pull_request" && target_branch == "my-branch" && ( "component-src-dir/***".pathChanged() || "dockerfiles/my-component/Dockerfile".pathChanged() )
-func generateCelExpressionForPipeline(component *appstudiov1alpha1.Component, gitClient gp.GitProviderClient, targetBranch string, onPull bool) (string, error) {
+func generateCelExpressionForPipeline(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, targetBranch string, onPull bool) (string, error) {
 	eventType := "push"
 	if onPull {
 		eventType = "pull_request"
@@ -1112,7 +1444,7 @@ func generateCelExpressionForPipeline(component *appstudiov1alpha1.Component, gi
 				repoUrl := component.Spec.Source.GitSource.URL
 				branch := component.Spec.Source.GitSource.Revision
 				dockerfilePath := contextDir + dockerfile.Uri
-				isDockerfileInContextDir, err := gitClient.IsFileExist(repoUrl, branch, dockerfilePath)
+				isDockerfileInContextDir, err := gitClient.IsFileExist(ctx, repoUrl, branch, dockerfilePath)
 				if err != nil {
 					return "", err
 				}
@@ -1154,7 +1486,13 @@ func createWorkspaceBinding(pipelineWorkspaces []tektonapi.PipelineWorkspaceDecl
 }
 
 // retrievePipelineSpec retrieves pipeline definition with given name from the given bundle.
+// Already retrieved pipelines are served from the cache.
 func retrievePipelineSpec(ctx context.Context, bundleUri, pipelineName string) (*tektonapi.PipelineSpec, error) {
+	return bundlePipelineSpecCache.Get(ctx, bundleUri, pipelineName)
+}
+
+// pullPipelineSpec pulls the given bundle and extracts the pipeline definition with given name from it.
+func pullPipelineSpec(ctx context.Context, bundleUri, pipelineName string) (*tektonapi.PipelineSpec, error) {
 	log := ctrllog.FromContext(ctx)
 
 	var obj runtime.Object
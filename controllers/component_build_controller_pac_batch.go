@@ -0,0 +1,210 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	pacv1alpha1 "github.com/openshift-pipelines/pipelines-as-code/pkg/apis/pipelinesascode/v1alpha1"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// PaCBatchMergeRequestsEnvName enables combining Pipelines as Code configuration of all Components
+	// which share the same git repository into single merge request.
+	PaCBatchMergeRequestsEnvName = "PAC_BATCH_MERGE_REQUESTS"
+	// PaCBatchMergeRequestsAnnotationName on a PaC Repository object overrides the global setting for the repository.
+	PaCBatchMergeRequestsAnnotationName = "build.appstudio.redhat.com/batch-merge-requests"
+)
+
+// isPaCBatchModeEnabled checks whether Pipelines as Code configuration of Components
+// from the git repository of the given PaC Repository should be proposed in single merge request.
+func isPaCBatchModeEnabled(pacRepository *pacv1alpha1.Repository) bool {
+	if value, exists := pacRepository.Annotations[PaCBatchMergeRequestsAnnotationName]; exists {
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv(PaCBatchMergeRequestsEnvName))
+	return enabled
+}
+
+func generateBatchMergeRequestSourceBranch(branchPrefix string, pacRepository *pacv1alpha1.Repository, baseBranch string) string {
+	return fmt.Sprintf("%sbatch-%s-%s", branchPrefix, pacRepository.Name, baseBranch)
+}
+
+func getComponentNames(components []appstudiov1alpha1.Component) []string {
+	names := make([]string, 0, len(components))
+	for i := range components {
+		names = append(names, components[i].Name)
+	}
+	return names
+}
+
+// getPaCBatchComponents returns Components which share the git repository and the base branch with the given Component,
+// have Pipelines as Code provisioned, but their configuration is not merged into the base branch yet.
+// The given Component itself is not included.
+func (r *ComponentBuildReconciler) getPaCBatchComponents(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, baseBranch string) ([]appstudiov1alpha1.Component, error) {
+	log := ctrllog.FromContext(ctx)
+
+	componentList := &appstudiov1alpha1.ComponentList{}
+	if err := r.Client.List(ctx, componentList, &client.ListOptions{Namespace: component.Namespace}); err != nil {
+		log.Error(err, "failed to list Components", l.Action, l.ActionView)
+		return nil, err
+	}
+
+	repoUrl := component.Spec.Source.GitSource.URL
+	defaultBranch := ""
+	var batchComponents []appstudiov1alpha1.Component
+	for _, otherComponent := range componentList.Items {
+		if otherComponent.Name == component.Name || otherComponent.DeletionTimestamp != nil {
+			continue
+		}
+		if otherComponent.Spec.Source.GitSource == nil || getPaCRepositoryUrl(otherComponent.Spec.Source.GitSource.URL) != getPaCRepositoryUrl(repoUrl) {
+			continue
+		}
+		if pacBuildStatus := readBuildStatus(&otherComponent).PaC; pacBuildStatus == nil || pacBuildStatus.State != "enabled" {
+			continue
+		}
+
+		otherBaseBranch := otherComponent.Spec.Source.GitSource.Revision
+		if otherBaseBranch == "" {
+			if defaultBranch == "" {
+				var err error
+				if defaultBranch, err = gitClient.GetDefaultBranch(ctx, repoUrl); err != nil {
+					return nil, err
+				}
+			}
+			otherBaseBranch = defaultBranch
+		}
+		if otherBaseBranch != baseBranch {
+			continue
+		}
+
+		// Do not override customizations of already onboarded Components
+		isMerged, err := gitClient.IsFileExist(ctx, repoUrl, baseBranch, ".tekton/"+otherComponent.Name+"-"+pipelineRunOnPushFilename)
+		if err != nil {
+			return nil, err
+		}
+		if isMerged {
+			continue
+		}
+
+		batchComponents = append(batchComponents, otherComponent)
+	}
+	return batchComponents, nil
+}
+
+// generatePaCBatchMergeRequestData returns data of the merge request which proposes Pipelines as Code configuration
+// of all the given Components from the same git repository.
+func (r *ComponentBuildReconciler) generatePaCBatchMergeRequestData(ctx context.Context, components []appstudiov1alpha1.Component, pacRepository *pacv1alpha1.Repository, gitClient gp.GitProviderClientWithContext, baseBranch, branchPrefix string) (*gp.MergeRequestData, error) {
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+
+	files := make([]gp.RepositoryFile, 0, 2*len(components))
+	for i := range components {
+		pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigs(ctx, &components[i], gitClient, baseBranch)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			gp.RepositoryFile{FullPath: ".tekton/" + components[i].Name + "-" + pipelineRunOnPushFilename, Content: pipelineRunOnPushYaml},
+			gp.RepositoryFile{FullPath: ".tekton/" + components[i].Name + "-" + pipelineRunOnPRFilename, Content: pipelineRunOnPRYaml},
+		)
+	}
+
+	return &gp.MergeRequestData{
+		CommitMessage:  "Appstudio update " + strings.Join(getComponentNames(components), ", "),
+		BranchName:     generateBatchMergeRequestSourceBranch(branchPrefix, pacRepository, baseBranch),
+		BaseBranchName: baseBranch,
+		Title:          "Appstudio update " + pacRepository.Spec.URL,
+		Text:           mergeRequestDescription,
+		AuthorName:     "redhat-appstudio",
+		AuthorEmail:    "rhtap@redhat.com",
+		Files:          files,
+	}, nil
+}
+
+// removeComponentFromPaCBatchMergeRequest removes Pipelines as Code configuration of the given Component
+// from the not merged batch merge request of its git repository.
+// The merge request branch is reset to the base branch with configuration of the rest of the Components,
+// so the merge request is kept, unless no Components left in the batch.
+// Returns the merge request web URL, which is empty if no Components left in the batch,
+// and false if the Component configuration is not a part of the batch merge request.
+func (r *ComponentBuildReconciler) removeComponentFromPaCBatchMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component, pacRepository *pacv1alpha1.Repository, gitClient gp.GitProviderClientWithContext, baseBranch string, mrTemplates mergeRequestTemplates) (string, bool, error) {
+	log := ctrllog.FromContext(ctx)
+
+	repoUrl := component.Spec.Source.GitSource.URL
+	sourceBranch := generateBatchMergeRequestSourceBranch(mrTemplates.getBranchPrefix(), pacRepository, baseBranch)
+
+	mergeRequest, err := gitClient.FindUnmergedPaCMergeRequest(ctx, repoUrl, &gp.MergeRequestData{BranchName: sourceBranch, BaseBranchName: baseBranch, AuthorName: "redhat-appstudio"})
+	if err != nil {
+		return "", false, err
+	}
+	if mergeRequest == nil {
+		return "", false, nil
+	}
+	isInBatch, err := gitClient.IsFileExist(ctx, repoUrl, sourceBranch, ".tekton/"+component.Name+"-"+pipelineRunOnPushFilename)
+	if err != nil {
+		return "", false, err
+	}
+	if !isInBatch {
+		return "", false, nil
+	}
+
+	batchComponents, err := r.getPaCBatchComponents(ctx, component, gitClient, baseBranch)
+	if err != nil {
+		return "", true, err
+	}
+
+	if len(batchComponents) == 0 {
+		// Deleting the branch closes the merge request
+		if _, err := gitClient.DeleteBranch(ctx, repoUrl, sourceBranch); err != nil {
+			return "", true, err
+		}
+		log.Info(fmt.Sprintf("batch pull request source branch %s is deleted", sourceBranch), l.Action, l.ActionDelete)
+		return "", true, nil
+	}
+
+	mrData, err := r.generatePaCBatchMergeRequestData(ctx, batchComponents, pacRepository, gitClient, baseBranch, mrTemplates.getBranchPrefix())
+	if err != nil {
+		return "", true, err
+	}
+	if appName, appSlug, err := gitClient.GetConfiguredGitAppName(ctx); err == nil {
+		mrData.CommitMessage = strings.Replace(mrData.CommitMessage, "Appstudio", appName, 1)
+		mrData.Title = strings.Replace(mrData.Title, "Appstudio", appName, 1)
+		mrData.AuthorName = appSlug
+	}
+	// The remaining Components are sorted by name, so the merge request is customized on behalf of the first one
+	if err := r.customizePaCMergeRequest(ctx, mrTemplates, mrData, &batchComponents[0], getComponentNames(batchComponents)); err != nil {
+		return "", true, err
+	}
+	mrUrl, err := gitClient.ResetPaCMergeRequest(ctx, repoUrl, mrData)
+	if err != nil {
+		return "", true, err
+	}
+	log.Info(fmt.Sprintf("batch pull request source branch %s is reset", sourceBranch), "MergeUrl", mrUrl, l.Action, l.ActionUpdate)
+	return mrUrl, true, nil
+}
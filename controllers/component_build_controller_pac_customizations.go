@@ -0,0 +1,412 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// PaCGeneratedConfigurationHashAnnotationName on a Component holds checksum of Pipelines as Code configuration files
+	// last proposed for the Component. The files are kept in a ConfigMap owned by the Component.
+	// They are the common ancestor which is used to tell user customizations from changes of the generated configuration.
+	PaCGeneratedConfigurationHashAnnotationName = "build.appstudio.openshift.io/pac-generated-configuration-hash"
+
+	pacGeneratedConfigurationConfigMapNameSuffix = "-pac-generated-configuration"
+
+	mergeRequestConflictsDescription = `
+## Conflicts with customizations
+
+The following generated values conflict with customizations of the Pipelines as Code configuration in the repository, so the customized values are kept:
+`
+)
+
+// getPaCConfigurationFilePaths returns paths of Pipelines as Code configuration files of the given Component in its repository.
+func getPaCConfigurationFilePaths(componentName string) []string {
+	return []string{
+		".tekton/" + componentName + "-" + pipelineRunOnPushFilename,
+		".tekton/" + componentName + "-" + pipelineRunOnPRFilename,
+	}
+}
+
+// getPaCGeneratedConfigurationConfigMapName returns name of the ConfigMap with Pipelines as Code configuration
+// last proposed for the given Component.
+func getPaCGeneratedConfigurationConfigMapName(component *appstudiov1alpha1.Component) string {
+	return component.Name + pacGeneratedConfigurationConfigMapNameSuffix
+}
+
+// hashGeneratedPaCConfiguration returns checksum of the given generated configuration ConfigMap data.
+func hashGeneratedPaCConfiguration(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(data[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// readGeneratedPaCConfiguration returns Pipelines as Code configuration files last proposed for the given Component.
+// The files are read from the ConfigMap owned by the Component, unless its content doesn't match the checksum
+// in the Component annotation, for example, because saving of the ConfigMap or the annotation failed.
+func (r *ComponentBuildReconciler) readGeneratedPaCConfiguration(ctx context.Context, component *appstudiov1alpha1.Component) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	configurationHash := component.Annotations[PaCGeneratedConfigurationHashAnnotationName]
+	if configurationHash == "" {
+		return files, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Namespace: component.Namespace, Name: getPaCGeneratedConfigurationConfigMapName(component)}
+	if err := r.Client.Get(ctx, configMapKey, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return files, nil
+		}
+		return nil, err
+	}
+	if hashGeneratedPaCConfiguration(configMap.Data) != configurationHash {
+		return files, nil
+	}
+
+	// ConfigMap keys cannot contain slashes, so the files are stored under their names
+	for _, filePath := range getPaCConfigurationFilePaths(component.Name) {
+		if content, exists := configMap.Data[path.Base(filePath)]; exists {
+			files[filePath] = []byte(content)
+		}
+	}
+	return files, nil
+}
+
+// saveGeneratedPaCConfiguration remembers Pipelines as Code configuration files which were just proposed for the Component,
+// so user customizations of them could be preserved next time the configuration is generated.
+// The files are stored in a ConfigMap owned by the Component, the Component annotation holds only their checksum.
+func (r *ComponentBuildReconciler) saveGeneratedPaCConfiguration(ctx context.Context, component *appstudiov1alpha1.Component, generatedFiles []gp.RepositoryFile) error {
+	log := ctrllog.FromContext(ctx)
+
+	data := map[string]string{}
+	for _, filePath := range getPaCConfigurationFilePaths(component.Name) {
+		for _, file := range generatedFiles {
+			if file.FullPath == filePath {
+				data[path.Base(filePath)] = string(file.Content)
+			}
+		}
+	}
+
+	latestComponent := &appstudiov1alpha1.Component{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, latestComponent); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Namespace: component.Namespace, Name: getPaCGeneratedConfigurationConfigMapName(component)}
+	if err := r.Client.Get(ctx, configMapKey, configMap); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get generated Pipelines as Code configuration ConfigMap", l.Action, l.ActionView)
+			return err
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapKey.Name,
+				Namespace: configMapKey.Namespace,
+				Labels: map[string]string{
+					ComponentNameLabelName: component.Name,
+					PartOfLabelName:        PartOfAppStudioLabelValue,
+				},
+			},
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(latestComponent, configMap, r.Scheme); err != nil {
+			log.Error(err, "failed to set owner for generated Pipelines as Code configuration ConfigMap", l.Action, l.ActionAdd)
+			return err
+		}
+		if err := r.Client.Create(ctx, configMap); err != nil {
+			log.Error(err, "failed to create generated Pipelines as Code configuration ConfigMap", l.Action, l.ActionAdd)
+			return err
+		}
+	} else {
+		configMap.Data = data
+		if err := r.Client.Update(ctx, configMap); err != nil {
+			log.Error(err, "failed to update generated Pipelines as Code configuration ConfigMap", l.Action, l.ActionUpdate)
+			return err
+		}
+	}
+
+	if latestComponent.Annotations == nil {
+		latestComponent.Annotations = make(map[string]string)
+	}
+	latestComponent.Annotations[PaCGeneratedConfigurationHashAnnotationName] = hashGeneratedPaCConfiguration(data)
+	if err := r.Client.Update(ctx, latestComponent); err != nil {
+		log.Error(err, "failed to save generated Pipelines as Code configuration checksum", l.Action, l.ActionUpdate)
+		return err
+	}
+	return nil
+}
+
+// generatedPaCConfiguration holds Pipelines as Code configuration files proposed in a merge request as generated,
+// before merge with the customizations, and the Components the files belong to.
+type generatedPaCConfiguration struct {
+	components []appstudiov1alpha1.Component
+	files      []gp.RepositoryFile
+}
+
+// saveGeneratedPaCConfigurations remembers just proposed Pipelines as Code configuration files of each Component
+// of the merge request, see saveGeneratedPaCConfiguration.
+func (r *ComponentBuildReconciler) saveGeneratedPaCConfigurations(ctx context.Context, generatedConfiguration *generatedPaCConfiguration) error {
+	for i := range generatedConfiguration.components {
+		if err := r.saveGeneratedPaCConfiguration(ctx, &generatedConfiguration.components[i], generatedConfiguration.files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePaCConfigurationCustomizations keeps customizations, which users made in Pipelines as Code configuration files
+// of the given Components in the repository, in the files of the given merge request data.
+// Only changes of the generated configuration since it was proposed last time are applied on top of the files from the repository.
+// Conflicting changes are not applied and are listed in the merge request description.
+func (r *ComponentBuildReconciler) mergePaCConfigurationCustomizations(ctx context.Context, gitClient gp.GitProviderClientWithContext, repoUrl string, components []appstudiov1alpha1.Component, mrData *gp.MergeRequestData) error {
+	previouslyGeneratedFiles := map[string][]byte{}
+	for i := range components {
+		componentFiles, err := r.readGeneratedPaCConfiguration(ctx, &components[i])
+		if err != nil {
+			return fmt.Errorf("failed to read generated Pipelines as Code configuration of %s Component: %w", components[i].Name, err)
+		}
+		for filePath, content := range componentFiles {
+			previouslyGeneratedFiles[filePath] = content
+		}
+	}
+
+	var conflictLines []string
+	for i, file := range mrData.Files {
+		exists, err := gitClient.IsFileExist(ctx, repoUrl, mrData.BaseBranchName, file.FullPath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		currentContent, err := gitClient.DownloadFileContent(ctx, repoUrl, mrData.BaseBranchName, file.FullPath)
+		if err != nil {
+			return err
+		}
+
+		previousContent, hasPrevious := previouslyGeneratedFiles[file.FullPath]
+		mergedContent, conflicts := mergePaCConfigurationFile(previousContent, hasPrevious, currentContent, file.Content)
+		mrData.Files[i].Content = mergedContent
+		for _, conflict := range conflicts {
+			conflictLines = append(conflictLines, fmt.Sprintf("- `%s`: `%s`", file.FullPath, conflict))
+		}
+	}
+
+	if len(conflictLines) > 0 {
+		mrData.Text += mergeRequestConflictsDescription + strings.Join(conflictLines, "\n") + "\n"
+	}
+	return nil
+}
+
+// mergePaCConfigurationFile does three-way merge of the given versions of a Pipelines as Code configuration file:
+// previously generated (the common ancestor), current (from the repository) and newly generated.
+// If there is no previously generated version, all differences are treated as conflicts, except additions.
+// Returns merged file content and paths of conflicting values, for which the current version is kept.
+func mergePaCConfigurationFile(previousContent []byte, hasPrevious bool, currentContent, generatedContent []byte) ([]byte, []string) {
+	var current, generated interface{}
+	if err := yaml.Unmarshal(currentContent, &current); err != nil {
+		// Nothing could be merged into a broken file
+		return currentContent, []string{"the file is not a valid YAML"}
+	}
+	if err := yaml.Unmarshal(generatedContent, &generated); err != nil {
+		return generatedContent, nil
+	}
+	previous := mergeValue{}
+	if hasPrevious {
+		var previousValue interface{}
+		if err := yaml.Unmarshal(previousContent, &previousValue); err == nil {
+			previous = mergeValue{value: previousValue, exists: true}
+		}
+	}
+
+	conflicts := []string{}
+	merged := mergeThreeWay("", previous, mergeValue{value: current, exists: true}, mergeValue{value: generated, exists: true}, &conflicts)
+	if !merged.exists || reflect.DeepEqual(merged.value, current) {
+		// Keep formatting of the file
+		return currentContent, conflicts
+	}
+	if reflect.DeepEqual(merged.value, generated) {
+		return generatedContent, conflicts
+	}
+	mergedContent, err := yaml.Marshal(merged.value)
+	if err != nil {
+		return currentContent, []string{"failed to serialize merged file"}
+	}
+	return mergedContent, conflicts
+}
+
+// mergeValue is a value within YAML document, which might be absent.
+type mergeValue struct {
+	value  interface{}
+	exists bool
+}
+
+func (v mergeValue) equals(other mergeValue) bool {
+	return v.exists == other.exists && (!v.exists || reflect.DeepEqual(v.value, other.value))
+}
+
+// mergeThreeWay merges values of the same path within previous, current and generated YAML documents.
+// Maps are merged key by key, lists of objects with unique names are merged item by item, other values are atomic.
+func mergeThreeWay(path string, previous, current, generated mergeValue, conflicts *[]string) mergeValue {
+	switch {
+	case current.equals(generated), generated.equals(previous):
+		return current
+	case current.equals(previous):
+		return generated
+	}
+
+	if currentMap, ok := current.value.(map[string]interface{}); ok {
+		if generatedMap, ok := generated.value.(map[string]interface{}); ok {
+			previousMap, _ := previous.value.(map[string]interface{})
+			return mergeValue{value: mergeMaps(path, previousMap, currentMap, generatedMap, conflicts), exists: true}
+		}
+	}
+	if currentList, ok := current.value.([]interface{}); ok && isNamedList(currentList) {
+		if generatedList, ok := generated.value.([]interface{}); ok && isNamedList(generatedList) {
+			previousList, _ := previous.value.([]interface{})
+			if !isNamedList(previousList) {
+				previousList = nil
+			}
+			return mergeValue{value: mergeNamedLists(path, previousList, currentList, generatedList, conflicts), exists: true}
+		}
+	}
+
+	*conflicts = append(*conflicts, path)
+	return current
+}
+
+func mergeMaps(path string, previous, current, generated map[string]interface{}, conflicts *[]string) map[string]interface{} {
+	keys := map[string]bool{}
+	for _, m := range []map[string]interface{}{previous, current, generated} {
+		for key := range m {
+			keys[key] = true
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	merged := map[string]interface{}{}
+	for _, key := range sortedKeys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		mergedValue := mergeThreeWay(keyPath, getMapValue(previous, key), getMapValue(current, key), getMapValue(generated, key), conflicts)
+		if mergedValue.exists {
+			merged[key] = mergedValue.value
+		}
+	}
+	return merged
+}
+
+func getMapValue(m map[string]interface{}, key string) mergeValue {
+	value, exists := m[key]
+	return mergeValue{value: value, exists: exists}
+}
+
+// mergeNamedLists merges lists of objects identified by name, like params, tasks or workspaces.
+// Order of the current list is kept, new generated items are appended.
+func mergeNamedLists(path string, previous, current, generated []interface{}, conflicts *[]string) []interface{} {
+	previousItems := indexNamedList(previous)
+	currentItems := indexNamedList(current)
+	generatedItems := indexNamedList(generated)
+
+	merged := []interface{}{}
+	mergeItem := func(name string) {
+		itemPath := fmt.Sprintf("%s[name=%s]", path, name)
+		mergedItem := mergeThreeWay(itemPath, getMapValue(previousItems, name), getMapValue(currentItems, name), getMapValue(generatedItems, name), conflicts)
+		if mergedItem.exists {
+			merged = append(merged, mergedItem.value)
+		}
+	}
+	for _, item := range current {
+		mergeItem(getItemName(item))
+	}
+	for _, item := range generated {
+		if name := getItemName(item); currentItems[name] == nil {
+			mergeItem(name)
+		}
+	}
+	return merged
+}
+
+// isNamedList checks whether all items of the given list are objects with unique names.
+func isNamedList(list []interface{}) bool {
+	names := map[string]bool{}
+	for _, item := range list {
+		name := getItemName(item)
+		if name == "" || names[name] {
+			return false
+		}
+		names[name] = true
+	}
+	return true
+}
+
+func indexNamedList(list []interface{}) map[string]interface{} {
+	items := map[string]interface{}{}
+	for _, item := range list {
+		items[getItemName(item)] = item
+	}
+	return items
+}
+
+func getItemName(item interface{}) string {
+	if itemMap, ok := item.(map[string]interface{}); ok {
+		if name, ok := itemMap["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// PaCDirectCommitAnnotationName on a Component, if set to true, makes Pipelines as Code configuration
+	// to be committed directly into the base branch of the Component repository instead of proposing it in a merge request.
+	// A merge request is still created if the base branch doesn't accept direct pushes.
+	PaCDirectCommitAnnotationName = "build.appstudio.openshift.io/pac-direct-commit"
+)
+
+// isPaCDirectCommitEnabled checks whether Pipelines as Code configuration of the given Component
+// should be committed directly into the base branch.
+func isPaCDirectCommitEnabled(component *appstudiov1alpha1.Component) bool {
+	enabled, _ := strconv.ParseBool(component.Annotations[PaCDirectCommitAnnotationName])
+	return enabled
+}
+
+// isPaCDirectCommitAllowed checks whether Pipelines as Code configuration of the given Component
+// might be committed directly into the base branch.
+// Batch merge requests combine configuration of other Components, so they are never bypassed.
+func (r *ComponentBuildReconciler) isPaCDirectCommitAllowed(ctx context.Context, component *appstudiov1alpha1.Component) (bool, error) {
+	if !isPaCDirectCommitEnabled(component) {
+		return false, nil
+	}
+
+	pacRepository, err := r.findPaCRepositoryForComponent(ctx, component)
+	if err != nil {
+		return false, err
+	}
+	if pacRepository != nil && isPaCBatchModeEnabled(pacRepository) {
+		ctrllog.FromContext(ctx).Info("direct commit of Pipelines as Code configuration is ignored in batch mode")
+		return false, nil
+	}
+	return true, nil
+}
+
+// commitPaCConfigurationDirectly pushes changes from the given merge request data into the base branch using the given commit function.
+// Returns false if the base branch is protected or rejects the push, so a merge request should be used instead.
+func (r *ComponentBuildReconciler) commitPaCConfigurationDirectly(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, mrData *gp.MergeRequestData,
+	commit func(ctx context.Context, repoUrl string, data *gp.MergeRequestData) (bool, error)) (bool, error) {
+	log := ctrllog.FromContext(ctx)
+	repoUrl := component.Spec.Source.GitSource.URL
+
+	isProtected, err := gitClient.IsBranchProtected(ctx, repoUrl, mrData.BaseBranchName)
+	if err != nil {
+		return false, err
+	}
+	if isProtected {
+		log.Info(fmt.Sprintf("%s branch is protected, using merge request instead of direct commit", mrData.BaseBranchName))
+		return false, nil
+	}
+
+	isCommitted, err := commit(ctx, repoUrl, mrData)
+	if err != nil {
+		if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.GetErrorId() == int(boerrors.EGitBranchPushRejected) {
+			log.Info(fmt.Sprintf("direct push into %s branch is rejected, using merge request instead", mrData.BaseBranchName), "reason", err.Error())
+			return false, nil
+		}
+		return false, err
+	}
+	if isCommitted {
+		message := fmt.Sprintf("Pipelines as Code configuration changes committed into %s branch", mrData.BaseBranchName)
+		log.Info(message, l.Action, l.ActionUpdate, l.Audit, "true")
+		r.EventRecorder.Event(component, "Normal", "PipelinesAsCodeConfiguration", message)
+	}
+	return true, nil
+}
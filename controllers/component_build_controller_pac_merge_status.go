@@ -0,0 +1,222 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// PaCMergeRequestCheckIntervalEnvName overrides how often the state of not merged
+	// Pipelines as Code configuration merge requests is checked, e.g. 30m.
+	PaCMergeRequestCheckIntervalEnvName = "PAC_MERGE_REQUEST_CHECK_INTERVAL"
+
+	pacMergeRequestCheckIntervalDefault = 10 * time.Minute
+
+	// Merge states of Pipelines as Code configuration
+	pacMergeStatePendingMerge = "pending-merge"
+	pacMergeStateMerged       = "merged"
+	pacMergeStateRejected     = "rejected"
+)
+
+var pipelinesAsCodeMergeRequestMergeTimeMetric = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsSubsystem,
+	Buckets:   []float64{300, 900, 3600, 4 * 3600, 24 * 3600, 3 * 24 * 3600, 7 * 24 * 3600, 30 * 24 * 3600},
+	Name:      "PaC_merge_request_merge_time",
+	Help:      "The time in seconds spent from the moment of Pipelines-as-Code configuration merge request creation till its merge.",
+})
+
+func getPaCMergeRequestCheckInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv(PaCMergeRequestCheckIntervalEnvName)); err == nil && interval > 0 {
+		return interval
+	}
+	return pacMergeRequestCheckIntervalDefault
+}
+
+// getInitialPaCMergeState returns merge state of just provisioned Pipelines as Code configuration.
+// Empty merge request URL means that the configuration is already in the base branch.
+func getInitialPaCMergeState(mergeUrl string) string {
+	if mergeUrl == "" {
+		return pacMergeStateMerged
+	}
+	return pacMergeStatePendingMerge
+}
+
+// getPaCMergeRequestCheckResult returns reconcile result which schedules next check of the merge request, if needed.
+func getPaCMergeRequestCheckResult(pacBuildStatus *PaCBuildStatus) ctrl.Result {
+	if pacBuildStatus != nil && pacBuildStatus.State == "enabled" && pacBuildStatus.MergeState == pacMergeStatePendingMerge {
+		return ctrl.Result{RequeueAfter: getPaCMergeRequestCheckInterval()}
+	}
+	return ctrl.Result{}
+}
+
+// getPaCMergeRequestCheckDelay returns time left until the next check of the merge request is due.
+// The check talks to the git provider, so it must not be done on every Component update.
+func getPaCMergeRequestCheckDelay(pacBuildStatus *PaCBuildStatus) time.Duration {
+	lastCheckTime, err := time.Parse(time.RFC1123, pacBuildStatus.MergeCheckTime)
+	if err != nil {
+		return 0
+	}
+	if delay := time.Until(lastCheckTime.Add(getPaCMergeRequestCheckInterval())); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// applyMergeRequestStatus updates the given build status according to the merge request state.
+// Returns true if the build status is changed.
+func applyMergeRequestStatus(pacBuildStatus *PaCBuildStatus, mrStatus *gp.MergeRequestStatus) bool {
+	oldStatus := *pacBuildStatus
+
+	switch mrStatus.State {
+	case gp.MergeRequestStateMerged:
+		pacBuildStatus.MergeState = pacMergeStateMerged
+		pacBuildStatus.Mergeable = nil
+		mergeTime := time.Now()
+		if mrStatus.MergedAt != nil {
+			mergeTime = *mrStatus.MergedAt
+		}
+		pacBuildStatus.MergeTime = mergeTime.Format(time.RFC1123)
+	case gp.MergeRequestStateClosed:
+		pacBuildStatus.MergeState = pacMergeStateRejected
+		pacBuildStatus.Mergeable = nil
+	default:
+		pacBuildStatus.MergeState = pacMergeStatePendingMerge
+		pacBuildStatus.Mergeable = mrStatus.Mergeable
+	}
+
+	isMergeableChanged := (oldStatus.Mergeable == nil) != (pacBuildStatus.Mergeable == nil) ||
+		(oldStatus.Mergeable != nil && *oldStatus.Mergeable != *pacBuildStatus.Mergeable)
+	return oldStatus.MergeState != pacBuildStatus.MergeState || oldStatus.MergeTime != pacBuildStatus.MergeTime || isMergeableChanged
+}
+
+// checkPaCMergeRequest follows Pipelines as Code configuration merge request of the Component
+// until it's merged or closed and reflects its state in the build status.
+// While the merge request is open, its branch is kept up to date with the base branch.
+func (r *ComponentBuildReconciler) checkPaCMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx).WithName("PaCMergeRequestCheck")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	buildStatus, err := r.readComponentBuildStatus(ctx, component)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if buildStatus.PaC == nil || buildStatus.PaC.MergeUrl == "" || buildStatus.PaC.State != "enabled" || buildStatus.PaC.MergeState != pacMergeStatePendingMerge {
+		return ctrl.Result{}, nil
+	}
+	if delay := getPaCMergeRequestCheckDelay(buildStatus.PaC); delay > 0 {
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	checkedMergeUrl := buildStatus.PaC.MergeUrl
+
+	isChanged, err := r.updatePaCMergeRequestStatus(ctx, component, buildStatus.PaC)
+	if err != nil {
+		boErr, ok := err.(*boerrors.BuildOpError)
+		if !ok || !boErr.IsPersistent() {
+			return getTransientErrorResult(err)
+		}
+		// Do not block the Component, try again later
+		log.Error(err, "failed to check Pipelines as Code merge request", "MergeUrl", checkedMergeUrl)
+	}
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: component.Name, Namespace: component.Namespace}, component); err != nil {
+		log.Error(err, "failed to get Component", l.Action, l.ActionView)
+		return ctrl.Result{}, err
+	}
+	currentBuildStatus, err := r.readComponentBuildStatus(ctx, component)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if currentBuildStatus.PaC == nil || currentBuildStatus.PaC.MergeUrl != checkedMergeUrl {
+		// Pipelines as Code configuration changed meanwhile
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if isChanged {
+		currentBuildStatus.PaC.MergeUrl = buildStatus.PaC.MergeUrl
+		currentBuildStatus.PaC.MergeState = buildStatus.PaC.MergeState
+		currentBuildStatus.PaC.Mergeable = buildStatus.PaC.Mergeable
+		currentBuildStatus.PaC.MergeTime = buildStatus.PaC.MergeTime
+	}
+	currentBuildStatus.PaC.MergeCheckTime = time.Now().Format(time.RFC1123)
+
+	writeBuildStatus(component, currentBuildStatus)
+	if err := r.writeComponentBuildStatus(ctx, component, currentBuildStatus); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Client.Update(ctx, component); err != nil {
+		log.Error(err, "failed to update Component build status", l.Action, l.ActionUpdate)
+		return ctrl.Result{}, err
+	}
+	if isChanged {
+		log.Info("Pipelines as Code merge request state changed", "MergeUrl", buildStatus.PaC.MergeUrl, "MergeState", buildStatus.PaC.MergeState, l.Action, l.ActionUpdate)
+	}
+
+	return getPaCMergeRequestCheckResult(buildStatus.PaC), nil
+}
+
+// updatePaCMergeRequestStatus reads state of the merge request from the git provider into the given build status
+// and refreshes the merge request branch if the merge request is still open and behind its base branch.
+// Returns true if the build status is changed.
+func (r *ComponentBuildReconciler) updatePaCMergeRequestStatus(ctx context.Context, component *appstudiov1alpha1.Component, pacBuildStatus *PaCBuildStatus) (bool, error) {
+	log := ctrllog.FromContext(ctx)
+
+	pacConfig, gitClient, err := r.getPaCConfigAndGitClientForComponent(ctx, component)
+	if err != nil {
+		return false, err
+	}
+	mrStatus, err := gitClient.GetMergeRequestStatus(ctx, component.Spec.Source.GitSource.URL, pacBuildStatus.MergeUrl)
+	if err != nil {
+		return false, err
+	}
+
+	isChanged := applyMergeRequestStatus(pacBuildStatus, mrStatus)
+	if pacBuildStatus.MergeState != pacMergeStatePendingMerge {
+		if pacBuildStatus.MergeState == pacMergeStateMerged && mrStatus.CreatedAt != nil && mrStatus.MergedAt != nil {
+			pipelinesAsCodeMergeRequestMergeTimeMetric.Observe(mrStatus.MergedAt.Sub(*mrStatus.CreatedAt).Seconds())
+		}
+		return isChanged, nil
+	}
+	if !mrStatus.IsBehind {
+		return isChanged, nil
+	}
+
+	// Keep the proposal based on the latest commit of the base branch
+	mrUrl, isRefreshed, err := r.refreshPaCMergeRequest(ctx, component, gitClient, pacConfig)
+	if err != nil {
+		// The merge request is still valid, try again on the next check
+		log.Error(err, "failed to refresh Pipelines as Code merge request", "MergeUrl", pacBuildStatus.MergeUrl)
+		return isChanged, nil
+	}
+	if isRefreshed {
+		applyPaCMergeRequestRefresh(pacBuildStatus, mrUrl)
+		isChanged = true
+	}
+	return isChanged, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+const (
+	// PaCMergeRequestTemplatesConfigMapName is the name of the optional ConfigMap in the Component namespace
+	// which customizes merge requests proposed into Component git repositories.
+	PaCMergeRequestTemplatesConfigMapName = "build-service-merge-request-templates"
+
+	// Go templates of the Pipelines as Code configuration merge request.
+	mergeRequestTitleTemplateKey         = "title"
+	mergeRequestCommitMessageTemplateKey = "commit-message"
+	mergeRequestDescriptionTemplateKey   = "description"
+	// Go templates of the Pipelines as Code configuration removal merge request.
+	purgeMergeRequestTitleTemplateKey         = "purge-title"
+	purgeMergeRequestCommitMessageTemplateKey = "purge-commit-message"
+	purgeMergeRequestDescriptionTemplateKey   = "purge-description"
+	// Go templates of the commits author, used in both kinds of merge requests.
+	mergeRequestAuthorNameTemplateKey  = "author-name"
+	mergeRequestAuthorEmailTemplateKey = "author-email"
+	// Plain value which replaces the default prefix of merge request source branches.
+	// Existing merge requests are found by their source branch, so the prefix should not be changed while they are open.
+	mergeRequestBranchPrefixKey = "branch-prefix"
+)
+
+// mergeRequestTemplateData is the data available in the merge request templates.
+type mergeRequestTemplateData struct {
+	// Component which the merge request is created for.
+	Component *appstudiov1alpha1.Component
+	// Application of the Component.
+	Application string
+	// Names of all Components whose configuration is in the merge request.
+	// Contains more than one item only if the Component is onboarded together with other Components from its repository.
+	Components []string
+	// Name and bundle of the pipeline selected for the Component.
+	// Empty for configuration removal merge requests.
+	PipelineName   string
+	PipelineBundle string
+}
+
+// mergeRequestTemplates holds customizations of the merge requests from the templates ConfigMap.
+// nil value means no customizations.
+type mergeRequestTemplates map[string]string
+
+// getMergeRequestTemplates returns merge request customizations configured in the given namespace.
+func (r *ComponentBuildReconciler) getMergeRequestTemplates(ctx context.Context, namespace string) (mergeRequestTemplates, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: PaCMergeRequestTemplatesConfigMapName}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		ctrllog.FromContext(ctx).Error(err, "failed to get merge request templates ConfigMap", l.Action, l.ActionView)
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
+// getBranchPrefix returns prefix of the source branches of merge requests created by build-service.
+func (t mergeRequestTemplates) getBranchPrefix() string {
+	if prefix := strings.TrimSpace(t[mergeRequestBranchPrefixKey]); prefix != "" {
+		return prefix
+	}
+	return pacMergeRequestSourceBranchPrefix
+}
+
+// apply renders the templates into the given merge request data.
+// Fields without corresponding template are left untouched.
+func (t mergeRequestTemplates) apply(mrData *gp.MergeRequestData, isPurge bool, data *mergeRequestTemplateData) error {
+	titleKey, commitMessageKey, descriptionKey := mergeRequestTitleTemplateKey, mergeRequestCommitMessageTemplateKey, mergeRequestDescriptionTemplateKey
+	if isPurge {
+		titleKey, commitMessageKey, descriptionKey = purgeMergeRequestTitleTemplateKey, purgeMergeRequestCommitMessageTemplateKey, purgeMergeRequestDescriptionTemplateKey
+	}
+
+	fields := []struct {
+		key   string
+		value *string
+		trim  bool
+	}{
+		{key: titleKey, value: &mrData.Title, trim: true},
+		{key: commitMessageKey, value: &mrData.CommitMessage, trim: true},
+		{key: descriptionKey, value: &mrData.Text},
+		{key: mergeRequestAuthorNameTemplateKey, value: &mrData.AuthorName, trim: true},
+		{key: mergeRequestAuthorEmailTemplateKey, value: &mrData.AuthorEmail, trim: true},
+	}
+	for _, field := range fields {
+		templateText, exists := t[field.key]
+		if !exists {
+			continue
+		}
+		value, err := renderMergeRequestTemplate(field.key, templateText, data)
+		if err != nil {
+			return err
+		}
+		if field.trim {
+			value = strings.TrimSpace(value)
+		}
+		*field.value = value
+	}
+	return nil
+}
+
+func renderMergeRequestTemplate(name, templateText string, data *mergeRequestTemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(templateText)
+	if err != nil {
+		return "", boerrors.NewBuildOpError(boerrors.EInvalidMergeRequestTemplate,
+			fmt.Errorf("failed to parse %s merge request template from %s ConfigMap: %w", name, PaCMergeRequestTemplatesConfigMapName, err))
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", boerrors.NewBuildOpError(boerrors.EInvalidMergeRequestTemplate,
+			fmt.Errorf("failed to render %s merge request template from %s ConfigMap: %w", name, PaCMergeRequestTemplatesConfigMapName, err))
+	}
+	return buf.String(), nil
+}
+
+// customizePaCMergeRequest applies the namespace merge request templates, if any, to the merge request
+// which proposes Pipelines as Code configuration of the given Components.
+func (r *ComponentBuildReconciler) customizePaCMergeRequest(ctx context.Context, templates mergeRequestTemplates, mrData *gp.MergeRequestData, component *appstudiov1alpha1.Component, componentNames []string) error {
+	if templates == nil {
+		return nil
+	}
+
+	data := &mergeRequestTemplateData{
+		Component:   component,
+		Application: component.Spec.Application,
+		Components:  componentNames,
+	}
+
+	pipelineRef, _, matchedSelector, err := r.getPipelineWithMatchForComponent(ctx, component)
+	if err != nil {
+		return err
+	}
+	pipelineRef, _, err = pinPipelineBundleDigest(ctx, pipelineRef, matchedSelector)
+	if err != nil {
+		return err
+	}
+	pipelineSource, err := newPipelineSource(pipelineRef, r.Client, component.Namespace, isBuildServiceSelector(matchedSelector))
+	if err != nil {
+		return err
+	}
+	data.PipelineName = pipelineSource.GetPipelineName()
+	data.PipelineBundle = getPipelineSourceBundle(pipelineSource)
+
+	return templates.apply(mrData, false, data)
+}
+
+// customizePaCPurgeMergeRequest applies the namespace merge request templates, if any, to the merge request
+// which removes Pipelines as Code configuration of the given Component.
+func customizePaCPurgeMergeRequest(templates mergeRequestTemplates, mrData *gp.MergeRequestData, component *appstudiov1alpha1.Component) error {
+	if templates == nil {
+		return nil
+	}
+	return templates.apply(mrData, true, &mergeRequestTemplateData{
+		Component:   component,
+		Application: component.Spec.Application,
+		Components:  []string{component.Name},
+	})
+}
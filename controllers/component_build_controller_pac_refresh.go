@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// RefreshPaCMergeRequestForComponent recreates the branch of the open Pipelines as Code configuration proposal merge request
+// of the given Component from the latest commit of the base branch, if the branch is behind the base branch.
+// Returns the merge request web URL, which might change if the git provider requires a new merge request,
+// and true if the proposal was refreshed.
+func (r *ComponentBuildReconciler) RefreshPaCMergeRequestForComponent(ctx context.Context, component *appstudiov1alpha1.Component) (string, bool, error) {
+	log := ctrllog.FromContext(ctx).WithName("PaC-refresh")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	buildStatus := readBuildStatus(component)
+	if buildStatus.PaC == nil || buildStatus.PaC.MergeUrl == "" {
+		// No open proposal
+		return "", false, nil
+	}
+
+	pacConfig, gitClient, err := r.getPaCConfigAndGitClientForComponent(ctx, component)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Generating the configuration is expensive, do it only if the proposal is outdated
+	mrStatus, err := gitClient.GetMergeRequestStatus(ctx, component.Spec.Source.GitSource.URL, buildStatus.PaC.MergeUrl)
+	if err != nil {
+		return "", false, err
+	}
+	if mrStatus.State != gp.MergeRequestStateOpen || !mrStatus.IsBehind {
+		return buildStatus.PaC.MergeUrl, false, nil
+	}
+	return r.refreshPaCMergeRequest(ctx, component, gitClient, pacConfig)
+}
+
+// refreshPaCMergeRequest regenerates Pipelines as Code configuration proposal of the given Component
+// and recreates the proposal branch with it, if the branch is outdated.
+func (r *ComponentBuildReconciler) refreshPaCMergeRequest(ctx context.Context, component *appstudiov1alpha1.Component, gitClient gp.GitProviderClientWithContext, pacConfig map[string][]byte) (string, bool, error) {
+	log := ctrllog.FromContext(ctx)
+
+	mrData, generatedConfiguration, err := r.generatePaCMergeRequestData(ctx, component, gitClient, pacConfig)
+	if err != nil {
+		return "", false, err
+	}
+
+	mrUrl, isRefreshed, err := gitClient.RefreshPaCMergeRequest(ctx, component.Spec.Source.GitSource.URL, mrData)
+	if err != nil {
+		r.EventRecorder.Event(component, "Warning", "ErrorRefreshingPaCMergeRequest", err.Error())
+		return "", false, err
+	}
+	if isRefreshed {
+		message := fmt.Sprintf("Pipelines as Code configuration merge request refreshed: %s", mrUrl)
+		log.Info(message, "BaseBranch", mrData.BaseBranchName)
+		r.EventRecorder.Event(component, "Normal", "PipelinesAsCodeConfiguration", message)
+
+		if err := r.saveGeneratedPaCConfigurations(ctx, generatedConfiguration); err != nil {
+			return "", false, err
+		}
+	}
+	return mrUrl, isRefreshed, nil
+}
+
+// applyPaCMergeRequestRefresh updates the given build status after the proposal merge request refresh.
+// Empty merge request URL means that the configuration is already in the base branch.
+func applyPaCMergeRequestRefresh(pacBuildStatus *PaCBuildStatus, mergeUrl string) {
+	pacBuildStatus.MergeUrl = mergeUrl
+	pacBuildStatus.MergeState = getInitialPaCMergeState(mergeUrl)
+	pacBuildStatus.Mergeable = nil
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	tektonapi_v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	pipelineselector "github.com/redhat-appstudio/build-service/pkg/pipeline-selector"
+)
+
+const (
+	bundlesResolverName = "bundles"
+	gitResolverName     = "git"
+	clusterResolverName = "cluster"
+
+	pipelineBundleAnnotationName    = "build.appstudio.redhat.com/bundle"
+	pipelineBundleTagAnnotationName = "build.appstudio.redhat.com/bundle_tag"
+
+	// PinPipelineBundleDigestEnvName enables pinning of pipeline bundles to digests for all BuildPipelineSelectors.
+	PinPipelineBundleDigestEnvName = "PIN_PIPELINE_BUNDLE_DIGEST"
+	// ClusterResolverNamespacesEnvName lists comma separated namespaces, which Pipelines could be read from
+	// with the cluster resolver by Components of any namespace. Otherwise, only the Component namespace is allowed.
+	ClusterResolverNamespacesEnvName = "CLUSTER_RESOLVER_NAMESPACES"
+)
+
+// PipelineSource provides definition of the build pipeline referenced by a Tekton pipelineRef.
+type PipelineSource interface {
+	// GetPipelineName returns name of the pipeline.
+	GetPipelineName() string
+	// GetLocation returns human readable location of the pipeline, e.g. bundle image reference.
+	GetLocation() string
+	// GetPipelineSpec retrieves definition of the pipeline.
+	GetPipelineSpec(ctx context.Context) (*tektonapi.PipelineSpec, error)
+}
+
+// newPipelineSource returns source of the pipeline referenced by the given pipelineRef.
+// The namespace is used to look up the pipeline with cluster resolver, if the pipelineRef doesn't specify it,
+// and to look up credentials to fetch the pipeline from a git repository.
+// Pipelines could be read with cluster resolver from the namespace and from the operator configured namespaces only.
+// The global git provider credentials are used for git resolver only if the pipelineRef comes
+// from a BuildPipelineSelector in build-service namespace, because tenants shouldn't be able to read
+// arbitrary repositories the global credentials have access to.
+func newPipelineSource(pipelineRef *tektonapi.PipelineRef, k8sClient client.Client, namespace string, allowGlobalCredentials bool) (PipelineSource, error) {
+	switch pipelineRef.Resolver {
+	case "", bundlesResolverName:
+		pipelineName, pipelineBundle, err := getPipelineNameAndBundle(pipelineRef)
+		if err != nil {
+			return nil, err
+		}
+		return &bundlePipelineSource{name: pipelineName, bundle: pipelineBundle}, nil
+
+	case gitResolverName:
+		source := &gitPipelineSource{
+			url:        getPipelineRefParam(pipelineRef, "url"),
+			revision:   getPipelineRefParam(pipelineRef, "revision"),
+			pathInRepo: getPipelineRefParam(pipelineRef, "pathInRepo"),
+			scmType:    getPipelineRefParam(pipelineRef, "scmType"),
+			k8sClient:  k8sClient,
+			namespace:  namespace,
+
+			allowGlobalCredentials: allowGlobalCredentials,
+		}
+		if source.url == "" || source.pathInRepo == "" {
+			return nil, boerrors.NewBuildOpError(
+				boerrors.EMissingParamsForPipelineResolver,
+				fmt.Errorf("missing url or pathInRepo in git resolver pipelineRef: url=%s pathInRepo=%s", source.url, source.pathInRepo),
+			)
+		}
+		return source, nil
+
+	case clusterResolverName:
+		source := &clusterPipelineSource{
+			name:      getPipelineRefParam(pipelineRef, "name"),
+			namespace: getPipelineRefParam(pipelineRef, "namespace"),
+			k8sClient: k8sClient,
+		}
+		if kind := getPipelineRefParam(pipelineRef, "kind"); kind != "" && kind != "pipeline" {
+			return nil, boerrors.NewBuildOpError(
+				boerrors.EUnsupportedPipelineRef,
+				fmt.Errorf("unsupported kind %q in cluster resolver pipelineRef", kind),
+			)
+		}
+		if source.namespace == "" {
+			source.namespace = namespace
+		}
+		if !isClusterResolverNamespaceAllowed(source.namespace, namespace) {
+			return nil, boerrors.NewBuildOpError(
+				boerrors.EPipelineSourceNotAllowed,
+				fmt.Errorf("reading pipelines from %s namespace with cluster resolver is not allowed", source.namespace),
+			)
+		}
+		if source.name == "" {
+			return nil, boerrors.NewBuildOpError(
+				boerrors.EMissingParamsForPipelineResolver,
+				fmt.Errorf("missing name in cluster resolver pipelineRef"),
+			)
+		}
+		return source, nil
+
+	default:
+		return nil, boerrors.NewBuildOpError(
+			boerrors.EUnsupportedPipelineRef,
+			fmt.Errorf("unsupported Tekton resolver %q", pipelineRef.Resolver),
+		)
+	}
+}
+
+// isClusterResolverNamespaceAllowed returns true if Pipelines could be read from the pipeline namespace
+// on behalf of the namespace, see ClusterResolverNamespacesEnvName.
+func isClusterResolverNamespaceAllowed(pipelineNamespace, namespace string) bool {
+	if pipelineNamespace == namespace {
+		return true
+	}
+	for _, allowedNamespace := range strings.Split(os.Getenv(ClusterResolverNamespacesEnvName), ",") {
+		if strings.TrimSpace(allowedNamespace) == pipelineNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// isBuildServiceSelector returns true if the matched pipeline selector is defined in build-service namespace,
+// so the pipelineRef isn't controlled by tenants.
+func isBuildServiceSelector(matchedSelector *pipelineselector.MatchedSelector) bool {
+	return matchedSelector != nil && matchedSelector.Namespace == buildServiceNamespaceName
+}
+
+// getPipelineRefParam returns string value of the given pipelineRef resolver parameter or empty string if it isn't set.
+func getPipelineRefParam(pipelineRef *tektonapi.PipelineRef, name string) string {
+	for _, param := range pipelineRef.Params {
+		if param.Name == name {
+			return param.Value.StringVal
+		}
+	}
+	return ""
+}
+
+// getPipelineSourceBundle returns the bundle the pipeline is taken from or empty string for non bundle sources.
+func getPipelineSourceBundle(source PipelineSource) string {
+	if bundleSource, ok := source.(*bundlePipelineSource); ok {
+		return bundleSource.bundle
+	}
+	return ""
+}
+
+// pinPipelineBundleDigest resolves tag of the pipeline bundle to digest, if it is requested by the matched selector
+// or globally. Returns the pipelineRef referring the bundle by digest and the original bundle reference.
+// The pipelineRef is returned as is with empty original bundle, if the bundle isn't pinned.
+func pinPipelineBundleDigest(ctx context.Context, pipelineRef *tektonapi.PipelineRef, matchedSelector *pipelineselector.MatchedSelector) (*tektonapi.PipelineRef, string, error) {
+	pinEnabled, _ := strconv.ParseBool(os.Getenv(PinPipelineBundleDigestEnvName))
+	if !pinEnabled && (matchedSelector == nil || !matchedSelector.PinBundleDigest) {
+		return pipelineRef, "", nil
+	}
+	if pipelineRef.Resolver != "" && pipelineRef.Resolver != bundlesResolverName {
+		return pipelineRef, "", nil
+	}
+	_, pipelineBundle, err := getPipelineNameAndBundle(pipelineRef)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Use the same digest as the cached pipeline definition
+	digestRef, err := bundlePipelineSpecCache.getBundleDigestRef(ctx, pipelineBundle, false)
+	if err != nil {
+		return nil, "", err
+	}
+	if digestRef == pipelineBundle {
+		return pipelineRef, "", nil
+	}
+
+	pinnedPipelineRef := pipelineRef.DeepCopy()
+	for i := range pinnedPipelineRef.Params {
+		if pinnedPipelineRef.Params[i].Name == "bundle" {
+			pinnedPipelineRef.Params[i].Value = *tektonapi.NewStructuredValues(digestRef)
+		}
+	}
+	return pinnedPipelineRef, pipelineBundle, nil
+}
+
+// bundlePipelineSource retrieves the pipeline from a Tekton bundle.
+type bundlePipelineSource struct {
+	name   string
+	bundle string
+}
+
+func (s *bundlePipelineSource) GetPipelineName() string {
+	return s.name
+}
+
+func (s *bundlePipelineSource) GetLocation() string {
+	return s.bundle
+}
+
+func (s *bundlePipelineSource) GetPipelineSpec(ctx context.Context) (*tektonapi.PipelineSpec, error) {
+	return retrievePipelineSpec(ctx, s.bundle, s.name)
+}
+
+// gitPipelineSource retrieves the pipeline from a file in a git repository using git provider API.
+type gitPipelineSource struct {
+	url        string
+	revision   string
+	pathInRepo string
+	// scmType is the git provider type, needed for self-hosted instances only.
+	scmType string
+
+	k8sClient client.Client
+	// namespace to look up Pipelines as Code secret in.
+	namespace string
+	// allowGlobalCredentials allows falling back to the global Pipelines as Code secret,
+	// if there is no secret in the namespace.
+	allowGlobalCredentials bool
+}
+
+func (s *gitPipelineSource) GetPipelineName() string {
+	return strings.TrimSuffix(strings.TrimSuffix(s.pathInRepo[strings.LastIndex(s.pathInRepo, "/")+1:], ".yaml"), ".yml")
+}
+
+func (s *gitPipelineSource) GetLocation() string {
+	location := s.url
+	if s.revision != "" {
+		location += "@" + s.revision
+	}
+	return location + ":" + s.pathInRepo
+}
+
+func (s *gitPipelineSource) GetPipelineSpec(ctx context.Context) (*tektonapi.PipelineSpec, error) {
+	// Reuse the git provider detection logic of Components
+	repository := appstudiov1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+		Spec: appstudiov1alpha1.ComponentSpec{
+			Source: appstudiov1alpha1.ComponentSource{
+				ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+					GitSource: &appstudiov1alpha1.GitSource{URL: s.url},
+				},
+			},
+		},
+	}
+	if s.scmType != "" {
+		repository.Annotations[gitops.GitProviderAnnotationName] = s.scmType
+	}
+	gitProvider, err := getGitProvider(repository)
+	if err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
+			fmt.Errorf("error detecting git provider of pipeline repository %s: %w", s.url, err))
+	}
+
+	pacSecret, err := s.getPaCSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	apiBaseUrl, err := getGitProviderApiUrl(s.url, pacSecret.Data)
+	if err != nil {
+		return nil, err
+	}
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacSecret.Data,
+		GitProvider:               gitProvider,
+		RepoUrl:                   s.url,
+		IsAppInstallationExpected: false,
+		ApiBaseUrl:                apiBaseUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineContent, err := gitClient.DownloadFileContent(ctx, s.url, s.revision, s.pathInRepo)
+	if err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("failed to download pipeline from %s: %w", s.GetLocation(), err))
+	}
+	return parsePipelineSpec(ctx, pipelineContent, s.GetLocation())
+}
+
+// getPaCSecret returns git provider credentials to fetch the pipeline with.
+func (s *gitPipelineSource) getPaCSecret(ctx context.Context) (*corev1.Secret, error) {
+	if s.allowGlobalCredentials {
+		return lookupPaCSecretInNamespace(ctx, s.k8sClient, s.namespace)
+	}
+	pacSecret := &corev1.Secret{}
+	pacSecretKey := types.NamespacedName{Namespace: s.namespace, Name: gitopsprepare.PipelinesAsCodeSecretName}
+	if err := s.k8sClient.Get(ctx, pacSecretKey, pacSecret); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get Pipelines as Code secret in %s namespace: %w", s.namespace, err)
+		}
+		return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound,
+			fmt.Errorf("Pipelines as Code secret to fetch pipeline from %s not found in %s namespace", s.url, s.namespace))
+	}
+	return pacSecret, nil
+}
+
+// clusterPipelineSource retrieves the pipeline from a Pipeline object in the cluster.
+// Pipelines are excluded from the client cache, so reading them doesn't require watching Pipelines in all namespaces.
+type clusterPipelineSource struct {
+	name      string
+	namespace string
+
+	k8sClient client.Client
+}
+
+func (s *clusterPipelineSource) GetPipelineName() string {
+	return s.name
+}
+
+func (s *clusterPipelineSource) GetLocation() string {
+	return s.namespace + "/" + s.name
+}
+
+func (s *clusterPipelineSource) GetPipelineSpec(ctx context.Context) (*tektonapi.PipelineSpec, error) {
+	pipeline := &tektonapi.Pipeline{}
+	if err := s.k8sClient.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: s.name}, pipeline); err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("failed to get pipeline %s: %w", s.GetLocation(), err))
+	}
+	pipelineSpec := pipeline.PipelineSpec()
+	return &pipelineSpec, nil
+}
+
+// parsePipelineSpec extracts pipeline definition from the given Pipeline YAML of v1 or v1beta1 version.
+func parsePipelineSpec(ctx context.Context, pipelineContent []byte, location string) (*tektonapi.PipelineSpec, error) {
+	log := ctrllog.FromContext(ctx)
+
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(pipelineContent, typeMeta); err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("failed to parse pipeline from %s: %w", location, err))
+	}
+	if typeMeta.Kind != "Pipeline" {
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("expected Pipeline in %s, but got %q kind", location, typeMeta.Kind))
+	}
+
+	var pipelineSpec tektonapi.PipelineSpec
+	switch typeMeta.APIVersion {
+	case tektonapi.SchemeGroupVersion.String():
+		pipeline := &tektonapi.Pipeline{}
+		if err := yaml.Unmarshal(pipelineContent, pipeline); err != nil {
+			return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+				fmt.Errorf("failed to parse pipeline from %s: %w", location, err))
+		}
+		pipelineSpec = pipeline.PipelineSpec()
+	case tektonapi_v1beta1.SchemeGroupVersion.String():
+		v1beta1Pipeline := &tektonapi_v1beta1.Pipeline{}
+		if err := yaml.Unmarshal(pipelineContent, v1beta1Pipeline); err != nil {
+			return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+				fmt.Errorf("failed to parse pipeline from %s: %w", location, err))
+		}
+		log.Info("Converting from v1beta1 to v1", "Location", location)
+		if err := v1beta1Pipeline.Spec.ConvertTo(ctx, &pipelineSpec); err != nil {
+			return nil, boerrors.NewBuildOpError(
+				boerrors.EPipelineConversionFailed,
+				fmt.Errorf("pipeline from %s: failed to convert from v1beta1 to v1: %w", location, err),
+			)
+		}
+	default:
+		return nil, boerrors.NewBuildOpError(boerrors.EPipelineRetrievalFailed,
+			fmt.Errorf("unsupported pipeline API version %q in %s", typeMeta.APIVersion, location))
+	}
+	return &pipelineSpec, nil
+}
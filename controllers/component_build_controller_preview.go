@@ -0,0 +1,240 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	pipelineselector "github.com/redhat-appstudio/build-service/pkg/pipeline-selector"
+)
+
+const (
+	buildPreviewConfigMapNameSuffix = "-build-preview"
+
+	buildPreviewTargetBranchKey         = "target-branch"
+	buildPreviewPipelineNameKey         = "pipeline-name"
+	buildPreviewPipelineBundleKey       = "pipeline-bundle"
+	buildPreviewPipelineSourceKey       = "pipeline-source"
+	buildPreviewPipelineSelectorKey     = "pipeline-selector"
+	buildPreviewPipelineSelectorItemKey = "pipeline-selector-item"
+
+	buildPreviewMergeRequestTitleKey        = "merge-request-title"
+	buildPreviewMergeRequestDescriptionKey  = "merge-request-description"
+	buildPreviewMergeRequestSourceBranchKey = "merge-request-source-branch"
+)
+
+// getBuildPreviewConfigMapName returns name of the ConfigMap with build preview of the given Component.
+func getBuildPreviewConfigMapName(component *appstudiov1alpha1.Component) string {
+	return component.Name + buildPreviewConfigMapNameSuffix
+}
+
+// PreviewPaCForComponent renders Pipelines as Code PipelineRuns which would be proposed for the given Component
+// and saves them together with the selected pipeline and the merge request details into a ConfigMap owned by the Component.
+// The PipelineRuns include customizations made in the repository, like in the real proposal.
+// No changes are done in the Component git repository and no PipelineRuns are created.
+func (r *ComponentBuildReconciler) PreviewPaCForComponent(ctx context.Context, component *appstudiov1alpha1.Component) error {
+	log := ctrllog.FromContext(ctx).WithName("BuildPreview")
+	ctx = ctrllog.IntoContext(ctx, log)
+
+	gitProvider, err := getGitProvider(*component)
+	if err != nil {
+		return boerrors.NewBuildOpError(boerrors.EUnknownGitProvider,
+			fmt.Errorf("error detecting git provider: %w", err))
+	}
+	if err := validatePaCGitProvider(gitProvider); err != nil {
+		return err
+	}
+
+	pacSecret, err := r.lookupPaCSecret(ctx, component)
+	if err != nil {
+		return err
+	}
+	if err := validatePaCConfiguration(gitProvider, pacSecret.Data); err != nil {
+		return boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+			fmt.Errorf("invalid configuration in Pipelines as Code secret: %w", err))
+	}
+
+	repoUrl := component.Spec.Source.GitSource.URL
+	apiBaseUrl, err := getGitProviderApiUrl(repoUrl, pacSecret.Data)
+	if err != nil {
+		return err
+	}
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
+		PacSecretData:             pacSecret.Data,
+		GitProvider:               gitProvider,
+		RepoUrl:                   repoUrl,
+		IsAppInstallationExpected: true,
+		ApiBaseUrl:                apiBaseUrl,
+	})
+	if err != nil {
+		return err
+	}
+
+	targetBranch := component.Spec.Source.GitSource.Revision
+	if targetBranch == "" {
+		targetBranch, err = gitClient.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return err
+		}
+	}
+
+	pipelineRef, additionalPipelineParams, matchedSelector, err := r.getPipelineWithMatchForComponent(ctx, component)
+	if err != nil {
+		return err
+	}
+	pipelineRef, pipelineBundleTag, err := pinPipelineBundleDigest(ctx, pipelineRef, matchedSelector)
+	if err != nil {
+		return err
+	}
+	pipelineSource, err := newPipelineSource(pipelineRef, r.Client, component.Namespace, isBuildServiceSelector(matchedSelector))
+	if err != nil {
+		return err
+	}
+
+	pipelineRunOnPushYaml, pipelineRunOnPRYaml, err := r.generatePaCPipelineRunConfigsForPipeline(ctx, component, pipelineRef, matchedSelector, pipelineBundleTag, additionalPipelineParams, gitClient, targetBranch)
+	if err != nil {
+		return err
+	}
+
+	// Show the proposal as it would be made, with the namespace templates and the repository customizations applied
+	mrTemplates, err := r.getMergeRequestTemplates(ctx, component.Namespace)
+	if err != nil {
+		return err
+	}
+	mrData := newPaCMergeRequestData(component, targetBranch, mrTemplates.getBranchPrefix(), pipelineRunOnPushYaml, pipelineRunOnPRYaml)
+	if err := r.customizePaCMergeRequest(ctx, mrTemplates, mrData, component, []string{component.Name}); err != nil {
+		return err
+	}
+	if err := r.mergePaCConfigurationCustomizations(ctx, gitClient, repoUrl, []appstudiov1alpha1.Component{*component}, mrData); err != nil {
+		return err
+	}
+
+	previewData := map[string]string{
+		buildPreviewTargetBranchKey:             targetBranch,
+		buildPreviewPipelineNameKey:             pipelineSource.GetPipelineName(),
+		buildPreviewPipelineSourceKey:           pipelineSource.GetLocation(),
+		buildPreviewMergeRequestTitleKey:        mrData.Title,
+		buildPreviewMergeRequestDescriptionKey:  mrData.Text,
+		buildPreviewMergeRequestSourceBranchKey: mrData.BranchName,
+	}
+	for _, file := range mrData.Files {
+		previewData[path.Base(file.FullPath)] = string(file.Content)
+	}
+	if pipelineBundle := getPipelineSourceBundle(pipelineSource); pipelineBundle != "" {
+		previewData[buildPreviewPipelineBundleKey] = pipelineBundle
+	}
+	if matchedSelector != nil {
+		previewData[buildPreviewPipelineSelectorKey] = matchedSelector.Namespace + "/" + matchedSelector.Name
+		previewData[buildPreviewPipelineSelectorItemKey] = getMatchedSelectorItemDisplayName(matchedSelector)
+	}
+
+	return r.ensureBuildPreviewConfigMap(ctx, component, previewData)
+}
+
+// ensureBuildPreviewConfigMap creates or updates the build preview ConfigMap of the given Component.
+func (r *ComponentBuildReconciler) ensureBuildPreviewConfigMap(ctx context.Context, component *appstudiov1alpha1.Component, data map[string]string) error {
+	log := ctrllog.FromContext(ctx)
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Namespace: component.Namespace, Name: getBuildPreviewConfigMapName(component)}
+	if err := r.Client.Get(ctx, configMapKey, configMap); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get build preview ConfigMap", l.Action, l.ActionView)
+			return err
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapKey.Name,
+				Namespace: configMapKey.Namespace,
+				Labels: map[string]string{
+					ComponentNameLabelName: component.Name,
+					PartOfLabelName:        PartOfAppStudioLabelValue,
+				},
+			},
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(component, configMap, r.Scheme); err != nil {
+			log.Error(err, "failed to set owner for build preview ConfigMap", l.Action, l.ActionAdd)
+			return err
+		}
+		if err := r.Client.Create(ctx, configMap); err != nil {
+			log.Error(err, "failed to create build preview ConfigMap", l.Action, l.ActionAdd)
+			return err
+		}
+		log.Info("Build preview ConfigMap created", "ConfigMapName", configMap.Name, l.Action, l.ActionAdd)
+		return nil
+	}
+
+	configMap.Data = data
+	if err := r.Client.Update(ctx, configMap); err != nil {
+		log.Error(err, "failed to update build preview ConfigMap", l.Action, l.ActionUpdate)
+		return err
+	}
+	log.Info("Build preview ConfigMap updated", "ConfigMapName", configMap.Name, l.Action, l.ActionUpdate)
+	return nil
+}
+
+// lookupPaCSecret returns Pipelines as Code secret from the Component namespace or the global one.
+// Unlike ensurePaCSecret, it never creates the secret in the Component namespace.
+func (r *ComponentBuildReconciler) lookupPaCSecret(ctx context.Context, component *appstudiov1alpha1.Component) (*corev1.Secret, error) {
+	return lookupPaCSecretInNamespace(ctx, r.Client, component.Namespace)
+}
+
+// lookupPaCSecretInNamespace returns Pipelines as Code secret from the given namespace or the global one.
+func lookupPaCSecretInNamespace(ctx context.Context, k8sClient client.Client, namespace string) (*corev1.Secret, error) {
+	pacSecret := &corev1.Secret{}
+	pacSecretKey := types.NamespacedName{Namespace: namespace, Name: gitopsprepare.PipelinesAsCodeSecretName}
+	if err := k8sClient.Get(ctx, pacSecretKey, pacSecret); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get Pipelines as Code secret in %s namespace: %w", namespace, err)
+		}
+
+		globalPaCSecretKey := types.NamespacedName{Namespace: buildServiceNamespaceName, Name: gitopsprepare.PipelinesAsCodeSecretName}
+		if err := k8sClient.Get(ctx, globalPaCSecretKey, pacSecret); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get Pipelines as Code secret in %s namespace: %w", globalPaCSecretKey.Namespace, err)
+			}
+			return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretNotFound,
+				fmt.Errorf(" Pipelines as Code secret not found in %s namespace nor in %s", pacSecretKey.Namespace, globalPaCSecretKey.Namespace))
+		}
+	}
+	return pacSecret, nil
+}
+
+func getMatchedSelectorItemDisplayName(matchedSelector *pipelineselector.MatchedSelector) string {
+	if matchedSelector.ItemName != "" {
+		return matchedSelector.ItemName
+	}
+	return "#" + strconv.Itoa(matchedSelector.Index)
+}
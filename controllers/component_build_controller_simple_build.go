@@ -27,7 +27,6 @@ import (
 	"time"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
-	"github.com/redhat-appstudio/application-service/gitops"
 	gitopsprepare "github.com/redhat-appstudio/application-service/gitops/prepare"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
 	"github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
@@ -47,11 +46,15 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 	log := ctrllog.FromContext(ctx).WithName("SimpleBuild")
 	ctx = ctrllog.IntoContext(ctx, log)
 
-	pipelineRef, additionalPipelineParams, err := r.GetPipelineForComponent(ctx, component)
+	pipelineRef, additionalPipelineParams, matchedSelector, err := r.getPipelineWithMatchForComponent(ctx, component)
 	if err != nil {
 		return err
 	}
-	pipelineName, pipelineBundle, err := getPipelineNameAndBundle(pipelineRef)
+	pipelineRef, pipelineBundleTag, err := pinPipelineBundleDigest(ctx, pipelineRef, matchedSelector)
+	if err != nil {
+		return err
+	}
+	pipelineSource, err := newPipelineSource(pipelineRef, r.Client, component.Namespace, isBuildServiceSelector(matchedSelector))
 	if err != nil {
 		return err
 	}
@@ -71,6 +74,9 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 		log.Error(err, fmt.Sprintf("Failed to generate PipelineRun to build %s component in %s namespace", component.Name, component.Namespace))
 		return err
 	}
+	if pipelineBundleTag != "" {
+		buildPipelineRun.Annotations[pipelineBundleTagAnnotationName] = pipelineBundleTag
+	}
 
 	err = controllerutil.SetOwnerReference(component, buildPipelineRun, r.Scheme)
 	if err != nil {
@@ -85,8 +91,8 @@ func (r *ComponentBuildReconciler) SubmitNewBuild(ctx context.Context, component
 
 	simpleBuildPipelineCreationTimeMetric.Observe(time.Since(component.CreationTimestamp.Time).Seconds())
 
-	log.Info(fmt.Sprintf("Build pipeline %s created for component %s in %s namespace using %s pipeline from %s bundle",
-		buildPipelineRun.Name, component.Name, component.Namespace, pipelineName, pipelineBundle),
+	log.Info(fmt.Sprintf("Build pipeline %s created for component %s in %s namespace using %s pipeline from %s",
+		buildPipelineRun.Name, component.Name, component.Namespace, pipelineSource.GetPipelineName(), pipelineSource.GetLocation()),
 		l.Action, l.ActionAdd, l.Audit, "true")
 
 	return nil
@@ -107,7 +113,7 @@ type buildGitInfo struct {
 func (r *ComponentBuildReconciler) getBuildGitInfo(ctx context.Context, component *appstudiov1alpha1.Component, pacConfig map[string][]byte) (*buildGitInfo, error) {
 	log := ctrllog.FromContext(ctx).WithName("getBuildGitInfo")
 
-	gitProvider, err := gitops.GetGitProvider(*component)
+	gitProvider, err := getGitProvider(*component)
 	if err != nil {
 		// There is no point to continue if git provider is not known
 		log.Error(err, "error detecting git provider")
@@ -116,11 +122,18 @@ func (r *ComponentBuildReconciler) getBuildGitInfo(ctx context.Context, componen
 
 	repoUrl := component.Spec.Source.GitSource.URL
 
-	gitClient, err := gitproviderfactory.CreateGitClient(gitproviderfactory.GitClientConfig{
+	apiBaseUrl, err := getGitProviderApiUrl(repoUrl, pacConfig)
+	if err != nil {
+		log.Error(err, "failed to get git provider API URL")
+		return nil, err
+	}
+
+	gitClient, err := gitproviderfactory.CreateGitClient(ctx, gitproviderfactory.GitClientConfig{
 		PacSecretData:             pacConfig,
 		GitProvider:               gitProvider,
 		RepoUrl:                   repoUrl,
 		IsAppInstallationExpected: false,
+		ApiBaseUrl:                apiBaseUrl,
 	})
 	if err != nil {
 		log.Error(err, "failed to instantiate git client")
@@ -128,7 +141,7 @@ func (r *ComponentBuildReconciler) getBuildGitInfo(ctx context.Context, componen
 	}
 
 	var gitSecretName string
-	isPublic, err := gitClient.IsRepositoryPublic(repoUrl)
+	isPublic, err := gitClient.IsRepositoryPublic(ctx, repoUrl)
 	if err != nil {
 		log.Error(err, "failed to determine whether component git repository public or private")
 		return nil, err
@@ -167,7 +180,7 @@ func (r *ComponentBuildReconciler) getBuildGitInfo(ctx context.Context, componen
 		}
 	}
 	if gitSourceSha == "" {
-		gitSourceSha, err = gitClient.GetBranchSha(repoUrl, revision)
+		gitSourceSha, err = gitClient.GetBranchSha(ctx, repoUrl, revision)
 		if err != nil {
 			log.Error(err, "failed to get git branch SHA, continue without it")
 		}
@@ -195,13 +208,16 @@ func generatePipelineRunForComponent(component *appstudiov1alpha1.Component, pip
 		revision = component.Spec.Source.GitSource.Revision
 	}
 
-	pipelineName, pipelineBundle, err := getPipelineNameAndBundle(pipelineRef)
+	// The PipelineRun refers the pipeline, so Tekton retrieves it and the client is not needed
+	pipelineSource, err := newPipelineSource(pipelineRef, nil, component.Namespace, false)
 	if err != nil {
 		return nil, err
 	}
 	annotations := map[string]string{
-		"build.appstudio.redhat.com/pipeline_name": pipelineName,
-		"build.appstudio.redhat.com/bundle":        pipelineBundle,
+		"build.appstudio.redhat.com/pipeline_name": pipelineSource.GetPipelineName(),
+	}
+	if pipelineBundle := getPipelineSourceBundle(pipelineSource); pipelineBundle != "" {
+		annotations[pipelineBundleAnnotationName] = pipelineBundle
 	}
 	if revision != "" {
 		annotations[gitTargetBranchAnnotationName] = revision
@@ -304,6 +320,43 @@ func getGitProviderUrl(gitURL string) (string, error) {
 	return u.Scheme + "://" + u.Host, nil
 }
 
+// publicGitProviderHosts are hosts of the git providers public instances.
+// Git clients use the default API endpoints for them.
+var publicGitProviderHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	// Azure DevOps client derives the API endpoint from the repository URL
+	"dev.azure.com":     true,
+	"ssh.dev.azure.com": true,
+}
+
+// getGitProviderApiUrlKey returns name of the Pipelines as Code secret field
+// that overrides API base URL of the git provider with the given host.
+func getGitProviderApiUrlKey(host string) string {
+	return host + ".api-url"
+}
+
+// getGitProviderApiUrl returns API base URL of the git provider instance that hosts the given repository.
+// Empty string is returned for public git provider instances.
+// For self-hosted instances the URL is taken from the Pipelines as Code secret if it is set for the host,
+// otherwise the git provider host URL is used and the clients append the API path.
+func getGitProviderApiUrl(gitURL string, pacConfig map[string][]byte) (string, error) {
+	gitProviderUrl, err := getGitProviderUrl(gitURL)
+	if err != nil {
+		return "", err
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(gitProviderUrl, "https://"), "http://")
+	if publicGitProviderHosts[strings.ToLower(host)] {
+		return "", nil
+	}
+
+	if apiUrl := strings.TrimSpace(string(pacConfig[getGitProviderApiUrlKey(host)])); apiUrl != "" {
+		return apiUrl, nil
+	}
+	return gitProviderUrl, nil
+}
+
 func getRandomString(length int) string {
 	bytes := make([]byte, length/2+1)
 	if _, err := rand.Read(bytes); err != nil {
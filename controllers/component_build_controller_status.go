@@ -0,0 +1,233 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	l "github.com/redhat-appstudio/build-service/pkg/logs"
+)
+
+// readComponentBuildStatus returns build status of the given Component.
+// The status is read from the ComponentBuildStatus object. If the object doesn't exist yet,
+// the build status annotation is used as a fallback.
+func (r *ComponentBuildReconciler) readComponentBuildStatus(ctx context.Context, component *appstudiov1alpha1.Component) (*BuildStatus, error) {
+	componentBuildStatus := &buildappstudiov1alpha1.ComponentBuildStatus{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: component.Namespace, Name: component.Name}, componentBuildStatus); err != nil {
+		if errors.IsNotFound(err) {
+			return readBuildStatus(component), nil
+		}
+		ctrllog.FromContext(ctx).Error(err, "failed to get ComponentBuildStatus", l.Action, l.ActionView)
+		return nil, err
+	}
+	if !metav1.IsControlledBy(componentBuildStatus, component) {
+		// The object belongs to a deleted Component with the same name and is not garbage collected yet
+		return readBuildStatus(component), nil
+	}
+	return fromComponentBuildStatus(&componentBuildStatus.Status), nil
+}
+
+// writeComponentBuildStatus creates or updates ComponentBuildStatus object of the given Component.
+// The object is owned by the Component, so it is garbage collected together with the Component.
+func (r *ComponentBuildReconciler) writeComponentBuildStatus(ctx context.Context, component *appstudiov1alpha1.Component, buildStatus *BuildStatus) error {
+	log := ctrllog.FromContext(ctx)
+
+	status := toComponentBuildStatus(buildStatus)
+	status.Pipeline, status.PipelineSelection = r.getSelectedPipeline(ctx, component)
+
+	componentBuildStatus := &buildappstudiov1alpha1.ComponentBuildStatus{}
+	componentBuildStatusKey := types.NamespacedName{Namespace: component.Namespace, Name: component.Name}
+	if err := r.Client.Get(ctx, componentBuildStatusKey, componentBuildStatus); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get ComponentBuildStatus", l.Action, l.ActionView)
+			return err
+		}
+
+		componentBuildStatus = &buildappstudiov1alpha1.ComponentBuildStatus{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      component.Name,
+				Namespace: component.Namespace,
+				Labels: map[string]string{
+					ComponentNameLabelName: component.Name,
+				},
+			},
+			Spec: buildappstudiov1alpha1.ComponentBuildStatusSpec{
+				ComponentName: component.Name,
+			},
+		}
+		if err := controllerutil.SetControllerReference(component, componentBuildStatus, r.Scheme); err != nil {
+			log.Error(err, "failed to set owner reference for ComponentBuildStatus", l.Action, l.ActionAdd)
+			return err
+		}
+		if err := r.Client.Create(ctx, componentBuildStatus); err != nil {
+			log.Error(err, "failed to create ComponentBuildStatus", l.Action, l.ActionAdd)
+			return err
+		}
+		log.Info("created ComponentBuildStatus", l.Action, l.ActionAdd)
+	} else if !metav1.IsControlledBy(componentBuildStatus, component) {
+		// Take over the object left from a deleted Component with the same name
+		componentBuildStatus.OwnerReferences = nil
+		if err := controllerutil.SetControllerReference(component, componentBuildStatus, r.Scheme); err != nil {
+			log.Error(err, "failed to set owner reference for ComponentBuildStatus", l.Action, l.ActionUpdate)
+			return err
+		}
+		if err := r.Client.Update(ctx, componentBuildStatus); err != nil {
+			log.Error(err, "failed to update ComponentBuildStatus owner", l.Action, l.ActionUpdate)
+			return err
+		}
+	}
+
+	if equality.Semantic.DeepEqual(componentBuildStatus.Status, *status) {
+		return nil
+	}
+	componentBuildStatus.Status = *status
+	if err := r.Client.Status().Update(ctx, componentBuildStatus); err != nil {
+		log.Error(err, "failed to update ComponentBuildStatus", l.Action, l.ActionUpdate)
+		return err
+	}
+	return nil
+}
+
+// ensureComponentBuildStatus creates ComponentBuildStatus object from the build status annotation,
+// if the object doesn't exist. It is needed for the Components processed before the object was introduced.
+func (r *ComponentBuildReconciler) ensureComponentBuildStatus(ctx context.Context, component *appstudiov1alpha1.Component) error {
+	componentBuildStatus := &buildappstudiov1alpha1.ComponentBuildStatus{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: component.Namespace, Name: component.Name}, componentBuildStatus); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if metav1.IsControlledBy(componentBuildStatus, component) {
+		return nil
+	}
+	return r.writeComponentBuildStatus(ctx, component, readBuildStatus(component))
+}
+
+// getSelectedPipeline returns the build pipeline selected for the Component or nil if it cannot be determined.
+// If no pipeline matches the Component, the selection trace is returned instead.
+func (r *ComponentBuildReconciler) getSelectedPipeline(ctx context.Context, component *appstudiov1alpha1.Component) (*buildappstudiov1alpha1.SelectedPipeline, []buildappstudiov1alpha1.PipelineSelectorTrace) {
+	selectionResult, err := r.evaluatePipelineSelectorsForComponent(ctx, component)
+	if err != nil {
+		return nil, nil
+	}
+	if selectionResult.PipelineRef == nil {
+		return nil, selectionResult.Trace
+	}
+	pipelineSource, err := newPipelineSource(selectionResult.PipelineRef, r.Client, component.Namespace, isBuildServiceSelector(selectionResult.Matched))
+	if err != nil {
+		return nil, nil
+	}
+	return &buildappstudiov1alpha1.SelectedPipeline{
+		Name:   pipelineSource.GetPipelineName(),
+		Bundle: getPipelineSourceBundle(pipelineSource),
+		Source: pipelineSource.GetLocation(),
+	}, nil
+}
+
+// toComponentBuildStatus converts build status annotation model into ComponentBuildStatus status.
+func toComponentBuildStatus(buildStatus *BuildStatus) *buildappstudiov1alpha1.ComponentBuildStatusStatus {
+	status := &buildappstudiov1alpha1.ComponentBuildStatusStatus{
+		Message: buildStatus.Message,
+	}
+	if buildStatus.Simple != nil {
+		status.Simple = &buildappstudiov1alpha1.SimpleBuildStatus{
+			BuildStartTime: parseBuildStatusTime(buildStatus.Simple.BuildStartTime),
+			BuildErrorInfo: buildappstudiov1alpha1.BuildErrorInfo{
+				ErrId:      buildStatus.Simple.ErrId,
+				ErrMessage: buildStatus.Simple.ErrMessage,
+			},
+		}
+	}
+	if buildStatus.PaC != nil {
+		status.PaC = &buildappstudiov1alpha1.PaCBuildStatus{
+			State:               buildStatus.PaC.State,
+			MergeUrl:            buildStatus.PaC.MergeUrl,
+			ConfigurationTime:   parseBuildStatusTime(buildStatus.PaC.ConfigurationTime),
+			CommitSigningMethod: buildStatus.PaC.CommitSigningMethod,
+			MergeState:          buildStatus.PaC.MergeState,
+			Mergeable:           buildStatus.PaC.Mergeable,
+			MergeTime:           parseBuildStatusTime(buildStatus.PaC.MergeTime),
+			MergeCheckTime:      parseBuildStatusTime(buildStatus.PaC.MergeCheckTime),
+			BuildErrorInfo: buildappstudiov1alpha1.BuildErrorInfo{
+				ErrId:      buildStatus.PaC.ErrId,
+				ErrMessage: buildStatus.PaC.ErrMessage,
+			},
+		}
+	}
+	return status
+}
+
+// fromComponentBuildStatus converts ComponentBuildStatus status into build status annotation model.
+func fromComponentBuildStatus(status *buildappstudiov1alpha1.ComponentBuildStatusStatus) *BuildStatus {
+	buildStatus := &BuildStatus{
+		Message: status.Message,
+	}
+	if status.Simple != nil {
+		buildStatus.Simple = &SimpleBuildStatus{
+			BuildStartTime: formatBuildStatusTime(status.Simple.BuildStartTime),
+			ErrorInfo: ErrorInfo{
+				ErrId:      status.Simple.ErrId,
+				ErrMessage: status.Simple.ErrMessage,
+			},
+		}
+	}
+	if status.PaC != nil {
+		buildStatus.PaC = &PaCBuildStatus{
+			State:               status.PaC.State,
+			MergeUrl:            status.PaC.MergeUrl,
+			ConfigurationTime:   formatBuildStatusTime(status.PaC.ConfigurationTime),
+			CommitSigningMethod: status.PaC.CommitSigningMethod,
+			MergeState:          status.PaC.MergeState,
+			Mergeable:           status.PaC.Mergeable,
+			MergeTime:           formatBuildStatusTime(status.PaC.MergeTime),
+			MergeCheckTime:      formatBuildStatusTime(status.PaC.MergeCheckTime),
+			ErrorInfo: ErrorInfo{
+				ErrId:      status.PaC.ErrId,
+				ErrMessage: status.PaC.ErrMessage,
+			},
+		}
+	}
+	return buildStatus
+}
+
+// parseBuildStatusTime parses time in RFC1123 format used in the build status annotation.
+func parseBuildStatusTime(value string) *metav1.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC1123, value)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t.UTC()}
+}
+
+func formatBuildStatusTime(t *metav1.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123)
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -44,6 +46,7 @@ import (
 	"github.com/redhat-appstudio/application-service/pkg/devfile"
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gitfake "github.com/redhat-appstudio/build-service/pkg/git/fake"
 	"github.com/redhat-appstudio/build-service/pkg/git/github"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 	gpf "github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
@@ -99,7 +102,7 @@ var _ = Describe("Component initial build controller", func() {
 	BeforeEach(func() {
 		createNamespace(buildServiceNamespaceName)
 		createDefaultBuildPipelineRunSelector(defaultSelectorKey)
-		github.GetAppInstallations = func(githubAppIdStr string, appPrivateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+		github.GetAppInstallations = func(ctx context.Context, githubAppIdStr string, appPrivateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 			return nil, "slug", nil
 		}
 	})
@@ -201,8 +204,116 @@ var _ = Describe("Component initial build controller", func() {
 			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", mergeUrl)
 		})
 
+		It("should submit PR with PaC definitions of a pipeline from git repository", func() {
+			deleteBuildPipelineRunSelector(defaultSelectorKey)
+			gitPipelineSelector := buildappstudiov1alpha1.BuildPipelineSelector{
+				ObjectMeta: metav1.ObjectMeta{Name: defaultSelectorKey.Name, Namespace: defaultSelectorKey.Namespace},
+				Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+					Selectors: []buildappstudiov1alpha1.PipelineSelector{
+						{
+							Name: SelectorDefaultName,
+							PipelineRef: buildappstudiov1alpha1.BackwardsCompatiblePipelineRef{
+								PipelineRef: tektonapi.PipelineRef{
+									ResolverRef: tektonapi.ResolverRef{
+										Resolver: "git",
+										Params: []tektonapi.Param{
+											{Name: "url", Value: *tektonapi.NewStructuredValues("https://github.com/org/pipelines")},
+											{Name: "revision", Value: *tektonapi.NewStructuredValues("main")},
+											{Name: "pathInRepo", Value: *tektonapi.NewStructuredValues("pipelines/git-build.yaml")},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &gitPipelineSelector)).To(Succeed())
+
+			DownloadFileContentFunc = func(repoUrl, revision, filePath string) ([]byte, error) {
+				defer GinkgoRecover()
+				Expect(repoUrl).To(Equal("https://github.com/org/pipelines"))
+				Expect(revision).To(Equal("main"))
+				Expect(filePath).To(Equal("pipelines/git-build.yaml"))
+				return []byte(`
+apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: git-build
+spec:
+  tasks:
+  - name: git-build-task
+    taskRef:
+      name: build
+`), nil
+			}
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				isCreatePaCPullRequestInvoked = true
+				defer GinkgoRecover()
+				Expect(len(d.Files)).To(Equal(2))
+				for _, file := range d.Files {
+					Expect(string(file.Content)).To(ContainSubstring("git-build-task"))
+				}
+				return "merge-url", nil
+			}
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+
+			Eventually(func() *buildappstudiov1alpha1.SelectedPipeline {
+				return getComponentBuildStatus(resourcePacPrepKey).Status.Pipeline
+			}, timeout, interval).Should(Equal(&buildappstudiov1alpha1.SelectedPipeline{
+				Name:   "git-build",
+				Source: "https://github.com/org/pipelines@main:pipelines/git-build.yaml",
+			}))
+		})
+
+		It("should submit PR customized by merge request templates from the namespace", func() {
+			templatesConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: PaCMergeRequestTemplatesConfigMapName, Namespace: resourcePacPrepKey.Namespace},
+				Data: map[string]string{
+					"title":          "ci({{ .Component.Name }}): onboard {{ .Application }} to {{ .PipelineName }}",
+					"commit-message": "ci({{ .Component.Name }}): add build pipeline\n\nRefs: BUILD-123",
+					"description":    "Pipeline bundle: {{ .PipelineBundle }}",
+					"author-name":    "org-bot",
+					"author-email":   "bot@example.com",
+					"branch-prefix":  "konflux/",
+				},
+			}
+			Expect(k8sClient.Create(ctx, templatesConfigMap)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, templatesConfigMap)).To(Succeed())
+			}()
+
+			isCreatePaCPullRequestInvoked := false
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				isCreatePaCPullRequestInvoked = true
+				defer GinkgoRecover()
+				Expect(d.Title).To(Equal(fmt.Sprintf("ci(%s): onboard %s to %s", resourcePacPrepKey.Name, HASAppName, defaultPipelineName)))
+				Expect(d.CommitMessage).To(Equal(fmt.Sprintf("ci(%s): add build pipeline\n\nRefs: BUILD-123", resourcePacPrepKey.Name)))
+				Expect(d.Text).To(Equal("Pipeline bundle: " + defaultPipelineBundle))
+				Expect(d.AuthorName).To(Equal("org-bot"))
+				Expect(d.AuthorEmail).To(Equal("bot@example.com"))
+				Expect(d.BranchName).To(Equal("konflux/" + resourcePacPrepKey.Name))
+				return "merge-url", nil
+			}
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+			Eventually(func() bool {
+				return isCreatePaCPullRequestInvoked
+			}, timeout, interval).Should(BeTrue())
+		})
+
 		It("should fail to submit PR if GitHub application is not installed into git repository", func() {
-			gpf.CreateGitClient = func(gpf.GitClientConfig) (gp.GitProviderClient, error) {
+			gpf.CreateGitClient = func(context.Context, gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
 				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled,
 					fmt.Errorf("GitHub Application is not installed into the repository"))
 			}
@@ -276,7 +387,7 @@ var _ = Describe("Component initial build controller", func() {
 		It("should successfully do PaC provision after error (when PaC GitHub Application was not installed)", func() {
 			appNotInstalledErr := boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, nil)
 			isCreateGithubClientInvoked := false
-			gpf.CreateGitClient = func(gitClientConfig gpf.GitClientConfig) (gp.GitProviderClient, error) {
+			gpf.CreateGitClient = func(ctx context.Context, gitClientConfig gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
 				isCreateGithubClientInvoked = true
 				return nil, appNotInstalledErr
 			}
@@ -293,7 +404,7 @@ var _ = Describe("Component initial build controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			// Ensure no more retries after permanent error
-			gpf.CreateGitClient = func(gitClientConfig gpf.GitClientConfig) (gp.GitProviderClient, error) {
+			gpf.CreateGitClient = func(ctx context.Context, gitClientConfig gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
 				defer GinkgoRecover()
 				Fail("Should not retry PaC provision on permanent error")
 				return nil, nil
@@ -304,8 +415,8 @@ var _ = Describe("Component initial build controller", func() {
 			}, ensureTimeout, interval).Should(BeTrue())
 
 			// Suppose PaC GH App is installed
-			gpf.CreateGitClient = func(gitClientConfig gpf.GitClientConfig) (gp.GitProviderClient, error) {
-				return testGitProviderClient, nil
+			gpf.CreateGitClient = func(ctx context.Context, gitClientConfig gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
+				return gp.NewClientWithContext(testGitProviderClient), nil
 			}
 			mergeUrl := "merge-url"
 			isCreatePaCPullRequestInvoked := false
@@ -335,46 +446,185 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should successfully submit PR with PaC definitions using token", func() {
-			isCreatePaCPullRequestInvoked := false
+			repoUrl := SampleRepoLink + "-" + resourcePacPrepKey.Name
+			fakeGitProvider := gitfake.NewGitProvider()
+			fakeGitProvider.AddRepository(repoUrl, "main", []gp.RepositoryFile{{FullPath: "README.md", Content: []byte("readme")}})
+			UseFakeGitProvider(fakeGitProvider)
+
+			pacSecretData := map[string]string{"github.token": "ghp_token"}
+			createSecret(pacSecretKey, pacSecretData)
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+
+			waitSecretCreated(namespacePaCSecretKey)
+			waitSecretCreated(webhookSecretKey)
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+
+			var mergeRequests []gitfake.MergeRequest
+			Eventually(func() []gitfake.MergeRequest {
+				mergeRequests, _ = fakeGitProvider.GetMergeRequests(repoUrl)
+				return mergeRequests
+			}, timeout, interval).Should(HaveLen(1))
+			mergeRequest := mergeRequests[0]
+			Expect(mergeRequest.State).To(Equal(gp.MergeRequestStateOpen))
+			Expect(mergeRequest.TargetBranch).To(Equal("main"))
+			Expect(mergeRequest.SourceBranch).ToNot(BeEmpty())
+			Expect(mergeRequest.Title).ToNot(BeEmpty())
+			Expect(mergeRequest.Text).ToNot(BeEmpty())
+			Expect(mergeRequest.Author).ToNot(BeEmpty())
+
+			proposal, err := fakeGitProvider.GetCommit(repoUrl, mergeRequest.SourceBranch)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proposal.Files).To(HaveLen(3))
+			Expect(proposal.Files).To(HaveKey(".tekton/" + resourcePacPrepKey.Name + "-" + pipelineRunOnPushFilename))
+			Expect(proposal.Files).To(HaveKey(".tekton/" + resourcePacPrepKey.Name + "-" + pipelineRunOnPRFilename))
+			Expect(proposal.Message).ToNot(BeEmpty())
+			Expect(proposal.AuthorName).ToNot(BeEmpty())
+			Expect(proposal.AuthorEmail).ToNot(BeEmpty())
+
+			webhooks, err := fakeGitProvider.GetWebhooks(repoUrl)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(webhooks).To(HaveLen(1))
+			Expect(webhooks[0].Url).To(Equal(pacWebhookUrl))
+			Expect(webhooks[0].Secret).ToNot(BeEmpty())
+
+			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", mergeRequest.WebUrl)
+		})
+
+		It("should refresh outdated PaC merge request periodically", func() {
 			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
-				isCreatePaCPullRequestInvoked = true
+				return "configure-merge-url", nil
+			}
+			GetMergeRequestStatusFunc = func(repoUrl string, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+				return &gp.MergeRequestStatus{State: gp.MergeRequestStateOpen, IsBehind: mergeRequestWebUrl == "configure-merge-url"}, nil
+			}
+			RefreshPaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, bool, error) {
 				Expect(repoUrl).To(Equal(SampleRepoLink + "-" + resourcePacPrepKey.Name))
 				Expect(len(d.Files)).To(Equal(2))
-				for _, file := range d.Files {
-					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
-				}
-				Expect(d.CommitMessage).ToNot(BeEmpty())
 				Expect(d.BranchName).ToNot(BeEmpty())
-				Expect(d.BaseBranchName).ToNot(BeEmpty())
-				Expect(d.Title).ToNot(BeEmpty())
-				Expect(d.Text).ToNot(BeEmpty())
-				Expect(d.AuthorName).ToNot(BeEmpty())
-				Expect(d.AuthorEmail).ToNot(BeEmpty())
-				return "url", nil
+				return "refreshed-merge-url", true, nil
 			}
-			isSetupPaCWebhookInvoked := false
-			SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string) error {
-				isSetupPaCWebhookInvoked = true
-				Expect(webhookUrl).To(Equal(pacWebhookUrl))
-				Expect(webhookSecret).ToNot(BeEmpty())
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+
+			Eventually(func() string {
+				return readBuildStatus(getComponent(resourcePacPrepKey)).PaC.MergeUrl
+			}, timeout, interval).Should(Equal("refreshed-merge-url"))
+			Expect(readBuildStatus(getComponent(resourcePacPrepKey)).PaC.MergeState).To(Equal(pacMergeStatePendingMerge))
+		})
+
+		It("should refresh outdated PaC merge request on request", func() {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				return "configure-merge-url", nil
+			}
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", "configure-merge-url")
+
+			GetMergeRequestStatusFunc = func(repoUrl string, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+				return &gp.MergeRequestStatus{State: gp.MergeRequestStateOpen, IsBehind: true}, nil
+			}
+			isRefreshPaCMergeRequestInvoked := false
+			RefreshPaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, bool, error) {
+				isRefreshPaCMergeRequestInvoked = true
+				return "configure-merge-url", true, nil
+			}
+			setComponentBuildRequest(resourcePacPrepKey, BuildRequestRefreshPaCAnnotationValue)
+			waitComponentAnnotationGone(resourcePacPrepKey, BuildRequestAnnotationName)
+
+			Expect(isRefreshPaCMergeRequestInvoked).To(BeTrue())
+			buildStatus := readBuildStatus(getComponent(resourcePacPrepKey))
+			Expect(buildStatus.Message).To(Equal("done"))
+			Expect(buildStatus.PaC.MergeUrl).To(Equal("configure-merge-url"))
+			Expect(buildStatus.PaC.MergeState).To(Equal(pacMergeStatePendingMerge))
+		})
+
+		It("should not refresh PaC merge request if PaC is not provisioned", func() {
+			RefreshPaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, bool, error) {
+				defer GinkgoRecover()
+				Fail("PaC merge request must not be refreshed")
+				return "", false, nil
+			}
+
+			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestRefreshPaCAnnotationValue)
+
+			buildStatus := readBuildStatus(getComponent(resourcePacPrepKey))
+			Expect(buildStatus.Message).To(ContainSubstring("nothing to refresh"))
+		})
+
+		It("should commit PaC definitions directly into base branch if requested", func() {
+			isCommitPaCConfigurationInvoked := false
+			CommitPaCConfigurationFunc = func(repoUrl string, d *gp.MergeRequestData) (bool, error) {
+				isCommitPaCConfigurationInvoked = true
 				Expect(repoUrl).To(Equal(SampleRepoLink + "-" + resourcePacPrepKey.Name))
-				return nil
+				Expect(len(d.Files)).To(Equal(2))
+				Expect(d.BaseBranchName).To(Equal("main"))
+				Expect(d.CommitMessage).ToNot(BeEmpty())
+				return true, nil
+			}
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				defer GinkgoRecover()
+				Fail("PaC merge request must not be created")
+				return "", nil
 			}
 
-			pacSecretData := map[string]string{"github.token": "ghp_token"}
-			createSecret(pacSecretKey, pacSecretData)
+			componentConfig := componentConfig{
+				componentKey: resourcePacPrepKey,
+				annotations:  map[string]string{PaCDirectCommitAnnotationName: "true"},
+			}
+			createCustomComponentWithBuildRequest(componentConfig, BuildRequestConfigurePaCAnnotationValue)
+			waitComponentAnnotationGone(resourcePacPrepKey, BuildRequestAnnotationName)
+			waitPaCRepositoryCreated(resourcePacPrepKey)
 
-			createComponentAndProcessBuildRequest(resourcePacPrepKey, BuildRequestConfigurePaCAnnotationValue)
+			Expect(isCommitPaCConfigurationInvoked).To(BeTrue())
+			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", "")
+			Expect(readBuildStatus(getComponent(resourcePacPrepKey)).PaC.MergeState).To(Equal(pacMergeStateMerged))
+		})
 
-			waitSecretCreated(namespacePaCSecretKey)
-			waitSecretCreated(webhookSecretKey)
+		It("should submit PR with PaC definitions if base branch is protected", func() {
+			IsBranchProtectedFunc = func(repoUrl, branchName string) (bool, error) {
+				Expect(branchName).To(Equal("main"))
+				return true, nil
+			}
+			CommitPaCConfigurationFunc = func(repoUrl string, d *gp.MergeRequestData) (bool, error) {
+				defer GinkgoRecover()
+				Fail("PaC configuration must not be pushed into protected branch")
+				return false, nil
+			}
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				return "merge-url", nil
+			}
+
+			componentConfig := componentConfig{
+				componentKey: resourcePacPrepKey,
+				annotations:  map[string]string{PaCDirectCommitAnnotationName: "true"},
+			}
+			createCustomComponentWithBuildRequest(componentConfig, BuildRequestConfigurePaCAnnotationValue)
+			waitComponentAnnotationGone(resourcePacPrepKey, BuildRequestAnnotationName)
 			waitPaCRepositoryCreated(resourcePacPrepKey)
-			Eventually(func() bool {
-				return isCreatePaCPullRequestInvoked
-			}, timeout, interval).Should(BeTrue())
-			Eventually(func() bool {
-				return isSetupPaCWebhookInvoked
-			}, timeout, interval).Should(BeTrue())
+
+			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", "merge-url")
+		})
+
+		It("should submit PR with PaC definitions if direct push is rejected", func() {
+			CommitPaCConfigurationFunc = func(repoUrl string, d *gp.MergeRequestData) (bool, error) {
+				return false, boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, fmt.Errorf("protected branch"))
+			}
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				return "merge-url", nil
+			}
+
+			componentConfig := componentConfig{
+				componentKey: resourcePacPrepKey,
+				annotations:  map[string]string{PaCDirectCommitAnnotationName: "true"},
+			}
+			createCustomComponentWithBuildRequest(componentConfig, BuildRequestConfigurePaCAnnotationValue)
+			waitComponentAnnotationGone(resourcePacPrepKey, BuildRequestAnnotationName)
+			waitPaCRepositoryCreated(resourcePacPrepKey)
+
+			expectPacBuildStatus(resourcePacPrepKey, "enabled", 0, "", "merge-url")
 		})
 
 		It("should provision PaC definitions after initial build, use simple build while PaC enabled, and be able to switch back to simple build only", func() {
@@ -551,6 +801,45 @@ var _ = Describe("Component initial build controller", func() {
 			Expect(buildStatus.Message).To(ContainSubstring("unexpected build request"))
 		})
 
+		It("should save build preview into ConfigMap without changes in the repository", func() {
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				defer GinkgoRecover()
+				Fail("PR creation should not be invoked on build preview")
+				return "", nil
+			}
+			SetupPaCWebhookFunc = func(string, string, string) error {
+				defer GinkgoRecover()
+				Fail("Webhook should not be configured on build preview")
+				return nil
+			}
+
+			createComponentWithBuildRequest(resourcePacPrepKey, BuildRequestPreviewAnnotationValue)
+			waitComponentAnnotationGone(resourcePacPrepKey, BuildRequestAnnotationName)
+
+			component := getComponent(resourcePacPrepKey)
+			previewConfigMapKey := types.NamespacedName{Namespace: resourcePacPrepKey.Namespace, Name: getBuildPreviewConfigMapName(component)}
+			previewConfigMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, previewConfigMapKey, previewConfigMap)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, previewConfigMap)).To(Succeed())
+			}()
+
+			Expect(previewConfigMap.Data[resourcePacPrepKey.Name+"-"+pipelineRunOnPushFilename]).To(ContainSubstring("kind: PipelineRun"))
+			Expect(previewConfigMap.Data[resourcePacPrepKey.Name+"-"+pipelineRunOnPRFilename]).To(ContainSubstring("kind: PipelineRun"))
+			Expect(previewConfigMap.Data[buildPreviewTargetBranchKey]).To(Equal("main"))
+			Expect(previewConfigMap.Data[buildPreviewPipelineNameKey]).To(Equal(defaultPipelineName))
+			Expect(previewConfigMap.Data[buildPreviewPipelineBundleKey]).To(Equal(defaultPipelineBundle))
+			Expect(previewConfigMap.Data[buildPreviewPipelineSelectorKey]).To(Equal(defaultSelectorKey.Namespace + "/" + defaultSelectorKey.Name))
+			Expect(previewConfigMap.Data[buildPreviewPipelineSelectorItemKey]).To(Equal(SelectorDefaultName))
+			Expect(metav1.IsControlledBy(previewConfigMap, component)).To(BeTrue())
+
+			buildStatus := readBuildStatus(component)
+			Expect(buildStatus.Message).To(ContainSubstring(previewConfigMapKey.Name))
+			Expect(buildStatus.PaC).To(BeNil())
+
+			ensureNoPipelineRunsCreated(resourcePacPrepKey)
+		})
+
 		It("should do nothing if the component devfile model is not set", func() {
 			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
 				defer GinkgoRecover()
@@ -834,6 +1123,45 @@ var _ = Describe("Component initial build controller", func() {
 			expectPacBuildStatus(resourceCleanupKey, "disabled", 0, "", "")
 		})
 
+		It("should delete PaC definitions directly from base branch if requested", func() {
+			pacSecretData := map[string]string{
+				"github-application-id": "12345",
+				"github-private-key":    githubAppPrivateKey,
+			}
+			createSecret(pacSecretKey, pacSecretData)
+			componentConfig := componentConfig{
+				componentKey: resourceCleanupKey,
+				annotations:  map[string]string{PaCDirectCommitAnnotationName: "true"},
+			}
+			createCustomComponentWithBuildRequest(componentConfig, BuildRequestConfigurePaCAnnotationValue)
+			waitComponentAnnotationGone(resourceCleanupKey, BuildRequestAnnotationName)
+			waitPaCFinalizerOnComponent(resourceCleanupKey)
+
+			isCommitPaCConfigurationRemovalInvoked := false
+			CommitPaCConfigurationRemovalFunc = func(repoUrl string, d *gp.MergeRequestData) (bool, error) {
+				isCommitPaCConfigurationRemovalInvoked = true
+				Expect(repoUrl).To(Equal(SampleRepoLink + "-" + resourceCleanupKey.Name))
+				Expect(len(d.Files)).To(Equal(2))
+				for _, file := range d.Files {
+					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
+				}
+				Expect(d.BaseBranchName).To(Equal("main"))
+				return true, nil
+			}
+			UndoPaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+				defer GinkgoRecover()
+				Fail("PaC removal merge request must not be created")
+				return "", nil
+			}
+
+			setComponentBuildRequest(resourceCleanupKey, BuildRequestUnconfigurePaCAnnotationValue)
+			waitPaCFinalizerOnComponentGone(resourceCleanupKey)
+			waitDoneMessageOnComponent(resourceCleanupKey)
+
+			Expect(isCommitPaCConfigurationRemovalInvoked).To(BeTrue())
+			expectPacBuildStatus(resourceCleanupKey, "disabled", 0, "", "")
+		})
+
 		It("should successfully submit PR with PaC definitions removal using GitHub application, remove all incomings and incoming secret (only current component is using)", func() {
 			mergeUrl := "merge-url"
 			isRemovePaCPullRequestInvoked := false
@@ -1006,31 +1334,10 @@ var _ = Describe("Component initial build controller", func() {
 		})
 
 		It("should successfully submit merge request with PaC definitions removal using token", func() {
-			mergeUrl := "merge-url"
-			isRemovePaCPullRequestInvoked := false
-			UndoPaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
-				isRemovePaCPullRequestInvoked = true
-				Expect(repoUrl).To(Equal(SampleRepoLink + "-" + resourceCleanupKey.Name))
-				Expect(len(d.Files)).To(Equal(2))
-				for _, file := range d.Files {
-					Expect(strings.HasPrefix(file.FullPath, ".tekton/")).To(BeTrue())
-				}
-				Expect(d.CommitMessage).ToNot(BeEmpty())
-				Expect(d.BranchName).ToNot(BeEmpty())
-				Expect(d.BaseBranchName).ToNot(BeEmpty())
-				Expect(d.Title).ToNot(BeEmpty())
-				Expect(d.Text).ToNot(BeEmpty())
-				Expect(d.AuthorName).ToNot(BeEmpty())
-				Expect(d.AuthorEmail).ToNot(BeEmpty())
-				return mergeUrl, nil
-			}
-			isDeletePaCWebhookInvoked := false
-			DeletePaCWebhookFunc = func(repoUrl string, webhookUrl string) error {
-				isDeletePaCWebhookInvoked = true
-				Expect(repoUrl).To(Equal(SampleRepoLink + "-" + resourceCleanupKey.Name))
-				Expect(webhookUrl).To(Equal(pacWebhookUrl))
-				return nil
-			}
+			repoUrl := SampleRepoLink + "-" + resourceCleanupKey.Name
+			fakeGitProvider := gitfake.NewGitProvider()
+			fakeGitProvider.AddRepository(repoUrl, "main", []gp.RepositoryFile{{FullPath: "README.md", Content: []byte("readme")}})
+			UseFakeGitProvider(fakeGitProvider)
 
 			pacSecretData := map[string]string{"github.token": "ghp_token"}
 			createSecret(pacSecretKey, pacSecretData)
@@ -1038,16 +1345,40 @@ var _ = Describe("Component initial build controller", func() {
 			createComponentAndProcessBuildRequest(resourceCleanupKey, BuildRequestConfigurePaCAnnotationValue)
 			waitPaCFinalizerOnComponent(resourceCleanupKey)
 
+			// Merge the configuration proposal, so there is the configuration to remove from the base branch
+			var mergeRequests []gitfake.MergeRequest
+			Eventually(func() []gitfake.MergeRequest {
+				mergeRequests, _ = fakeGitProvider.GetMergeRequests(repoUrl)
+				return mergeRequests
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(fakeGitProvider.MergeMergeRequest(repoUrl, mergeRequests[0].Number)).To(Succeed())
+
 			setComponentBuildRequest(resourceCleanupKey, BuildRequestUnconfigurePaCAnnotationValue)
 
-			Eventually(func() bool {
-				return isRemovePaCPullRequestInvoked
-			}, timeout, interval).Should(BeTrue())
-			Eventually(func() bool {
-				return isDeletePaCWebhookInvoked
-			}, timeout, interval).Should(BeTrue())
+			Eventually(func() []gitfake.MergeRequest {
+				mergeRequests, _ = fakeGitProvider.GetMergeRequests(repoUrl)
+				return mergeRequests
+			}, timeout, interval).Should(HaveLen(2))
+			removalMergeRequest := mergeRequests[1]
+			Expect(removalMergeRequest.State).To(Equal(gp.MergeRequestStateOpen))
+			Expect(removalMergeRequest.TargetBranch).To(Equal("main"))
+			Expect(removalMergeRequest.Title).ToNot(BeEmpty())
+			Expect(removalMergeRequest.Text).ToNot(BeEmpty())
 
-			expectPacBuildStatus(resourceCleanupKey, "disabled", 0, "", mergeUrl)
+			removal, err := fakeGitProvider.GetCommit(repoUrl, removalMergeRequest.SourceBranch)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(removal.Files).To(HaveLen(1))
+			Expect(removal.Files).To(HaveKey("README.md"))
+			Expect(removal.Message).ToNot(BeEmpty())
+			Expect(removal.AuthorName).ToNot(BeEmpty())
+			Expect(removal.AuthorEmail).ToNot(BeEmpty())
+
+			Eventually(func() []gitfake.Webhook {
+				webhooks, _ := fakeGitProvider.GetWebhooks(repoUrl)
+				return webhooks
+			}, timeout, interval).Should(BeEmpty())
+
+			expectPacBuildStatus(resourceCleanupKey, "disabled", 0, "", removalMergeRequest.WebUrl)
 		})
 
 		It("should not block component deletion if PaC definitions removal failed", func() {
@@ -1282,6 +1613,45 @@ var _ = Describe("Component initial build controller", func() {
 			Expect(k8sClient.List(ctx, pacRepositoriesList, &client.ListOptions{Namespace: component1Key.Namespace})).To(Succeed())
 			Expect(pacRepositoriesList.Items).To(HaveLen(2)) // 2-nd repository for the anotherComponentKey component
 		})
+
+		It("should propose PaC configuration of components from the same git repository in one merge request", func() {
+			os.Setenv(PaCBatchMergeRequestsEnvName, "true")
+			defer os.Unsetenv(PaCBatchMergeRequestsEnvName)
+
+			component1Key := types.NamespacedName{Name: "test-batch-component1", Namespace: HASAppNamespace}
+			component2Key := types.NamespacedName{Name: "test-batch-component2", Namespace: HASAppNamespace}
+			batchBranch := "appstudio-batch-" + component1Key.Name + "-dafaultbranch"
+
+			// Nothing is merged yet
+			IsFileExistFunc = func(repoUrl, branchName, filePath string) (bool, error) {
+				return false, nil
+			}
+			batchMergeRequestFilesNumber := 0
+			EnsurePaCMergeRequestFunc = func(repoUrl string, d *gp.MergeRequestData) (string, error) {
+				defer GinkgoRecover()
+				Expect(d.BranchName).To(Equal(batchBranch))
+				batchMergeRequestFilesNumber = len(d.Files)
+				return "url", nil
+			}
+
+			createCustomComponentWithBuildRequest(componentConfig{
+				componentKey: component1Key,
+				gitURL:       multiComponentGitRepositoryUrl,
+			}, BuildRequestConfigurePaCAnnotationValue)
+			defer deleteComponent(component1Key)
+			waitComponentAnnotationGone(component1Key, BuildRequestAnnotationName)
+			Eventually(func() int { return batchMergeRequestFilesNumber }, timeout, interval).Should(Equal(2))
+			waitPaCRepositoryCreated(component1Key)
+			defer deletePaCRepository(component1Key)
+
+			createCustomComponentWithBuildRequest(componentConfig{
+				componentKey: component2Key,
+				gitURL:       multiComponentGitRepositoryUrl,
+			}, BuildRequestConfigurePaCAnnotationValue)
+			defer deleteComponent(component2Key)
+			waitComponentAnnotationGone(component2Key, BuildRequestAnnotationName)
+			Eventually(func() int { return batchMergeRequestFilesNumber }, timeout, interval).Should(Equal(4))
+		})
 	})
 
 	Context("Test simple build flow", func() {
@@ -2046,6 +2416,24 @@ var _ = Describe("Component initial build controller", func() {
 			assertBuildFail(false, "No pipeline is selected")
 		})
 
+		It("should explain in the build status why no pipeline is selected", func() {
+			createBuildPipelineSelector(defaultResolverRef, nonMatchingConditions)
+			assertBuildFail(true, "No pipeline is selected")
+
+			componentBuildStatus := getComponentBuildStatus(resourceNoMatchPKey)
+			Expect(componentBuildStatus.Status.Pipeline).To(BeNil())
+			selectionTrace := componentBuildStatus.Status.PipelineSelection
+			Expect(selectionTrace).To(HaveLen(3))
+			Expect(selectionTrace[0].Name).To(Equal(HASAppName))
+			Expect(selectionTrace[0].NotFound).To(BeTrue())
+			Expect(selectionTrace[2].Namespace).To(Equal(defaultSelectorKey.Namespace))
+			Expect(selectionTrace[2].Name).To(Equal(defaultSelectorKey.Name))
+			Expect(selectionTrace[2].NotFound).To(BeFalse())
+			Expect(selectionTrace[2].Items).To(HaveLen(1))
+			Expect(selectionTrace[2].Items[0].Name).To(Equal("java"))
+			Expect(selectionTrace[2].Items[0].FailedCondition).To(Equal(`language "nodejs" is not one of "java"`))
+		})
+
 		It("initial build should fail when no BuildPipelineSelector CR is defined", func() {
 			assertBuildFail(true, "Build pipeline selector is not defined")
 		})
@@ -32,6 +32,7 @@ import (
 	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
 	"github.com/redhat-appstudio/build-service/pkg/git/github"
 	l "github.com/redhat-appstudio/build-service/pkg/logs"
+	"golang.org/x/exp/slices"
 	batch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -70,8 +71,10 @@ type GitTektonResourcesRenovater struct {
 }
 
 type installationStruct struct {
-	id           int
-	token        string
+	id    int
+	token string
+	// apiUrl is the API endpoint of GitHub Enterprise instance or empty string for github.com
+	apiUrl       string
 	repositories []renovateRepository
 }
 
@@ -128,14 +131,6 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, nil
 	}
 
-	// Load GitHub App and get GitHub Installations
-	githubAppIdStr := string(pacSecret.Data[gitops.PipelinesAsCode_githubAppIdKey])
-	privateKey := pacSecret.Data[gitops.PipelinesAsCode_githubPrivateKey]
-	githubAppInstallations, slug, err := github.GetAppInstallations(githubAppIdStr, privateKey)
-	if err != nil {
-		return ctrl.Result{}, err
-	}
-
 	// Get Components
 	componentList := &appstudiov1alpha1.ComponentList{}
 	if err := r.Client.List(ctx, componentList, &client.ListOptions{}); err != nil {
@@ -143,6 +138,9 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, err
 	}
 	componentUrlToBranchesMap := make(map[string][]string)
+	// The application might be registered in a GitHub Enterprise instance,
+	// so the installations are looked up in each GitHub instance the Components come from.
+	githubApiUrls := []string{}
 	for _, component := range componentList.Items {
 		gitSource := component.Spec.Source.GitSource
 		if gitSource != nil {
@@ -152,9 +150,41 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 				branch = InternalDefaultBranch
 			}
 			componentUrlToBranchesMap[url] = append(componentUrlToBranchesMap[url], branch)
+
+			if gitProvider, err := getGitProvider(component); err != nil || gitProvider != "github" {
+				continue
+			}
+			apiUrl, err := getGitProviderApiUrl(gitSource.URL, pacSecret.Data)
+			if err != nil {
+				continue
+			}
+			if !slices.Contains(githubApiUrls, apiUrl) {
+				githubApiUrls = append(githubApiUrls, apiUrl)
+			}
 		}
 	}
 
+	// Load GitHub App and get GitHub Installations
+	githubAppIdStr := string(pacSecret.Data[gitops.PipelinesAsCode_githubAppIdKey])
+	privateKey := pacSecret.Data[gitops.PipelinesAsCode_githubPrivateKey]
+	var githubAppInstallations []github.ApplicationInstallation
+	var slug string
+	var installationsErr error
+	for _, apiUrl := range githubApiUrls {
+		installations, appSlug, err := github.GetAppInstallations(ctx, githubAppIdStr, privateKey, apiUrl)
+		if err != nil {
+			// The application exists in one of the instances only
+			log.Error(err, "failed to get GitHub App installations", "ApiUrl", apiUrl, l.Action, l.ActionView)
+			installationsErr = err
+			continue
+		}
+		githubAppInstallations = append(githubAppInstallations, installations...)
+		slug = appSlug
+	}
+	if slug == "" && installationsErr != nil {
+		return ctrl.Result{}, installationsErr
+	}
+
 	// Match installed repositories with Components and get custom branch if defined
 	installationsToUpdate := []installationStruct{}
 	for _, githubAppInstallation := range githubAppInstallations {
@@ -184,6 +214,7 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 			installationStruct{
 				id:           int(githubAppInstallation.ID),
 				token:        githubAppInstallation.Token,
+				apiUrl:       githubAppInstallation.ApiUrl,
 				repositories: repositories,
 			})
 	}
@@ -205,8 +236,7 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 		if end > len(installationsToUpdate) {
 			end = len(installationsToUpdate)
 		}
-		err = r.CreateRenovaterJob(ctx, installationsToUpdate[i:end], slug)
-		if err != nil {
+		if err := r.CreateRenovaterJob(ctx, installationsToUpdate[i:end], slug); err != nil {
 			log.Error(err, "failed to create a job", l.Action, l.ActionAdd)
 		}
 	}
@@ -214,11 +244,16 @@ func (r *GitTektonResourcesRenovater) Reconcile(ctx context.Context, req ctrl.Re
 	return ctrl.Result{RequeueAfter: NextReconcile}, nil
 }
 
-func generateConfigJS(slug string, repositories []renovateRepository) string {
+func generateConfigJS(slug string, apiUrl string, repositories []renovateRepository) string {
 	repositoriesData, _ := json.Marshal(repositories)
+	var endpoint string
+	if apiUrl != "" {
+		endpoint = fmt.Sprintf("endpoint: %q,", apiUrl)
+	}
 	template := `
 	module.exports = {
 		platform: "github",
+		%s
 		username: "%s[bot]",
 		gitAuthor:"%s <123456+%s[bot]@users.noreply.github.com>",
 		onboarding: false,
@@ -259,7 +294,7 @@ func generateConfigJS(slug string, repositories []renovateRepository) string {
 	if renovatePattern == "" {
 		renovatePattern = DefaultRenovateMatchPattern
 	}
-	return fmt.Sprintf(template, slug, slug, slug, repositoriesData, renovatePattern, renovatePattern, renovatePattern)
+	return fmt.Sprintf(template, endpoint, slug, slug, slug, repositoriesData, renovatePattern, renovatePattern, renovatePattern)
 }
 
 func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, installations []installationStruct, slug string) error {
@@ -275,7 +310,7 @@ func (r *GitTektonResourcesRenovater) CreateRenovaterJob(ctx context.Context, in
 	renovateCmds := []string{}
 	for _, installation := range installations {
 		secretTokens[fmt.Sprint(installation.id)] = installation.token
-		configmaps[fmt.Sprintf("%d.js", installation.id)] = generateConfigJS(slug, installation.repositories)
+		configmaps[fmt.Sprintf("%d.js", installation.id)] = generateConfigJS(slug, installation.apiUrl, installation.repositories)
 		renovateCmds = append(renovateCmds,
 			fmt.Sprintf("RENOVATE_TOKEN=$TOKEN_%d RENOVATE_CONFIG_FILE=/configs/%d.js renovate", installation.id, installation.id),
 		)
@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -57,7 +58,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetAppInstallations = func(appIdStr string, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetAppInstallations = func(ctx context.Context, appIdStr string, privateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
@@ -72,7 +73,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetAppInstallations = func(appIdStr string, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetAppInstallations = func(ctx context.Context, appIdStr string, privateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
@@ -87,7 +88,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetAppInstallations = func(appIdStr string, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetAppInstallations = func(ctx context.Context, appIdStr string, privateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
@@ -121,7 +122,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test5/repo1",
 				"https://github/test5/repo2",
 			}
-			github.GetAppInstallations = func(appIdStr string, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetAppInstallations = func(ctx context.Context, appIdStr string, privateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 				return []github.ApplicationInstallation{
 					generateInstallation(generateRepositories(installedRepositoryUrls1)),
 					generateInstallation(generateRepositories(installedRepositoryUrls2)),
@@ -153,7 +154,7 @@ var _ = Describe("Git tekton resources renovater", func() {
 				"https://github/test/repo1",
 				"https://github/test/repo2",
 			}
-			github.GetAppInstallations = func(appIdStr string, privateKeyPem []byte) ([]github.ApplicationInstallation, string, error) {
+			github.GetAppInstallations = func(ctx context.Context, appIdStr string, privateKeyPem []byte, baseUrl string) ([]github.ApplicationInstallation, string, error) {
 				repositories := generateRepositories(installedRepositoryUrls)
 				return []github.ApplicationInstallation{generateInstallation(repositories)}, "slug", nil
 			}
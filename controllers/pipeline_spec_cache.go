@@ -0,0 +1,244 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+)
+
+const (
+	// PipelineSpecCacheTagTTLEnvName overrides how long a resolved digest of a bundle tag is trusted, e.g. 5m.
+	PipelineSpecCacheTagTTLEnvName = "PIPELINE_SPEC_CACHE_TAG_TTL"
+	// PipelineSpecCachePrefetchEnvName enables prefetch of pipelines referenced by a changed BuildPipelineSelector.
+	PipelineSpecCachePrefetchEnvName = "PIPELINE_SPEC_CACHE_PREFETCH"
+
+	pipelineSpecCacheTagTTLDefault = 10 * time.Minute
+	pipelineSpecCacheMaxEntries    = 100
+
+	pipelineSpecCacheHit  = "hit"
+	pipelineSpecCacheMiss = "miss"
+)
+
+var (
+	bundlePipelineSpecCache = newPipelineSpecCache(pipelineSpecCacheMaxEntries, getPipelineSpecCacheTagTTL())
+
+	pipelineSpecCacheRequestsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pipeline_spec_cache_requests_total",
+		Help:      "Number of pipeline definition lookups in the bundle cache by result (hit or miss).",
+	}, []string{"result"})
+)
+
+// pipelineSpecCache keeps converted pipeline definitions from Tekton bundles.
+// Entries are keyed by bundle digest, so they never go stale and are evicted only when the cache is full.
+// Tag references are resolved to a digest first, the resolved digest is trusted for tagTTL.
+type pipelineSpecCache struct {
+	mutex sync.Mutex
+
+	maxEntries int
+	tagTTL     time.Duration
+
+	// entries holds *pipelineSpecCacheEntry elements, the most recently used first.
+	entries      *list.List
+	entriesByKey map[string]*list.Element
+	// tagDigests maps bundle tag references to digest references.
+	tagDigests map[string]resolvedBundleTag
+
+	now           func() time.Time
+	resolveDigest func(ctx context.Context, bundleUri string) (string, error)
+	pullPipeline  func(ctx context.Context, bundleUri, pipelineName string) (*tektonapi.PipelineSpec, error)
+}
+
+type pipelineSpecCacheEntry struct {
+	key          string
+	pipelineSpec *tektonapi.PipelineSpec
+}
+
+type resolvedBundleTag struct {
+	digestRef string
+	expiresAt time.Time
+}
+
+func newPipelineSpecCache(maxEntries int, tagTTL time.Duration) *pipelineSpecCache {
+	return &pipelineSpecCache{
+		maxEntries:    maxEntries,
+		tagTTL:        tagTTL,
+		entries:       list.New(),
+		entriesByKey:  map[string]*list.Element{},
+		tagDigests:    map[string]resolvedBundleTag{},
+		now:           time.Now,
+		resolveDigest: resolveBundleDigest,
+		pullPipeline:  pullPipelineSpec,
+	}
+}
+
+// Get returns definition of the pipeline with given name from the given bundle.
+// The returned object is a copy which may be modified by the caller.
+func (c *pipelineSpecCache) Get(ctx context.Context, bundleUri, pipelineName string) (*tektonapi.PipelineSpec, error) {
+	digestRef, err := c.getBundleDigestRef(ctx, bundleUri, false)
+	if err != nil {
+		return nil, err
+	}
+	return c.getByDigestRef(ctx, digestRef, pipelineName)
+}
+
+// Prefetch resolves the bundle tag again, ignoring the TTL, and loads the pipeline into the cache.
+func (c *pipelineSpecCache) Prefetch(ctx context.Context, bundleUri, pipelineName string) error {
+	digestRef, err := c.getBundleDigestRef(ctx, bundleUri, true)
+	if err != nil {
+		return err
+	}
+	_, err = c.getByDigestRef(ctx, digestRef, pipelineName)
+	return err
+}
+
+// getBundleDigestRef returns the bundle reference pinned to digest.
+// If the registry is not available, the last known digest of a tag is used even if it is expired.
+func (c *pipelineSpecCache) getBundleDigestRef(ctx context.Context, bundleUri string, refresh bool) (string, error) {
+	ref, err := name.ParseReference(bundleUri)
+	if err != nil {
+		return "", fmt.Errorf("%s is an unparseable bundle reference: %w", bundleUri, err)
+	}
+	if _, isDigest := ref.(name.Digest); isDigest {
+		return bundleUri, nil
+	}
+
+	c.mutex.Lock()
+	resolvedTag, found := c.tagDigests[bundleUri]
+	c.mutex.Unlock()
+	if found && !refresh && c.now().Before(resolvedTag.expiresAt) {
+		return resolvedTag.digestRef, nil
+	}
+
+	digestRef, err := c.resolveDigest(ctx, bundleUri)
+	if err != nil {
+		if found {
+			ctrllog.FromContext(ctx).Error(err, "failed to resolve bundle digest, using the last known one", "Bundle", bundleUri, "DigestRef", resolvedTag.digestRef)
+			return resolvedTag.digestRef, nil
+		}
+		return "", fmt.Errorf("failed to resolve digest of %s bundle: %w", bundleUri, err)
+	}
+
+	c.mutex.Lock()
+	c.tagDigests[bundleUri] = resolvedBundleTag{digestRef: digestRef, expiresAt: c.now().Add(c.tagTTL)}
+	c.mutex.Unlock()
+	return digestRef, nil
+}
+
+func (c *pipelineSpecCache) getByDigestRef(ctx context.Context, digestRef, pipelineName string) (*tektonapi.PipelineSpec, error) {
+	key := digestRef + "#" + pipelineName
+
+	c.mutex.Lock()
+	if element, found := c.entriesByKey[key]; found {
+		c.entries.MoveToFront(element)
+		pipelineSpec := element.Value.(*pipelineSpecCacheEntry).pipelineSpec.DeepCopy()
+		c.mutex.Unlock()
+		pipelineSpecCacheRequestsMetric.WithLabelValues(pipelineSpecCacheHit).Inc()
+		return pipelineSpec, nil
+	}
+	c.mutex.Unlock()
+	pipelineSpecCacheRequestsMetric.WithLabelValues(pipelineSpecCacheMiss).Inc()
+
+	pipelineSpec, err := c.pullPipeline(ctx, digestRef, pipelineName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, found := c.entriesByKey[key]; !found {
+		c.entriesByKey[key] = c.entries.PushFront(&pipelineSpecCacheEntry{key: key, pipelineSpec: pipelineSpec.DeepCopy()})
+		for c.entries.Len() > c.maxEntries {
+			oldest := c.entries.Back()
+			c.entries.Remove(oldest)
+			delete(c.entriesByKey, oldest.Value.(*pipelineSpecCacheEntry).key)
+		}
+	}
+	return pipelineSpec, nil
+}
+
+// resolveBundleDigest returns the given bundle reference pinned to the digest it currently points to.
+func resolveBundleDigest(ctx context.Context, bundleUri string) (string, error) {
+	ref, err := name.ParseReference(bundleUri)
+	if err != nil {
+		return "", err
+	}
+	descriptor, err := remote.Head(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return ref.Context().Digest(descriptor.Digest.String()).String(), nil
+}
+
+func getPipelineSpecCacheTagTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv(PipelineSpecCacheTagTTLEnvName)); err == nil && ttl >= 0 {
+		return ttl
+	}
+	return pipelineSpecCacheTagTTLDefault
+}
+
+func isPipelineSpecPrefetchEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(PipelineSpecCachePrefetchEnvName))
+	return enabled
+}
+
+// pipelinePrefetchResult is the outcome of loading the pipeline referenced by a selector into the cache.
+type pipelinePrefetchResult struct {
+	err error
+}
+
+// prefetchPipelineSpecs loads pipelines from bundles referenced by the given selectors into the cache.
+// Returns the prefetch results by selector index, so the selectors validation doesn't retrieve the pipelines again.
+// Selectors which don't reference a bundle have no result.
+// Failures are only logged, because the selectors validation reports them.
+func prefetchPipelineSpecs(ctx context.Context, selectors []buildappstudiov1alpha1.PipelineSelector) map[int]*pipelinePrefetchResult {
+	log := ctrllog.FromContext(ctx)
+
+	results := map[int]*pipelinePrefetchResult{}
+	for i := range selectors {
+		pipelineSource, err := newPipelineSource(selectors[i].PipelineRef.AsPipelineRef(), nil, "", false)
+		if err != nil {
+			continue
+		}
+		pipelineBundle := getPipelineSourceBundle(pipelineSource)
+		if pipelineBundle == "" {
+			continue
+		}
+		err = bundlePipelineSpecCache.Prefetch(ctx, pipelineBundle, pipelineSource.GetPipelineName())
+		if err != nil {
+			log.Error(err, "failed to prefetch pipeline", "Bundle", pipelineBundle, "PipelineName", pipelineSource.GetPipelineName())
+		}
+		results[i] = &pipelinePrefetchResult{err: err}
+	}
+	return results
+}
@@ -151,6 +151,13 @@ var _ = BeforeSuite(func() {
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&BuildPipelineSelectorReconciler{
+		Client:        k8sManager.GetClient(),
+		Scheme:        k8sManager.GetScheme(),
+		EventRecorder: k8sManager.GetEventRecorderFor("BuildPipelineSelector"),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&GitTektonResourcesRenovater{
 		Client:        k8sManager.GetClient(),
 		Scheme:        k8sManager.GetScheme(),
@@ -17,6 +17,11 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	gitfake "github.com/redhat-appstudio/build-service/pkg/git/fake"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 	gpf "github.com/redhat-appstudio/build-service/pkg/git/gitproviderfactory"
 )
@@ -26,23 +31,39 @@ var (
 	DefaultBrowseRepository = "https://githost.com/user/repo?rev="
 	UndoPacMergeRequestURL  = "https://githost.com/mr/5678"
 
-	EnsurePaCMergeRequestFunc        func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error)
-	UndoPaCMergeRequestFunc          func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error)
-	FindUnmergedPaCMergeRequestFunc  func(repoUrl string, data *gp.MergeRequestData) (*gp.MergeRequest, error)
-	SetupPaCWebhookFunc              func(repoUrl string, webhookUrl string, webhookSecret string) error
-	DeletePaCWebhookFunc             func(repoUrl string, webhookUrl string) error
-	GetDefaultBranchFunc             func(repoUrl string) (string, error)
-	DeleteBranchFunc                 func(repoUrl string, branchName string) (bool, error)
-	GetBranchShaFunc                 func(repoUrl string, branchName string) (string, error)
-	GetBrowseRepositoryAtShaLinkFunc func(repoUrl string, sha string) string
-	IsFileExistFunc                  func(repoUrl, branchName, filePath string) (bool, error)
-	IsRepositoryPublicFunc           func(repoUrl string) (bool, error)
-	GetConfiguredGitAppNameFunc      func() (string, string, error)
+	EnsurePaCMergeRequestFunc         func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error)
+	UndoPaCMergeRequestFunc           func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error)
+	FindUnmergedPaCMergeRequestFunc   func(repoUrl string, data *gp.MergeRequestData) (*gp.MergeRequest, error)
+	RefreshPaCMergeRequestFunc        func(repoUrl string, data *gp.MergeRequestData) (webUrl string, refreshed bool, err error)
+	ResetPaCMergeRequestFunc          func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error)
+	CommitPaCConfigurationFunc        func(repoUrl string, data *gp.MergeRequestData) (committed bool, err error)
+	CommitPaCConfigurationRemovalFunc func(repoUrl string, data *gp.MergeRequestData) (committed bool, err error)
+	IsBranchProtectedFunc             func(repoUrl, branchName string) (bool, error)
+	GetMergeRequestStatusFunc         func(repoUrl string, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error)
+	SetupPaCWebhookFunc               func(repoUrl string, webhookUrl string, webhookSecret string) error
+	DeletePaCWebhookFunc              func(repoUrl string, webhookUrl string) error
+	GetDefaultBranchFunc              func(repoUrl string) (string, error)
+	DeleteBranchFunc                  func(repoUrl string, branchName string) (bool, error)
+	GetBranchShaFunc                  func(repoUrl string, branchName string) (string, error)
+	GetBrowseRepositoryAtShaLinkFunc  func(repoUrl string, sha string) string
+	IsFileExistFunc                   func(repoUrl, branchName, filePath string) (bool, error)
+	DownloadFileContentFunc           func(repoUrl, revision, filePath string) ([]byte, error)
+	IsRepositoryPublicFunc            func(repoUrl string) (bool, error)
+	GetConfiguredGitAppNameFunc       func() (string, string, error)
 )
 
+// UseFakeGitProvider makes the operator work with the given in-memory git provider instead of the mock functions below.
+// The provider state, like merge requests and webhooks, could be checked after the operator actions.
+// ResetTestGitProviderClient switches back to the mock functions.
+func UseFakeGitProvider(fakeGitProvider *gitfake.GitProvider) {
+	gpf.CreateGitClient = func(ctx context.Context, gitClientConfig gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
+		return gp.NewClientWithContext(fakeGitProvider), nil
+	}
+}
+
 func ResetTestGitProviderClient() {
-	gpf.CreateGitClient = func(gitClientConfig gpf.GitClientConfig) (gp.GitProviderClient, error) {
-		return testGitProviderClient, nil
+	gpf.CreateGitClient = func(ctx context.Context, gitClientConfig gpf.GitClientConfig) (gp.GitProviderClientWithContext, error) {
+		return gp.NewClientWithContext(testGitProviderClient), nil
 	}
 
 	EnsurePaCMergeRequestFunc = func(repoUrl string, data *gp.MergeRequestData) (webUrl string, err error) {
@@ -54,6 +75,24 @@ func ResetTestGitProviderClient() {
 	FindUnmergedPaCMergeRequestFunc = func(repoUrl string, data *gp.MergeRequestData) (*gp.MergeRequest, error) {
 		return nil, nil
 	}
+	RefreshPaCMergeRequestFunc = func(repoUrl string, data *gp.MergeRequestData) (string, bool, error) {
+		return "", false, nil
+	}
+	ResetPaCMergeRequestFunc = func(repoUrl string, data *gp.MergeRequestData) (string, error) {
+		return "", nil
+	}
+	CommitPaCConfigurationFunc = func(repoUrl string, data *gp.MergeRequestData) (bool, error) {
+		return true, nil
+	}
+	CommitPaCConfigurationRemovalFunc = func(repoUrl string, data *gp.MergeRequestData) (bool, error) {
+		return true, nil
+	}
+	IsBranchProtectedFunc = func(repoUrl, branchName string) (bool, error) {
+		return false, nil
+	}
+	GetMergeRequestStatusFunc = func(repoUrl string, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+		return &gp.MergeRequestStatus{State: gp.MergeRequestStateOpen}, nil
+	}
 	SetupPaCWebhookFunc = func(repoUrl string, webhookUrl string, webhookSecret string) error {
 		return nil
 	}
@@ -73,7 +112,11 @@ func ResetTestGitProviderClient() {
 		return DefaultBrowseRepository + sha
 	}
 	IsFileExistFunc = func(repoUrl, branchName, filePath string) (bool, error) {
-		return true, nil
+		// There is no Pipelines as Code configuration in the repository yet
+		return !strings.HasPrefix(filePath, ".tekton/"), nil
+	}
+	DownloadFileContentFunc = func(repoUrl, revision, filePath string) ([]byte, error) {
+		return nil, fmt.Errorf("file %s not found", filePath)
 	}
 	IsRepositoryPublicFunc = func(repoUrl string) (bool, error) {
 		return true, nil
@@ -96,6 +139,24 @@ func (*TestGitProviderClient) UndoPaCMergeRequest(repoUrl string, data *gp.Merge
 func (*TestGitProviderClient) FindUnmergedPaCMergeRequest(repoUrl string, data *gp.MergeRequestData) (*gp.MergeRequest, error) {
 	return FindUnmergedPaCMergeRequestFunc(repoUrl, data)
 }
+func (*TestGitProviderClient) RefreshPaCMergeRequest(repoUrl string, data *gp.MergeRequestData) (string, bool, error) {
+	return RefreshPaCMergeRequestFunc(repoUrl, data)
+}
+func (*TestGitProviderClient) ResetPaCMergeRequest(repoUrl string, data *gp.MergeRequestData) (string, error) {
+	return ResetPaCMergeRequestFunc(repoUrl, data)
+}
+func (*TestGitProviderClient) CommitPaCConfiguration(repoUrl string, data *gp.MergeRequestData) (bool, error) {
+	return CommitPaCConfigurationFunc(repoUrl, data)
+}
+func (*TestGitProviderClient) CommitPaCConfigurationRemoval(repoUrl string, data *gp.MergeRequestData) (bool, error) {
+	return CommitPaCConfigurationRemovalFunc(repoUrl, data)
+}
+func (*TestGitProviderClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	return IsBranchProtectedFunc(repoUrl, branchName)
+}
+func (*TestGitProviderClient) GetMergeRequestStatus(repoUrl string, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	return GetMergeRequestStatusFunc(repoUrl, mergeRequestWebUrl)
+}
 func (*TestGitProviderClient) SetupPaCWebhook(repoUrl string, webhookUrl string, webhookSecret string) error {
 	return SetupPaCWebhookFunc(repoUrl, webhookUrl, webhookSecret)
 }
@@ -117,6 +178,9 @@ func (*TestGitProviderClient) GetBrowseRepositoryAtShaLink(repoUrl string, sha s
 func (*TestGitProviderClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
 	return IsFileExistFunc(repoUrl, branchName, filePath)
 }
+func (*TestGitProviderClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	return DownloadFileContentFunc(repoUrl, revision, filePath)
+}
 func (*TestGitProviderClient) IsRepositoryPublic(repoUrl string) (bool, error) {
 	return IsRepositoryPublicFunc(repoUrl)
 }
@@ -248,6 +248,17 @@ func getComponent(componentKey types.NamespacedName) *appstudiov1alpha1.Componen
 	return component
 }
 
+func getComponentBuildStatus(componentKey types.NamespacedName) *buildappstudiov1alpha1.ComponentBuildStatus {
+	componentBuildStatus := &buildappstudiov1alpha1.ComponentBuildStatus{}
+	Eventually(func() bool {
+		if err := k8sClient.Get(ctx, componentKey, componentBuildStatus); err != nil {
+			return false
+		}
+		return componentBuildStatus.ResourceVersion != ""
+	}, timeout, interval).Should(BeTrue())
+	return componentBuildStatus
+}
+
 // deleteComponent deletes the specified component resource and verifies it was properly deleted
 func deleteComponent(componentKey types.NamespacedName) {
 	component := &appstudiov1alpha1.Component{}
@@ -344,6 +355,13 @@ func expectPacBuildStatus(componentKey types.NamespacedName, state string, errID
 	} else {
 		Expect(buildStatus.PaC.ConfigurationTime).To(BeEmpty())
 	}
+
+	componentBuildStatus := getComponentBuildStatus(componentKey)
+	Expect(componentBuildStatus.Spec.ComponentName).To(Equal(componentKey.Name))
+	Expect(componentBuildStatus.Status.PaC).ToNot(BeNil())
+	Expect(componentBuildStatus.Status.PaC.State).To(Equal(state))
+	Expect(componentBuildStatus.Status.PaC.ErrId).To(Equal(errID))
+	Expect(componentBuildStatus.Status.PaC.MergeUrl).To(Equal(mergeURL))
 }
 
 func expectSimpleBuildStatus(componentKey types.NamespacedName, errID int, errMessage string, startTimeEmpty bool) {
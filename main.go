@@ -156,6 +156,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&controllers.BuildPipelineSelectorReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("BuildPipelineSelector"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BuildPipelineSelector")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.GitTektonResourcesRenovater{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
@@ -199,6 +208,8 @@ func getCacheExcludedObjectsTypes() []client.Object {
 	return []client.Object{
 		&corev1.Secret{},
 		&corev1.ConfigMap{},
+		// Pipelines are read on demand only, do not watch them in all namespaces
+		&tektonapi.Pipeline{},
 	}
 }
 
@@ -17,7 +17,9 @@ limitations under the License.
 package boerrors
 
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
 var _ error = (*BuildOpError)(nil)
@@ -34,6 +36,9 @@ type BuildOpError struct {
 	// Optional. To provide extra information about this error
 	// If set, it will be appended to the error message returned from Error
 	ExtraInfo string
+	// requeueAfter is the delay before the failed operation could be retried, if known.
+	// Used only for transient errors, e.g. when a git provider rate limit is hit.
+	requeueAfter time.Duration
 }
 
 func NewBuildOpError(id BOErrorId, err error) *BuildOpError {
@@ -44,6 +49,16 @@ func NewBuildOpError(id BOErrorId, err error) *BuildOpError {
 	}
 }
 
+// NewTransientBuildOpError creates transient error of an operation which should not be retried earlier than after the given delay.
+// Not positive delay means that the retry time is unknown.
+func NewTransientBuildOpError(err error, requeueAfter time.Duration) *BuildOpError {
+	boErr := NewBuildOpError(ETransientError, err)
+	if requeueAfter > 0 {
+		boErr.requeueAfter = requeueAfter
+	}
+	return boErr
+}
+
 func (r BuildOpError) Error() string {
 	if r.err == nil {
 		return r.ShortError()
@@ -75,6 +90,29 @@ func (r BuildOpError) IsPersistent() bool {
 	return r.id != ETransientError
 }
 
+func (r BuildOpError) Unwrap() error {
+	return r.err
+}
+
+// GetRequeueAfter returns the delay before retry of the operation failed with the given error.
+// Transient build operation errors could wrap each other, the first known delay in the chain is returned.
+// Zero is returned if the error is persistent or the retry time is unknown.
+func GetRequeueAfter(err error) time.Duration {
+	for ; err != nil; err = errors.Unwrap(err) {
+		boErr, ok := err.(*BuildOpError)
+		if !ok {
+			continue
+		}
+		if boErr.IsPersistent() {
+			return 0
+		}
+		if boErr.requeueAfter > 0 {
+			return boErr.requeueAfter
+		}
+	}
+	return 0
+}
+
 type BOErrorId int
 
 const (
@@ -95,6 +133,10 @@ const (
 	// If self-hosted instance of the supported git providers is used, then "git-provider" annotation must be set:
 	// git-provider: gitlab
 	EUnknownGitProvider BOErrorId = 60
+	// Happens when Component source repository is hosted on a git provider which is supported by Build Service,
+	// but not by the Pipelines as Code version used in the cluster, for example, Azure DevOps.
+	// Pipelines as Code cannot be provisioned for such Components.
+	EPaCGitProviderNotSupported BOErrorId = 61
 
 	// Happens when configured in cluster Pipelines as Code application is not installed in Component source repository.
 	// User must install the application to fix this error.
@@ -118,6 +160,7 @@ const (
 	// not have sufficient scope, e.g. list webhooks from a repository, but scope "read:repo_hook" is set.
 	EGitHubNoResourceToOperateOn BOErrorId = 75
 	// EGitHubReachRateLimit reach the GitHub REST API rate limit.
+	// Not used anymore, rate limits are reported as transient errors with the delay until the limit reset.
 	EGitHubReachRateLimit BOErrorId = 76
 
 	// EGitLabTokenUnauthorized access token is not recognized by GitLab and 401 is responded.
@@ -126,6 +169,23 @@ const (
 	// EGitLabTokenInsufficientScope the access token does not have sufficient scope and 403 is responded.
 	EGitLabTokenInsufficientScope BOErrorId = 91
 
+	// EBitbucketTokenUnauthorized username and app password pair is not recognized by Bitbucket and 401 is responded.
+	EBitbucketTokenUnauthorized BOErrorId = 100
+	// EBitbucketTokenInsufficientScope the app password does not have sufficient permissions and 403 is responded.
+	EBitbucketTokenInsufficientScope BOErrorId = 101
+
+	// EGiteaTokenUnauthorized access token is not recognized by Gitea and 401 is responded.
+	// The access token may be malformed or expired.
+	EGiteaTokenUnauthorized BOErrorId = 110
+	// EGiteaTokenInsufficientScope the access token does not have sufficient permissions and 403 is responded.
+	EGiteaTokenInsufficientScope BOErrorId = 111
+
+	// EAzureDevOpsTokenUnauthorized personal access token is not recognized by Azure DevOps.
+	// The token may be malformed, expired or revoked.
+	EAzureDevOpsTokenUnauthorized BOErrorId = 120
+	// EAzureDevOpsTokenInsufficientScope the personal access token does not have sufficient scopes and 403 is responded.
+	EAzureDevOpsTokenInsufficientScope BOErrorId = 121
+
 	// Value of 'image.redhat.com/image' component annotation is not a valid json or the json has invalid structure.
 	EFailedToParseImageAnnotation BOErrorId = 200
 	// The secret with git credentials specified in component.Spec.Secret does not exist in the user's namespace.
@@ -137,6 +197,12 @@ const (
 
 	// EInvalidDevfile devfile of the component is not valid.
 	EInvalidDevfile BOErrorId = 220
+	// EInvalidMergeRequestTemplate a merge request template in the namespace ConfigMap cannot be parsed or rendered.
+	EInvalidMergeRequestTemplate BOErrorId = 221
+	// EForkRepositoryConflict a repository which is not a fork of the Component repository occupies the fork name.
+	EForkRepositoryConflict BOErrorId = 222
+	// EGitBranchPushRejected the git provider rejected direct push into a branch, e.g. because the branch is protected.
+	EGitBranchPushRejected BOErrorId = 223
 
 	// ENoPipelineIsSelected no pipeline can be selected based on a component repository
 	ENoPipelineIsSelected BOErrorId = 300
@@ -147,6 +213,13 @@ const (
 	EUnsupportedPipelineRef BOErrorId = 302
 	// EMissingParamsForBundleResolver The pipelineRef selected for a component is missing parameters required for the bundle resolver.
 	EMissingParamsForBundleResolver BOErrorId = 303
+	// EInvalidPipelineSelectorExpression The expression in a BuildPipelineSelector when condition cannot be compiled or evaluated.
+	EInvalidPipelineSelectorExpression BOErrorId = 304
+	// EMissingParamsForPipelineResolver The pipelineRef selected for a component is missing parameters required for the git or cluster resolver.
+	EMissingParamsForPipelineResolver BOErrorId = 305
+	// EPipelineSourceNotAllowed The pipelineRef selected for a component refers to a location
+	// the component is not allowed to read pipelines from, e.g. Pipeline in another namespace.
+	EPipelineSourceNotAllowed BOErrorId = 306
 
 	// EPipelineRetrievalFailed Failed to retrieve a Tekton Pipeline.
 	EPipelineRetrievalFailed BOErrorId = 400
@@ -164,7 +237,8 @@ var boErrorMessages = map[BOErrorId]string{
 	EPaCRouteDoesNotExist:   "Pipelines as Code public route does not exist",
 	EPaCDuplicateRepository: "Git repository is already handled by Pipelines as Code",
 
-	EUnknownGitProvider: "unknown git provider of the source repository",
+	EUnknownGitProvider:         "unknown git provider of the source repository",
+	EPaCGitProviderNotSupported: "Pipelines as Code does not support git provider of the source repository",
 
 	EGitHubAppNotInstalled:         "GitHub Application is not installed in user repository",
 	EGitHubAppMalformedPrivateKey:  "malformed GitHub Application private key",
@@ -179,17 +253,32 @@ var boErrorMessages = map[BOErrorId]string{
 	EGitLabTokenInsufficientScope: "GitLab access token does not have enough scope",
 	EGitLabTokenUnauthorized:      "Access token is unrecognizable by remote GitLab service",
 
+	EBitbucketTokenUnauthorized:      "Username or app password is unrecognizable by Bitbucket",
+	EBitbucketTokenInsufficientScope: "Bitbucket app password does not have enough permissions",
+
+	EGiteaTokenUnauthorized:      "Access token is unrecognizable by remote Gitea service",
+	EGiteaTokenInsufficientScope: "Gitea access token does not have enough permissions",
+
+	EAzureDevOpsTokenUnauthorized:      "Personal access token is unrecognizable by Azure DevOps",
+	EAzureDevOpsTokenInsufficientScope: "Azure DevOps personal access token does not have enough scopes",
+
 	EFailedToParseImageAnnotation:        "Failed to parse image.redhat.com/image annotation value",
 	EComponentGitSecretMissing:           "Secret with git credential not found",
 	EComponentImageRegistrySecretMissing: "Component image repository secret not found",
 	EComponentGitSecretNotSpecified:      "Git credentials for private Component git repository not given",
 
-	EInvalidDevfile: "Component Devfile is invalid",
-
-	ENoPipelineIsSelected:            "No pipeline is selected for component repository based on predefined selectors.",
-	EBuildPipelineSelectorNotDefined: "Build pipeline selector is not defined yet.",
-	EUnsupportedPipelineRef:          "The pipelineRef for this component (based on pipeline selectors) is not supported.",
-	EMissingParamsForBundleResolver:  "The pipelineRef for this component is missing required parameters ('name' and/or 'bundle').",
+	EInvalidDevfile:              "Component Devfile is invalid",
+	EInvalidMergeRequestTemplate: "Merge request template is invalid",
+	EForkRepositoryConflict:      "Repository with the fork name exists, but it is not a fork of the Component repository",
+	EGitBranchPushRejected:       "Direct push into the git branch is rejected",
+
+	ENoPipelineIsSelected:              "No pipeline is selected for component repository based on predefined selectors.",
+	EBuildPipelineSelectorNotDefined:   "Build pipeline selector is not defined yet.",
+	EUnsupportedPipelineRef:            "The pipelineRef for this component (based on pipeline selectors) is not supported.",
+	EMissingParamsForBundleResolver:    "The pipelineRef for this component is missing required parameters ('name' and/or 'bundle').",
+	EInvalidPipelineSelectorExpression: "The expression in a pipeline selector condition is invalid.",
+	EMissingParamsForPipelineResolver:  "The pipelineRef for this component is missing parameters required by its resolver.",
+	EPipelineSourceNotAllowed:          "The pipelineRef for this component refers to a pipeline the component is not allowed to use.",
 
 	EPipelineRetrievalFailed:  "Failed to retrieve the pipeline selected for this component.",
 	EPipelineConversionFailed: "Failed to convert the selected pipeline to the supported Tekton API version.",
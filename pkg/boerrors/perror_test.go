@@ -19,6 +19,7 @@ package boerrors
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestPersistentErrorDetection(t *testing.T) {
@@ -127,3 +128,49 @@ func TestShortErrorMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRequeueAfter(t *testing.T) {
+	tests := []struct {
+		name                 string
+		err                  error
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name:                 "should return delay of transient error",
+			err:                  NewTransientBuildOpError(fmt.Errorf("rate limit exceeded"), time.Minute),
+			expectedRequeueAfter: time.Minute,
+		},
+		{
+			name:                 "should return delay of wrapped transient error",
+			err:                  fmt.Errorf("failed to get branch: %w", NewTransientBuildOpError(fmt.Errorf("rate limit exceeded"), time.Minute)),
+			expectedRequeueAfter: time.Minute,
+		},
+		{
+			name:                 "should return delay of transient error wrapped into another transient error",
+			err:                  NewBuildOpError(ETransientError, fmt.Errorf("request failed: %w", NewTransientBuildOpError(fmt.Errorf("rate limit exceeded"), time.Minute))),
+			expectedRequeueAfter: time.Minute,
+		},
+		{
+			name:                 "should return zero for transient error without delay",
+			err:                  NewTransientBuildOpError(fmt.Errorf("network error"), -time.Second),
+			expectedRequeueAfter: 0,
+		},
+		{
+			name:                 "should return zero for persistent error",
+			err:                  NewBuildOpError(EGitHubTokenUnauthorized, fmt.Errorf("bad credentials")),
+			expectedRequeueAfter: 0,
+		},
+		{
+			name:                 "should return zero for not build operation error",
+			err:                  fmt.Errorf("an error"),
+			expectedRequeueAfter: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if requeueAfter := GetRequeueAfter(tt.err); requeueAfter != tt.expectedRequeueAfter {
+				t.Errorf("Expected %v requeue delay, but got %v", tt.expectedRequeueAfter, requeueAfter)
+			}
+		})
+	}
+}
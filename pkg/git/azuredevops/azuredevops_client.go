@@ -0,0 +1,234 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// Allow mocking for tests
+var NewAzureDevOpsClient func(accessToken, baseUrl string) (*AzureDevOpsClient, error) = newAzureDevOpsClient
+
+var _ gp.GitProviderClient = (*AzureDevOpsClient)(nil)
+var _ gp.ContextBinder = (*AzureDevOpsClient)(nil)
+
+// AzureDevOpsClient implements git provider client for Azure DevOps Services and Azure DevOps Server.
+// A personal access token is used for authentication.
+// Organization and project of a repository are taken from the repository URL.
+// Only builds without Pipelines as Code are supported, because Pipelines as Code has no Azure DevOps provider,
+// so all Pipelines as Code related operations return EPaCGitProviderNotSupported error.
+type AzureDevOpsClient struct {
+	ctx        context.Context
+	httpClient *http.Client
+	// baseUrl overrides scheme and host of the repository URLs to call the API.
+	// Empty string means the host of the repository URL.
+	baseUrl string
+
+	accessToken string
+}
+
+// WithContext returns a shallow copy of the client which makes all its requests within the given context.
+func (a *AzureDevOpsClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	client := *a
+	client.ctx = ctx
+	return &client, nil
+}
+
+func newPaCNotSupportedError() error {
+	return boerrors.NewBuildOpError(boerrors.EPaCGitProviderNotSupported,
+		fmt.Errorf("Pipelines as Code does not support azure-devops git provider"))
+}
+
+func (a *AzureDevOpsClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	return "", newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	return "", newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
+	return nil, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return "", false, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	return "", newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	return nil, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
+	return newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) DeletePaCWebhook(repoUrl, webhookUrl string) error {
+	return newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	return false, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	return false, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	return false, newPaCNotSupportedError()
+}
+
+func (a *AzureDevOpsClient) DeleteBranch(repoUrl, branchName string) (bool, error) {
+	return false, newPaCNotSupportedError()
+}
+
+// GetDefaultBranch returns name of default branch in the given repository
+func (a *AzureDevOpsClient) GetDefaultBranch(repoUrl string) (string, error) {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return a.getDefaultBranch(repo)
+}
+
+// GetBranchSha returns SHA of top commit in the given branch
+// If branch name is empty, default branch is used.
+func (a *AzureDevOpsClient) GetBranchSha(repoUrl, branchName string) (string, error) {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return "", err
+	}
+
+	// If branch is not specified, use default branch
+	if branchName == "" {
+		defaultBranchName, err := a.getDefaultBranch(repo)
+		if err != nil {
+			return "", err
+		}
+		branchName = defaultBranchName
+	}
+
+	return a.getBranchSha(repo, branchName)
+}
+
+// IsFileExist check whether given file exists in the given branch of the reposiotry.
+// If branch is empty string, default branch is used.
+func (a *AzureDevOpsClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return false, err
+	}
+
+	if branchName == "" {
+		branchName, err = a.getDefaultBranch(repo)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	directory := filepath.Dir(filePath)
+	if directory == "." {
+		// The file is in the root of the repository
+		directory = ""
+	}
+	files, err := a.filesExistInDirectory(repo, branchName, directory, []gp.RepositoryFile{{FullPath: filePath}})
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (a *AzureDevOpsClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision == "" {
+		revision, err = a.getDefaultBranch(repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileContent, err := a.getFileContent(repo, revision, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if fileContent == nil {
+		return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+	}
+	return fileContent, nil
+}
+
+// IsRepositoryPublic returns true if the repository could be accessed without authentication.
+// Visibility of Azure DevOps repositories is defined by their project.
+func (a *AzureDevOpsClient) IsRepositoryPublic(repoUrl string) (bool, error) {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return false, err
+	}
+
+	repoInfo, err := a.getRepositoryInfo(repo)
+	if err != nil {
+		return false, err
+	}
+	if repoInfo == nil {
+		return false, nil
+	}
+	return repoInfo.Project.Visibility == "public", nil
+}
+
+// GetBrowseRepositoryAtShaLink returns web URL of repository state at given SHA
+func (a *AzureDevOpsClient) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	repo, err := getRepositoryFromUrl(repoUrl)
+	if err != nil {
+		return strings.TrimSuffix(repoUrl, ".git")
+	}
+	return repo.webUrl() + "?version=GC" + url.QueryEscape(sha)
+}
+
+func (a *AzureDevOpsClient) GetConfiguredGitAppName() (string, string, error) {
+	return "", "", fmt.Errorf("Azure DevOps does not support applications")
+}
+
+func newAzureDevOpsClient(accessToken, baseUrl string) (*AzureDevOpsClient, error) {
+	if accessToken == "" {
+		return nil, fmt.Errorf("personal access token is required to create Azure DevOps client")
+	}
+	return &AzureDevOpsClient{
+		ctx:         context.TODO(),
+		httpClient:  &http.Client{},
+		baseUrl:     strings.TrimSuffix(baseUrl, "/"),
+		accessToken: accessToken,
+	}, nil
+}
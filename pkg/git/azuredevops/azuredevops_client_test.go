@@ -0,0 +1,221 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	testRepoUrl          = "https://dev.azure.com/org/project/_git/repository"
+	testProjectApiPrefix = "/org/project/_apis"
+	testRepoApiPrefix    = testProjectApiPrefix + "/git/repositories/repository"
+	testBranchSha        = "1111111111111111111111111111111111111111"
+)
+
+// newTestClient returns Azure DevOps client which talks to a local HTTP server with the given handler.
+func newTestClient(t *testing.T, mux *http.ServeMux) *AzureDevOpsClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "" || password != "pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") != apiVersion {
+			t.Errorf("API version is not set: %s", r.URL)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewAzureDevOpsClient("pat", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestGetRepositoryFromUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoUrl string
+		want    *repository
+		webUrl  string
+		wantErr bool
+	}{
+		{
+			name:    "should parse Azure DevOps Services URL",
+			repoUrl: "https://dev.azure.com/org/project/_git/repository",
+			want:    &repository{host: "https://dev.azure.com", collection: "/org", project: "project", name: "repository"},
+			webUrl:  "https://dev.azure.com/org/project/_git/repository",
+		},
+		{
+			name:    "should parse Azure DevOps Services URL with user",
+			repoUrl: "https://org@dev.azure.com/org/project/_git/repository.git",
+			want:    &repository{host: "https://dev.azure.com", collection: "/org", project: "project", name: "repository"},
+			webUrl:  "https://dev.azure.com/org/project/_git/repository",
+		},
+		{
+			name:    "should parse legacy Visual Studio URL",
+			repoUrl: "https://org.visualstudio.com/project/_git/repository",
+			want:    &repository{host: "https://org.visualstudio.com", collection: "", project: "project", name: "repository"},
+			webUrl:  "https://org.visualstudio.com/project/_git/repository",
+		},
+		{
+			name:    "should parse Azure DevOps Server URL",
+			repoUrl: "https://ado.example.com/tfs/collection/project/_git/repository/",
+			want:    &repository{host: "https://ado.example.com", collection: "/tfs/collection", project: "project", name: "repository"},
+			webUrl:  "https://ado.example.com/tfs/collection/project/_git/repository",
+		},
+		{
+			name:    "should parse SSH URL",
+			repoUrl: "git@ssh.dev.azure.com:v3/org/project/repository",
+			want:    &repository{host: "https://dev.azure.com", collection: "/org", project: "project", name: "repository"},
+			webUrl:  "https://dev.azure.com/org/project/_git/repository",
+		},
+		{
+			name:    "should reject URL without repository",
+			repoUrl: "https://dev.azure.com/org/project",
+			wantErr: true,
+		},
+		{
+			name:    "should reject URL without project",
+			repoUrl: "https://dev.azure.com/_git/repository",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRepositoryFromUrl(tt.repoUrl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRepositoryFromUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("getRepositoryFromUrl() = %#v, want %#v", got, tt.want)
+			}
+			if got.webUrl() != tt.webUrl {
+				t.Errorf("webUrl() = %s, want %s", got.webUrl(), tt.webUrl)
+			}
+		})
+	}
+}
+
+func TestNewAzureDevOpsClient(t *testing.T) {
+	if _, err := NewAzureDevOpsClient("", ""); err == nil {
+		t.Errorf("expected error if personal access token is not set")
+	}
+	client, err := NewAzureDevOpsClient("pat", "https://ado.example.com/")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	repo, _ := getRepositoryFromUrl("https://ado.example.com/tfs/collection/project/_git/repository")
+	if apiUrl := client.repoApiUrl(repo, "refs"); apiUrl != "https://ado.example.com/tfs/collection/project/_apis/git/repositories/repository/refs" {
+		t.Errorf("unexpected API URL: %s", apiUrl)
+	}
+}
+
+func TestPaCNotSupported(t *testing.T) {
+	client := newTestClient(t, http.NewServeMux())
+	d := &gp.MergeRequestData{BranchName: "appstudio-repository", BaseBranchName: "main"}
+
+	_, ensureErr := client.EnsurePaCMergeRequest(testRepoUrl, d)
+	_, undoErr := client.UndoPaCMergeRequest(testRepoUrl, d)
+	_, commitErr := client.CommitPaCConfiguration(testRepoUrl, d)
+	webhookErr := client.SetupPaCWebhook(testRepoUrl, "https://pac.example.com", "secret")
+	for _, err := range []error{ensureErr, undoErr, commitErr, webhookErr} {
+		boErr, ok := err.(*boerrors.BuildOpError)
+		if !ok || boErr.GetErrorId() != int(boerrors.EPaCGitProviderNotSupported) {
+			t.Errorf("expected Pipelines as Code not supported error, got: %v", err)
+		}
+	}
+}
+
+func TestGetVersionType(t *testing.T) {
+	if versionType := getVersionType("main"); versionType != "branch" {
+		t.Errorf("unexpected version type of a branch: %s", versionType)
+	}
+	if versionType := getVersionType(testBranchSha); versionType != "commit" {
+		t.Errorf("unexpected version type of a commit: %s", versionType)
+	}
+}
+
+func TestGetBrowseRepositoryAtShaLink(t *testing.T) {
+	client := &AzureDevOpsClient{}
+	link := client.GetBrowseRepositoryAtShaLink(testRepoUrl+".git", testBranchSha)
+	if link != testRepoUrl+"?version=GC"+testBranchSha {
+		t.Errorf("unexpected link: %s", link)
+	}
+}
+
+func TestRefineGitHostingServiceError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantErrorId boerrors.BOErrorId
+	}{
+		{
+			name:        "should detect unrecognized token",
+			status:      http.StatusUnauthorized,
+			wantErrorId: boerrors.EAzureDevOpsTokenUnauthorized,
+		},
+		{
+			name:        "should detect sign in page response",
+			status:      http.StatusNonAuthoritativeInfo,
+			wantErrorId: boerrors.EAzureDevOpsTokenUnauthorized,
+		},
+		{
+			name:        "should detect insufficient scopes",
+			status:      http.StatusForbidden,
+			wantErrorId: boerrors.EAzureDevOpsTokenInsufficientScope,
+		},
+		{
+			name:   "should keep other errors",
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc(testRepoApiPrefix, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			client := newTestClient(t, mux)
+			_, err := client.GetDefaultBranch(testRepoUrl)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			boErr, ok := err.(*boerrors.BuildOpError)
+			if tt.wantErrorId == 0 {
+				if ok {
+					t.Errorf("unexpected build operation error: %v", err)
+				}
+				return
+			}
+			if !ok || boErr.GetErrorId() != int(tt.wantErrorId) {
+				t.Errorf("expected %d error, got: %v", tt.wantErrorId, err)
+			}
+		})
+	}
+}
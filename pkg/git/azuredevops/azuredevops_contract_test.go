@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/fake"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovidertest"
+)
+
+// contractClient passes the owner/repository URLs of the contract tests to Azure DevOps client
+// as repositories of the owner project in a test organization.
+type contractClient struct {
+	*AzureDevOpsClient
+}
+
+// toAzureDevOpsUrl converts https://host/owner/repository into https://host/org/owner/_git/repository
+func toAzureDevOpsUrl(repoUrl string) string {
+	repositorySeparator := strings.LastIndex(repoUrl, "/")
+	ownerSeparator := strings.LastIndex(repoUrl[:repositorySeparator], "/")
+	return repoUrl[:ownerSeparator] + "/org" + repoUrl[ownerSeparator:repositorySeparator] + "/_git" + repoUrl[repositorySeparator:]
+}
+
+func (c contractClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	client, err := c.AzureDevOpsClient.WithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return contractClient{client.(*AzureDevOpsClient)}, nil
+}
+
+func (c contractClient) GetDefaultBranch(repoUrl string) (string, error) {
+	return c.AzureDevOpsClient.GetDefaultBranch(toAzureDevOpsUrl(repoUrl))
+}
+
+func (c contractClient) GetBranchSha(repoUrl, branchName string) (string, error) {
+	return c.AzureDevOpsClient.GetBranchSha(toAzureDevOpsUrl(repoUrl), branchName)
+}
+
+func (c contractClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
+	return c.AzureDevOpsClient.IsFileExist(toAzureDevOpsUrl(repoUrl), branchName, filePath)
+}
+
+func (c contractClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	return c.AzureDevOpsClient.DownloadFileContent(toAzureDevOpsUrl(repoUrl), revision, filePath)
+}
+
+func (c contractClient) IsRepositoryPublic(repoUrl string) (bool, error) {
+	return c.AzureDevOpsClient.IsRepositoryPublic(toAzureDevOpsUrl(repoUrl))
+}
+
+// Azure DevOps client supports builds without Pipelines as Code only, so the repository operations are checked.
+func TestAzureDevOpsClientContract(t *testing.T) {
+	gitprovidertest.RunRepositoryContractTests(t, func(t *testing.T, provider *fake.GitProvider) gp.GitProviderClient {
+		server := httptest.NewServer(fake.NewAzureDevOpsHandler(provider))
+		t.Cleanup(server.Close)
+		client, err := NewAzureDevOpsClient("pat", server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contractClient{client}
+	})
+}
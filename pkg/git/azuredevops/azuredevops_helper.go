@@ -0,0 +1,354 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	apiVersion = "7.0"
+
+	branchRefPrefix = "refs/heads/"
+)
+
+var commitShaRegex = regexp.MustCompile("^[0-9a-fA-F]{40}$")
+
+// repository identifies an Azure DevOps git repository.
+type repository struct {
+	// host is the scheme and host of the repository URL, e.g. https://dev.azure.com
+	host string
+	// collection is the path of the organization (collection) on the host, e.g. /org
+	// It is empty for legacy https://org.visualstudio.com URLs.
+	collection string
+	project    string
+	name       string
+}
+
+type repositoryInfo struct {
+	ID            string `json:"id"`
+	DefaultBranch string `json:"defaultBranch"`
+	Project       struct {
+		ID string `json:"id"`
+		// Visibility is one of: private, public
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+type gitRef struct {
+	Name     string `json:"name"`
+	ObjectID string `json:"objectId"`
+}
+
+type item struct {
+	Path     string `json:"path"`
+	IsFolder bool   `json:"isFolder"`
+}
+
+// list is the envelope of Azure DevOps API responses with collections.
+type list[T any] struct {
+	Value []T `json:"value"`
+	Count int `json:"count"`
+}
+
+// apiError is the error structure returned by Azure DevOps API.
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// getRepositoryFromUrl parses Azure DevOps repository URL.
+// Supported formats are:
+// https://dev.azure.com/org/project/_git/repository
+// https://org.visualstudio.com/project/_git/repository
+// https://server/collection/project/_git/repository (Azure DevOps Server)
+// git@ssh.dev.azure.com:v3/org/project/repository
+func getRepositoryFromUrl(repoUrl string) (*repository, error) {
+	repoUrl = strings.TrimSuffix(strings.TrimSuffix(repoUrl, "/"), ".git")
+
+	if strings.HasPrefix(repoUrl, "git@") {
+		hostAndPath := strings.SplitN(strings.TrimPrefix(repoUrl, "git@"), ":", 2)
+		pathParts := strings.Split(strings.TrimPrefix(hostAndPath[len(hostAndPath)-1], "v3/"), "/")
+		if len(hostAndPath) != 2 || len(pathParts) != 3 {
+			return nil, fmt.Errorf("unsupported Azure DevOps repository URL: %s", repoUrl)
+		}
+		return &repository{
+			host:       "https://" + strings.TrimPrefix(hostAndPath[0], "ssh."),
+			collection: "/" + pathParts[0],
+			project:    pathParts[1],
+			name:       pathParts[2],
+		}, nil
+	}
+
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure DevOps repository URL %s: %w", repoUrl, err)
+	}
+	projectPath, repositoryName, found := strings.Cut(u.Path, "/_git/")
+	projectPathSeparator := strings.LastIndex(projectPath, "/")
+	if !found || repositoryName == "" || strings.Contains(repositoryName, "/") || projectPathSeparator < 0 {
+		return nil, fmt.Errorf("unsupported Azure DevOps repository URL: %s", repoUrl)
+	}
+	return &repository{
+		host:       u.Scheme + "://" + u.Host,
+		collection: projectPath[:projectPathSeparator],
+		project:    projectPath[projectPathSeparator+1:],
+		name:       repositoryName,
+	}, nil
+}
+
+// webUrl returns URL of the repository web page.
+func (r *repository) webUrl() string {
+	return fmt.Sprintf("%s%s/%s/_git/%s", r.host, r.collection, url.PathEscape(r.project), url.PathEscape(r.name))
+}
+
+// refineGitHostingServiceError generates expected permanent error from Azure DevOps response.
+// If no one is detected, the original error will be returned.
+// refineGitHostingServiceError should be called just after every Azure DevOps API call.
+func refineGitHostingServiceError(response *http.Response, originErr error) error {
+	if response == nil || originErr == nil {
+		return originErr
+	}
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusNonAuthoritativeInfo:
+		return boerrors.NewBuildOpError(boerrors.EAzureDevOpsTokenUnauthorized, originErr)
+	case http.StatusForbidden:
+		return boerrors.NewBuildOpError(boerrors.EAzureDevOpsTokenInsufficientScope, originErr)
+	default:
+		return originErr
+	}
+}
+
+// apiHost returns scheme and host of the API endpoint for the given repository.
+func (a *AzureDevOpsClient) apiHost(repo *repository) string {
+	if a.baseUrl != "" {
+		return a.baseUrl
+	}
+	return repo.host
+}
+
+// projectApiUrl returns Azure DevOps API URL of the given project level resource.
+func (a *AzureDevOpsClient) projectApiUrl(repo *repository, resourcePath ...string) string {
+	apiUrl := fmt.Sprintf("%s%s/%s/_apis", a.apiHost(repo), repo.collection, url.PathEscape(repo.project))
+	for _, element := range resourcePath {
+		apiUrl += "/" + url.PathEscape(element)
+	}
+	return apiUrl
+}
+
+// repoApiUrl returns Azure DevOps API URL of the given repository resource.
+// Each element of the resource path is escaped.
+func (a *AzureDevOpsClient) repoApiUrl(repo *repository, resourcePath ...string) string {
+	return a.projectApiUrl(repo, append([]string{"git", "repositories", repo.name}, resourcePath...)...)
+}
+
+// doRequest performs Azure DevOps API call and returns the response body.
+// Any not 2xx response is converted into an error, the response is returned in any case if available.
+func (a *AzureDevOpsClient) doRequest(method, requestUrl string, query url.Values, body io.Reader, contentType string) ([]byte, *http.Response, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("api-version", apiVersion)
+	requestUrl += "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(a.ctx, method, requestUrl, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Personal access token is sent as the password with an empty username
+	req.SetBasicAuth("", a.accessToken)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if resp.StatusCode == http.StatusNonAuthoritativeInfo {
+		// Azure DevOps responds with a sign in page instead of 401 to some requests with unrecognized credentials
+		return nil, resp, fmt.Errorf("%s %s: %d credentials are not recognized", method, requestUrl, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := string(respBody)
+		adoErr := &apiError{}
+		if err := json.Unmarshal(respBody, adoErr); err == nil && adoErr.Message != "" {
+			message = adoErr.Message
+		}
+		return respBody, resp, fmt.Errorf("%s %s: %d %s", method, requestUrl, resp.StatusCode, message)
+	}
+	return respBody, resp, nil
+}
+
+// doJsonRequest sends given payload (if any) as JSON and decodes JSON response into result (if given).
+func (a *AzureDevOpsClient) doJsonRequest(method, requestUrl string, query url.Values, payload interface{}, result interface{}) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payloadBytes)
+		contentType = "application/json"
+	}
+
+	respBody, resp, err := a.doRequest(method, requestUrl, query, body, contentType)
+	if err != nil {
+		return resp, err
+	}
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return resp, fmt.Errorf("failed to decode Azure DevOps API response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// getRepositoryInfo returns the repository details or nil if the repository doesn't exist.
+func (a *AzureDevOpsClient) getRepositoryInfo(repo *repository) (*repositoryInfo, error) {
+	info := &repositoryInfo{}
+	resp, err := a.doJsonRequest(http.MethodGet, a.repoApiUrl(repo), nil, nil, info)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return info, nil
+}
+
+func (a *AzureDevOpsClient) getDefaultBranch(repo *repository) (string, error) {
+	info, err := a.getRepositoryInfo(repo)
+	if err != nil {
+		return "", err
+	}
+	if info == nil {
+		return "", fmt.Errorf("repository %s not found", repo.webUrl())
+	}
+	if info.DefaultBranch == "" {
+		return "", fmt.Errorf("repository %s has no default branch", repo.webUrl())
+	}
+	return strings.TrimPrefix(info.DefaultBranch, branchRefPrefix), nil
+}
+
+// getBranch returns the branch ref or nil if the branch doesn't exist.
+func (a *AzureDevOpsClient) getBranch(repo *repository, branchName string) (*gitRef, error) {
+	// The filter matches refs by prefix, so the exact match has to be found
+	query := url.Values{"filter": {"heads/" + branchName}}
+	refs := &list[gitRef]{}
+	resp, err := a.doJsonRequest(http.MethodGet, a.repoApiUrl(repo, "refs"), query, nil, refs)
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	for _, ref := range refs.Value {
+		if ref.Name == branchRefPrefix+branchName {
+			return &ref, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *AzureDevOpsClient) getBranchSha(repo *repository, branchName string) (string, error) {
+	branch, err := a.getBranch(repo, branchName)
+	if err != nil {
+		return "", err
+	}
+	if branch == nil {
+		return "", fmt.Errorf("branch %s not found", branchName)
+	}
+	return branch.ObjectID, nil
+}
+
+// getFileContent returns content of the file at the given revision or nil if the file doesn't exist.
+// The revision is either a branch name or a commit SHA.
+func (a *AzureDevOpsClient) getFileContent(repo *repository, revision, filePath string) ([]byte, error) {
+	query := url.Values{
+		"path":                          {"/" + strings.TrimPrefix(filePath, "/")},
+		"$format":                       {"octetStream"},
+		"versionDescriptor.version":     {revision},
+		"versionDescriptor.versionType": {getVersionType(revision)},
+	}
+	content, resp, err := a.doRequest(http.MethodGet, a.repoApiUrl(repo, "items"), query, nil, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return content, nil
+}
+
+func getVersionType(revision string) string {
+	if commitShaRegex.MatchString(revision) {
+		return "commit"
+	}
+	return "branch"
+}
+
+// filesExistInDirectory checks if given files exist under specified directory.
+// Returns subset of given files which exist.
+func (a *AzureDevOpsClient) filesExistInDirectory(repo *repository, branchName, directoryPath string, files []gp.RepositoryFile) ([]gp.RepositoryFile, error) {
+	existingFiles := make([]gp.RepositoryFile, 0, len(files))
+
+	query := url.Values{
+		"scopePath":                     {"/" + strings.Trim(directoryPath, "/")},
+		"recursionLevel":                {"oneLevel"},
+		"versionDescriptor.version":     {branchName},
+		"versionDescriptor.versionType": {"branch"},
+	}
+	items := &list[item]{}
+	resp, err := a.doJsonRequest(http.MethodGet, a.repoApiUrl(repo, "items"), query, nil, items)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return existingFiles, nil
+		}
+		return existingFiles, refineGitHostingServiceError(resp, err)
+	}
+
+	for _, file := range items.Value {
+		if file.IsFolder {
+			continue
+		}
+		filePath := strings.TrimPrefix(file.Path, "/")
+		for _, f := range files {
+			if filePath == f.FullPath {
+				existingFiles = append(existingFiles, gp.RepositoryFile{FullPath: filePath})
+				break
+			}
+		}
+	}
+
+	return existingFiles, nil
+}
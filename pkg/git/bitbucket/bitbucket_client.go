@@ -0,0 +1,527 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// Allow mocking for tests
+var NewBitbucketClient func(username, appPassword string) (*BitbucketClient, error) = newBitbucketClient
+
+const (
+	bitbucketCloudApiUrl = "https://api.bitbucket.org/2.0"
+
+	pacWebhookDescription = "Pipelines as Code"
+)
+
+var (
+	appStudioPaCWebhookEvents = [...]string{"repo:push", "pullrequest:created", "pullrequest:updated", "pullrequest:comment_created"}
+)
+
+var _ gp.GitProviderClient = (*BitbucketClient)(nil)
+var _ gp.ContextBinder = (*BitbucketClient)(nil)
+
+// BitbucketClient implements git provider client for Bitbucket Cloud.
+// Bitbucket Cloud doesn't have access tokens bound to a user, so username and app password pair is used.
+type BitbucketClient struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseUrl    string
+
+	username    string
+	appPassword string
+}
+
+// WithContext returns a shallow copy of the client which makes all its requests within the given context.
+func (b *BitbucketClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	client := *b
+	client.ctx = ctx
+	return &client, nil
+}
+
+// EnsurePaCMergeRequest creates or updates existing (if needed) Pipelines as Code configuration proposal pull request.
+// Returns the pull request web URL.
+// If there is no error and web URL is empty, it means that the pull request is not needed (main branch is up to date).
+func (b *BitbucketClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return "", err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	pacConfigurationUpToDate, err := b.filesUpToDate(workspace, repository, d.BaseBranchName, d.Files)
+	if err != nil {
+		return "", err
+	}
+	if pacConfigurationUpToDate {
+		// Nothing to do, the configuration is alredy in the main branch of the repository
+		return "", nil
+	}
+
+	prBranchExists, err := b.branchExist(workspace, repository, d.BranchName)
+	if err != nil {
+		return "", err
+	}
+
+	if prBranchExists {
+		prBranchUpToDate, err := b.filesUpToDate(workspace, repository, d.BranchName, d.Files)
+		if err != nil {
+			return "", err
+		}
+		if !prBranchUpToDate {
+			err := b.commitFilesIntoBranch(workspace, repository, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		pr, err := b.findPullRequestByBranches(workspace, repository, d.BranchName, d.BaseBranchName)
+		if err != nil {
+			return "", err
+		}
+		if pr != nil {
+			// Pull request already exists
+			return pr.Links.Html.Href, nil
+		}
+
+		diffExists, err := b.diffNotEmpty(workspace, repository, d.BranchName, d.BaseBranchName)
+		if err != nil {
+			return "", err
+		}
+		if !diffExists {
+			// This situation occurs if a PR was merged but the branch was not deleted and main is changed after the merge.
+			// The branch is already "included" in main, so it's not possible to create a PR from it.
+			if _, err := b.deleteBranch(workspace, repository, d.BranchName); err != nil {
+				return "", err
+			}
+			return b.EnsurePaCMergeRequest(repoUrl, d)
+		}
+
+		return b.createPullRequestWithinRepository(workspace, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+	} else {
+		// Need to create branch and PR with Pipelines as Code configuration
+		if err := b.createBranch(workspace, repository, d.BranchName, d.BaseBranchName); err != nil {
+			return "", err
+		}
+
+		err = b.commitFilesIntoBranch(workspace, repository, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+		if err != nil {
+			return "", err
+		}
+
+		return b.createPullRequestWithinRepository(workspace, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+	}
+}
+
+// UndoPaCMergeRequest creates or updates existing Pipelines as Code configuration removal pull request.
+// Returns the pull request web URL.
+// If there is no error and web URL is empty, it means that the pull request is not needed (the configuraton has already been deleted).
+func (b *BitbucketClient) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return "", err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := b.filesExistInDirectory(workspace, repository, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		// Nothing to prune
+		return "", nil
+	}
+
+	// Need to create PR that deletes PaC configuration of the component
+
+	// Delete old branch, if any
+	if _, err := b.deleteBranch(workspace, repository, d.BranchName); err != nil {
+		return "", err
+	}
+
+	// Create branch, commit and pull request
+	if err := b.createBranch(workspace, repository, d.BranchName, d.BaseBranchName); err != nil {
+		return "", err
+	}
+
+	err = b.addDeleteCommitToBranch(workspace, repository, d.BranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files)
+	if err != nil {
+		return "", err
+	}
+
+	return b.createPullRequestWithinRepository(workspace, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+}
+
+// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal pull request
+func (b *BitbucketClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	prs, err := b.findOpenPullRequestsByBranches(workspace, repository, d.BranchName, d.BaseBranchName)
+	if err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	pr := prs[0]
+	return &gp.MergeRequest{
+		Id:        pr.ID,
+		CreatedAt: &pr.CreatedOn,
+		WebUrl:    pr.Links.Html.Href,
+		Title:     pr.Title,
+	}, nil
+}
+
+// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch, if the pull request branch is behind the base branch.
+// Bitbucket API doesn't allow force update of branches, so the outdated pull request is declined
+// and a new one is created from the recreated branch.
+func (b *BitbucketClient) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return b.refreshPaCMergeRequest(repoUrl, d, false)
+}
+
+// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch with the given files, even if the branch is up to date.
+// The pull request is declined and a new one is created, see RefreshPaCMergeRequest.
+func (b *BitbucketClient) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	webUrl, _, err = b.refreshPaCMergeRequest(repoUrl, d, true)
+	return webUrl, err
+}
+
+// refreshPaCMergeRequest recreates the branch of the open proposal pull request if it's behind the base branch or force is set.
+func (b *BitbucketClient) refreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData, force bool) (webUrl string, refreshed bool, err error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return "", false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	pr, err := b.findPullRequestByBranches(workspace, repository, d.BranchName, d.BaseBranchName)
+	if err != nil || pr == nil {
+		return "", false, err
+	}
+
+	if !force {
+		// The branch is behind if the base branch has commits which are not in the branch
+		isBehind, err := b.diffNotEmpty(workspace, repository, d.BaseBranchName, d.BranchName)
+		if err != nil || !isBehind {
+			return pr.Links.Html.Href, false, err
+		}
+	}
+
+	if err := b.declinePullRequest(workspace, repository, pr.ID); err != nil {
+		return "", false, err
+	}
+	if _, err := b.deleteBranch(workspace, repository, d.BranchName); err != nil {
+		return "", false, err
+	}
+	webUrl, err = b.EnsurePaCMergeRequest(repoUrl, d)
+	if err != nil {
+		return "", false, err
+	}
+	return webUrl, true, nil
+}
+
+// GetMergeRequestStatus returns current state of the pull request with the given web URL in the given repository.
+// Bitbucket doesn't provide mergeability of pull requests nor the time of merge,
+// so the time of the last pull request update is reported as the merge time.
+func (b *BitbucketClient) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	id, err := gp.GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := b.getPullRequest(workspace, repository, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &gp.MergeRequestStatus{
+		State:     gp.MergeRequestStateOpen,
+		CreatedAt: &pr.CreatedOn,
+	}
+	switch pr.State {
+	case "MERGED":
+		status.State = gp.MergeRequestStateMerged
+		status.MergedAt = &pr.UpdatedOn
+	case "DECLINED", "SUPERSEDED":
+		status.State = gp.MergeRequestStateClosed
+	default:
+		// The branch is behind if the base branch has commits which are not in the branch
+		if status.IsBehind, err = b.diffNotEmpty(workspace, repository, pr.Destination.Branch.Name, pr.Source.Branch.Name); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// SetupPaCWebhook creates Pipelines as Code webhook in the given repository
+func (b *BitbucketClient) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	existingWebhook, err := b.getWebhookByTargetUrl(workspace, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+
+	pacWebhook := getPaCWebhook(webhookUrl, webhookSecret)
+	if existingWebhook == nil {
+		return b.createWebhook(workspace, repository, pacWebhook)
+	}
+
+	// Need to always update the webhook in order to make sure that the webhook secret is up to date
+	// (it is not possible to read existing webhook secret)
+	return b.updateWebhook(workspace, repository, existingWebhook.UUID, pacWebhook)
+}
+
+// DeletePaCWebhook deletes Pipelines as Code webhook in the given repository
+func (b *BitbucketClient) DeletePaCWebhook(repoUrl, webhookUrl string) error {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	existingWebhook, err := b.getWebhookByTargetUrl(workspace, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+	if existingWebhook == nil {
+		// Webhook doesn't exist, nothing to do
+		return nil
+	}
+
+	return b.deleteWebhook(workspace, repository, existingWebhook.UUID)
+}
+
+// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch
+func (b *BitbucketClient) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	upToDate, err := b.filesUpToDate(workspace, repository, d.BaseBranchName, d.Files)
+	if err != nil || upToDate {
+		return false, err
+	}
+
+	if err := b.commitFilesIntoBranch(workspace, repository, d.BaseBranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch
+func (b *BitbucketClient) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := b.filesExistInDirectory(workspace, repository, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil || len(files) == 0 {
+		return false, err
+	}
+
+	if err := b.addDeleteCommitToBranch(workspace, repository, d.BaseBranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// IsBranchProtected returns true if there is a push restriction which applies to the given branch.
+// Users allowed to push by the restriction are not taken into account.
+// Only repository administrators can read branch restrictions, so false is returned if access is denied.
+func (b *BitbucketClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	restrictions, err := b.getPushRestrictions(workspace, repository)
+	if err != nil {
+		return false, err
+	}
+	for _, restriction := range restrictions {
+		if restriction.Kind != "push" {
+			continue
+		}
+		if restriction.BranchMatchKind != "glob" {
+			// Branching model restrictions apply to branch types, which cannot be resolved here, so assume the worst
+			return true, nil
+		}
+		if matched, _ := path.Match(restriction.Pattern, branchName); matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetDefaultBranch returns name of default branch in the given repository
+func (b *BitbucketClient) GetDefaultBranch(repoUrl string) (string, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+	return b.getDefaultBranch(workspace, repository)
+}
+
+// DeleteBranch deletes given branch from repository
+func (b *BitbucketClient) DeleteBranch(repoUrl, branchName string) (bool, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+	return b.deleteBranch(workspace, repository, branchName)
+}
+
+// GetBranchSha returns SHA of top commit in the given branch
+// If branch name is empty, default branch is used.
+func (b *BitbucketClient) GetBranchSha(repoUrl, branchName string) (string, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	// If branch is not specified, use default branch
+	if branchName == "" {
+		defaultBranchName, err := b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return "", err
+		}
+		branchName = defaultBranchName
+	}
+
+	br, err := b.getBranch(workspace, repository, branchName)
+	if err != nil {
+		return "", err
+	}
+	if br == nil {
+		return "", fmt.Errorf("branch %s not found", branchName)
+	}
+	if br.Target == nil {
+		return "", fmt.Errorf("unexpected response while getting branch top commit SHA")
+	}
+	return br.Target.Hash, nil
+}
+
+// IsFileExist check whether given file exists in the given branch of the reposiotry.
+// If branch is empty string, default branch is used.
+func (b *BitbucketClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	if branchName == "" {
+		var err error
+		branchName, err = b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	directory := filepath.Dir(filePath)
+	files, err := b.filesExistInDirectory(workspace, repository, branchName, directory, []gp.RepositoryFile{{FullPath: filePath}})
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (b *BitbucketClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	if revision == "" {
+		var err error
+		revision, err = b.getDefaultBranch(workspace, repository)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileContent, err := b.getFileContent(workspace, repository, revision, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if fileContent == nil {
+		return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+	}
+	return fileContent, nil
+}
+
+// IsRepositoryPublic returns true if the repository could be accessed without authentication
+func (b *BitbucketClient) IsRepositoryPublic(repoUrl string) (bool, error) {
+	workspace, repository := getWorkspaceAndRepoFromUrl(repoUrl)
+
+	repoInfo, err := b.getRepositoryInfo(workspace, repository)
+	if err != nil {
+		return false, err
+	}
+	if repoInfo == nil {
+		return false, nil
+	}
+	return !repoInfo.IsPrivate, nil
+}
+
+// GetBrowseRepositoryAtShaLink returns web URL of repository state at given SHA
+func (b *BitbucketClient) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	repoUrl = strings.TrimSuffix(repoUrl, ".git")
+	gitSourceUrlParts := strings.Split(repoUrl, "/")
+	gitProviderHost := "https://" + gitSourceUrlParts[2]
+	workspace := gitSourceUrlParts[3]
+	repository := gitSourceUrlParts[4]
+
+	return fmt.Sprintf("%s/%s/%s/src/%s", gitProviderHost, workspace, repository, sha)
+}
+
+func (b *BitbucketClient) GetConfiguredGitAppName() (string, string, error) {
+	return "", "", fmt.Errorf("Bitbucket does not support applications")
+}
+
+func newBitbucketClient(username, appPassword string) (*BitbucketClient, error) {
+	if username == "" || appPassword == "" {
+		return nil, fmt.Errorf("both username and app password are required to create Bitbucket client")
+	}
+	return &BitbucketClient{
+		ctx:         context.TODO(),
+		httpClient:  &http.Client{},
+		baseUrl:     bitbucketCloudApiUrl,
+		username:    username,
+		appPassword: appPassword,
+	}, nil
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/fake"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	testRepoUrl       = "https://bitbucket.org/workspace/repository"
+	testRepoApiPrefix = "/repositories/workspace/repository"
+)
+
+// newTestClient returns Bitbucket client which talks to a local HTTP server with the given handler.
+func newTestClient(t *testing.T, handler http.Handler) *BitbucketClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "app-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewBitbucketClient("user", "app-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.baseUrl = server.URL
+	return client
+}
+
+func writeJson(t *testing.T, w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshPaCMergeRequestDeclinesOutdatedPullRequest(t *testing.T) {
+	provider := fake.NewGitProvider()
+	provider.AddRepository(testRepoUrl, "main", []gp.RepositoryFile{{FullPath: "main.py", Content: []byte("print('Hello')")}})
+	client := newTestClient(t, fake.NewBitbucketHandler(provider))
+	newMergeRequestData := func() *gp.MergeRequestData {
+		return &gp.MergeRequestData{
+			CommitMessage: "Pipelines as Code configuration",
+			BranchName:    "appstudio-component",
+			Title:         "Pipelines as Code configuration",
+			AuthorName:    "build-service",
+			AuthorEmail:   "build-service@example.com",
+			Files:         []gp.RepositoryFile{{FullPath: ".tekton/component-push.yaml", Content: []byte("push")}},
+		}
+	}
+
+	outdatedWebUrl, err := client.EnsurePaCMergeRequest(testRepoUrl, newMergeRequestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.PushFiles(testRepoUrl, "main", []gp.RepositoryFile{{FullPath: "main.py", Content: []byte("print('Bye')")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	webUrl, refreshed, err := client.RefreshPaCMergeRequest(testRepoUrl, newMergeRequestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed || webUrl == outdatedWebUrl {
+		t.Fatalf("expected new pull request, got %s", webUrl)
+	}
+	status, err := client.GetMergeRequestStatus(testRepoUrl, outdatedWebUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != gp.MergeRequestStateClosed {
+		t.Errorf("outdated pull request should be declined, got %s", status.State)
+	}
+	d := newMergeRequestData()
+	d.BaseBranchName = "main"
+	mr, err := client.FindUnmergedPaCMergeRequest(testRepoUrl, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr == nil || mr.WebUrl != webUrl {
+		t.Errorf("the new pull request should be the only open one, found: %+v", mr)
+	}
+}
+
+func TestCommitPaCConfigurationRejectsFormFieldPath(t *testing.T) {
+	provider := fake.NewGitProvider()
+	provider.AddRepository(testRepoUrl, "main", []gp.RepositoryFile{{FullPath: "main.py", Content: []byte("print('Hello')")}})
+	client := newTestClient(t, fake.NewBitbucketHandler(provider))
+	baseCommit, err := provider.GetCommit(testRepoUrl, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filePath := range []string{"message", "branch", "author", "parents", "files"} {
+		d := &gp.MergeRequestData{
+			CommitMessage: "Pipelines as Code configuration",
+			AuthorName:    "build-service",
+			AuthorEmail:   "build-service@example.com",
+			Files: []gp.RepositoryFile{
+				{FullPath: ".tekton/component-push.yaml", Content: []byte("push")},
+				{FullPath: filePath, Content: []byte("content")},
+			},
+		}
+		if _, err := client.CommitPaCConfiguration(testRepoUrl, d); err == nil {
+			t.Errorf("expected error on commit of %s file", filePath)
+		}
+	}
+	headCommit, err := provider.GetCommit(testRepoUrl, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headCommit.Sha != baseCommit.Sha {
+		t.Errorf("nothing should be committed, got commit %q", headCommit.Message)
+	}
+
+	// Nested paths don't collide with the form fields
+	d := &gp.MergeRequestData{
+		CommitMessage: "Pipelines as Code configuration",
+		Files:         []gp.RepositoryFile{{FullPath: ".tekton/message", Content: []byte("content")}},
+	}
+	if _, err := client.CommitPaCConfiguration(testRepoUrl, d); err != nil {
+		t.Fatal(err)
+	}
+	if headCommit, err = provider.GetCommit(testRepoUrl, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if headCommit.Message != d.CommitMessage || string(headCommit.Files[".tekton/message"]) != "content" {
+		t.Errorf("unexpected commit %q with files %v", headCommit.Message, headCommit.Files)
+	}
+}
+
+func TestIsBranchProtected(t *testing.T) {
+	tests := []struct {
+		name               string
+		restrictionsStatus int
+		restrictions       []map[string]string
+		expected           bool
+	}{
+		{
+			name:               "should detect push restriction matching the branch",
+			restrictionsStatus: http.StatusOK,
+			restrictions:       []map[string]string{{"kind": "push", "branch_match_kind": "glob", "pattern": "ma*"}},
+			expected:           true,
+		},
+		{
+			name:               "should ignore push restriction of other branches",
+			restrictionsStatus: http.StatusOK,
+			restrictions:       []map[string]string{{"kind": "push", "branch_match_kind": "glob", "pattern": "release-*"}},
+			expected:           false,
+		},
+		{
+			name:               "should assume branching model restriction applies to the branch",
+			restrictionsStatus: http.StatusOK,
+			restrictions:       []map[string]string{{"kind": "push", "branch_match_kind": "branching_model", "branch_type": "production"}},
+			expected:           true,
+		},
+		{
+			name:               "should not report protection if restrictions cannot be read",
+			restrictionsStatus: http.StatusForbidden,
+			expected:           false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc(testRepoApiPrefix+"/branch-restrictions", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("kind") != "push" {
+					t.Errorf("unexpected restrictions kind: %s", r.URL.Query().Get("kind"))
+				}
+				if tt.restrictionsStatus != http.StatusOK {
+					w.WriteHeader(tt.restrictionsStatus)
+					return
+				}
+				writeJson(t, w, map[string]interface{}{"values": tt.restrictions})
+			})
+
+			client := newTestClient(t, mux)
+			isProtected, err := client.IsBranchProtected(testRepoUrl, "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isProtected != tt.expected {
+				t.Errorf("expected protected to be %t", tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetBrowseRepositoryAtShaLink(t *testing.T) {
+	client, err := NewBitbucketClient("user", "app-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := client.GetBrowseRepositoryAtShaLink("https://bitbucket.org/workspace/repository.git", "1234abcd")
+	if link != "https://bitbucket.org/workspace/repository/src/1234abcd" {
+		t.Errorf("unexpected link: %s", link)
+	}
+}
+
+func TestRefineGitHostingServiceError(t *testing.T) {
+	client := newTestClient(t, http.NewServeMux())
+	client.appPassword = "wrong"
+
+	_, err := client.GetDefaultBranch(testRepoUrl)
+	boErr, ok := err.(*boerrors.BuildOpError)
+	if !ok {
+		t.Fatalf("expected BuildOpError, got %v", err)
+	}
+	if boErr.GetErrorId() != int(boerrors.EBitbucketTokenUnauthorized) {
+		t.Errorf("unexpected error id: %d", boErr.GetErrorId())
+	}
+}
+
+func TestWithContextAbortsRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(testRepoApiPrefix, func(w http.ResponseWriter, r *http.Request) {
+		// Simulate hung git host
+		<-r.Context().Done()
+	})
+	client := gp.NewClientWithContext(newTestClient(t, mux))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetDefaultBranch(ctx, testRepoUrl); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got: %v", err)
+	}
+}
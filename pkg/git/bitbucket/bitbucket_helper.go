@@ -0,0 +1,530 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// commitFormFields are the fields of Bitbucket commit creation form, which are not file paths.
+// Files are submitted as form fields named by the file path, so a file with such path cannot be committed.
+var commitFormFields = map[string]bool{"branch": true, "message": true, "author": true, "parents": true, "files": true}
+
+type repositoryInfo struct {
+	IsPrivate  bool `json:"is_private"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+type branch struct {
+	Name   string `json:"name"`
+	Target *struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+type pullRequest struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	// State is one of: OPEN, MERGED, DECLINED, SUPERSEDED
+	State     string    `json:"state"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+	Links     struct {
+		Html struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Source      pullRequestEndpoint `json:"source"`
+	Destination pullRequestEndpoint `json:"destination"`
+}
+
+type pullRequestEndpoint struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+}
+
+type branchRestriction struct {
+	Kind string `json:"kind"`
+	// BranchMatchKind is one of: glob, branching_model
+	BranchMatchKind string `json:"branch_match_kind"`
+	Pattern         string `json:"pattern"`
+}
+
+type webhook struct {
+	UUID        string   `json:"uuid,omitempty"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Active      bool     `json:"active"`
+	Secret      string   `json:"secret,omitempty"`
+	Events      []string `json:"events"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// page is the envelope of all paginated Bitbucket API responses.
+type page[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+// apiError is the error structure returned by Bitbucket API.
+type apiError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func getWorkspaceAndRepoFromUrl(repoUrl string) (workspace string, repository string) {
+	// https://bitbucket.org/workspace/repository
+	gitSourceUrlParts := strings.Split(strings.TrimSuffix(repoUrl, ".git"), "/")
+	workspace = gitSourceUrlParts[3]
+	repository = gitSourceUrlParts[4]
+	return workspace, repository
+}
+
+// refineGitHostingServiceError generates expected permanent error from Bitbucket response.
+// If no one is detected, the original error will be returned.
+// refineGitHostingServiceError should be called just after every Bitbucket API call.
+func refineGitHostingServiceError(response *http.Response, originErr error) error {
+	if response == nil || originErr == nil {
+		return originErr
+	}
+	switch response.StatusCode {
+	case http.StatusUnauthorized:
+		return boerrors.NewBuildOpError(boerrors.EBitbucketTokenUnauthorized, originErr)
+	case http.StatusForbidden:
+		return boerrors.NewBuildOpError(boerrors.EBitbucketTokenInsufficientScope, originErr)
+	default:
+		return originErr
+	}
+}
+
+// repoApiUrl returns Bitbucket API URL of the given repository resource.
+// Each element of the resource path is escaped.
+func (b *BitbucketClient) repoApiUrl(workspace, repository string, resourcePath ...string) string {
+	apiUrl := fmt.Sprintf("%s/repositories/%s/%s", b.baseUrl, url.PathEscape(workspace), url.PathEscape(repository))
+	for _, element := range resourcePath {
+		apiUrl += "/" + url.PathEscape(element)
+	}
+	return apiUrl
+}
+
+// doRequest performs Bitbucket API call and returns the response body.
+// Any not 2xx response is converted into an error, the response is returned in any case if available.
+func (b *BitbucketClient) doRequest(method, requestUrl string, body io.Reader, contentType string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(b.ctx, method, requestUrl, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.SetBasicAuth(b.username, b.appPassword)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := string(respBody)
+		bbErr := &apiError{}
+		if err := json.Unmarshal(respBody, bbErr); err == nil && bbErr.Error.Message != "" {
+			message = bbErr.Error.Message
+		}
+		return respBody, resp, fmt.Errorf("%s %s: %d %s", method, requestUrl, resp.StatusCode, message)
+	}
+	return respBody, resp, nil
+}
+
+// doJsonRequest sends given payload (if any) as JSON and decodes JSON response into result (if given).
+func (b *BitbucketClient) doJsonRequest(method, requestUrl string, payload interface{}, result interface{}) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payloadBytes)
+		contentType = "application/json"
+	}
+
+	respBody, resp, err := b.doRequest(method, requestUrl, body, contentType)
+	if err != nil {
+		return resp, err
+	}
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return resp, fmt.Errorf("failed to decode Bitbucket API response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// listAll walks through all pages of the given paginated resource.
+func listAll[T any](b *BitbucketClient, requestUrl string) ([]T, *http.Response, error) {
+	var all []T
+	for requestUrl != "" {
+		p := &page[T]{}
+		resp, err := b.doJsonRequest(http.MethodGet, requestUrl, nil, p)
+		if err != nil {
+			return nil, resp, err
+		}
+		all = append(all, p.Values...)
+		requestUrl = p.Next
+	}
+	return all, nil, nil
+}
+
+// refinePushRejectedError converts error of a commit, which Bitbucket refused because of a branch restriction,
+// into push rejected error. Bitbucket responds with forbidden status in such case.
+func refinePushRejectedError(err error) error {
+	if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.GetErrorId() == int(boerrors.EBitbucketTokenInsufficientScope) {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	}
+	return err
+}
+
+func (b *BitbucketClient) getRepositoryInfo(workspace, repository string) (*repositoryInfo, error) {
+	repo := &repositoryInfo{}
+	resp, err := b.doJsonRequest(http.MethodGet, b.repoApiUrl(workspace, repository), nil, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return repo, nil
+}
+
+func (b *BitbucketClient) getDefaultBranch(workspace, repository string) (string, error) {
+	repo, err := b.getRepositoryInfo(workspace, repository)
+	if err != nil {
+		return "", err
+	}
+	if repo == nil || repo.MainBranch == nil {
+		return "", fmt.Errorf("repository info is empty in Bitbucket API response")
+	}
+	return repo.MainBranch.Name, nil
+}
+
+// getBranch returns the branch or nil if the branch doesn't exist.
+func (b *BitbucketClient) getBranch(workspace, repository, branchName string) (*branch, error) {
+	br := &branch{}
+	resp, err := b.doJsonRequest(http.MethodGet, b.repoApiUrl(workspace, repository, "refs", "branches", branchName), nil, br)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return br, nil
+}
+
+func (b *BitbucketClient) branchExist(workspace, repository, branchName string) (bool, error) {
+	br, err := b.getBranch(workspace, repository, branchName)
+	return br != nil, err
+}
+
+func (b *BitbucketClient) createBranch(workspace, repository, branchName, baseBranchName string) error {
+	baseBranch, err := b.getBranch(workspace, repository, baseBranchName)
+	if err != nil {
+		return err
+	}
+	if baseBranch == nil || baseBranch.Target == nil {
+		return fmt.Errorf("base branch %s not found", baseBranchName)
+	}
+
+	newBranch := map[string]interface{}{
+		"name":   branchName,
+		"target": map[string]string{"hash": baseBranch.Target.Hash},
+	}
+	resp, err := b.doJsonRequest(http.MethodPost, b.repoApiUrl(workspace, repository, "refs", "branches"), newBranch, nil)
+	return refineGitHostingServiceError(resp, err)
+}
+
+func (b *BitbucketClient) deleteBranch(workspace, repository, branchName string) (bool, error) {
+	_, resp, err := b.doRequest(http.MethodDelete, b.repoApiUrl(workspace, repository, "refs", "branches", branchName), nil, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// The given branch doesn't exist
+			return false, nil
+		}
+		return false, refineGitHostingServiceError(resp, err)
+	}
+	return true, nil
+}
+
+// getFileContent returns content of the file in the given branch or nil if the file doesn't exist.
+func (b *BitbucketClient) getFileContent(workspace, repository, branchName, filePath string) ([]byte, error) {
+	fileUrl := b.repoApiUrl(workspace, repository, "src", branchName) + "/" + escapeFilePath(filePath)
+	content, resp, err := b.doRequest(http.MethodGet, fileUrl, nil, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return content, nil
+}
+
+func (b *BitbucketClient) filesUpToDate(workspace, repository, branchName string, files []gp.RepositoryFile) (bool, error) {
+	for _, file := range files {
+		fileContent, err := b.getFileContent(workspace, repository, branchName, file.FullPath)
+		if err != nil {
+			return false, err
+		}
+		if fileContent == nil {
+			// Given file not found
+			return false, nil
+		}
+		if !bytes.Equal(fileContent, file.Content) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// filesExistInDirectory checks if given files exist under specified directory.
+// Returns subset of given files which exist.
+func (b *BitbucketClient) filesExistInDirectory(workspace, repository, branchName, directoryPath string, files []gp.RepositoryFile) ([]gp.RepositoryFile, error) {
+	existingFiles := make([]gp.RepositoryFile, 0, len(files))
+
+	// Trailing slash makes Bitbucket to list the directory instead of returning the raw content
+	dirUrl := b.repoApiUrl(workspace, repository, "src", branchName) + "/" + escapeFilePath(directoryPath) + "/?pagelen=100"
+	dirContent, resp, err := listAll[treeEntry](b, dirUrl)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return existingFiles, nil
+		}
+		return existingFiles, refineGitHostingServiceError(resp, err)
+	}
+
+	for _, file := range dirContent {
+		if file.Type != "commit_file" {
+			continue
+		}
+		for _, f := range files {
+			if file.Path == f.FullPath {
+				existingFiles = append(existingFiles, gp.RepositoryFile{FullPath: file.Path})
+				break
+			}
+		}
+	}
+
+	return existingFiles, nil
+}
+
+// commitFilesIntoBranch creates a commit on top of the given branch that creates or updates given files.
+func (b *BitbucketClient) commitFilesIntoBranch(workspace, repository, branchName, commitMessage, authorName, authorEmail string, files []gp.RepositoryFile) error {
+	form := url.Values{}
+	for _, file := range files {
+		if commitFormFields[file.FullPath] {
+			return fmt.Errorf("file %s cannot be committed via Bitbucket API: the path collides with a commit form field", file.FullPath)
+		}
+		form.Set(file.FullPath, string(file.Content))
+	}
+	return b.createCommit(workspace, repository, branchName, commitMessage, authorName, authorEmail, form)
+}
+
+// addDeleteCommitToBranch creates a commit on top of the given branch that deletes given files.
+func (b *BitbucketClient) addDeleteCommitToBranch(workspace, repository, branchName, authorName, authorEmail, commitMessage string, files []gp.RepositoryFile) error {
+	form := url.Values{}
+	for _, file := range files {
+		form.Add("files", file.FullPath)
+	}
+	return b.createCommit(workspace, repository, branchName, commitMessage, authorName, authorEmail, form)
+}
+
+func (b *BitbucketClient) createCommit(workspace, repository, branchName, commitMessage, authorName, authorEmail string, form url.Values) error {
+	form.Set("branch", branchName)
+	form.Set("message", commitMessage)
+	if authorName != "" && authorEmail != "" {
+		form.Set("author", fmt.Sprintf("%s <%s>", authorName, authorEmail))
+	}
+
+	_, resp, err := b.doRequest(http.MethodPost, b.repoApiUrl(workspace, repository, "src"),
+		strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	return refineGitHostingServiceError(resp, err)
+}
+
+// getPushRestrictions returns branch restrictions of the repository which limit pushes.
+// Returns no restrictions if the credentials are not allowed to read them.
+func (b *BitbucketClient) getPushRestrictions(workspace, repository string) ([]branchRestriction, error) {
+	restrictionsUrl := b.repoApiUrl(workspace, repository, "branch-restrictions") + "?kind=push"
+	restrictions, resp, err := listAll[branchRestriction](b, restrictionsUrl)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusForbidden {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return restrictions, nil
+}
+
+// diffNotEmpty checks whether the branch has commits which are not in the base branch.
+func (b *BitbucketClient) diffNotEmpty(workspace, repository, branchName, baseBranchName string) (bool, error) {
+	commitsUrl := b.repoApiUrl(workspace, repository, "commits", branchName) + "?exclude=" + url.QueryEscape(baseBranchName)
+	commits := &page[json.RawMessage]{}
+	resp, err := b.doJsonRequest(http.MethodGet, commitsUrl, nil, commits)
+	if err != nil {
+		return false, refineGitHostingServiceError(resp, err)
+	}
+	return len(commits.Values) > 0, nil
+}
+
+// findOpenPullRequestsByBranches returns open pull requests from the given branch into the base branch.
+func (b *BitbucketClient) findOpenPullRequestsByBranches(workspace, repository, branchName, baseBranchName string) ([]pullRequest, error) {
+	query := fmt.Sprintf(`source.branch.name="%s" AND destination.branch.name="%s" AND state="OPEN"`, branchName, baseBranchName)
+	prsUrl := b.repoApiUrl(workspace, repository, "pullrequests") + "?pagelen=50&q=" + url.QueryEscape(query)
+	prs, resp, err := listAll[pullRequest](b, prsUrl)
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return prs, nil
+}
+
+func (b *BitbucketClient) findPullRequestByBranches(workspace, repository, branchName, baseBranchName string) (*pullRequest, error) {
+	prs, err := b.findOpenPullRequestsByBranches(workspace, repository, branchName, baseBranchName)
+	if err != nil {
+		return nil, err
+	}
+	switch len(prs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &prs[0], nil
+	default:
+		return nil, fmt.Errorf("failed to find pull request by branch %s: %d matches found", branchName, len(prs))
+	}
+}
+
+func (b *BitbucketClient) getPullRequest(workspace, repository string, id int) (*pullRequest, error) {
+	pr := &pullRequest{}
+	resp, err := b.doJsonRequest(http.MethodGet, b.repoApiUrl(workspace, repository, "pullrequests", strconv.Itoa(id)), nil, pr)
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return pr, nil
+}
+
+// declinePullRequest closes the given pull request without merge.
+func (b *BitbucketClient) declinePullRequest(workspace, repository string, id int64) error {
+	resp, err := b.doJsonRequest(http.MethodPost, b.repoApiUrl(workspace, repository, "pullrequests", strconv.FormatInt(id, 10), "decline"), nil, nil)
+	return refineGitHostingServiceError(resp, err)
+}
+
+// createPullRequestWithinRepository create a new pull request into the same repository.
+// Returns url to the created pull request.
+func (b *BitbucketClient) createPullRequestWithinRepository(workspace, repository, branchName, baseBranchName, prTitle, prText string) (string, error) {
+	newPullRequest := map[string]interface{}{
+		"title":       prTitle,
+		"description": prText,
+		"source": map[string]interface{}{
+			"branch": map[string]string{"name": branchName},
+		},
+		"destination": map[string]interface{}{
+			"branch": map[string]string{"name": baseBranchName},
+		},
+		"close_source_branch": true,
+	}
+	pr := &pullRequest{}
+	resp, err := b.doJsonRequest(http.MethodPost, b.repoApiUrl(workspace, repository, "pullrequests"), newPullRequest, pr)
+	if err != nil {
+		return "", refineGitHostingServiceError(resp, err)
+	}
+	return pr.Links.Html.Href, nil
+}
+
+// getWebhookByTargetUrl returns webhook by its target url or nil if such webhook doesn't exist.
+func (b *BitbucketClient) getWebhookByTargetUrl(workspace, repository, webhookTargetUrl string) (*webhook, error) {
+	webhooks, resp, err := listAll[webhook](b, b.repoApiUrl(workspace, repository, "hooks")+"?pagelen=100")
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	for _, hook := range webhooks {
+		if hook.URL == webhookTargetUrl {
+			return &hook, nil
+		}
+	}
+	// Webhook with the given URL not found
+	return nil, nil
+}
+
+func (b *BitbucketClient) createWebhook(workspace, repository string, hook *webhook) error {
+	resp, err := b.doJsonRequest(http.MethodPost, b.repoApiUrl(workspace, repository, "hooks"), hook, nil)
+	return refineGitHostingServiceError(resp, err)
+}
+
+func (b *BitbucketClient) updateWebhook(workspace, repository, webhookUUID string, hook *webhook) error {
+	resp, err := b.doJsonRequest(http.MethodPut, b.repoApiUrl(workspace, repository, "hooks", webhookUUID), hook, nil)
+	return refineGitHostingServiceError(resp, err)
+}
+
+func (b *BitbucketClient) deleteWebhook(workspace, repository, webhookUUID string) error {
+	_, resp, err := b.doRequest(http.MethodDelete, b.repoApiUrl(workspace, repository, "hooks", webhookUUID), nil, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return refineGitHostingServiceError(resp, err)
+	}
+	return nil
+}
+
+func getPaCWebhook(webhookTargetUrl, webhookSecret string) *webhook {
+	return &webhook{
+		Description: pacWebhookDescription,
+		URL:         webhookTargetUrl,
+		Active:      true,
+		Secret:      webhookSecret,
+		Events:      appStudioPaCWebhookEvents[:],
+	}
+}
+
+// escapeFilePath escapes each element of the given path, keeping path separators as is.
+func escapeFilePath(filePath string) string {
+	pathElements := strings.Split(strings.Trim(filePath, "/"), "/")
+	for i, element := range pathElements {
+		pathElements[i] = url.PathEscape(element)
+	}
+	return strings.Join(pathElements, "/")
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commitsigning
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+)
+
+const (
+	// Pipelines as Code secret fields with commit signing configuration.
+
+	// GPGKeySecretKey is the ASCII armored GPG private key to sign commits with.
+	GPGKeySecretKey = "commit-signing-gpg-key"
+	// GPGPassphraseSecretKey is the passphrase of the GPG private key, if it's encrypted.
+	GPGPassphraseSecretKey = "commit-signing-gpg-passphrase"
+	// SSHKeySecretKey is the SSH private key in PEM (OpenSSH) format to sign commits with.
+	SSHKeySecretKey = "commit-signing-ssh-key"
+	// SSHPassphraseSecretKey is the passphrase of the SSH private key, if it's encrypted.
+	SSHPassphraseSecretKey = "commit-signing-ssh-passphrase"
+	// GitHubAppSecretKey set to true makes GitHub sign commits created by the GitHub Application.
+	// Such commits do not have custom author and are shown as verified commits of the application.
+	GitHubAppSecretKey = "commit-signing-github-app"
+
+	// Commit signing methods.
+	MethodGPG       = "gpg"
+	MethodSSH       = "ssh"
+	MethodGitHubApp = "github-app"
+
+	sshSignatureNamespace     = "git"
+	sshSignatureHashAlgorithm = "sha512"
+	sshSignatureVersion       = 1
+	sshSignatureMagic         = "SSHSIG"
+)
+
+// Signer creates signatures of git commit objects.
+type Signer interface {
+	// Method returns the signing method, e.g. gpg.
+	Method() string
+	// Sign returns ASCII armored signature of the given commit object payload.
+	// The signature is supposed to be put into the gpgsig header of the commit.
+	Sign(payload []byte) (string, error)
+}
+
+// CommitIdentity is an author or a committer of a commit.
+type CommitIdentity struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// GetSigningMethod returns commit signing method configured in the given Pipelines as Code secret data.
+// Returns empty string if commits are not signed.
+func GetSigningMethod(gitProvider string, config map[string][]byte) string {
+	if len(bytes.TrimSpace(config[GPGKeySecretKey])) != 0 {
+		return MethodGPG
+	}
+	if len(bytes.TrimSpace(config[SSHKeySecretKey])) != 0 {
+		return MethodSSH
+	}
+	if IsGitHubAppSigningEnabled(gitProvider, config) {
+		return MethodGitHubApp
+	}
+	return ""
+}
+
+// IsGitHubAppSigningEnabled checks whether commits should be signed by GitHub on behalf of the configured GitHub Application.
+func IsGitHubAppSigningEnabled(gitProvider string, config map[string][]byte) bool {
+	if gitProvider != "github" || !gitops.IsPaCApplicationConfigured(gitProvider, config) {
+		return false
+	}
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(string(config[GitHubAppSecretKey])))
+	return enabled
+}
+
+// NewSignerFromSecret creates commit signer from the given Pipelines as Code secret data.
+// Returns nil if no signing key is configured.
+func NewSignerFromSecret(config map[string][]byte) (Signer, error) {
+	switch GetSigningMethod("", config) {
+	case MethodGPG:
+		return NewGPGSigner(config[GPGKeySecretKey], config[GPGPassphraseSecretKey])
+	case MethodSSH:
+		return NewSSHSigner(config[SSHKeySecretKey], config[SSHPassphraseSecretKey])
+	}
+	return nil, nil
+}
+
+type gpgSigner struct {
+	entity *openpgp.Entity
+}
+
+// NewGPGSigner creates commit signer from ASCII armored GPG private key.
+func NewGPGSigner(armoredKey, passphrase []byte) (Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPG signing key: %w", err)
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("GPG signing key does not contain a private key")
+	}
+	entity := entities[0]
+	if len(passphrase) != 0 {
+		if err := entity.DecryptPrivateKeys(passphrase); err != nil {
+			return nil, fmt.Errorf("failed to decrypt GPG signing key: %w", err)
+		}
+	} else if entity.PrivateKey.Encrypted {
+		return nil, fmt.Errorf("GPG signing key is encrypted, but passphrase is not set")
+	}
+	return &gpgSigner{entity: entity}, nil
+}
+
+func (s *gpgSigner) Method() string {
+	return MethodGPG
+}
+
+func (s *gpgSigner) Sign(payload []byte) (string, error) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, s.entity, bytes.NewReader(payload), nil); err != nil {
+		return "", err
+	}
+	return signature.String(), nil
+}
+
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+// NewSSHSigner creates commit signer from SSH private key.
+func NewSSHSigner(privateKey, passphrase []byte) (Signer, error) {
+	var signer ssh.Signer
+	var err error
+	if len(passphrase) != 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH signing key: %w", err)
+	}
+	return &sshSigner{signer: signer}, nil
+}
+
+func (s *sshSigner) Method() string {
+	return MethodSSH
+}
+
+// Sign creates SSH signature in the format used by ssh-keygen -Y sign, see PROTOCOL.sshsig of OpenSSH.
+func (s *sshSigner) Sign(payload []byte) (string, error) {
+	hash := sha512.Sum512(payload)
+	signedData := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sshSignatureNamespace, "", sshSignatureHashAlgorithm, hash[:]})
+	signedData = append([]byte(sshSignatureMagic), signedData...)
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SHA-1 RSA signatures are not accepted anymore
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	blob := ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{sshSignatureVersion, s.signer.PublicKey().Marshal(), sshSignatureNamespace, "", sshSignatureHashAlgorithm, ssh.Marshal(signature)})
+	blob = append([]byte(sshSignatureMagic), blob...)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob})), nil
+}
+
+// CommitPayload returns git commit object content without signature, i.e. the data to sign.
+// The dates are encoded with seconds precision in their time zone.
+func CommitPayload(treeSha string, parentShas []string, author, committer CommitIdentity, message string) []byte {
+	var payload bytes.Buffer
+	fmt.Fprintf(&payload, "tree %s\n", treeSha)
+	for _, parentSha := range parentShas {
+		fmt.Fprintf(&payload, "parent %s\n", parentSha)
+	}
+	fmt.Fprintf(&payload, "author %s\n", formatCommitIdentity(author))
+	fmt.Fprintf(&payload, "committer %s\n", formatCommitIdentity(committer))
+	fmt.Fprintf(&payload, "\n%s", message)
+	return payload.Bytes()
+}
+
+func formatCommitIdentity(identity CommitIdentity) string {
+	return fmt.Sprintf("%s <%s> %d %s", identity.Name, identity.Email, identity.When.Unix(), identity.When.Format("-0700"))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commitsigning
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+func generateArmoredGPGKey(t *testing.T) (*openpgp.Entity, []byte) {
+	entity, err := openpgp.NewEntity("Bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var armoredKey bytes.Buffer
+	writer, err := armor.Encode(&armoredKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(writer, nil); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	return entity, armoredKey.Bytes()
+}
+
+func generateSSHKey(t *testing.T) (ssh.PublicKey, []byte) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBlock, err := ssh.MarshalPrivateKey(privateKey, "bot@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sshPublicKey, pem.EncodeToMemory(pemBlock)
+}
+
+func TestGetSigningMethod(t *testing.T) {
+	tests := []struct {
+		name        string
+		gitProvider string
+		config      map[string][]byte
+		want        string
+	}{
+		{
+			name:        "should not sign commits by default",
+			gitProvider: "github",
+			config:      map[string][]byte{"github.token": []byte("ghp_token")},
+			want:        "",
+		},
+		{
+			name:        "should sign commits with GPG key",
+			gitProvider: "gitlab",
+			config:      map[string][]byte{GPGKeySecretKey: []byte("key")},
+			want:        MethodGPG,
+		},
+		{
+			name:        "should sign commits with SSH key",
+			gitProvider: "gitlab",
+			config:      map[string][]byte{SSHKeySecretKey: []byte("key")},
+			want:        MethodSSH,
+		},
+		{
+			name:        "should prefer signing key over GitHub application signing",
+			gitProvider: "github",
+			config:      map[string][]byte{"github-application-id": []byte("1"), "github-private-key": []byte("key"), SSHKeySecretKey: []byte("key"), GitHubAppSecretKey: []byte("true")},
+			want:        MethodSSH,
+		},
+		{
+			name:        "should sign commits by GitHub application",
+			gitProvider: "github",
+			config:      map[string][]byte{"github-application-id": []byte("1"), "github-private-key": []byte("key"), GitHubAppSecretKey: []byte("true")},
+			want:        MethodGitHubApp,
+		},
+		{
+			name:        "should ignore GitHub application signing without GitHub application",
+			gitProvider: "github",
+			config:      map[string][]byte{"github.token": []byte("ghp_token"), GitHubAppSecretKey: []byte("true")},
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetSigningMethod(tt.gitProvider, tt.config); got != tt.want {
+				t.Errorf("GetSigningMethod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGPGSigner(t *testing.T) {
+	entity, armoredKey := generateArmoredGPGKey(t)
+
+	signer, err := NewSignerFromSecret(map[string][]byte{GPGKeySecretKey: armoredKey})
+	if err != nil {
+		t.Fatalf("NewSignerFromSecret() unexpected error: %v", err)
+	}
+	if signer.Method() != MethodGPG {
+		t.Errorf("Method() = %s, want %s", signer.Method(), MethodGPG)
+	}
+
+	payload := []byte("tree abc\n\nmessage")
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader(payload), strings.NewReader(signature), nil); err != nil {
+		t.Errorf("Sign() produced invalid signature: %v", err)
+	}
+
+	if _, err := NewGPGSigner([]byte("not a key"), nil); err == nil {
+		t.Errorf("NewGPGSigner() expected error for invalid key")
+	}
+}
+
+func TestSSHSigner(t *testing.T) {
+	publicKey, privateKey := generateSSHKey(t)
+
+	signer, err := NewSignerFromSecret(map[string][]byte{SSHKeySecretKey: privateKey})
+	if err != nil {
+		t.Fatalf("NewSignerFromSecret() unexpected error: %v", err)
+	}
+	if signer.Method() != MethodSSH {
+		t.Errorf("Method() = %s, want %s", signer.Method(), MethodSSH)
+	}
+
+	payload := []byte("tree abc\n\nmessage")
+	armoredSignature, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+
+	block, _ := pem.Decode([]byte(armoredSignature))
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		t.Fatalf("Sign() produced malformed signature: %s", armoredSignature)
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		t.Fatalf("Sign() signature doesn't start with magic preamble")
+	}
+	var blob struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &blob); err != nil {
+		t.Fatalf("failed to parse signature: %v", err)
+	}
+	if blob.Version != sshSignatureVersion || blob.Namespace != "git" || blob.HashAlgorithm != "sha512" {
+		t.Errorf("Sign() unexpected signature header: %+v", blob)
+	}
+	if !bytes.Equal(blob.PublicKey, publicKey.Marshal()) {
+		t.Errorf("Sign() signature has wrong public key")
+	}
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(blob.Signature, signature); err != nil {
+		t.Fatalf("failed to parse signature: %v", err)
+	}
+
+	hash := sha512.Sum512(payload)
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{"git", "", "sha512", hash[:]})...)
+	if err := publicKey.Verify(signedData, signature); err != nil {
+		t.Errorf("Sign() produced invalid signature: %v", err)
+	}
+}
+
+func TestCommitPayload(t *testing.T) {
+	when := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	identity := CommitIdentity{Name: "Bot", Email: "bot@example.com", When: when}
+
+	got := string(CommitPayload("1111", []string{"2222"}, identity, identity, "Add pipelines"))
+	want := "tree 1111\n" +
+		"parent 2222\n" +
+		"author Bot <bot@example.com> 1672671845 +0000\n" +
+		"committer Bot <bot@example.com> 1672671845 +0000\n" +
+		"\n" +
+		"Add pipelines"
+	if got != want {
+		t.Errorf("CommitPayload() = %q, want %q", got, want)
+	}
+}
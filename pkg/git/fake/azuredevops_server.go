@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const azureDevOpsBranchRefPrefix = "refs/heads/"
+
+// azureDevOpsServer serves the read only subset of Azure DevOps REST API used by the build service Azure DevOps client.
+type azureDevOpsServer struct {
+	provider *GitProvider
+}
+
+// NewAzureDevOpsHandler returns HTTP handler which serves Azure DevOps REST API backed by the given fake git provider.
+// The Azure DevOps client should be created with the server URL as the API base URL.
+// Project and repository of the API paths are matched to owner and name of the fake repositories,
+// the organization (collection) is ignored. Only repository read operations are supported.
+func NewAzureDevOpsHandler(provider *GitProvider) http.Handler {
+	return &azureDevOpsServer{provider: provider}
+}
+
+func (s *azureDevOpsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.provider.mutex.Lock()
+	defer s.provider.mutex.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, escapedPart := range parts {
+		part, err := url.PathUnescape(escapedPart)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		parts[i] = part
+	}
+
+	// /collection/project/_apis/git/repositories/repository/rest
+	apisIndex := -1
+	for i, part := range parts {
+		if part == "_apis" {
+			apisIndex = i
+			break
+		}
+	}
+	if apisIndex < 1 || len(parts) < apisIndex+4 || parts[apisIndex+1] != "git" || parts[apisIndex+2] != "repositories" {
+		writeUnsupported(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[apisIndex-1] + "/" + parts[apisIndex+3])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "TF401019: The Git repository does not exist or you do not have permissions for the operation you are attempting.")
+		return
+	}
+
+	switch rest := parts[apisIndex+4:]; {
+	case len(rest) == 0:
+		s.getRepository(w, repo)
+	case len(rest) == 1 && rest[0] == "refs":
+		s.listRefs(w, r, repo)
+	case len(rest) == 1 && rest[0] == "items":
+		s.getItems(w, r, repo)
+	default:
+		writeUnsupported(w, r)
+	}
+}
+
+func (s *azureDevOpsServer) getRepository(w http.ResponseWriter, repo *repository) {
+	repositoryPath := getRepositoryPath(repo.url)
+	project, name, _ := strings.Cut(repositoryPath, "/")
+	visibility := "private"
+	if repo.public {
+		visibility = "public"
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"id":            repositoryPath,
+		"name":          name,
+		"defaultBranch": azureDevOpsBranchRefPrefix + repo.defaultBranch,
+		"webUrl":        repo.url,
+		"project":       map[string]interface{}{"id": project, "name": project, "visibility": visibility},
+	})
+}
+
+// listRefs returns branches which match the filter by prefix, as Azure DevOps does.
+func (s *azureDevOpsServer) listRefs(w http.ResponseWriter, r *http.Request, repo *repository) {
+	filter := r.URL.Query().Get("filter")
+	refs := []map[string]interface{}{}
+	for branchName, c := range repo.branches {
+		if strings.HasPrefix("heads/"+branchName, filter) {
+			refs = append(refs, map[string]interface{}{
+				"name":     azureDevOpsBranchRefPrefix + branchName,
+				"objectId": c.sha,
+			})
+		}
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{"value": refs, "count": len(refs)})
+}
+
+// getItems returns content of the file in path parameter or entries of the directory in scopePath parameter.
+func (s *azureDevOpsServer) getItems(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	var c *commit
+	switch versionType := query.Get("versionDescriptor.versionType"); versionType {
+	case "branch":
+		c = repo.branches[query.Get("versionDescriptor.version")]
+	case "commit":
+		c = repo.commits[query.Get("versionDescriptor.version")]
+	default:
+		writeUnsupported(w, r)
+		return
+	}
+	if c == nil {
+		writeError(w, http.StatusNotFound, "TF401175: The version descriptor could not be resolved to a version in the repository.")
+		return
+	}
+
+	if scopePath := query.Get("scopePath"); scopePath != "" {
+		if query.Get("recursionLevel") != "oneLevel" {
+			writeUnsupported(w, r)
+			return
+		}
+		directory := strings.Trim(scopePath, "/")
+		fileNames, directoryNames := listDirectory(c.files, directory)
+		if directory != "" && len(fileNames) == 0 && len(directoryNames) == 0 {
+			writeError(w, http.StatusNotFound, "TF401174: The item could not be found in the repository.")
+			return
+		}
+		// The scope directory itself is the first item
+		items := []map[string]interface{}{{"path": "/" + directory, "isFolder": true}}
+		for _, name := range directoryNames {
+			items = append(items, map[string]interface{}{"path": "/" + path.Join(directory, name), "isFolder": true})
+		}
+		for _, name := range fileNames {
+			items = append(items, map[string]interface{}{"path": "/" + path.Join(directory, name), "isFolder": false})
+		}
+		writeJson(w, http.StatusOK, map[string]interface{}{"value": items, "count": len(items)})
+		return
+	}
+
+	if query.Get("$format") != "octetStream" {
+		writeUnsupported(w, r)
+		return
+	}
+	content, exists := c.files[strings.TrimPrefix(query.Get("path"), "/")]
+	if !exists {
+		writeError(w, http.StatusNotFound, "TF401174: The item could not be found in the repository.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,515 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	bitbucketPullRequestWebUrlFormat = "%s/pull-requests/%d"
+)
+
+// bitbucketCommitFormFields are the form fields of commit creation request, which are not file paths.
+var bitbucketCommitFormFields = map[string]bool{"branch": true, "message": true, "author": true, "parents": true, "files": true}
+
+// bitbucketServer serves the subset of Bitbucket Cloud REST API 2.0 used by the build service Bitbucket client.
+type bitbucketServer struct {
+	provider *GitProvider
+}
+
+// NewBitbucketHandler returns HTTP handler which serves Bitbucket Cloud REST API 2.0 backed by the given fake git provider.
+// The Bitbucket client should use the server URL as API URL.
+// Any credentials are accepted, pull requests are opened by TokenOwner.
+// Push restrictions are reported for protected branches, branching model restrictions are not supported.
+func NewBitbucketHandler(provider *GitProvider) http.Handler {
+	return &bitbucketServer{provider: provider}
+}
+
+// writeBitbucketError writes error response in the format of Bitbucket API.
+func writeBitbucketError(w http.ResponseWriter, statusCode int, message string) {
+	writeJson(w, statusCode, map[string]interface{}{"type": "error", "error": map[string]interface{}{"message": message}})
+}
+
+// writeBitbucketPage writes the given values as the only page of a paginated response.
+func writeBitbucketPage(w http.ResponseWriter, values []map[string]interface{}) {
+	writeJson(w, http.StatusOK, map[string]interface{}{"values": values, "page": 1, "size": len(values)})
+}
+
+func (s *bitbucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.provider.mutex.Lock()
+	defer s.provider.mutex.Unlock()
+
+	// Branch names and file path elements are escaped path segments
+	escapedParts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	parts := make([]string, 0, len(escapedParts))
+	for _, escapedPart := range escapedParts {
+		part, err := url.PathUnescape(escapedPart)
+		if err != nil {
+			writeBitbucketError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		parts = append(parts, part)
+	}
+
+	// /repositories/workspace/repository/rest
+	if len(parts) < 3 || parts[0] != "repositories" {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[1] + "/" + parts[2])
+	if err != nil {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Repository %s/%s not found", parts[1], parts[2]))
+		return
+	}
+	rest := parts[3:]
+	resource := ""
+	if len(rest) > 0 {
+		resource = rest[0]
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		s.getRepository(w, repo)
+	case len(rest) == 3 && resource == "refs" && rest[1] == "branches" && r.Method == http.MethodGet:
+		s.getBranch(w, repo, rest[2])
+	case len(rest) == 2 && resource == "refs" && rest[1] == "branches" && r.Method == http.MethodPost:
+		s.createBranch(w, r, repo)
+	case len(rest) == 3 && resource == "refs" && rest[1] == "branches" && r.Method == http.MethodDelete:
+		s.deleteBranch(w, repo, rest[2])
+	case len(rest) > 2 && resource == "src" && r.Method == http.MethodGet:
+		s.getSource(w, repo, rest[1], rest[2:])
+	case len(rest) == 1 && resource == "src" && r.Method == http.MethodPost:
+		s.createCommit(w, r, repo)
+	case len(rest) == 2 && resource == "commits" && r.Method == http.MethodGet:
+		s.listCommits(w, r, repo, rest[1])
+	case len(rest) == 1 && resource == "pullrequests" && r.Method == http.MethodGet:
+		s.listPullRequests(w, r, repo)
+	case len(rest) == 1 && resource == "pullrequests" && r.Method == http.MethodPost:
+		s.createPullRequest(w, r, repo)
+	case len(rest) == 2 && resource == "pullrequests" && r.Method == http.MethodGet:
+		s.getPullRequest(w, repo, rest[1])
+	case len(rest) == 3 && resource == "pullrequests" && rest[2] == "decline" && r.Method == http.MethodPost:
+		s.declinePullRequest(w, repo, rest[1])
+	case len(rest) == 1 && resource == "branch-restrictions" && r.Method == http.MethodGet:
+		s.listBranchRestrictions(w, r, repo)
+	case len(rest) == 1 && resource == "hooks" && r.Method == http.MethodGet:
+		s.listHooks(w, repo)
+	case len(rest) == 1 && resource == "hooks" && r.Method == http.MethodPost:
+		s.saveHook(w, r, repo, nil)
+	case len(rest) == 2 && resource == "hooks" && r.Method == http.MethodPut:
+		webhook := getWebhookByIdParam(repo, strings.Trim(rest[1], "{}"))
+		if webhook == nil {
+			writeBitbucketError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		s.saveHook(w, r, repo, webhook)
+	case len(rest) == 2 && resource == "hooks" && r.Method == http.MethodDelete:
+		webhook := getWebhookByIdParam(repo, strings.Trim(rest[1], "{}"))
+		if webhook == nil {
+			writeBitbucketError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		repo.deleteWebhook(webhook.Id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeUnsupported(w, r)
+	}
+}
+
+func (s *bitbucketServer) getRepository(w http.ResponseWriter, repo *repository) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"full_name":  getRepositoryPath(repo.url),
+		"is_private": !repo.public,
+		"mainbranch": map[string]interface{}{"type": "branch", "name": repo.defaultBranch},
+		"links":      map[string]interface{}{"html": map[string]interface{}{"href": repo.url}},
+	})
+}
+
+func bitbucketBranch(repo *repository, branchName string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "branch",
+		"name":   branchName,
+		"target": map[string]interface{}{"type": "commit", "hash": repo.branches[branchName].sha},
+	}
+}
+
+func (s *bitbucketServer) getBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Branch %q not found", branchName))
+		return
+	}
+	writeJson(w, http.StatusOK, bitbucketBranch(repo, branchName))
+}
+
+func (s *bitbucketServer) createBranch(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Name   string `json:"name"`
+		Target struct {
+			Hash string `json:"hash"`
+		} `json:"target"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, exists := repo.branches[request.Name]; exists {
+		writeBitbucketError(w, http.StatusBadRequest, fmt.Sprintf("Branch %q already exists", request.Name))
+		return
+	}
+	c, exists := repo.commits[request.Target.Hash]
+	if !exists {
+		writeBitbucketError(w, http.StatusBadRequest, fmt.Sprintf("Commit %q not found", request.Target.Hash))
+		return
+	}
+	repo.branches[request.Name] = c
+	writeJson(w, http.StatusCreated, bitbucketBranch(repo, request.Name))
+}
+
+func (s *bitbucketServer) deleteBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Branch %q not found", branchName))
+		return
+	}
+	delete(repo.branches, branchName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSource returns raw content of the file at the revision.
+// The directory is listed instead if the path has trailing slash.
+func (s *bitbucketServer) getSource(w http.ResponseWriter, repo *repository, revision string, pathElements []string) {
+	c, err := repo.resolveRevision(revision)
+	if err != nil {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Commit %q not found", revision))
+		return
+	}
+	filePath := strings.Join(pathElements, "/")
+	if !strings.HasSuffix(filePath, "/") {
+		content, exists := c.files[filePath]
+		if !exists {
+			writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("No such file or directory: %s", filePath))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content)
+		return
+	}
+
+	directory := strings.Trim(filePath, "/")
+	if directory == "." {
+		directory = ""
+	}
+	fileNames, directoryNames := listDirectory(c.files, directory)
+	if len(fileNames) == 0 && len(directoryNames) == 0 {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("No such file or directory: %s", directory))
+		return
+	}
+	entries := []map[string]interface{}{}
+	for _, name := range directoryNames {
+		entries = append(entries, map[string]interface{}{"type": "commit_directory", "path": path.Join(directory, name)})
+	}
+	for _, name := range fileNames {
+		entries = append(entries, map[string]interface{}{"type": "commit_file", "path": path.Join(directory, name)})
+	}
+	writeBitbucketPage(w, entries)
+}
+
+// createCommit supports creating, updating and deleting files on top of the branch.
+// A missing branch is created from the first given parent or from the default branch.
+func (s *bitbucketServer) createCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	if err := r.ParseForm(); err != nil {
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	form := r.PostForm
+	branchName := form.Get("branch")
+	if branchName == "" {
+		branchName = repo.defaultBranch
+	}
+	parent, exists := repo.branches[branchName]
+	if !exists {
+		var err error
+		if parent, err = repo.resolveRevision(form.Get("parents")); err != nil {
+			writeBitbucketError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	files := copyFiles(parent.files)
+	for filePath, values := range form {
+		if !bitbucketCommitFormFields[filePath] {
+			files[filePath] = []byte(values[0])
+		}
+	}
+	for _, filePath := range form["files"] {
+		if _, exists := files[filePath]; !exists {
+			writeBitbucketError(w, http.StatusBadRequest, fmt.Sprintf("No such file: %s", filePath))
+			return
+		}
+		delete(files, filePath)
+	}
+
+	authorName, authorEmail := TokenOwner, ""
+	if author := form.Get("author"); author != "" {
+		name, email, _ := strings.Cut(author, " <")
+		authorName, authorEmail = name, strings.TrimSuffix(email, ">")
+	}
+	c := s.provider.newCommit(repo, []*commit{parent}, files, form.Get("message"), authorName, authorEmail)
+	if err := repo.updateBranch(branchName, c, false); err != nil {
+		if isPushRejected(err) {
+			writeBitbucketError(w, http.StatusForbidden, fmt.Sprintf("Branch %q is restricted", branchName))
+			return
+		}
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/repositories/%s/commit/%s", getRepositoryPath(repo.url), c.sha))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// listCommits returns commits of the revision, which are not reachable from the excluded revision.
+func (s *bitbucketServer) listCommits(w http.ResponseWriter, r *http.Request, repo *repository, revision string) {
+	c, err := repo.resolveRevision(revision)
+	if err != nil {
+		writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Commit %q not found", revision))
+		return
+	}
+	excludedHistory := map[string]bool{}
+	if excluded := r.URL.Query().Get("exclude"); excluded != "" {
+		excludedCommit, err := repo.resolveRevision(excluded)
+		if err != nil {
+			writeBitbucketError(w, http.StatusNotFound, fmt.Sprintf("Commit %q not found", excluded))
+			return
+		}
+		excludedHistory = getHistory(excludedCommit)
+	}
+	commits := []map[string]interface{}{}
+	for sha := range getHistory(c) {
+		if !excludedHistory[sha] {
+			commits = append(commits, map[string]interface{}{"type": "commit", "hash": sha, "message": repo.commits[sha].message})
+		}
+	}
+	writeBitbucketPage(w, commits)
+}
+
+func bitbucketPullRequest(mr *MergeRequest) map[string]interface{} {
+	state := "OPEN"
+	switch mr.State {
+	case gp.MergeRequestStateMerged:
+		state = "MERGED"
+	case gp.MergeRequestStateClosed:
+		state = "DECLINED"
+	}
+	updatedOn := &mr.CreatedAt
+	if mr.MergedAt != nil {
+		updatedOn = mr.MergedAt
+	}
+	return map[string]interface{}{
+		"id":          mr.Number,
+		"title":       mr.Title,
+		"description": mr.Text,
+		"state":       state,
+		"created_on":  formatTime(&mr.CreatedAt),
+		"updated_on":  formatTime(updatedOn),
+		"links":       map[string]interface{}{"html": map[string]interface{}{"href": mr.WebUrl}},
+		"source":      map[string]interface{}{"branch": map[string]interface{}{"name": mr.SourceBranch}},
+		"destination": map[string]interface{}{"branch": map[string]interface{}{"name": mr.TargetBranch}},
+		"author":      map[string]interface{}{"nickname": mr.Author},
+	}
+}
+
+// listPullRequests supports filtering by source and destination branches and state joined with AND operator.
+// Only open pull requests are listed if the state is not queried, as Bitbucket does.
+func (s *bitbucketServer) listPullRequests(w http.ResponseWriter, r *http.Request, repo *repository) {
+	filter := map[string]string{"state": "OPEN"}
+	if query := r.URL.Query().Get("q"); query != "" {
+		for _, condition := range strings.Split(query, " AND ") {
+			field, value, found := strings.Cut(condition, "=")
+			if !found {
+				writeBitbucketError(w, http.StatusBadRequest, fmt.Sprintf("Invalid query: %s", query))
+				return
+			}
+			field = strings.TrimSpace(field)
+			if field != "source.branch.name" && field != "destination.branch.name" && field != "state" {
+				writeBitbucketError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported query field: %s", field))
+				return
+			}
+			filter[field] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	pullRequests := []map[string]interface{}{}
+	for _, mr := range repo.mergeRequests {
+		pullRequest := bitbucketPullRequest(mr)
+		if pullRequest["state"] != filter["state"] {
+			continue
+		}
+		if sourceBranch, ok := filter["source.branch.name"]; ok && sourceBranch != mr.SourceBranch {
+			continue
+		}
+		if destinationBranch, ok := filter["destination.branch.name"]; ok && destinationBranch != mr.TargetBranch {
+			continue
+		}
+		pullRequests = append(pullRequests, pullRequest)
+	}
+	writeBitbucketPage(w, pullRequests)
+}
+
+func (s *bitbucketServer) createPullRequest(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Source      struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Repository *struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"destination"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Source.Repository != nil {
+		writeBitbucketError(w, http.StatusBadRequest, "pull requests from other repositories are not supported")
+		return
+	}
+	targetBranch := request.Destination.Branch.Name
+	if targetBranch == "" {
+		targetBranch = repo.defaultBranch
+	}
+	mr, err := repo.createMergeRequest(request.Source.Branch.Name, targetBranch, request.Title, request.Description, TokenOwner, bitbucketPullRequestWebUrlFormat)
+	if err != nil {
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, bitbucketPullRequest(mr))
+}
+
+func (s *bitbucketServer) getPullRequest(w http.ResponseWriter, repo *repository, idParam string) {
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		writeBitbucketError(w, http.StatusNotFound, "Pull request not found")
+		return
+	}
+	mr := repo.getMergeRequest(id)
+	if mr == nil {
+		writeBitbucketError(w, http.StatusNotFound, "Pull request not found")
+		return
+	}
+	writeJson(w, http.StatusOK, bitbucketPullRequest(mr))
+}
+
+func (s *bitbucketServer) declinePullRequest(w http.ResponseWriter, repo *repository, idParam string) {
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		writeBitbucketError(w, http.StatusNotFound, "Pull request not found")
+		return
+	}
+	mr := repo.getMergeRequest(id)
+	if mr == nil {
+		writeBitbucketError(w, http.StatusNotFound, "Pull request not found")
+		return
+	}
+	if mr.State != gp.MergeRequestStateOpen {
+		writeBitbucketError(w, http.StatusBadRequest, "Only open pull requests can be declined")
+		return
+	}
+	mr.State = gp.MergeRequestStateClosed
+	writeJson(w, http.StatusOK, bitbucketPullRequest(mr))
+}
+
+// listBranchRestrictions returns push restriction of each protected branch.
+func (s *bitbucketServer) listBranchRestrictions(w http.ResponseWriter, r *http.Request, repo *repository) {
+	restrictions := []map[string]interface{}{}
+	if kind := r.URL.Query().Get("kind"); kind != "" && kind != "push" {
+		writeBitbucketPage(w, restrictions)
+		return
+	}
+	protectedBranches := []string{}
+	for branchName, protected := range repo.protectedBranches {
+		if protected {
+			protectedBranches = append(protectedBranches, branchName)
+		}
+	}
+	sort.Strings(protectedBranches)
+	for i, branchName := range protectedBranches {
+		restrictions = append(restrictions, map[string]interface{}{
+			"id":                i + 1,
+			"kind":              "push",
+			"branch_match_kind": "glob",
+			"pattern":           branchName,
+		})
+	}
+	writeBitbucketPage(w, restrictions)
+}
+
+func bitbucketHook(webhook *Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"uuid":                   fmt.Sprintf("{%d}", webhook.Id),
+		"url":                    webhook.Url,
+		"active":                 true,
+		"secret_set":             webhook.Secret != "",
+		"skip_cert_verification": webhook.InsecureSSL,
+		"events":                 []string{"repo:push", "pullrequest:created", "pullrequest:updated", "pullrequest:comment_created"},
+	}
+}
+
+func (s *bitbucketServer) listHooks(w http.ResponseWriter, repo *repository) {
+	hooks := []map[string]interface{}{}
+	for _, webhook := range repo.webhooks {
+		hooks = append(hooks, bitbucketHook(webhook))
+	}
+	writeBitbucketPage(w, hooks)
+}
+
+// saveHook creates new webhook if the given one is nil, updates the given webhook otherwise.
+func (s *bitbucketServer) saveHook(w http.ResponseWriter, r *http.Request, repo *repository, webhook *Webhook) {
+	var request struct {
+		Url                  string `json:"url"`
+		Secret               string `json:"secret"`
+		SkipCertVerification bool   `json:"skip_cert_verification"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeBitbucketError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	statusCode := http.StatusOK
+	if webhook == nil {
+		webhook = repo.addWebhook(Webhook{})
+		statusCode = http.StatusCreated
+	}
+	webhook.Url = request.Url
+	webhook.Secret = request.Secret
+	webhook.InsecureSSL = request.SkipCertVerification
+	writeJson(w, statusCode, bitbucketHook(webhook))
+}
@@ -0,0 +1,579 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides stateful in-memory git provider for tests.
+// GitProvider implements git provider client interface itself and could also be served
+// via HTTP stand-ins of GitHub, GitLab, Bitbucket, Gitea and Azure DevOps APIs, so the real clients could be tested against the same state.
+package fake
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// TokenOwner is the account the HTTP stand-ins authenticate any token as.
+const TokenOwner = "build-service-bot"
+
+// GitProvider keeps repositories with their branches, commits, merge requests and webhooks in memory.
+// It's safe for concurrent use.
+type GitProvider struct {
+	mutex sync.Mutex
+
+	// repositories are indexed by owner/repository path
+	repositories map[string]*repository
+	// commitsCount makes SHAs of commits with the same content unique
+	commitsCount int
+
+	appName string
+	appId   string
+}
+
+type repository struct {
+	url           string
+	defaultBranch string
+	public        bool
+
+	commits           map[string]*commit
+	branches          map[string]*commit
+	protectedBranches map[string]bool
+
+	mergeRequests []*MergeRequest
+	webhooks      []*Webhook
+	nextWebhookId int64
+}
+
+type commit struct {
+	sha         string
+	parents     []*commit
+	files       map[string][]byte
+	message     string
+	authorName  string
+	authorEmail string
+}
+
+// Commit is a snapshot of a commit in a fake repository.
+type Commit struct {
+	Sha         string
+	ParentShas  []string
+	Files       map[string][]byte
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+}
+
+// MergeRequest is a merge request in a fake repository.
+type MergeRequest struct {
+	// Number is the merge request number within the repository
+	Number       int
+	WebUrl       string
+	Title        string
+	Text         string
+	SourceBranch string
+	TargetBranch string
+	Author       string
+	// State is one of: open, merged, closed.
+	State     string
+	CreatedAt time.Time
+	MergedAt  *time.Time
+}
+
+// Webhook is a webhook in a fake repository.
+type Webhook struct {
+	Id     int64
+	Url    string
+	Secret string
+	// InsecureSSL disables verification of the target TLS certificate
+	InsecureSSL bool
+}
+
+var _ gp.GitProviderClient = (*GitProvider)(nil)
+
+// NewGitProvider returns fake git provider without repositories.
+func NewGitProvider() *GitProvider {
+	return &GitProvider{repositories: map[string]*repository{}}
+}
+
+// getRepositoryPath returns owner/repository part of the given repository URL.
+// Owner/repository path is accepted as well, so HTTP stand-ins could use API paths.
+func getRepositoryPath(repoUrl string) string {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(repoUrl, "/"), ".git"), "/")
+	if len(parts) < 2 {
+		return repoUrl
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// AddRepository creates repository with single commit in the default branch, which contains the given files.
+// An existing repository with the same owner and name is replaced.
+func (p *GitProvider) AddRepository(repoUrl, defaultBranch string, files []gp.RepositoryFile) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo := &repository{
+		url:               strings.TrimSuffix(repoUrl, ".git"),
+		defaultBranch:     defaultBranch,
+		public:            true,
+		commits:           map[string]*commit{},
+		branches:          map[string]*commit{},
+		protectedBranches: map[string]bool{},
+		nextWebhookId:     1,
+	}
+	initialCommit := p.newCommit(repo, nil, applyFiles(nil, files, false), "Initial commit", TokenOwner, "")
+	repo.branches[defaultBranch] = initialCommit
+	p.repositories[getRepositoryPath(repoUrl)] = repo
+}
+
+// SetRepositoryPublic changes visibility of the repository.
+func (p *GitProvider) SetRepositoryPublic(repoUrl string, public bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return err
+	}
+	repo.public = public
+	return nil
+}
+
+// SetBranchProtected changes whether pushes into the branch are rejected.
+func (p *GitProvider) SetBranchProtected(repoUrl, branchName string, protected bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return err
+	}
+	repo.protectedBranches[branchName] = protected
+	return nil
+}
+
+// SetConfiguredGitApp sets the git application returned by GetConfiguredGitAppName.
+func (p *GitProvider) SetConfiguredGitApp(appName, appId string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.appName = appName
+	p.appId = appId
+}
+
+// PushFiles adds commit with the given files on top of the given branch, as a user would do.
+// The branch is created from the default branch if it doesn't exist. Branch protection is ignored.
+// Returns SHA of the new commit.
+func (p *GitProvider) PushFiles(repoUrl, branchName string, files []gp.RepositoryFile) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	parent, ok := repo.branches[branchName]
+	if !ok {
+		parent = repo.branches[repo.defaultBranch]
+	}
+	newCommit := p.newCommit(repo, []*commit{parent}, applyFiles(parent.files, files, false), "Update files", TokenOwner, "")
+	repo.branches[branchName] = newCommit
+	return newCommit.sha, nil
+}
+
+// GetCommit returns the commit at the given revision, which is a branch name, refs/heads/ reference or commit SHA.
+func (p *GitProvider) GetCommit(repoUrl, revision string) (*Commit, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	c, err := repo.resolveRevision(revision)
+	if err != nil {
+		return nil, err
+	}
+	return c.snapshot(), nil
+}
+
+// GetMergeRequests returns copies of all merge requests of the repository.
+func (p *GitProvider) GetMergeRequests(repoUrl string) ([]MergeRequest, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	mergeRequests := make([]MergeRequest, 0, len(repo.mergeRequests))
+	for _, mr := range repo.mergeRequests {
+		mergeRequests = append(mergeRequests, *mr)
+	}
+	return mergeRequests, nil
+}
+
+// MergeMergeRequest merges the open merge request with merge commit, as a user would do.
+// The source branch is kept.
+func (p *GitProvider) MergeMergeRequest(repoUrl string, number int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, mr, err := p.getOpenMergeRequest(repoUrl, number)
+	if err != nil {
+		return err
+	}
+	target := repo.branches[mr.TargetBranch]
+	source, ok := repo.branches[mr.SourceBranch]
+	if !ok || target == nil {
+		return fmt.Errorf("branches of merge request %d don't exist", number)
+	}
+
+	// Changes of the source branch win, there is no conflicts detection
+	files := copyFiles(target.files)
+	base := findMergeBase(source, target)
+	for path, content := range source.files {
+		if base == nil || !bytes.Equal(base.files[path], content) {
+			files[path] = content
+		}
+	}
+	if base != nil {
+		for path := range base.files {
+			if _, exists := source.files[path]; !exists {
+				delete(files, path)
+			}
+		}
+	}
+	message := fmt.Sprintf("Merge branch '%s' into '%s'", mr.SourceBranch, mr.TargetBranch)
+	repo.branches[mr.TargetBranch] = p.newCommit(repo, []*commit{target, source}, files, message, TokenOwner, "")
+
+	mergedAt := time.Now()
+	mr.State = gp.MergeRequestStateMerged
+	mr.MergedAt = &mergedAt
+	return nil
+}
+
+// CloseMergeRequest closes the open merge request without merge.
+func (p *GitProvider) CloseMergeRequest(repoUrl string, number int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	_, mr, err := p.getOpenMergeRequest(repoUrl, number)
+	if err != nil {
+		return err
+	}
+	mr.State = gp.MergeRequestStateClosed
+	return nil
+}
+
+// GetWebhooks returns copies of all webhooks of the repository.
+func (p *GitProvider) GetWebhooks(repoUrl string) ([]Webhook, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]Webhook, 0, len(repo.webhooks))
+	for _, webhook := range repo.webhooks {
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, nil
+}
+
+// getRepository returns the repository by its URL or owner/repository path.
+// Must be called with the mutex locked.
+func (p *GitProvider) getRepository(repoUrl string) (*repository, error) {
+	repo, ok := p.repositories[getRepositoryPath(repoUrl)]
+	if !ok {
+		return nil, fmt.Errorf("repository %s not found", repoUrl)
+	}
+	return repo, nil
+}
+
+// getOpenMergeRequest returns the repository and its open merge request with the given number.
+// Must be called with the mutex locked.
+func (p *GitProvider) getOpenMergeRequest(repoUrl string, number int) (*repository, *MergeRequest, error) {
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	mr := repo.getMergeRequest(number)
+	if mr == nil {
+		return nil, nil, fmt.Errorf("merge request %d not found in %s", number, repoUrl)
+	}
+	if mr.State != gp.MergeRequestStateOpen {
+		return nil, nil, fmt.Errorf("merge request %d in %s is %s", number, repoUrl, mr.State)
+	}
+	return repo, mr, nil
+}
+
+// newCommit creates commit object in the repository, which doesn't belong to any branch yet.
+// Must be called with the mutex locked.
+func (p *GitProvider) newCommit(repo *repository, parents []*commit, files map[string][]byte, message, authorName, authorEmail string) *commit {
+	p.commitsCount++
+
+	hash := sha1.New()
+	fmt.Fprintf(hash, "%d\n%s\n%s <%s>\n", p.commitsCount, message, authorName, authorEmail)
+	for _, parent := range parents {
+		fmt.Fprintf(hash, "parent %s\n", parent.sha)
+	}
+	for _, path := range sortedPaths(files) {
+		fmt.Fprintf(hash, "%s\n%s\n", path, files[path])
+	}
+
+	c := &commit{
+		sha:         hex.EncodeToString(hash.Sum(nil)),
+		parents:     parents,
+		files:       files,
+		message:     message,
+		authorName:  authorName,
+		authorEmail: authorEmail,
+	}
+	repo.commits[c.sha] = c
+	return c
+}
+
+// updateBranch points the branch to the given commit, the branch is created if it doesn't exist.
+// Unless forced, only fast-forward updates are allowed.
+func (repo *repository) updateBranch(branchName string, c *commit, force bool) error {
+	if repo.protectedBranches[branchName] {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, fmt.Errorf("branch %s is protected", branchName))
+	}
+	if head, exists := repo.branches[branchName]; exists && !force && !isAncestor(head, c) {
+		return fmt.Errorf("update of branch %s is not a fast forward", branchName)
+	}
+	repo.branches[branchName] = c
+	return nil
+}
+
+// resolveRevision returns the commit at the given branch name, refs/heads/ reference or commit SHA.
+// Default branch is used if the revision is empty.
+func (repo *repository) resolveRevision(revision string) (*commit, error) {
+	if revision == "" {
+		revision = repo.defaultBranch
+	}
+	if c, ok := repo.branches[strings.TrimPrefix(revision, "refs/heads/")]; ok {
+		return c, nil
+	}
+	if c, ok := repo.commits[revision]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("revision %s not found in %s", revision, repo.url)
+}
+
+// getBranch returns head commit of the given branch, default branch is used if the name is empty.
+func (repo *repository) getBranch(branchName string) (*commit, error) {
+	if branchName == "" {
+		branchName = repo.defaultBranch
+	}
+	c, ok := repo.branches[branchName]
+	if !ok {
+		return nil, fmt.Errorf("branch %s not found in %s", branchName, repo.url)
+	}
+	return c, nil
+}
+
+func (repo *repository) getMergeRequest(number int) *MergeRequest {
+	if number < 1 || number > len(repo.mergeRequests) {
+		return nil
+	}
+	return repo.mergeRequests[number-1]
+}
+
+// findOpenMergeRequest returns open merge request between the given branches.
+// Merge requests into any branch are considered if the target branch is empty.
+func (repo *repository) findOpenMergeRequest(sourceBranch, targetBranch string) *MergeRequest {
+	for _, mr := range repo.mergeRequests {
+		if mr.State == gp.MergeRequestStateOpen && mr.SourceBranch == sourceBranch && (targetBranch == "" || mr.TargetBranch == targetBranch) {
+			return mr
+		}
+	}
+	return nil
+}
+
+// createMergeRequest opens merge request with the given web URL format, which gets the repository URL and the merge request number.
+// Similar to git providers, the merge request is refused if the source branch has no commits the target branch doesn't have
+// or there is an open merge request between the same branches.
+func (repo *repository) createMergeRequest(sourceBranch, targetBranch, title, text, author, webUrlFormat string) (*MergeRequest, error) {
+	source, sourceExists := repo.branches[sourceBranch]
+	target, targetExists := repo.branches[targetBranch]
+	if !sourceExists || !targetExists {
+		return nil, fmt.Errorf("branch %s or %s not found in %s", sourceBranch, targetBranch, repo.url)
+	}
+	if isAncestor(source, target) {
+		return nil, fmt.Errorf("No commits between %s and %s", targetBranch, sourceBranch)
+	}
+	if repo.findOpenMergeRequest(sourceBranch, targetBranch) != nil {
+		return nil, fmt.Errorf("merge request from %s into %s already exists", sourceBranch, targetBranch)
+	}
+
+	number := len(repo.mergeRequests) + 1
+	mr := &MergeRequest{
+		Number:       number,
+		WebUrl:       fmt.Sprintf(webUrlFormat, repo.url, number),
+		Title:        title,
+		Text:         text,
+		SourceBranch: sourceBranch,
+		TargetBranch: targetBranch,
+		Author:       author,
+		State:        gp.MergeRequestStateOpen,
+		CreatedAt:    time.Now(),
+	}
+	repo.mergeRequests = append(repo.mergeRequests, mr)
+	return mr, nil
+}
+
+// countBehind returns number of commits in the base branch which are not in the given branch.
+func (repo *repository) countBehind(branchName, baseBranchName string) (int, error) {
+	branch, err := repo.getBranch(branchName)
+	if err != nil {
+		return 0, err
+	}
+	base, err := repo.getBranch(baseBranchName)
+	if err != nil {
+		return 0, err
+	}
+	branchHistory := getHistory(branch)
+	behind := 0
+	for sha := range getHistory(base) {
+		if !branchHistory[sha] {
+			behind++
+		}
+	}
+	return behind, nil
+}
+
+// findWebhook returns webhook with the given target URL or nil if there is no such webhook.
+func (repo *repository) findWebhook(webhookUrl string) *Webhook {
+	for _, webhook := range repo.webhooks {
+		if webhook.Url == webhookUrl {
+			return webhook
+		}
+	}
+	return nil
+}
+
+func (repo *repository) addWebhook(webhook Webhook) *Webhook {
+	webhook.Id = repo.nextWebhookId
+	repo.nextWebhookId++
+	repo.webhooks = append(repo.webhooks, &webhook)
+	return &webhook
+}
+
+// deleteWebhook returns false if there is no webhook with the given id.
+func (repo *repository) deleteWebhook(id int64) bool {
+	for i, webhook := range repo.webhooks {
+		if webhook.Id == id {
+			repo.webhooks = append(repo.webhooks[:i], repo.webhooks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *commit) snapshot() *Commit {
+	parentShas := make([]string, 0, len(c.parents))
+	for _, parent := range c.parents {
+		parentShas = append(parentShas, parent.sha)
+	}
+	return &Commit{
+		Sha:         c.sha,
+		ParentShas:  parentShas,
+		Files:       copyFiles(c.files),
+		Message:     c.message,
+		AuthorName:  c.authorName,
+		AuthorEmail: c.authorEmail,
+	}
+}
+
+// getHistory returns SHAs of the given commit and all its ancestors.
+func getHistory(c *commit) map[string]bool {
+	history := map[string]bool{}
+	queue := []*commit{c}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if history[current.sha] {
+			continue
+		}
+		history[current.sha] = true
+		queue = append(queue, current.parents...)
+	}
+	return history
+}
+
+// isAncestor returns true if the ancestor commit is in history of the given commit, including the commit itself.
+func isAncestor(ancestor, c *commit) bool {
+	return getHistory(c)[ancestor.sha]
+}
+
+// findMergeBase returns the latest common ancestor of the commits found by breadth-first search or nil if there is none.
+func findMergeBase(a, b *commit) *commit {
+	aHistory := getHistory(a)
+	queue := []*commit{b}
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if aHistory[current.sha] {
+			return current
+		}
+		if visited[current.sha] {
+			continue
+		}
+		visited[current.sha] = true
+		queue = append(queue, current.parents...)
+	}
+	return nil
+}
+
+// applyFiles returns copy of the files with the given files added, updated or deleted.
+func applyFiles(files map[string][]byte, changes []gp.RepositoryFile, deleteFiles bool) map[string][]byte {
+	result := copyFiles(files)
+	for _, change := range changes {
+		if deleteFiles {
+			delete(result, change.FullPath)
+		} else {
+			result[change.FullPath] = append([]byte(nil), change.Content...)
+		}
+	}
+	return result
+}
+
+func copyFiles(files map[string][]byte) map[string][]byte {
+	result := make(map[string][]byte, len(files))
+	for path, content := range files {
+		result[path] = content
+	}
+	return result
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
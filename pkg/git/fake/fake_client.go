@@ -0,0 +1,452 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// mergeRequestWebUrlFormat is used for merge requests created via the client interface
+const mergeRequestWebUrlFormat = "%s/pull/%d"
+
+// EnsurePaCMergeRequest creates or updates existing Pipelines as Code configuration proposal merge request
+func (p *GitProvider) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	if d.BaseBranchName == "" {
+		d.BaseBranchName = repo.defaultBranch
+	}
+	base, err := repo.getBranch(d.BaseBranchName)
+	if err != nil {
+		return "", err
+	}
+	if filesUpToDate(base, d.Files) {
+		// Nothing to do, the configuration is already in the base branch
+		return "", nil
+	}
+
+	branch, branchExists := repo.branches[d.BranchName]
+	if !branchExists {
+		branch = base
+	}
+	if !branchExists || !filesUpToDate(branch, d.Files) {
+		newCommit := p.newCommit(repo, []*commit{branch}, applyFiles(branch.files, d.Files, false), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+		if err := repo.updateBranch(d.BranchName, newCommit, false); err != nil {
+			return "", err
+		}
+	}
+
+	if mr := repo.findOpenMergeRequest(d.BranchName, d.BaseBranchName); mr != nil {
+		return mr.WebUrl, nil
+	}
+	if isAncestor(repo.branches[d.BranchName], base) {
+		// The branch was merged before, but not deleted. Start over from the current base branch.
+		newCommit := p.newCommit(repo, []*commit{base}, applyFiles(base.files, d.Files, false), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+		if err := repo.updateBranch(d.BranchName, newCommit, true); err != nil {
+			return "", err
+		}
+	}
+	mr, err := repo.createMergeRequest(d.BranchName, d.BaseBranchName, d.Title, d.Text, d.AuthorName, mergeRequestWebUrlFormat)
+	if err != nil {
+		return "", err
+	}
+	return mr.WebUrl, nil
+}
+
+// UndoPaCMergeRequest creates or updates existing Pipelines as Code configuration removal merge request
+func (p *GitProvider) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	if d.BaseBranchName == "" {
+		d.BaseBranchName = repo.defaultBranch
+	}
+	base, err := repo.getBranch(d.BaseBranchName)
+	if err != nil {
+		return "", err
+	}
+	files := existingFiles(base, d.Files)
+	if len(files) == 0 {
+		// Nothing to prune
+		return "", nil
+	}
+
+	// The old branch, if any, is replaced
+	newCommit := p.newCommit(repo, []*commit{base}, applyFiles(base.files, files, true), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+	if err := repo.updateBranch(d.BranchName, newCommit, true); err != nil {
+		return "", err
+	}
+
+	if mr := repo.findOpenMergeRequest(d.BranchName, d.BaseBranchName); mr != nil {
+		return mr.WebUrl, nil
+	}
+	mr, err := repo.createMergeRequest(d.BranchName, d.BaseBranchName, d.Title, d.Text, d.AuthorName, mergeRequestWebUrlFormat)
+	if err != nil {
+		return "", err
+	}
+	return mr.WebUrl, nil
+}
+
+// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal merge request
+func (p *GitProvider) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	mr := repo.findOpenMergeRequest(d.BranchName, d.BaseBranchName)
+	if mr == nil {
+		return nil, nil
+	}
+	createdAt := mr.CreatedAt
+	return &gp.MergeRequest{
+		Id:        int64(mr.Number),
+		CreatedAt: &createdAt,
+		WebUrl:    mr.WebUrl,
+		Title:     mr.Title,
+	}, nil
+}
+
+// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+// from the current top of the base branch, if the merge request branch is behind the base branch.
+func (p *GitProvider) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return p.refreshPaCMergeRequest(repoUrl, d, false)
+}
+
+// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+// from the current top of the base branch with the given files, even if the branch is up to date.
+func (p *GitProvider) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	webUrl, _, err = p.refreshPaCMergeRequest(repoUrl, d, true)
+	return webUrl, err
+}
+
+// refreshPaCMergeRequest recreates the branch of the open proposal merge request if it's behind the base branch or force is set.
+func (p *GitProvider) refreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData, force bool) (webUrl string, refreshed bool, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", false, err
+	}
+	if d.BaseBranchName == "" {
+		d.BaseBranchName = repo.defaultBranch
+	}
+	mr := repo.findOpenMergeRequest(d.BranchName, d.BaseBranchName)
+	if mr == nil {
+		return "", false, nil
+	}
+
+	if !force {
+		behind, err := repo.countBehind(d.BranchName, d.BaseBranchName)
+		if err != nil || behind == 0 {
+			return mr.WebUrl, false, err
+		}
+	}
+
+	base := repo.branches[d.BaseBranchName]
+	newCommit := p.newCommit(repo, []*commit{base}, applyFiles(base.files, d.Files, false), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+	if err := repo.updateBranch(d.BranchName, newCommit, true); err != nil {
+		return "", false, err
+	}
+	return mr.WebUrl, true, nil
+}
+
+// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch
+func (p *GitProvider) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	if d.BaseBranchName == "" {
+		d.BaseBranchName = repo.defaultBranch
+	}
+	base, err := repo.getBranch(d.BaseBranchName)
+	if err != nil || filesUpToDate(base, d.Files) {
+		return false, err
+	}
+
+	newCommit := p.newCommit(repo, []*commit{base}, applyFiles(base.files, d.Files, false), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+	if err := repo.updateBranch(d.BaseBranchName, newCommit, false); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch
+func (p *GitProvider) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	if d.BaseBranchName == "" {
+		d.BaseBranchName = repo.defaultBranch
+	}
+	base, err := repo.getBranch(d.BaseBranchName)
+	if err != nil {
+		return false, err
+	}
+	files := existingFiles(base, d.Files)
+	if len(files) == 0 {
+		return false, nil
+	}
+
+	newCommit := p.newCommit(repo, []*commit{base}, applyFiles(base.files, files, true), d.CommitMessage, d.AuthorName, d.AuthorEmail)
+	if err := repo.updateBranch(d.BaseBranchName, newCommit, false); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsBranchProtected returns true if direct pushes into the given branch are rejected
+func (p *GitProvider) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	if _, err := repo.getBranch(branchName); err != nil {
+		return false, err
+	}
+	return repo.protectedBranches[branchName], nil
+}
+
+// GetMergeRequestStatus returns current state of the merge request with the given web URL in the given repository
+func (p *GitProvider) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	number, err := gp.GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl)
+	if err != nil {
+		return nil, err
+	}
+	mr := repo.getMergeRequest(number)
+	if mr == nil {
+		return nil, fmt.Errorf("merge request %d not found in %s", number, repoUrl)
+	}
+	createdAt := mr.CreatedAt
+	status := &gp.MergeRequestStatus{
+		State:     mr.State,
+		CreatedAt: &createdAt,
+		MergedAt:  mr.MergedAt,
+	}
+	if mr.State == gp.MergeRequestStateOpen {
+		behind, err := repo.countBehind(mr.SourceBranch, mr.TargetBranch)
+		if err != nil {
+			return nil, err
+		}
+		status.IsBehind = behind > 0
+	}
+	return status, nil
+}
+
+// SetupPaCWebhook creates Pipelines as Code webhook in the given repository or updates its secret
+func (p *GitProvider) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return err
+	}
+	if webhook := repo.findWebhook(webhookUrl); webhook != nil {
+		webhook.Secret = webhookSecret
+		webhook.InsecureSSL = gp.IsInsecureSSL()
+		return nil
+	}
+	repo.addWebhook(Webhook{Url: webhookUrl, Secret: webhookSecret, InsecureSSL: gp.IsInsecureSSL()})
+	return nil
+}
+
+// DeletePaCWebhook deletes Pipelines as Code webhook in the given repository
+func (p *GitProvider) DeletePaCWebhook(repoUrl, webhookUrl string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return err
+	}
+	if webhook := repo.findWebhook(webhookUrl); webhook != nil {
+		repo.deleteWebhook(webhook.Id)
+	}
+	return nil
+}
+
+// GetDefaultBranch returns name of default branch in the given repository
+func (p *GitProvider) GetDefaultBranch(repoUrl string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	return repo.defaultBranch, nil
+}
+
+// DeleteBranch deletes given branch from repository.
+// Returns true if branch was deleted, false if the branch didn't exist.
+func (p *GitProvider) DeleteBranch(repoUrl, branchName string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	if _, exists := repo.branches[branchName]; !exists {
+		return false, nil
+	}
+	delete(repo.branches, branchName)
+	return true, nil
+}
+
+// GetBranchSha returns SHA of top commit in the given branch.
+// If branch name is empty, default branch is used.
+func (p *GitProvider) GetBranchSha(repoUrl, branchName string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return "", err
+	}
+	branch, err := repo.getBranch(branchName)
+	if err != nil {
+		return "", err
+	}
+	return branch.sha, nil
+}
+
+// IsFileExist check whether given file exists in the given branch of the repository.
+// If branch is empty string, default branch is used.
+func (p *GitProvider) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	branch, err := repo.getBranch(branchName)
+	if err != nil {
+		// Git providers report missing files in missing branches
+		return false, nil
+	}
+	_, exists := branch.files[filepath.Clean(filePath)]
+	return exists, nil
+}
+
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (p *GitProvider) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+	c, err := repo.resolveRevision(revision)
+	if err != nil {
+		return nil, err
+	}
+	content, exists := c.files[filepath.Clean(filePath)]
+	if !exists {
+		return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+	}
+	return append([]byte(nil), content...), nil
+}
+
+// IsRepositoryPublic returns true if the repository could be accessed without authentication
+func (p *GitProvider) IsRepositoryPublic(repoUrl string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	repo, err := p.getRepository(repoUrl)
+	if err != nil {
+		return false, err
+	}
+	return repo.public, nil
+}
+
+// GetBrowseRepositoryAtShaLink returns web URL of repository state at given SHA
+func (p *GitProvider) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	return fmt.Sprintf("%s?rev=%s", strings.TrimSuffix(repoUrl, ".git"), sha)
+}
+
+// GetConfiguredGitAppName returns the git application set by SetConfiguredGitApp
+func (p *GitProvider) GetConfiguredGitAppName() (string, string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.appName == "" {
+		return "", "", fmt.Errorf("git application is not configured")
+	}
+	return p.appName, p.appId, nil
+}
+
+// filesUpToDate returns true if the commit has all the given files with the same content.
+func filesUpToDate(c *commit, files []gp.RepositoryFile) bool {
+	for _, file := range files {
+		content, exists := c.files[file.FullPath]
+		if !exists || !bytes.Equal(content, file.Content) {
+			return false
+		}
+	}
+	return true
+}
+
+// existingFiles returns subset of the given files which exist in the commit.
+func existingFiles(c *commit, files []gp.RepositoryFile) []gp.RepositoryFile {
+	existing := make([]gp.RepositoryFile, 0, len(files))
+	for _, file := range files {
+		if _, exists := c.files[file.FullPath]; exists {
+			existing = append(existing, gp.RepositoryFile{FullPath: file.FullPath})
+		}
+	}
+	return existing
+}
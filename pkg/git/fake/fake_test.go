@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake_test
+
+import (
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/fake"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovidertest"
+)
+
+func TestGitProviderContract(t *testing.T) {
+	gitprovidertest.RunContractTests(t, func(t *testing.T, provider *fake.GitProvider) gp.GitProviderClient {
+		return provider
+	})
+}
+
+func TestMergeMergeRequest(t *testing.T) {
+	const repoUrl = "https://github.com/owner/repository"
+	provider := fake.NewGitProvider()
+	provider.AddRepository(repoUrl, "main", []gp.RepositoryFile{
+		{FullPath: "README.md", Content: []byte("readme")},
+		{FullPath: "main.go", Content: []byte("package main")},
+	})
+	d := &gp.MergeRequestData{
+		BranchName: "pac",
+		Files:      []gp.RepositoryFile{{FullPath: ".tekton/push.yaml", Content: []byte("push")}},
+	}
+	webUrl, err := provider.EnsurePaCMergeRequest(repoUrl, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.PushFiles(repoUrl, "main", []gp.RepositoryFile{{FullPath: "README.md", Content: []byte("new readme")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	number, err := gp.GetMergeRequestNumberFromWebUrl(webUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.MergeMergeRequest(repoUrl, number); err != nil {
+		t.Fatal(err)
+	}
+
+	mergeCommit, err := provider.GetCommit(repoUrl, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mergeCommit.ParentShas) != 2 {
+		t.Errorf("expected merge commit, parents: %v", mergeCommit.ParentShas)
+	}
+	expectedFiles := map[string]string{"README.md": "new readme", "main.go": "package main", ".tekton/push.yaml": "push"}
+	if len(mergeCommit.Files) != len(expectedFiles) {
+		t.Errorf("unexpected files after merge: %v", mergeCommit.Files)
+	}
+	for path, content := range expectedFiles {
+		if string(mergeCommit.Files[path]) != content {
+			t.Errorf("unexpected content of %s after merge: %s", path, mergeCommit.Files[path])
+		}
+	}
+	if err := provider.MergeMergeRequest(repoUrl, number); err == nil {
+		t.Errorf("merged merge request should not be merged again")
+	}
+}
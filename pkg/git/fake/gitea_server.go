@@ -0,0 +1,557 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	giteaApiPath = "/api/v1"
+
+	giteaPullRequestWebUrlFormat = "%s/pulls/%d"
+	// giteaRepositoryId is the id of every repository, forks are not supported
+	giteaRepositoryId = 1
+)
+
+// giteaServer serves the subset of Gitea REST API used by the build service Gitea client.
+type giteaServer struct {
+	provider *GitProvider
+}
+
+// NewGiteaHandler returns HTTP handler which serves Gitea REST API backed by the given fake git provider.
+// The Gitea client should be created with the server URL as the instance URL.
+// All tokens are authenticated as TokenOwner, forks are not supported.
+func NewGiteaHandler(provider *GitProvider) http.Handler {
+	return &giteaServer{provider: provider}
+}
+
+func (s *giteaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.provider.mutex.Lock()
+	defer s.provider.mutex.Unlock()
+
+	// Branch names are single escaped path segments
+	escapedParts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.EscapedPath(), giteaApiPath), "/"), "/")
+	parts := make([]string, 0, len(escapedParts))
+	for _, escapedPart := range escapedParts {
+		part, err := url.PathUnescape(escapedPart)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		parts = append(parts, part)
+	}
+
+	// /repos/owner/repository/rest
+	if len(parts) < 3 || parts[0] != "repos" {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[1] + "/" + parts[2])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	rest := parts[3:]
+	resource := ""
+	if len(rest) > 0 {
+		resource = rest[0]
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		s.getRepository(w, repo)
+	case len(rest) == 2 && resource == "branches" && r.Method == http.MethodGet:
+		s.getBranch(w, repo, rest[1])
+	case len(rest) == 1 && resource == "branches" && r.Method == http.MethodPost:
+		s.createBranch(w, r, repo)
+	case len(rest) == 2 && resource == "branches" && r.Method == http.MethodDelete:
+		s.deleteBranch(w, repo, rest[1])
+	case len(rest) > 1 && resource == "raw" && r.Method == http.MethodGet:
+		s.getRawFile(w, r, repo, strings.Join(rest[1:], "/"))
+	case len(rest) >= 1 && resource == "contents" && r.Method == http.MethodGet:
+		s.getContents(w, r, repo, strings.Join(rest[1:], "/"))
+	case len(rest) > 1 && resource == "contents" && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		s.changeFile(w, r, repo, strings.Join(rest[1:], "/"))
+	case len(rest) == 1 && resource == "pulls" && r.Method == http.MethodGet:
+		s.listPullRequests(w, r, repo)
+	case len(rest) == 1 && resource == "pulls" && r.Method == http.MethodPost:
+		s.createPullRequest(w, r, repo)
+	case len(rest) == 2 && resource == "pulls" && r.Method == http.MethodGet:
+		s.getPullRequest(w, repo, rest[1])
+	case len(rest) == 2 && resource == "pulls" && r.Method == http.MethodPatch:
+		s.editPullRequest(w, r, repo, rest[1])
+	case len(rest) == 1 && resource == "hooks" && r.Method == http.MethodGet:
+		s.listHooks(w, repo)
+	case len(rest) == 1 && resource == "hooks" && r.Method == http.MethodPost:
+		s.saveHook(w, r, repo, nil)
+	case len(rest) == 2 && resource == "hooks" && r.Method == http.MethodPatch:
+		webhook := getWebhookByIdParam(repo, rest[1])
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "The target couldn't be found.")
+			return
+		}
+		s.saveHook(w, r, repo, webhook)
+	case len(rest) == 2 && resource == "hooks" && r.Method == http.MethodDelete:
+		webhook := getWebhookByIdParam(repo, rest[1])
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "The target couldn't be found.")
+			return
+		}
+		repo.deleteWebhook(webhook.Id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeUnsupported(w, r)
+	}
+}
+
+func (s *giteaServer) getRepository(w http.ResponseWriter, repo *repository) {
+	repositoryPath := getRepositoryPath(repo.url)
+	owner, name, _ := strings.Cut(repositoryPath, "/")
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"id":             giteaRepositoryId,
+		"owner":          map[string]interface{}{"login": owner},
+		"name":           name,
+		"full_name":      repositoryPath,
+		"html_url":       repo.url,
+		"default_branch": repo.defaultBranch,
+		"private":        !repo.public,
+		"internal":       false,
+	})
+}
+
+func giteaBranch(repo *repository, branchName string) map[string]interface{} {
+	protected := repo.protectedBranches[branchName]
+	c := repo.branches[branchName]
+	return map[string]interface{}{
+		"name":          branchName,
+		"commit":        map[string]interface{}{"id": c.sha, "message": c.message},
+		"protected":     protected,
+		"user_can_push": !protected,
+	}
+}
+
+func (s *giteaServer) getBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusNotFound, "Branch doesn't exist.")
+		return
+	}
+	writeJson(w, http.StatusOK, giteaBranch(repo, branchName))
+}
+
+func (s *giteaServer) createBranch(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		BranchName    string `json:"new_branch_name"`
+		OldBranchName string `json:"old_branch_name"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, exists := repo.branches[request.BranchName]; exists {
+		writeError(w, http.StatusConflict, "The branch already exists.")
+		return
+	}
+	c, err := repo.getBranch(request.OldBranchName)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The old branch does not exist")
+		return
+	}
+	repo.branches[request.BranchName] = c
+	writeJson(w, http.StatusCreated, giteaBranch(repo, request.BranchName))
+}
+
+func (s *giteaServer) deleteBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusNotFound, "Branch doesn't exist.")
+		return
+	}
+	if repo.protectedBranches[branchName] {
+		writeError(w, http.StatusForbidden, "Branch is protected")
+		return
+	}
+	delete(repo.branches, branchName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *giteaServer) getRawFile(w http.ResponseWriter, r *http.Request, repo *repository, filePath string) {
+	c, err := repo.resolveRevision(r.URL.Query().Get("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	content, exists := c.files[filePath]
+	if !exists {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+// blobSha returns SHA of the file content, which Gitea requires to update or delete the file.
+func blobSha(content []byte) string {
+	hash := sha1.New()
+	fmt.Fprintf(hash, "blob %d\x00", len(content))
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func giteaFileContents(filePath string, content []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     path.Base(filePath),
+		"path":     filePath,
+		"sha":      blobSha(content),
+		"type":     "file",
+		"size":     len(content),
+		"encoding": "base64",
+		"content":  base64.StdEncoding.EncodeToString(content),
+	}
+}
+
+// getContents returns metadata and content of the file or metadata of the directory entries.
+func (s *giteaServer) getContents(w http.ResponseWriter, r *http.Request, repo *repository, filePath string) {
+	c, err := repo.resolveRevision(r.URL.Query().Get("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	if content, exists := c.files[filePath]; exists {
+		writeJson(w, http.StatusOK, giteaFileContents(filePath, content))
+		return
+	}
+
+	directory := strings.Trim(filePath, "/")
+	if directory == "." {
+		directory = ""
+	}
+	fileNames, directoryNames := listDirectory(c.files, directory)
+	if len(fileNames) == 0 && len(directoryNames) == 0 {
+		writeError(w, http.StatusNotFound, "object does not exist")
+		return
+	}
+	entries := []map[string]interface{}{}
+	for _, name := range directoryNames {
+		entries = append(entries, map[string]interface{}{"type": "dir", "name": name, "path": path.Join(directory, name)})
+	}
+	for _, name := range fileNames {
+		entry := giteaFileContents(path.Join(directory, name), c.files[path.Join(directory, name)])
+		// Content is returned for a single file only
+		delete(entry, "content")
+		delete(entry, "encoding")
+		entries = append(entries, entry)
+	}
+	writeJson(w, http.StatusOK, entries)
+}
+
+// changeFile creates (POST), updates (PUT) or deletes (DELETE) the file with a commit on top of the branch.
+func (s *giteaServer) changeFile(w http.ResponseWriter, r *http.Request, repo *repository, filePath string) {
+	var request struct {
+		Content       string `json:"content"`
+		SHA           string `json:"sha"`
+		Message       string `json:"message"`
+		BranchName    string `json:"branch"`
+		NewBranchName string `json:"new_branch"`
+		Author        struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.NewBranchName != "" {
+		writeError(w, http.StatusUnprocessableEntity, "new_branch is not supported")
+		return
+	}
+	branchName := request.BranchName
+	if branchName == "" {
+		branchName = repo.defaultBranch
+	}
+	parent, exists := repo.branches[branchName]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Branch doesn't exist.")
+		return
+	}
+
+	files := copyFiles(parent.files)
+	content, fileExists := files[filePath]
+	switch {
+	case r.Method == http.MethodPost && fileExists:
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("repository file already exists [path: %s]", filePath))
+		return
+	case r.Method != http.MethodPost && !fileExists:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("repository file does not exist [path: %s]", filePath))
+		return
+	case r.Method != http.MethodPost && request.SHA != blobSha(content):
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("sha does not match [given: %s, expected: %s]", request.SHA, blobSha(content)))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		delete(files, filePath)
+	} else {
+		newContent, err := base64.StdEncoding.DecodeString(request.Content)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		files[filePath] = newContent
+	}
+
+	authorName := request.Author.Name
+	if authorName == "" {
+		authorName = TokenOwner
+	}
+	c := s.provider.newCommit(repo, []*commit{parent}, files, request.Message, authorName, request.Author.Email)
+	if err := repo.updateBranch(branchName, c, false); err != nil {
+		if isPushRejected(err) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("user %s is not allowed to push to protected branch %s", TokenOwner, branchName))
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{"commit": map[string]interface{}{"sha": c.sha, "message": c.message}}
+	statusCode := http.StatusOK
+	switch r.Method {
+	case http.MethodPost:
+		statusCode = http.StatusCreated
+		response["content"] = giteaFileContents(filePath, files[filePath])
+	case http.MethodPut:
+		response["content"] = giteaFileContents(filePath, files[filePath])
+	}
+	writeJson(w, statusCode, response)
+}
+
+func giteaPullRequest(repo *repository, mr *MergeRequest) map[string]interface{} {
+	state := "open"
+	if mr.State != gp.MergeRequestStateOpen {
+		state = "closed"
+	}
+	prBranchInfo := func(branchName string) map[string]interface{} {
+		info := map[string]interface{}{"label": branchName, "ref": branchName, "repo_id": giteaRepositoryId}
+		if c, exists := repo.branches[branchName]; exists {
+			info["sha"] = c.sha
+		}
+		return info
+	}
+	mergeBase := ""
+	source, sourceExists := repo.branches[mr.SourceBranch]
+	target, targetExists := repo.branches[mr.TargetBranch]
+	if sourceExists && targetExists {
+		if base := findMergeBase(source, target); base != nil {
+			mergeBase = base.sha
+		}
+	}
+	return map[string]interface{}{
+		"id":         mr.Number,
+		"number":     mr.Number,
+		"html_url":   mr.WebUrl,
+		"title":      mr.Title,
+		"body":       mr.Text,
+		"state":      state,
+		"user":       map[string]interface{}{"login": mr.Author},
+		"mergeable":  mr.State == gp.MergeRequestStateOpen,
+		"merged":     mr.State == gp.MergeRequestStateMerged,
+		"merged_at":  formatTime(mr.MergedAt),
+		"created_at": formatTime(&mr.CreatedAt),
+		"base":       prBranchInfo(mr.TargetBranch),
+		"head":       prBranchInfo(mr.SourceBranch),
+		"merge_base": mergeBase,
+	}
+}
+
+// listPullRequests supports filtering by state and pagination.
+func (s *giteaServer) listPullRequests(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	pullRequests := []map[string]interface{}{}
+	for _, mr := range repo.mergeRequests {
+		pullRequest := giteaPullRequest(repo, mr)
+		if state == "all" || state == pullRequest["state"] {
+			pullRequests = append(pullRequests, pullRequest)
+		}
+	}
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 30
+	}
+	start := (page - 1) * limit
+	if start > len(pullRequests) {
+		start = len(pullRequests)
+	}
+	end := start + limit
+	if end > len(pullRequests) {
+		end = len(pullRequests)
+	}
+	writeJson(w, http.StatusOK, pullRequests[start:end])
+}
+
+func (s *giteaServer) createPullRequest(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.Contains(request.Head, ":") {
+		writeError(w, http.StatusUnprocessableEntity, "pull requests from forks are not supported")
+		return
+	}
+	source, sourceExists := repo.branches[request.Head]
+	target, targetExists := repo.branches[request.Base]
+	if !sourceExists || !targetExists {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	if repo.findOpenMergeRequest(request.Head, request.Base) != nil {
+		writeError(w, http.StatusConflict, "pull request already exists for these targets")
+		return
+	}
+	if isAncestor(source, target) {
+		writeError(w, http.StatusUnprocessableEntity, "There are no changes between the head and the base")
+		return
+	}
+	mr, err := repo.createMergeRequest(request.Head, request.Base, request.Title, request.Body, TokenOwner, giteaPullRequestWebUrlFormat)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, giteaPullRequest(repo, mr))
+}
+
+func (s *giteaServer) getPullRequest(w http.ResponseWriter, repo *repository, indexParam string) {
+	index, err := strconv.Atoi(indexParam)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	mr := repo.getMergeRequest(index)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	writeJson(w, http.StatusOK, giteaPullRequest(repo, mr))
+}
+
+// editPullRequest supports changing title, body and closing of the pull request.
+func (s *giteaServer) editPullRequest(w http.ResponseWriter, r *http.Request, repo *repository, indexParam string) {
+	index, err := strconv.Atoi(indexParam)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	mr := repo.getMergeRequest(index)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	var request struct {
+		Title string  `json:"title"`
+		Body  string  `json:"body"`
+		Base  string  `json:"base"`
+		State *string `json:"state"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Base != "" && request.Base != mr.TargetBranch {
+		writeError(w, http.StatusUnprocessableEntity, "change of the base branch is not supported")
+		return
+	}
+	if request.State != nil {
+		switch {
+		case *request.State == "closed" && mr.State == gp.MergeRequestStateOpen:
+			mr.State = gp.MergeRequestStateClosed
+		case *request.State == "closed" || (*request.State == "open" && mr.State == gp.MergeRequestStateOpen):
+		default:
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("state change of %s pull request to %s is not supported", mr.State, *request.State))
+			return
+		}
+	}
+	if request.Title != "" {
+		mr.Title = request.Title
+	}
+	mr.Text = request.Body
+	writeJson(w, http.StatusCreated, giteaPullRequest(repo, mr))
+}
+
+func giteaHook(webhook *Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     webhook.Id,
+		"type":   "gitea",
+		"config": map[string]interface{}{"url": webhook.Url, "content_type": "json"},
+		"events": []string{"push", "pull_request", "issue_comment"},
+		"active": true,
+	}
+}
+
+func (s *giteaServer) listHooks(w http.ResponseWriter, repo *repository) {
+	hooks := []map[string]interface{}{}
+	for _, webhook := range repo.webhooks {
+		hooks = append(hooks, giteaHook(webhook))
+	}
+	writeJson(w, http.StatusOK, hooks)
+}
+
+// saveHook creates new webhook if the given one is nil, updates the given webhook otherwise.
+func (s *giteaServer) saveHook(w http.ResponseWriter, r *http.Request, repo *repository, webhook *Webhook) {
+	var request struct {
+		Config map[string]string `json:"config"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Config["url"] == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Missing config option: url")
+		return
+	}
+	statusCode := http.StatusOK
+	if webhook == nil {
+		webhook = repo.addWebhook(Webhook{})
+		statusCode = http.StatusCreated
+	}
+	webhook.Url = request.Config["url"]
+	webhook.Secret = request.Config["secret"]
+	writeJson(w, statusCode, giteaHook(webhook))
+}
@@ -0,0 +1,568 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	githubApiPath = "/api/v3"
+	githubRawPath = "/raw"
+
+	githubPullRequestWebUrlFormat = "%s/pull/%d"
+)
+
+// githubServer serves the subset of GitHub Enterprise REST API used by the build service GitHub client.
+type githubServer struct {
+	provider *GitProvider
+	// trees keeps files of created git trees by the tree SHA, guarded by the provider mutex
+	trees map[string]map[string][]byte
+}
+
+// NewGithubHandler returns HTTP handler which serves GitHub Enterprise REST API backed by the given fake git provider.
+// The GitHub client should be created with the server URL as base URL.
+// All tokens are authenticated as TokenOwner, forks are not supported.
+func NewGithubHandler(provider *GitProvider) http.Handler {
+	return &githubServer{provider: provider, trees: map[string]map[string][]byte{}}
+}
+
+func (s *githubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.provider.mutex.Lock()
+	defer s.provider.mutex.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, githubRawPath+"/") {
+		s.serveRawFile(w, r)
+		return
+	}
+
+	apiPath := strings.TrimPrefix(r.URL.Path, githubApiPath)
+	if apiPath == "/user" && r.Method == http.MethodGet {
+		writeJson(w, http.StatusOK, map[string]interface{}{"login": TokenOwner})
+		return
+	}
+	// /repos/owner/repository/rest
+	parts := strings.SplitN(strings.TrimPrefix(apiPath, "/"), "/", 4)
+	if len(parts) < 3 || parts[0] != "repos" {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[1] + "/" + parts[2])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	rest := ""
+	if len(parts) == 4 {
+		rest = parts[3]
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		s.getRepository(w, repo)
+	case strings.HasPrefix(rest, "branches/") && r.Method == http.MethodGet:
+		s.getBranch(w, repo, strings.TrimPrefix(rest, "branches/"))
+	case strings.HasPrefix(rest, "git/ref/heads/") && r.Method == http.MethodGet:
+		s.getRef(w, repo, strings.TrimPrefix(rest, "git/ref/heads/"))
+	case rest == "git/refs" && r.Method == http.MethodPost:
+		s.createRef(w, r, repo)
+	case strings.HasPrefix(rest, "git/refs/heads/") && r.Method == http.MethodPatch:
+		s.updateRef(w, r, repo, strings.TrimPrefix(rest, "git/refs/heads/"))
+	case strings.HasPrefix(rest, "git/refs/heads/") && r.Method == http.MethodDelete:
+		s.deleteRef(w, repo, strings.TrimPrefix(rest, "git/refs/heads/"))
+	case (rest == "contents" || strings.HasPrefix(rest, "contents/")) && r.Method == http.MethodGet:
+		s.getContents(w, r, repo, strings.TrimPrefix(strings.TrimPrefix(rest, "contents"), "/"))
+	case strings.HasPrefix(rest, "commits/") && r.Method == http.MethodGet:
+		s.getCommit(w, repo, strings.TrimPrefix(rest, "commits/"))
+	case rest == "git/trees" && r.Method == http.MethodPost:
+		s.createTree(w, r, repo)
+	case rest == "git/commits" && r.Method == http.MethodPost:
+		s.createCommit(w, r, repo)
+	case strings.HasPrefix(rest, "compare/") && r.Method == http.MethodGet:
+		s.compare(w, repo, strings.TrimPrefix(rest, "compare/"))
+	case rest == "pulls" && r.Method == http.MethodGet:
+		s.listPullRequests(w, r, repo)
+	case rest == "pulls" && r.Method == http.MethodPost:
+		s.createPullRequest(w, r, repo)
+	case strings.HasPrefix(rest, "pulls/") && r.Method == http.MethodGet:
+		s.getPullRequest(w, repo, strings.TrimPrefix(rest, "pulls/"))
+	case rest == "hooks" && r.Method == http.MethodGet:
+		s.listHooks(w, repo)
+	case rest == "hooks" && r.Method == http.MethodPost:
+		s.saveHook(w, r, repo, nil)
+	case strings.HasPrefix(rest, "hooks/") && r.Method == http.MethodPatch:
+		webhook := getWebhookByIdParam(repo, strings.TrimPrefix(rest, "hooks/"))
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.saveHook(w, r, repo, webhook)
+	case strings.HasPrefix(rest, "hooks/") && r.Method == http.MethodDelete:
+		webhook := getWebhookByIdParam(repo, strings.TrimPrefix(rest, "hooks/"))
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		repo.deleteWebhook(webhook.Id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeUnsupported(w, r)
+	}
+}
+
+func (s *githubServer) getRepository(w http.ResponseWriter, repo *repository) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"full_name":      getRepositoryPath(repo.url),
+		"html_url":       repo.url,
+		"default_branch": repo.defaultBranch,
+		"private":        !repo.public,
+	})
+}
+
+func (s *githubServer) getBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	branch, exists := repo.branches[branchName]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"name":      branchName,
+		"protected": repo.protectedBranches[branchName],
+		"commit":    map[string]interface{}{"sha": branch.sha},
+	})
+}
+
+func githubRef(branchName, sha string) map[string]interface{} {
+	return map[string]interface{}{
+		"ref":    "refs/heads/" + branchName,
+		"object": map[string]interface{}{"type": "commit", "sha": sha},
+	}
+}
+
+func (s *githubServer) getRef(w http.ResponseWriter, repo *repository, branchName string) {
+	branch, exists := repo.branches[branchName]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, githubRef(branchName, branch.sha))
+}
+
+func (s *githubServer) createRef(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	branchName := strings.TrimPrefix(request.Ref, "refs/heads/")
+	c, exists := repo.commits[request.Sha]
+	if !exists {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	if _, exists := repo.branches[branchName]; exists {
+		writeError(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+	if err := repo.updateBranch(branchName, c, false); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, githubRef(branchName, c.sha))
+}
+
+func (s *githubServer) updateRef(w http.ResponseWriter, r *http.Request, repo *repository, branchName string) {
+	var request struct {
+		Sha   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, exists := repo.commits[request.Sha]
+	if !exists {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	if err := repo.updateBranch(branchName, c, request.Force); err != nil {
+		if isPushRejected(err) {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Protected branch update failed for refs/heads/%s.", branchName))
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+		return
+	}
+	writeJson(w, http.StatusOK, githubRef(branchName, c.sha))
+}
+
+func (s *githubServer) deleteRef(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	delete(repo.branches, branchName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getContents returns a file with its content or listing of a directory.
+// Files are downloaded by their download URL, so the base URL of the request is needed.
+func (s *githubServer) getContents(w http.ResponseWriter, r *http.Request, repo *repository, contentPath string) {
+	c, err := repo.resolveRevision(r.URL.Query().Get("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "No commit found for the ref")
+		return
+	}
+	contentPath = strings.Trim(contentPath, "/")
+	if contentPath == "." {
+		contentPath = ""
+	}
+
+	downloadUrl := func(filePath string) string {
+		return fmt.Sprintf("http://%s%s/%s/%s/%s", r.Host, githubRawPath, getRepositoryPath(repo.url), c.sha, filePath)
+	}
+	if content, isFile := c.files[contentPath]; isFile {
+		writeJson(w, http.StatusOK, map[string]interface{}{
+			"type":         "file",
+			"name":         path.Base(contentPath),
+			"path":         contentPath,
+			"encoding":     "base64",
+			"content":      base64.StdEncoding.EncodeToString(content),
+			"download_url": downloadUrl(contentPath),
+		})
+		return
+	}
+
+	fileNames, directoryNames := listDirectory(c.files, contentPath)
+	if len(fileNames) == 0 && len(directoryNames) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	entries := []map[string]interface{}{}
+	for _, name := range directoryNames {
+		entries = append(entries, map[string]interface{}{"type": "dir", "name": name, "path": path.Join(contentPath, name)})
+	}
+	for _, name := range fileNames {
+		filePath := path.Join(contentPath, name)
+		entries = append(entries, map[string]interface{}{"type": "file", "name": name, "path": filePath, "download_url": downloadUrl(filePath)})
+	}
+	writeJson(w, http.StatusOK, entries)
+}
+
+// serveRawFile serves /raw/owner/repository/sha/path download URLs.
+func (s *githubServer) serveRawFile(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, githubRawPath+"/"), "/", 4)
+	if len(parts) != 4 {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[0] + "/" + parts[1])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	c, exists := repo.commits[parts[2]]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	content, exists := c.files[parts[3]]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+func (s *githubServer) getCommit(w http.ResponseWriter, repo *repository, sha string) {
+	c, exists := repo.commits[sha]
+	if !exists {
+		writeError(w, http.StatusNotFound, "No commit found for SHA: "+sha)
+		return
+	}
+	s.trees[treeSha(c.files)] = c.files
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"sha": c.sha,
+		"commit": map[string]interface{}{
+			"message": c.message,
+			"tree":    map[string]interface{}{"sha": treeSha(c.files)},
+		},
+	})
+}
+
+// createTree creates tree from the base tree with the given entries.
+// Entries without content and SHA delete the files.
+func (s *githubServer) createTree(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string  `json:"path"`
+			Content *string `json:"content"`
+			Sha     *string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Commit SHA is accepted as base tree as well
+	baseFiles, exists := s.trees[request.BaseTree]
+	if !exists {
+		c, commitExists := repo.commits[request.BaseTree]
+		if !commitExists {
+			writeError(w, http.StatusUnprocessableEntity, "base_tree is not a valid tree")
+			return
+		}
+		baseFiles = c.files
+	}
+	files := copyFiles(baseFiles)
+	for _, entry := range request.Tree {
+		switch {
+		case entry.Content != nil:
+			files[entry.Path] = []byte(*entry.Content)
+		case entry.Sha == nil:
+			delete(files, entry.Path)
+		default:
+			writeError(w, http.StatusUnprocessableEntity, "blob SHAs are not supported")
+			return
+		}
+	}
+
+	sha := treeSha(files)
+	s.trees[sha] = files
+	writeJson(w, http.StatusCreated, map[string]interface{}{"sha": sha})
+}
+
+func (s *githubServer) createCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+		Author  *struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	files, exists := s.trees[request.Tree]
+	if !exists {
+		writeError(w, http.StatusUnprocessableEntity, "Tree SHA does not exist")
+		return
+	}
+	parents := []*commit{}
+	for _, parentSha := range request.Parents {
+		parent, exists := repo.commits[parentSha]
+		if !exists {
+			writeError(w, http.StatusUnprocessableEntity, "Parent SHA does not exist")
+			return
+		}
+		parents = append(parents, parent)
+	}
+	// The application is the author if the author isn't set
+	authorName, authorEmail := TokenOwner, ""
+	if request.Author != nil {
+		authorName, authorEmail = request.Author.Name, request.Author.Email
+	}
+
+	c := s.provider.newCommit(repo, parents, copyFiles(files), request.Message, authorName, authorEmail)
+	writeJson(w, http.StatusCreated, map[string]interface{}{
+		"sha":     c.sha,
+		"message": c.message,
+		"tree":    map[string]interface{}{"sha": request.Tree},
+	})
+}
+
+// compare supports comparison of branches in base...head form, where head could be prefixed by the owner.
+func (s *githubServer) compare(w http.ResponseWriter, repo *repository, basehead string) {
+	base, head, found := strings.Cut(basehead, "...")
+	if !found {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if _, branchName, hasOwner := strings.Cut(head, ":"); hasOwner {
+		head = branchName
+	}
+	behindBy, err := repo.countBehind(head, base)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	aheadBy, err := repo.countBehind(base, head)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{"behind_by": behindBy, "ahead_by": aheadBy})
+}
+
+func githubPullRequest(repo *repository, mr *MergeRequest) map[string]interface{} {
+	state := "open"
+	if mr.State != gp.MergeRequestStateOpen {
+		state = "closed"
+	}
+	return map[string]interface{}{
+		"id":         mr.Number,
+		"number":     mr.Number,
+		"url":        fmt.Sprintf("%s/pulls/%d", repo.url, mr.Number),
+		"html_url":   mr.WebUrl,
+		"title":      mr.Title,
+		"body":       mr.Text,
+		"state":      state,
+		"merged":     mr.State == gp.MergeRequestStateMerged,
+		"created_at": formatTime(&mr.CreatedAt),
+		"merged_at":  formatTime(mr.MergedAt),
+		"head":       map[string]interface{}{"ref": mr.SourceBranch},
+		"base":       map[string]interface{}{"ref": mr.TargetBranch},
+		"user":       map[string]interface{}{"login": mr.Author},
+	}
+}
+
+// listPullRequests supports filtering by state, head in owner:branch form and base branch.
+func (s *githubServer) listPullRequests(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	head := query.Get("head")
+	if _, branchName, hasOwner := strings.Cut(head, ":"); hasOwner {
+		head = branchName
+	}
+	base := query.Get("base")
+
+	pullRequests := []map[string]interface{}{}
+	for _, mr := range repo.mergeRequests {
+		isOpen := mr.State == gp.MergeRequestStateOpen
+		if (state == "open" && !isOpen) || (state == "closed" && isOpen) {
+			continue
+		}
+		if (head != "" && mr.SourceBranch != head) || (base != "" && mr.TargetBranch != base) {
+			continue
+		}
+		pullRequests = append(pullRequests, githubPullRequest(repo, mr))
+	}
+	writeJson(w, http.StatusOK, pullRequests)
+}
+
+func (s *githubServer) createPullRequest(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Title string `json:"title"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+		Body  string `json:"body"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	head := request.Head
+	if _, branchName, hasOwner := strings.Cut(head, ":"); hasOwner {
+		head = branchName
+	}
+	mr, err := repo.createMergeRequest(head, request.Base, request.Title, request.Body, TokenOwner, githubPullRequestWebUrlFormat)
+	if err != nil {
+		writeJson(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "Validation Failed",
+			"errors":  []map[string]interface{}{{"resource": "PullRequest", "code": "custom", "message": err.Error()}},
+		})
+		return
+	}
+	writeJson(w, http.StatusCreated, githubPullRequest(repo, mr))
+}
+
+func (s *githubServer) getPullRequest(w http.ResponseWriter, repo *repository, numberParam string) {
+	number, err := strconv.Atoi(numberParam)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	mr := repo.getMergeRequest(number)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, githubPullRequest(repo, mr))
+}
+
+func githubHook(webhook *Webhook) map[string]interface{} {
+	insecureSSL := "0"
+	if webhook.InsecureSSL {
+		insecureSSL = "1"
+	}
+	return map[string]interface{}{
+		"id":     webhook.Id,
+		"active": true,
+		"events": []string{"pull_request", "push", "issue_comment", "commit_comment"},
+		"config": map[string]interface{}{
+			"url":          webhook.Url,
+			"content_type": "json",
+			"insecure_ssl": insecureSSL,
+			// GitHub never returns the secret
+			"secret": "********",
+		},
+	}
+}
+
+func (s *githubServer) listHooks(w http.ResponseWriter, repo *repository) {
+	hooks := []map[string]interface{}{}
+	for _, webhook := range repo.webhooks {
+		hooks = append(hooks, githubHook(webhook))
+	}
+	writeJson(w, http.StatusOK, hooks)
+}
+
+// saveHook creates new webhook if the given one is nil, updates the given webhook otherwise.
+func (s *githubServer) saveHook(w http.ResponseWriter, r *http.Request, repo *repository, webhook *Webhook) {
+	var request struct {
+		Config struct {
+			Url         string `json:"url"`
+			Secret      string `json:"secret"`
+			InsecureSSL string `json:"insecure_ssl"`
+		} `json:"config"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	statusCode := http.StatusOK
+	if webhook == nil {
+		webhook = repo.addWebhook(Webhook{Url: request.Config.Url})
+		statusCode = http.StatusCreated
+	}
+	webhook.Secret = request.Config.Secret
+	webhook.InsecureSSL = request.Config.InsecureSSL == "1"
+	writeJson(w, statusCode, githubHook(webhook))
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	gitlabApiPath = "/api/v4"
+
+	gitlabMergeRequestWebUrlFormat = "%s/-/merge_requests/%d"
+)
+
+// gitlabServer serves the subset of GitLab REST API used by the build service GitLab client.
+type gitlabServer struct {
+	provider *GitProvider
+}
+
+// NewGitlabHandler returns HTTP handler which serves GitLab REST API backed by the given fake git provider.
+// The GitLab client should be created with the server URL as base URL.
+// All tokens are authenticated as TokenOwner, forks and numeric project ids are not supported.
+func NewGitlabHandler(provider *GitProvider) http.Handler {
+	return &gitlabServer{provider: provider}
+}
+
+func (s *gitlabServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.provider.mutex.Lock()
+	defer s.provider.mutex.Unlock()
+
+	// Project path, file path and branch name are single escaped path segments
+	escapedParts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.EscapedPath(), gitlabApiPath), "/"), "/")
+	parts := make([]string, 0, len(escapedParts))
+	for _, escapedPart := range escapedParts {
+		part, err := url.PathUnescape(escapedPart)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 1 && parts[0] == "user" && r.Method == http.MethodGet {
+		writeJson(w, http.StatusOK, map[string]interface{}{"username": TokenOwner})
+		return
+	}
+	if len(parts) < 2 || parts[0] != "projects" {
+		writeUnsupported(w, r)
+		return
+	}
+	repo, err := s.provider.getRepository(parts[1])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+	rest := strings.Join(parts[2:], "/")
+	// Branch names and file paths are escaped, so each of them is a single element
+	lastPart := parts[len(parts)-1]
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		s.getProject(w, repo)
+	case len(parts) == 5 && strings.HasPrefix(rest, "repository/branches/") && r.Method == http.MethodGet:
+		s.getBranch(w, repo, lastPart)
+	case rest == "repository/branches" && r.Method == http.MethodPost:
+		s.createBranch(w, r, repo)
+	case len(parts) == 5 && strings.HasPrefix(rest, "repository/branches/") && r.Method == http.MethodDelete:
+		s.deleteBranch(w, repo, lastPart)
+	case len(parts) == 6 && strings.HasPrefix(rest, "repository/files/") && lastPart == "raw" && r.Method == http.MethodGet:
+		s.getRawFile(w, r, repo, parts[4])
+	case rest == "repository/tree" && r.Method == http.MethodGet:
+		s.listTree(w, r, repo)
+	case rest == "repository/commits" && r.Method == http.MethodPost:
+		s.createCommit(w, r, repo)
+	case rest == "repository/compare" && r.Method == http.MethodGet:
+		s.compare(w, r, repo)
+	case rest == "merge_requests" && r.Method == http.MethodGet:
+		s.listMergeRequests(w, r, repo)
+	case rest == "merge_requests" && r.Method == http.MethodPost:
+		s.createMergeRequest(w, r, repo)
+	case len(parts) == 4 && parts[2] == "merge_requests" && r.Method == http.MethodGet:
+		s.getMergeRequest(w, repo, lastPart)
+	case rest == "hooks" && r.Method == http.MethodGet:
+		s.listHooks(w, repo)
+	case rest == "hooks" && r.Method == http.MethodPost:
+		s.saveHook(w, r, repo, nil)
+	case len(parts) == 4 && parts[2] == "hooks" && r.Method == http.MethodPut:
+		webhook := getWebhookByIdParam(repo, lastPart)
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		s.saveHook(w, r, repo, webhook)
+	case len(parts) == 4 && parts[2] == "hooks" && r.Method == http.MethodDelete:
+		webhook := getWebhookByIdParam(repo, lastPart)
+		if webhook == nil {
+			writeError(w, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		repo.deleteWebhook(webhook.Id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeUnsupported(w, r)
+	}
+}
+
+func (s *gitlabServer) getProject(w http.ResponseWriter, repo *repository) {
+	visibility := "private"
+	if repo.public {
+		visibility = "public"
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"path_with_namespace": getRepositoryPath(repo.url),
+		"web_url":             repo.url,
+		"default_branch":      repo.defaultBranch,
+		"visibility":          visibility,
+	})
+}
+
+func gitlabBranch(repo *repository, branchName string) map[string]interface{} {
+	protected := repo.protectedBranches[branchName]
+	return map[string]interface{}{
+		"name":      branchName,
+		"protected": protected,
+		"can_push":  !protected,
+		"commit":    map[string]interface{}{"id": repo.branches[branchName].sha},
+	}
+}
+
+func (s *gitlabServer) getBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, gitlabBranch(repo, branchName))
+}
+
+func (s *gitlabServer) createBranch(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Branch string `json:"branch"`
+		Ref    string `json:"ref"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, exists := repo.branches[request.Branch]; exists {
+		writeError(w, http.StatusBadRequest, "Branch already exists")
+		return
+	}
+	c, err := repo.resolveRevision(request.Ref)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid reference name")
+		return
+	}
+	repo.branches[request.Branch] = c
+	writeJson(w, http.StatusCreated, gitlabBranch(repo, request.Branch))
+}
+
+func (s *gitlabServer) deleteBranch(w http.ResponseWriter, repo *repository, branchName string) {
+	if _, exists := repo.branches[branchName]; !exists {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	delete(repo.branches, branchName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *gitlabServer) getRawFile(w http.ResponseWriter, r *http.Request, repo *repository, filePath string) {
+	c, err := repo.resolveRevision(r.URL.Query().Get("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Commit Not Found")
+		return
+	}
+	content, exists := c.files[filePath]
+	if !exists {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+func (s *gitlabServer) listTree(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	c, err := repo.resolveRevision(query.Get("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Tree Not Found")
+		return
+	}
+	directory := strings.Trim(query.Get("path"), "/")
+	if directory == "." {
+		directory = ""
+	}
+	fileNames, directoryNames := listDirectory(c.files, directory)
+	if len(fileNames) == 0 && len(directoryNames) == 0 {
+		writeError(w, http.StatusNotFound, "404 Tree Not Found")
+		return
+	}
+	entries := []map[string]interface{}{}
+	for _, name := range directoryNames {
+		entries = append(entries, map[string]interface{}{"type": "tree", "name": name, "path": path.Join(directory, name), "mode": "040000"})
+	}
+	for _, name := range fileNames {
+		entries = append(entries, map[string]interface{}{"type": "blob", "name": name, "path": path.Join(directory, name), "mode": "100644"})
+	}
+	writeJson(w, http.StatusOK, entries)
+}
+
+// createCommit supports creating, updating and deleting files in the branch or in the new branch based on the start branch.
+func (s *gitlabServer) createCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		Branch        string `json:"branch"`
+		StartBranch   string `json:"start_branch"`
+		StartProject  string `json:"start_project"`
+		CommitMessage string `json:"commit_message"`
+		AuthorName    string `json:"author_name"`
+		AuthorEmail   string `json:"author_email"`
+		Force         bool   `json:"force"`
+		Actions       []struct {
+			Action   string `json:"action"`
+			FilePath string `json:"file_path"`
+			Content  string `json:"content"`
+		} `json:"actions"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.StartProject != "" {
+		writeError(w, http.StatusBadRequest, "start_project is not supported")
+		return
+	}
+
+	parent, branchExists := repo.branches[request.Branch]
+	if request.StartBranch != "" && (!branchExists || request.Force) {
+		startBranch, exists := repo.branches[request.StartBranch]
+		if !exists {
+			writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+			return
+		}
+		parent = startBranch
+	} else if !branchExists {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+
+	files := copyFiles(parent.files)
+	for _, action := range request.Actions {
+		_, fileExists := files[action.FilePath]
+		switch {
+		case action.Action == "create" && !fileExists:
+			files[action.FilePath] = []byte(action.Content)
+		case action.Action == "update" && fileExists:
+			files[action.FilePath] = []byte(action.Content)
+		case action.Action == "delete" && fileExists:
+			delete(files, action.FilePath)
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to %s %s", action.Action, action.FilePath))
+			return
+		}
+	}
+
+	c := s.provider.newCommit(repo, []*commit{parent}, files, request.CommitMessage, request.AuthorName, request.AuthorEmail)
+	if err := repo.updateBranch(request.Branch, c, true); err != nil {
+		if isPushRejected(err) {
+			writeError(w, http.StatusForbidden, "You are not allowed to push into this branch")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, map[string]interface{}{"id": c.sha, "message": c.message})
+}
+
+// compare returns changes of the "to" revision since the merge base with the "from" revision.
+func (s *gitlabServer) compare(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	from, err := repo.resolveRevision(query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Ref Not Found")
+		return
+	}
+	to, err := repo.resolveRevision(query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Ref Not Found")
+		return
+	}
+	if query.Get("straight") == "true" {
+		writeUnsupported(w, r)
+		return
+	}
+
+	base := findMergeBase(from, to)
+	baseFiles := map[string][]byte{}
+	if base != nil {
+		baseFiles = base.files
+	}
+	diffs := []map[string]interface{}{}
+	for _, filePath := range sortedPaths(to.files) {
+		if content, exists := baseFiles[filePath]; !exists || !bytes.Equal(content, to.files[filePath]) {
+			diffs = append(diffs, map[string]interface{}{"new_path": filePath, "old_path": filePath, "new_file": !exists})
+		}
+	}
+	for _, filePath := range sortedPaths(baseFiles) {
+		if _, exists := to.files[filePath]; !exists {
+			diffs = append(diffs, map[string]interface{}{"new_path": filePath, "old_path": filePath, "deleted_file": true})
+		}
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{"diffs": diffs})
+}
+
+func gitlabMergeRequest(repo *repository, mr *MergeRequest) map[string]interface{} {
+	state := "opened"
+	switch mr.State {
+	case gp.MergeRequestStateMerged:
+		state = "merged"
+	case gp.MergeRequestStateClosed:
+		state = "closed"
+	}
+	divergedCommitsCount, _ := repo.countBehind(mr.SourceBranch, mr.TargetBranch)
+	return map[string]interface{}{
+		"id":                     mr.Number,
+		"iid":                    mr.Number,
+		"web_url":                mr.WebUrl,
+		"title":                  mr.Title,
+		"description":            mr.Text,
+		"state":                  state,
+		"source_branch":          mr.SourceBranch,
+		"target_branch":          mr.TargetBranch,
+		"created_at":             formatTime(&mr.CreatedAt),
+		"merged_at":              formatTime(mr.MergedAt),
+		"has_conflicts":          false,
+		"detailed_merge_status":  "mergeable",
+		"diverged_commits_count": divergedCommitsCount,
+		"author":                 map[string]interface{}{"username": mr.Author},
+	}
+}
+
+// listMergeRequests supports filtering by state, source and target branches and author.
+func (s *gitlabServer) listMergeRequests(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	mergeRequests := []map[string]interface{}{}
+	for _, mr := range repo.mergeRequests {
+		mergeRequest := gitlabMergeRequest(repo, mr)
+		if state := query.Get("state"); state != "" && state != "all" && state != mergeRequest["state"] {
+			continue
+		}
+		if sourceBranch := query.Get("source_branch"); sourceBranch != "" && sourceBranch != mr.SourceBranch {
+			continue
+		}
+		if targetBranch := query.Get("target_branch"); targetBranch != "" && targetBranch != mr.TargetBranch {
+			continue
+		}
+		if author := query.Get("author_username"); author != "" && author != mr.Author {
+			continue
+		}
+		mergeRequests = append(mergeRequests, mergeRequest)
+	}
+	writeJson(w, http.StatusOK, mergeRequests)
+}
+
+func (s *gitlabServer) createMergeRequest(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var request struct {
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		Title           string `json:"title"`
+		Description     string `json:"description"`
+		TargetProjectId *int   `json:"target_project_id"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.TargetProjectId != nil {
+		writeError(w, http.StatusBadRequest, "target_project_id is not supported")
+		return
+	}
+	mr, err := repo.createMergeRequest(request.SourceBranch, request.TargetBranch, request.Title, request.Description, TokenOwner, gitlabMergeRequestWebUrlFormat)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, gitlabMergeRequest(repo, mr))
+}
+
+func (s *gitlabServer) getMergeRequest(w http.ResponseWriter, repo *repository, iidParam string) {
+	iid, err := strconv.Atoi(iidParam)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+	mr := repo.getMergeRequest(iid)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, gitlabMergeRequest(repo, mr))
+}
+
+func gitlabHook(webhook *Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      webhook.Id,
+		"url":                     webhook.Url,
+		"enable_ssl_verification": !webhook.InsecureSSL,
+		"push_events":             true,
+		"merge_requests_events":   true,
+		"note_events":             true,
+	}
+}
+
+func (s *gitlabServer) listHooks(w http.ResponseWriter, repo *repository) {
+	hooks := []map[string]interface{}{}
+	for _, webhook := range repo.webhooks {
+		hooks = append(hooks, gitlabHook(webhook))
+	}
+	writeJson(w, http.StatusOK, hooks)
+}
+
+// saveHook creates new webhook if the given one is nil, updates the given webhook otherwise.
+func (s *gitlabServer) saveHook(w http.ResponseWriter, r *http.Request, repo *repository, webhook *Webhook) {
+	var request struct {
+		Url                   string `json:"url"`
+		Token                 string `json:"token"`
+		EnableSSLVerification *bool  `json:"enable_ssl_verification"`
+	}
+	if err := readJson(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	statusCode := http.StatusOK
+	if webhook == nil {
+		webhook = repo.addWebhook(Webhook{})
+		statusCode = http.StatusCreated
+	}
+	webhook.Url = request.Url
+	webhook.Secret = request.Token
+	webhook.InsecureSSL = request.EnableSSLVerification != nil && !*request.EnableSSLVerification
+	writeJson(w, statusCode, gitlabHook(webhook))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+// writeJson writes the given object as JSON response with the given status code.
+func writeJson(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// writeError writes error response in the format both GitHub and GitLab clients understand.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJson(w, statusCode, map[string]interface{}{"message": message})
+}
+
+// writeUnsupported reports API call which the stand-in doesn't implement.
+// Bad request is used because clients retry server errors and treat not found as a valid answer.
+func writeUnsupported(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusBadRequest, fmt.Sprintf("fake git provider doesn't support %s %s", r.Method, r.URL.Path))
+}
+
+func readJson(r *http.Request, data interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(data)
+}
+
+// isPushRejected returns true if the error is a branch update refused because of the branch protection.
+func isPushRejected(err error) bool {
+	var boErr *boerrors.BuildOpError
+	return errors.As(err, &boErr) && boErr.GetErrorId() == int(boerrors.EGitBranchPushRejected)
+}
+
+// treeSha returns SHA of the given files tree.
+func treeSha(files map[string][]byte) string {
+	hash := sha1.New()
+	for _, path := range sortedPaths(files) {
+		fmt.Fprintf(hash, "%s\n%s\n", path, files[path])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// listDirectory returns names of files and subdirectories of the directory in the given files tree.
+// Root directory is denoted by an empty path.
+func listDirectory(files map[string][]byte, directory string) (fileNames []string, directoryNames []string) {
+	prefix := ""
+	if directory != "" && directory != "." {
+		prefix = strings.TrimSuffix(directory, "/") + "/"
+	}
+	seenDirectories := map[string]bool{}
+	for _, path := range sortedPaths(files) {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		name, _, isInSubdirectory := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+		if !isInSubdirectory {
+			fileNames = append(fileNames, name)
+		} else if !seenDirectories[name] {
+			seenDirectories[name] = true
+			directoryNames = append(directoryNames, name)
+		}
+	}
+	return fileNames, directoryNames
+}
+
+// getWebhookByIdParam returns webhook with the id from the request path or nil if there is no such webhook.
+func getWebhookByIdParam(repo *repository, idParam string) *Webhook {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return nil
+	}
+	for _, webhook := range repo.webhooks {
+		if webhook.Id == id {
+			return webhook
+		}
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,528 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// Allow mocking for tests
+var NewGiteaClient func(accessToken, baseUrl string) (*GiteaClient, error) = newGiteaClient
+
+const (
+	webhookContentType = "json"
+)
+
+var (
+	appStudioPaCWebhookEvents = [...]string{"push", "pull_request", "issue_comment"}
+)
+
+var _ gp.GitProviderClient = (*GiteaClient)(nil)
+var _ gp.ContextBinder = (*GiteaClient)(nil)
+
+// GiteaClient implements git provider client for Gitea and its forks with compatible API, e.g. Forgejo.
+type GiteaClient struct {
+	client *gitea.Client
+	// baseUrl and accessToken are needed to create copies of the client bound to a context
+	baseUrl     string
+	accessToken string
+}
+
+// WithContext returns a copy of the client which makes all its requests within the given context.
+// The SDK client keeps the context in its internal state, so a new SDK client is created.
+func (g *GiteaClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	c, err := newGiteaSdkClient(g.accessToken, g.baseUrl, gitea.SetContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &GiteaClient{client: c, baseUrl: g.baseUrl, accessToken: g.accessToken}, nil
+}
+
+// EnsurePaCMergeRequest creates or updates existing (if needed) Pipelines as Code configuration proposal pull request.
+// Returns the pull request web URL.
+// If there is no error and web URL is empty, it means that the pull request is not needed (main branch is up to date).
+func (g *GiteaClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return "", err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	pacConfigurationUpToDate, err := g.filesUpToDate(owner, repository, d.BaseBranchName, d.Files)
+	if err != nil {
+		return "", err
+	}
+	if pacConfigurationUpToDate {
+		// Nothing to do, the configuration is alredy in the main branch of the repository
+		return "", nil
+	}
+
+	prBranchExists, err := g.branchExist(owner, repository, d.BranchName)
+	if err != nil {
+		return "", err
+	}
+
+	if prBranchExists {
+		err := g.commitFilesIntoBranch(owner, repository, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+		if err != nil {
+			return "", err
+		}
+
+		pr, err := g.findPullRequestByBranches(owner, repository, d.BranchName, d.BaseBranchName)
+		if err != nil {
+			return "", err
+		}
+		if pr != nil {
+			// Pull request already exists
+			return pr.HTMLURL, nil
+		}
+
+		prUrl, err := g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+		if err != nil {
+			if strings.Contains(err.Error(), "no changes between") {
+				// This could happen when a PR was created and merged, but PR branch was not deleted. Then main was updated.
+				// Current branch has correct configuration, but it's not possible to create a PR,
+				// because current branch reference is included into main branch.
+				if _, err := g.deleteBranch(owner, repository, d.BranchName); err != nil {
+					return "", err
+				}
+				return g.EnsurePaCMergeRequest(repoUrl, d)
+			}
+			return "", err
+		}
+		return prUrl, nil
+	} else {
+		// Need to create branch and PR with Pipelines as Code configuration
+		if err := g.createBranch(owner, repository, d.BranchName, d.BaseBranchName); err != nil {
+			return "", err
+		}
+
+		err = g.commitFilesIntoBranch(owner, repository, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+		if err != nil {
+			return "", err
+		}
+
+		return g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+	}
+}
+
+// UndoPaCMergeRequest creates or updates existing Pipelines as Code configuration removal pull request.
+// Returns the pull request web URL.
+// If there is no error and web URL is empty, it means that the pull request is not needed (the configuraton has already been deleted).
+func (g *GiteaClient) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return "", err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := g.filesExistInDirectory(owner, repository, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		// Nothing to prune
+		return "", nil
+	}
+
+	// Need to create PR that deletes PaC configuration of the component
+
+	// Delete old branch, if any
+	if _, err := g.deleteBranch(owner, repository, d.BranchName); err != nil {
+		return "", err
+	}
+
+	// Create branch, commit and pull request
+	if err := g.createBranch(owner, repository, d.BranchName, d.BaseBranchName); err != nil {
+		return "", err
+	}
+
+	err = g.addDeleteCommitToBranch(owner, repository, d.BranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files)
+	if err != nil {
+		return "", err
+	}
+
+	return g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+}
+
+// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal pull request
+func (g *GiteaClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	pr, err := g.findPullRequestByBranches(owner, repository, d.BranchName, d.BaseBranchName)
+	if err != nil {
+		return nil, err
+	}
+	if pr == nil {
+		return nil, nil
+	}
+	return &gp.MergeRequest{
+		Id:        pr.ID,
+		CreatedAt: pr.Created,
+		WebUrl:    pr.HTMLURL,
+		Title:     pr.Title,
+	}, nil
+}
+
+// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch, if the pull request branch is behind the base branch.
+// Gitea API doesn't allow force update of branches, so the outdated pull request is closed
+// and a new one is created from the recreated branch.
+func (g *GiteaClient) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return g.refreshPaCMergeRequest(repoUrl, d, false)
+}
+
+// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch with the given files, even if the branch is up to date.
+// The pull request is closed and a new one is created, see RefreshPaCMergeRequest.
+func (g *GiteaClient) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	webUrl, _, err = g.refreshPaCMergeRequest(repoUrl, d, true)
+	return webUrl, err
+}
+
+// refreshPaCMergeRequest recreates the branch of the open proposal pull request if it's behind the base branch or force is set.
+func (g *GiteaClient) refreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData, force bool) (webUrl string, refreshed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return "", false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	pr, err := g.findPullRequestByBranches(owner, repository, d.BranchName, d.BaseBranchName)
+	if err != nil || pr == nil {
+		return "", false, err
+	}
+
+	if !force {
+		baseBranch, err := g.getBranch(owner, repository, d.BaseBranchName)
+		if err != nil {
+			return "", false, err
+		}
+		if baseBranch == nil || baseBranch.Commit == nil {
+			return "", false, fmt.Errorf("base branch %s not found", d.BaseBranchName)
+		}
+		if pr.MergeBase == baseBranch.Commit.ID {
+			// The branch is based on the latest commit of the base branch
+			return pr.HTMLURL, false, nil
+		}
+	}
+
+	if err := g.closePullRequest(owner, repository, pr); err != nil {
+		return "", false, err
+	}
+	if _, err := g.deleteBranch(owner, repository, d.BranchName); err != nil {
+		return "", false, err
+	}
+	webUrl, err = g.EnsurePaCMergeRequest(repoUrl, d)
+	if err != nil {
+		return "", false, err
+	}
+	return webUrl, true, nil
+}
+
+// GetMergeRequestStatus returns current state of the pull request with the given web URL in the given repository
+func (g *GiteaClient) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	index, err := gp.GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl)
+	if err != nil {
+		return nil, err
+	}
+	pr, resp, err := g.client.GetPullRequest(owner, repository, int64(index))
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+
+	status := &gp.MergeRequestStatus{
+		State:     gp.MergeRequestStateOpen,
+		CreatedAt: pr.Created,
+		MergedAt:  pr.Merged,
+	}
+	switch {
+	case pr.HasMerged:
+		status.State = gp.MergeRequestStateMerged
+	case pr.State == gitea.StateClosed:
+		status.State = gp.MergeRequestStateClosed
+	default:
+		status.Mergeable = &pr.Mergeable
+		if pr.Base == nil {
+			break
+		}
+		baseBranch, err := g.getBranch(owner, repository, pr.Base.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if baseBranch == nil || baseBranch.Commit == nil {
+			return nil, fmt.Errorf("base branch %s not found", pr.Base.Ref)
+		}
+		status.IsBehind = pr.MergeBase != baseBranch.Commit.ID
+	}
+	return status, nil
+}
+
+// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch
+func (g *GiteaClient) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	upToDate, err := g.filesUpToDate(owner, repository, d.BaseBranchName, d.Files)
+	if err != nil || upToDate {
+		return false, err
+	}
+
+	if err := g.commitFilesIntoBranch(owner, repository, d.BaseBranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch
+func (g *GiteaClient) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := g.filesExistInDirectory(owner, repository, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil || len(files) == 0 {
+		return false, err
+	}
+
+	if err := g.addDeleteCommitToBranch(owner, repository, d.BaseBranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// IsBranchProtected returns true if the given branch is protected and the token owner is not allowed to push into it
+func (g *GiteaClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	branch, err := g.getBranch(owner, repository, branchName)
+	if err != nil {
+		return false, err
+	}
+	if branch == nil {
+		return false, fmt.Errorf("branch %s not found in %s/%s repository", branchName, owner, repository)
+	}
+	return branch.Protected && !branch.UserCanPush, nil
+}
+
+// SetupPaCWebhook creates Pipelines as Code webhook in the given repository
+func (g *GiteaClient) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	existingWebhook, err := g.getWebhookByTargetUrl(owner, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+
+	if existingWebhook == nil {
+		return g.createPaCWebhook(owner, repository, webhookUrl, webhookSecret)
+	}
+
+	// Need to always update the webhook in order to make sure that the webhook secret is up to date
+	// (it is not possible to read existing webhook secret)
+	return g.updatePaCWebhook(owner, repository, existingWebhook.ID, webhookUrl, webhookSecret)
+}
+
+// DeletePaCWebhook deletes Pipelines as Code webhook in the given repository
+func (g *GiteaClient) DeletePaCWebhook(repoUrl, webhookUrl string) error {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	existingWebhook, err := g.getWebhookByTargetUrl(owner, repository, webhookUrl)
+	if err != nil {
+		return err
+	}
+	if existingWebhook == nil {
+		// Webhook doesn't exist, nothing to do
+		return nil
+	}
+
+	return g.deleteWebhook(owner, repository, existingWebhook.ID)
+}
+
+// GetDefaultBranch returns name of default branch in the given repository
+func (g *GiteaClient) GetDefaultBranch(repoUrl string) (string, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+	return g.getDefaultBranch(owner, repository)
+}
+
+// DeleteBranch deletes given branch from repository
+func (g *GiteaClient) DeleteBranch(repoUrl, branchName string) (bool, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+	return g.deleteBranch(owner, repository, branchName)
+}
+
+// GetBranchSha returns SHA of top commit in the given branch
+// If branch name is empty, default branch is used.
+func (g *GiteaClient) GetBranchSha(repoUrl, branchName string) (string, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// If branch is not specified, use default branch
+	if branchName == "" {
+		defaultBranchName, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return "", err
+		}
+		branchName = defaultBranchName
+	}
+
+	branch, err := g.getBranch(owner, repository, branchName)
+	if err != nil {
+		return "", err
+	}
+	if branch == nil {
+		return "", fmt.Errorf("branch %s not found", branchName)
+	}
+	if branch.Commit == nil {
+		return "", fmt.Errorf("unexpected response while getting branch top commit SHA")
+	}
+	return branch.Commit.ID, nil
+}
+
+// IsFileExist check whether given file exists in the given branch of the reposiotry.
+// If branch is empty string, default branch is used.
+func (g *GiteaClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	if branchName == "" {
+		var err error
+		branchName, err = g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	directory := filepath.Dir(filePath)
+	files, err := g.filesExistInDirectory(owner, repository, branchName, directory, []gp.RepositoryFile{{FullPath: filePath}})
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (g *GiteaClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	if revision == "" {
+		var err error
+		revision, err = g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileContent, resp, err := g.client.GetFile(owner, repository, revision, filePath)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return fileContent, nil
+}
+
+// IsRepositoryPublic returns true if the repository could be accessed without authentication
+func (g *GiteaClient) IsRepositoryPublic(repoUrl string) (bool, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	repoInfo, err := g.getRepositoryInfo(owner, repository)
+	if err != nil {
+		return false, err
+	}
+	if repoInfo == nil {
+		return false, nil
+	}
+	// Internal repositories are visible to signed-in users only
+	return !repoInfo.Private && !repoInfo.Internal, nil
+}
+
+// GetBrowseRepositoryAtShaLink returns web URL of repository state at given SHA
+func (g *GiteaClient) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	repoUrl = strings.TrimSuffix(repoUrl, ".git")
+	gitSourceUrlParts := strings.Split(repoUrl, "/")
+	gitProviderHost := "https://" + gitSourceUrlParts[2]
+	owner := gitSourceUrlParts[3]
+	repository := gitSourceUrlParts[4]
+
+	return fmt.Sprintf("%s/%s/%s/src/commit/%s", gitProviderHost, owner, repository, sha)
+}
+
+func (g *GiteaClient) GetConfiguredGitAppName() (string, string, error) {
+	return "", "", fmt.Errorf("Gitea does not support applications")
+}
+
+// newGiteaClient creates client for the Gitea instance with the given URL.
+// Gitea doesn't have a public instance that could be used by default, so the URL is mandatory.
+func newGiteaClient(accessToken, baseUrl string) (*GiteaClient, error) {
+	if baseUrl == "" {
+		return nil, fmt.Errorf("Gitea instance URL is required to create Gitea client")
+	}
+	// The client appends API path to the instance URL
+	baseUrl = strings.TrimSuffix(strings.TrimSuffix(baseUrl, "/"), "/api/v1")
+
+	c, err := newGiteaSdkClient(accessToken, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &GiteaClient{client: c, baseUrl: baseUrl, accessToken: accessToken}, nil
+}
+
+func newGiteaSdkClient(accessToken, baseUrl string, options ...gitea.ClientOption) (*gitea.Client, error) {
+	// Do not query the server version on client creation, the client is used for a few requests only
+	options = append([]gitea.ClientOption{gitea.SetToken(accessToken), gitea.SetGiteaVersion("")}, options...)
+	return gitea.NewClient(baseUrl, options...)
+}
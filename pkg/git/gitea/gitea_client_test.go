@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+const (
+	testRepoUrl       = "https://gitea.example.com/owner/repository"
+	testRepoApiPrefix = "/api/v1/repos/owner/repository"
+)
+
+// newTestClient returns Gitea client which talks to a local HTTP server with the given handler.
+func newTestClient(t *testing.T, mux *http.ServeMux) *GiteaClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewGiteaClient("access-token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func writeJson(t *testing.T, w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewGiteaClient(t *testing.T) {
+	if _, err := NewGiteaClient("token", ""); err == nil {
+		t.Errorf("expected error if Gitea URL is not set")
+	}
+	if _, err := NewGiteaClient("token", "https://gitea.example.com/api/v1/"); err != nil {
+		t.Errorf("failed to create client: %v", err)
+	}
+}
+
+func TestIsBranchProtected(t *testing.T) {
+	tests := []struct {
+		name        string
+		protected   bool
+		userCanPush bool
+		expected    bool
+	}{
+		{name: "should detect protected branch", protected: true, userCanPush: false, expected: true},
+		{name: "should allow push into protected branch if user is allowed", protected: true, userCanPush: true, expected: false},
+		{name: "should allow push into not protected branch", protected: false, userCanPush: true, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc(testRepoApiPrefix+"/branches/main", func(w http.ResponseWriter, r *http.Request) {
+				writeJson(t, w, map[string]interface{}{"name": "main", "protected": tt.protected, "user_can_push": tt.userCanPush})
+			})
+
+			client := newTestClient(t, mux)
+			isProtected, err := client.IsBranchProtected(testRepoUrl, "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isProtected != tt.expected {
+				t.Errorf("expected protected to be %t", tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsRepositoryPublic(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/owner/public", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(t, w, map[string]interface{}{"private": false})
+	})
+	mux.HandleFunc("/api/v1/repos/owner/private", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(t, w, map[string]interface{}{"private": true})
+	})
+	mux.HandleFunc("/api/v1/repos/owner/internal", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(t, w, map[string]interface{}{"private": false, "internal": true})
+	})
+
+	client := newTestClient(t, mux)
+	tests := []struct {
+		repoUrl  string
+		isPublic bool
+	}{
+		{repoUrl: "https://gitea.example.com/owner/public", isPublic: true},
+		{repoUrl: "https://gitea.example.com/owner/private.git", isPublic: false},
+		{repoUrl: "https://gitea.example.com/owner/internal", isPublic: false},
+		{repoUrl: "https://gitea.example.com/owner/missing", isPublic: false},
+	}
+	for _, tt := range tests {
+		isPublic, err := client.IsRepositoryPublic(tt.repoUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isPublic != tt.isPublic {
+			t.Errorf("repository %s: expected public=%t", tt.repoUrl, tt.isPublic)
+		}
+	}
+}
+
+func TestGetBrowseRepositoryAtShaLink(t *testing.T) {
+	client := &GiteaClient{}
+	link := client.GetBrowseRepositoryAtShaLink("https://gitea.example.com/owner/repository.git", "1234abcd")
+	if link != "https://gitea.example.com/owner/repository/src/commit/1234abcd" {
+		t.Errorf("unexpected link: %s", link)
+	}
+}
+
+func TestRefineGitHostingServiceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJson(t, w, map[string]string{"message": "token is required"})
+	}))
+	defer server.Close()
+
+	client, err := NewGiteaClient("wrong-token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetDefaultBranch(testRepoUrl)
+	boErr, ok := err.(*boerrors.BuildOpError)
+	if !ok {
+		t.Fatalf("expected BuildOpError, got %v", err)
+	}
+	if boErr.GetErrorId() != int(boerrors.EGiteaTokenUnauthorized) {
+		t.Errorf("unexpected error id: %d", boErr.GetErrorId())
+	}
+}
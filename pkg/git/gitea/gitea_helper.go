@@ -0,0 +1,395 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+func getOwnerAndRepoFromUrl(repoUrl string) (owner string, repository string) {
+	// https://gitea.host/owner/repository
+	gitSourceUrlParts := strings.Split(strings.TrimSuffix(repoUrl, ".git"), "/")
+	owner = gitSourceUrlParts[3]
+	repository = gitSourceUrlParts[4]
+	return owner, repository
+}
+
+// refineGitHostingServiceError generates expected permanent error from Gitea response.
+// If no one is detected, the original error will be returned.
+// refineGitHostingServiceError should be called just after every Gitea API call.
+func refineGitHostingServiceError(response *gitea.Response, originErr error) error {
+	// Gitea SDK does not return a response object if the error is not related to an HTTP request.
+	if response == nil || response.Response == nil || originErr == nil {
+		return originErr
+	}
+	switch response.StatusCode {
+	case 401:
+		return boerrors.NewBuildOpError(boerrors.EGiteaTokenUnauthorized, originErr)
+	case 403:
+		return boerrors.NewBuildOpError(boerrors.EGiteaTokenInsufficientScope, originErr)
+	default:
+		return originErr
+	}
+}
+
+// refinePushRejectedError converts error of a commit, which Gitea refused because the branch is protected,
+// into push rejected error. Gitea responds with forbidden status in such case.
+func refinePushRejectedError(err error) error {
+	if boErr, ok := err.(*boerrors.BuildOpError); ok && boErr.GetErrorId() == int(boerrors.EGiteaTokenInsufficientScope) {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	}
+	if strings.Contains(strings.ToLower(err.Error()), "protected branch") {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	}
+	return err
+}
+
+func isNotFound(response *gitea.Response) bool {
+	return response != nil && response.Response != nil && response.StatusCode == 404
+}
+
+func (g *GiteaClient) getBranch(owner, repository, branchName string) (*gitea.Branch, error) {
+	branch, resp, err := g.client.GetRepoBranch(owner, repository, branchName)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return branch, nil
+}
+
+func (g *GiteaClient) branchExist(owner, repository, branchName string) (bool, error) {
+	branch, err := g.getBranch(owner, repository, branchName)
+	if err != nil {
+		return false, err
+	}
+	return branch != nil, nil
+}
+
+func (g *GiteaClient) createBranch(owner, repository, branchName, baseBranchName string) error {
+	opts := gitea.CreateBranchOption{
+		BranchName:    branchName,
+		OldBranchName: baseBranchName,
+	}
+	_, resp, err := g.client.CreateBranch(owner, repository, opts)
+	return refineGitHostingServiceError(resp, err)
+}
+
+// deleteBranch deletes given branch.
+// Returns false if the branch doesn't exist.
+func (g *GiteaClient) deleteBranch(owner, repository, branchName string) (bool, error) {
+	// Gitea SDK doesn't return an error for unexpected response status, only reports whether the branch was deleted
+	deleted, resp, err := g.client.DeleteRepoBranch(owner, repository, branchName)
+	if deleted {
+		return true, nil
+	}
+	if isNotFound(resp) {
+		return false, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("failed to delete branch %s", branchName)
+	}
+	return false, refineGitHostingServiceError(resp, err)
+}
+
+func (g *GiteaClient) getDefaultBranch(owner, repository string) (string, error) {
+	repoInfo, err := g.getRepositoryInfo(owner, repository)
+	if err != nil {
+		return "", err
+	}
+	if repoInfo == nil {
+		return "", fmt.Errorf("repository info is empty in Gitea API response")
+	}
+	return repoInfo.DefaultBranch, nil
+}
+
+// getRepositoryInfo returns repository information or nil if the repository doesn't exist.
+func (g *GiteaClient) getRepositoryInfo(owner, repository string) (*gitea.Repository, error) {
+	repoInfo, resp, err := g.client.GetRepo(owner, repository)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return repoInfo, nil
+}
+
+// getFileInfo returns metadata of the given file or nil if the file doesn't exist.
+func (g *GiteaClient) getFileInfo(owner, repository, branchName, filePath string) (*gitea.ContentsResponse, error) {
+	fileInfo, resp, err := g.client.GetContents(owner, repository, branchName, filePath)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, nil
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return fileInfo, nil
+}
+
+func (g *GiteaClient) filesUpToDate(owner, repository, branchName string, files []gp.RepositoryFile) (bool, error) {
+	for _, file := range files {
+		fileContent, resp, err := g.client.GetFile(owner, repository, branchName, file.FullPath)
+		if err != nil {
+			if isNotFound(resp) {
+				return false, nil
+			}
+			return false, refineGitHostingServiceError(resp, err)
+		}
+		if !bytes.Equal(fileContent, file.Content) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// filesExistInDirectory checks if given files exist under specified directory.
+// Returns subset of given files which exist.
+func (g *GiteaClient) filesExistInDirectory(owner, repository, branchName, directoryPath string, files []gp.RepositoryFile) ([]gp.RepositoryFile, error) {
+	existingFiles := make([]gp.RepositoryFile, 0, len(files))
+
+	dirContent, resp, err := g.client.ListContents(owner, repository, branchName, directoryPath)
+	if err != nil {
+		if isNotFound(resp) {
+			return existingFiles, nil
+		}
+		return existingFiles, refineGitHostingServiceError(resp, err)
+	}
+
+	for _, file := range dirContent {
+		if file.Type != "file" {
+			continue
+		}
+		for _, f := range files {
+			if file.Path == f.FullPath {
+				existingFiles = append(existingFiles, gp.RepositoryFile{FullPath: file.Path})
+				break
+			}
+		}
+	}
+
+	return existingFiles, nil
+}
+
+func getFileOptions(branchName, commitMessage, authorName, authorEmail string) gitea.FileOptions {
+	author := gitea.Identity{Name: authorName, Email: authorEmail}
+	return gitea.FileOptions{
+		Message:    commitMessage,
+		BranchName: branchName,
+		Author:     author,
+		Committer:  author,
+	}
+}
+
+// commitFilesIntoBranch creates or updates given files in the specified branch.
+// Gitea contents API changes one file per request, so a commit is created for each modified file.
+func (g *GiteaClient) commitFilesIntoBranch(owner, repository, branchName, commitMessage, authorName, authorEmail string, files []gp.RepositoryFile) error {
+	fileOpts := getFileOptions(branchName, commitMessage, authorName, authorEmail)
+
+	for _, file := range files {
+		// Detect file action: update or create
+		fileInfo, err := g.getFileInfo(owner, repository, branchName, file.FullPath)
+		if err != nil {
+			return err
+		}
+		content := base64.StdEncoding.EncodeToString(file.Content)
+
+		if fileInfo == nil {
+			opts := gitea.CreateFileOptions{
+				FileOptions: fileOpts,
+				Content:     content,
+			}
+			_, resp, err := g.client.CreateFile(owner, repository, file.FullPath, opts)
+			if err != nil {
+				return refineGitHostingServiceError(resp, err)
+			}
+			continue
+		}
+
+		if fileInfo.Content != nil && *fileInfo.Content == content {
+			// The file is up to date
+			continue
+		}
+		opts := gitea.UpdateFileOptions{
+			FileOptions: fileOpts,
+			SHA:         fileInfo.SHA,
+			Content:     content,
+		}
+		_, resp, err := g.client.UpdateFile(owner, repository, file.FullPath, opts)
+		if err != nil {
+			return refineGitHostingServiceError(resp, err)
+		}
+	}
+	return nil
+}
+
+// addDeleteCommitToBranch deletes given files from the specified branch.
+// Gitea contents API changes one file per request, so a commit is created for each deleted file.
+func (g *GiteaClient) addDeleteCommitToBranch(owner, repository, branchName, authorName, authorEmail, commitMessage string, files []gp.RepositoryFile) error {
+	fileOpts := getFileOptions(branchName, commitMessage, authorName, authorEmail)
+
+	for _, file := range files {
+		fileInfo, err := g.getFileInfo(owner, repository, branchName, file.FullPath)
+		if err != nil {
+			return err
+		}
+		if fileInfo == nil {
+			// Nothing to delete
+			continue
+		}
+
+		opts := gitea.DeleteFileOptions{
+			FileOptions: fileOpts,
+			SHA:         fileInfo.SHA,
+		}
+		resp, err := g.client.DeleteFile(owner, repository, file.FullPath, opts)
+		if err != nil {
+			return refineGitHostingServiceError(resp, err)
+		}
+	}
+	return nil
+}
+
+// findPullRequestByBranches searches for an opened pull request within repository by current and target (base) branch.
+func (g *GiteaClient) findPullRequestByBranches(owner, repository, branchName, baseBranchName string) (*gitea.PullRequest, error) {
+	opts := gitea.ListPullRequestsOptions{
+		ListOptions: gitea.ListOptions{Page: 1, PageSize: 50},
+		State:       gitea.StateOpen,
+	}
+	var found []*gitea.PullRequest
+	for {
+		prs, resp, err := g.client.ListRepoPullRequests(owner, repository, opts)
+		if err != nil {
+			return nil, refineGitHostingServiceError(resp, err)
+		}
+		for _, pr := range prs {
+			if pr.Head != nil && pr.Base != nil && pr.Head.Ref == branchName && pr.Base.Ref == baseBranchName &&
+				pr.Head.RepoID == pr.Base.RepoID {
+				found = append(found, pr)
+			}
+		}
+		if len(prs) < opts.PageSize {
+			break
+		}
+		opts.Page++
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	default:
+		return nil, fmt.Errorf("failed to find pull request by branch %s: %d matches found", branchName, len(found))
+	}
+}
+
+// closePullRequest closes the given pull request without merge.
+func (g *GiteaClient) closePullRequest(owner, repository string, pr *gitea.PullRequest) error {
+	closedState := gitea.StateClosed
+	opts := gitea.EditPullRequestOption{
+		// Title and body are always sent, keep them as is
+		Title: pr.Title,
+		Body:  pr.Body,
+		State: &closedState,
+	}
+	_, resp, err := g.client.EditPullRequest(owner, repository, pr.Index, opts)
+	return refineGitHostingServiceError(resp, err)
+}
+
+// createPullRequestWithinRepository create a new pull request into the same repository.
+// Returns url to the created pull request.
+func (g *GiteaClient) createPullRequestWithinRepository(owner, repository, branchName, baseBranchName, prTitle, prText string) (string, error) {
+	opts := gitea.CreatePullRequestOption{
+		Head:  branchName,
+		Base:  baseBranchName,
+		Title: prTitle,
+		Body:  prText,
+	}
+	pr, resp, err := g.client.CreatePullRequest(owner, repository, opts)
+	if err != nil {
+		return "", refineGitHostingServiceError(resp, err)
+	}
+	return pr.HTMLURL, nil
+}
+
+// getWebhookByTargetUrl returns webhook by its target url or nil if such webhook doesn't exist.
+func (g *GiteaClient) getWebhookByTargetUrl(owner, repository, webhookTargetUrl string) (*gitea.Hook, error) {
+	// Suppose that the repository does not have more than 50 webhooks
+	opts := gitea.ListHooksOptions{ListOptions: gitea.ListOptions{Page: 1, PageSize: 50}}
+	webhooks, resp, err := g.client.ListRepoHooks(owner, repository, opts)
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	for _, webhook := range webhooks {
+		if webhook.Config["url"] == webhookTargetUrl {
+			return webhook, nil
+		}
+	}
+	// Webhook with the given URL not found
+	return nil, nil
+}
+
+func (g *GiteaClient) createPaCWebhook(owner, repository, webhookTargetUrl, webhookSecret string) error {
+	opts := gitea.CreateHookOption{
+		Type:   gitea.HookTypeGitea,
+		Config: getPaCWebhookConfig(webhookTargetUrl, webhookSecret),
+		Events: appStudioPaCWebhookEvents[:],
+		Active: true,
+	}
+	_, resp, err := g.client.CreateRepoHook(owner, repository, opts)
+	return refineGitHostingServiceError(resp, err)
+}
+
+func (g *GiteaClient) updatePaCWebhook(owner, repository string, webhookId int64, webhookTargetUrl, webhookSecret string) error {
+	active := true
+	opts := gitea.EditHookOption{
+		Config: getPaCWebhookConfig(webhookTargetUrl, webhookSecret),
+		Events: appStudioPaCWebhookEvents[:],
+		Active: &active,
+	}
+	resp, err := g.client.EditRepoHook(owner, repository, webhookId, opts)
+	return refineGitHostingServiceError(resp, err)
+}
+
+func (g *GiteaClient) deleteWebhook(owner, repository string, webhookId int64) error {
+	resp, err := g.client.DeleteRepoHook(owner, repository, webhookId)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil
+		}
+		return refineGitHostingServiceError(resp, err)
+	}
+	return nil
+}
+
+func getPaCWebhookConfig(webhookTargetUrl, webhookSecret string) map[string]string {
+	return map[string]string{
+		"url":          webhookTargetUrl,
+		"content_type": webhookContentType,
+		"secret":       webhookSecret,
+	}
+}
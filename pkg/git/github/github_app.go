@@ -27,90 +27,74 @@ import (
 	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v45/github"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
 )
 
 // Allow mocking for tests
-var NewGithubClientByApp func(appId int64, privateKeyPem []byte, repoUrl string) (*GithubClient, error) = newGithubClientByApp
-var NewGithubClientForSimpleBuildByApp func(appId int64, privateKeyPem []byte) (*GithubClient, error) = newGithubClientForSimpleBuildByApp
+var NewGithubClientByApp func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*GithubClient, error) = newGithubClientByApp
+var NewGithubClientForSimpleBuildByApp func(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string) (*GithubClient, error) = newGithubClientForSimpleBuildByApp
 
 var IsAppInstalledIntoRepository func(ghclient *GithubClient, repoUrl string) (bool, error) = isAppInstalledIntoRepository
-var GetAppInstallations func(githubAppIdStr string, appPrivateKeyPem []byte) ([]ApplicationInstallation, string, error) = getAppInstallations
-
-func newGithubClientByApp(appId int64, privateKeyPem []byte, repoUrl string) (*GithubClient, error) {
-	owner, _ := getOwnerAndRepoFromUrl(repoUrl)
+var GetAppInstallations func(ctx context.Context, githubAppIdStr string, appPrivateKeyPem []byte, baseUrl string) ([]ApplicationInstallation, string, error) = getAppInstallations
 
+// newAppsClient creates go-github client authenticated as GitHub Application.
+// If base URL is empty, github.com is used.
+func newAppsClient(appId int64, privateKeyPem []byte, baseUrl string) (*github.Client, error) {
 	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
 	if err != nil {
 		// Inability to create transport based on a private key indicates that the key is bad formatted
 		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
-
-	var installId int64
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
-	}
-	for installId == 0 {
-		installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
-		if err != nil {
-			if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
-				switch resp.StatusCode {
-				case 401:
-					return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
-				case 404:
-					return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppDoesNotExist, err)
-				}
-			}
-			return nil, boerrors.NewBuildOpError(boerrors.ETransientError, err)
-		}
-		for _, val := range installations {
-			if strings.EqualFold(val.GetAccount().GetLogin(), owner) {
-				installId = val.GetID()
-				break
-			}
-		}
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
-	}
-	if installId == 0 {
-		err := fmt.Errorf("unable to find GitHub InstallationID for user %s", owner)
-		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, err)
+	client, err := newGithubApiClient(&http.Client{Transport: ratelimit.NewTransport(itr)}, baseUrl)
+	if err != nil {
+		return nil, err
 	}
-	// The user has the application installed,
-	// but it doesn't guarantee that the application is installed into all user's repositories.
+	// The transport has to talk to the same GitHub instance as the client
+	itr.BaseURL = strings.TrimSuffix(client.BaseURL.String(), "/")
+	return client, nil
+}
+
+// newGithubClientByApp creates GitHub client based on a token of the application installation
+// which the given repository belongs to.
+func newGithubClientByApp(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*GithubClient, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
 
-	token, _, err := client.Apps.CreateInstallationToken(
-		context.Background(),
-		installId,
-		&github.InstallationTokenOptions{})
+	client, err := appClients.getAppsClient(appId, privateKeyPem, baseUrl)
 	if err != nil {
-		// TODO analyze the error
 		return nil, err
 	}
 
-	githubClient := NewGithubClient(token.GetToken())
-	githubClient.appId = appId
-	githubClient.appPrivateKeyPem = privateKeyPem
-	return githubClient, nil
+	installation, resp, err := client.Apps.FindRepositoryInstallation(ctx, owner, repository)
+	if err != nil {
+		if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
+			switch resp.StatusCode {
+			case 401:
+				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppPrivateKeyNotMatched, err)
+			case 404:
+				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled,
+					fmt.Errorf("unable to find GitHub InstallationID for repository %s/%s: %w", owner, repository, err))
+			}
+		}
+		return nil, boerrors.NewBuildOpError(boerrors.ETransientError, err)
+	}
+	// The installation is found only if the application is enabled for the repository
+
+	return appClients.getInstallationClient(ctx, appId, privateKeyPem, baseUrl, installation.GetID())
 }
 
 // newGithubClientForSimpleBuildByApp creates GitHub client based on an installation token.
 // The installation token is generated based on a randomly picked app installation.
 // This tricky approach is required for simple builds to make requests to GitHub API. Otherwise, rate limit will be hit.
-func newGithubClientForSimpleBuildByApp(appId int64, privateKeyPem []byte) (*GithubClient, error) {
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appId, privateKeyPem)
+func newGithubClientForSimpleBuildByApp(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string) (*GithubClient, error) {
+	client, err := appClients.getAppsClient(appId, privateKeyPem, baseUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return nil, err
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 
 	opt := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
-	installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
+	installations, resp, err := client.Apps.ListInstallations(ctx, &opt.ListOptions)
 	if err != nil {
 		if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
 			switch resp.StatusCode {
@@ -128,24 +112,15 @@ func newGithubClientForSimpleBuildByApp(appId int64, privateKeyPem []byte) (*Git
 	}
 	installId := installations[rand.Intn(len(installations))].GetID()
 
-	token, _, err := client.Apps.CreateInstallationToken(
-		context.Background(),
-		installId,
-		&github.InstallationTokenOptions{})
-	if err != nil {
-		// TODO analyze the error
-		return nil, err
-	}
-
-	return NewGithubClient(token.GetToken()), nil
+	return appClients.getInstallationClient(ctx, appId, privateKeyPem, baseUrl, installId)
 }
 
 // IsAppInstalledIntoRepository finds out if the application is installed into given repository.
 // The application is identified by it's installation token, i.e. the client itself must be created
 // from an application installation token. See newGithubClientByApp for details.
 // This method should be used only with clients created by newGithubClientByApp.
-func (g *GithubClient) IsAppInstalledIntoRepository(repoUrl string) (bool, error) {
-	return IsAppInstalledIntoRepository(g, repoUrl)
+func (g *GithubClient) IsAppInstalledIntoRepository(ctx context.Context, repoUrl string) (bool, error) {
+	return IsAppInstalledIntoRepository(g.withContext(ctx), repoUrl)
 }
 
 func isAppInstalledIntoRepository(ghclient *GithubClient, repoUrl string) (bool, error) {
@@ -176,12 +151,10 @@ func isAppInstalledIntoRepository(ghclient *GithubClient, repoUrl string) (bool,
 func (g *GithubClient) GetConfiguredGitAppName() (string, string, error) {
 	g.ensureAppConfigured()
 
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, g.appId, g.appPrivateKeyPem)
+	client, err := appClients.getAppsClient(g.appId, g.appPrivateKeyPem, g.baseUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return "", "", boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return "", "", err
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 	githubApp, _, err := client.Apps.Get(g.ctx, "")
 	if err != nil {
 		return "", "", err
@@ -200,35 +173,42 @@ func (g *GithubClient) ensureAppConfigured() {
 }
 
 type ApplicationInstallation struct {
-	Token        string
-	ID           int64
+	Token string
+	ID    int64
+	// ApiUrl is the API endpoint of the GitHub Enterprise instance the installation belongs to
+	// or empty string for github.com.
+	ApiUrl       string
 	Repositories []*github.Repository
 }
 
-func getAppInstallations(githubAppIdStr string, appPrivateKeyPem []byte) ([]ApplicationInstallation, string, error) {
+// getAppInstallations returns all installations of the application together with their repositories and the application slug.
+// If base URL is empty, the application is looked up in github.com.
+func getAppInstallations(ctx context.Context, githubAppIdStr string, appPrivateKeyPem []byte, baseUrl string) ([]ApplicationInstallation, string, error) {
 	githubAppId, err := strconv.ParseInt(githubAppIdStr, 10, 64)
 	if err != nil {
 		return nil, "", boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedId,
 			fmt.Errorf("failed to convert %s to int: %w", githubAppIdStr, err))
 	}
 
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, githubAppId, appPrivateKeyPem)
+	client, err := appClients.getAppsClient(githubAppId, appPrivateKeyPem, baseUrl)
 	if err != nil {
-		// Inability to create transport based on a private key indicates that the key is bad formatted
-		return nil, "", boerrors.NewBuildOpError(boerrors.EGitHubAppMalformedPrivateKey, err)
+		return nil, "", err
+	}
+	var apiUrl string
+	if baseUrl != "" {
+		apiUrl = client.BaseURL.String()
 	}
-	client := github.NewClient(&http.Client{Transport: itr})
 	appInstallations := []ApplicationInstallation{}
 	opt := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
-	githubApp, _, err := client.Apps.Get(context.Background(), "")
+	githubApp, _, err := client.Apps.Get(ctx, "")
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to load GitHub app metadata, %w", err)
 	}
 	slug := (githubApp.GetSlug())
 	for {
-		installations, resp, err := client.Apps.ListInstallations(context.Background(), &opt.ListOptions)
+		installations, resp, err := client.Apps.ListInstallations(ctx, &opt.ListOptions)
 		if err != nil {
 			if resp != nil && resp.Response != nil && resp.Response.StatusCode != 0 {
 				switch resp.StatusCode {
@@ -241,23 +221,29 @@ func getAppInstallations(githubAppIdStr string, appPrivateKeyPem []byte) ([]Appl
 			return nil, "", boerrors.NewBuildOpError(boerrors.ETransientError, err)
 		}
 		for _, val := range installations {
+			// The tokens are passed to renovate jobs, so new tokens with full lifetime are created instead of cached ones
 			token, _, err := client.Apps.CreateInstallationToken(
-				context.Background(),
+				ctx,
 				*val.ID,
 				&github.InstallationTokenOptions{})
 			if err != nil {
 				// TODO analyze the error
 				continue
 			}
-			installationClient := NewGithubClient(token.GetToken())
+			InstallationTokensMintedMetric.Inc()
+			installationClient, err := NewGithubClient(token.GetToken(), baseUrl)
+			if err != nil {
+				continue
+			}
 
-			repositories, err := getRepositoriesFromClient(installationClient)
+			repositories, err := getRepositoriesFromClient(ctx, installationClient)
 			if err != nil {
 				continue
 			}
 			appInstallations = append(appInstallations, ApplicationInstallation{
 				Token:        token.GetToken(),
 				ID:           *val.ID,
+				ApiUrl:       apiUrl,
 				Repositories: repositories,
 			})
 		}
@@ -270,11 +256,11 @@ func getAppInstallations(githubAppIdStr string, appPrivateKeyPem []byte) ([]Appl
 	return appInstallations, slug, nil
 }
 
-func getRepositoriesFromClient(ghClient *GithubClient) ([]*github.Repository, error) {
+func getRepositoriesFromClient(ctx context.Context, ghClient *GithubClient) ([]*github.Repository, error) {
 	opt := &github.ListOptions{PerPage: 100}
 	var repos []*github.Repository
 	for {
-		repoList, resp, err := ghClient.client.Apps.ListRepos(context.TODO(), opt)
+		repoList, resp, err := ghClient.client.Apps.ListRepos(ctx, opt)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// installationTokenRenewalMargin is how long before its expiration a cached installation token is replaced,
+	// so the token doesn't expire in the middle of an operation.
+	installationTokenRenewalMargin = 5 * time.Minute
+	// installationTokenDefaultLifetime is used if GitHub doesn't return expiration time of the token.
+	installationTokenDefaultLifetime = time.Hour
+)
+
+// InstallationTokensMintedMetric counts installation tokens created by the build service.
+// It's registered together with the other build service metrics.
+var InstallationTokensMintedMetric = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "redhat_appstudio",
+	Subsystem: "buildservice",
+	Name:      "github_app_installation_tokens_minted_total",
+	Help:      "Number of GitHub Application installation tokens created.",
+})
+
+// appClients keeps GitHub Application clients and installation tokens between reconciles.
+var appClients = newAppClientCache()
+
+type appKey struct {
+	appId   int64
+	baseUrl string
+	// privateKeyHash distinguishes rotated application private keys
+	privateKeyHash [sha256.Size]byte
+}
+
+type installationKey struct {
+	app            appKey
+	installationId int64
+}
+
+type cachedInstallationClient struct {
+	client    *GithubClient
+	expiresAt time.Time
+}
+
+// appClientCache shares clients authenticated as GitHub Application and clients authenticated by installation tokens.
+// Installation tokens are valid for an hour, so a cached installation client is used until shortly before the token expires.
+type appClientCache struct {
+	mutex sync.Mutex
+
+	appsClients         map[appKey]*github.Client
+	installationClients map[installationKey]*cachedInstallationClient
+
+	now func() time.Time
+}
+
+func newAppClientCache() *appClientCache {
+	return &appClientCache{
+		appsClients:         map[appKey]*github.Client{},
+		installationClients: map[installationKey]*cachedInstallationClient{},
+		now:                 time.Now,
+	}
+}
+
+func newAppKey(appId int64, privateKeyPem []byte, baseUrl string) appKey {
+	return appKey{appId: appId, baseUrl: baseUrl, privateKeyHash: sha256.Sum256(privateKeyPem)}
+}
+
+// getAppsClient returns go-github client authenticated as the given GitHub Application.
+func (c *appClientCache) getAppsClient(appId int64, privateKeyPem []byte, baseUrl string) (*github.Client, error) {
+	key := newAppKey(appId, privateKeyPem, baseUrl)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if client, ok := c.appsClients[key]; ok {
+		return client, nil
+	}
+	client, err := newAppsClient(appId, privateKeyPem, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	c.appsClients[key] = client
+	return client, nil
+}
+
+// getInstallationClient returns client authenticated by a token of the given installation of the GitHub Application.
+// The token is created only if there is no cached one or it's about to expire.
+// The returned client is a copy which could be configured by the caller.
+func (c *appClientCache) getInstallationClient(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string, installationId int64) (*GithubClient, error) {
+	key := installationKey{app: newAppKey(appId, privateKeyPem, baseUrl), installationId: installationId}
+
+	c.mutex.Lock()
+	cached, ok := c.installationClients[key]
+	c.mutex.Unlock()
+	if ok && c.now().Add(installationTokenRenewalMargin).Before(cached.expiresAt) {
+		client := *cached.client
+		return &client, nil
+	}
+
+	appsClient, err := c.getAppsClient(appId, privateKeyPem, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	// Concurrent reconciles could mint a token for the same installation, the last one is cached.
+	token, _, err := appsClient.Apps.CreateInstallationToken(ctx, installationId, &github.InstallationTokenOptions{})
+	if err != nil {
+		// TODO analyze the error
+		return nil, err
+	}
+	InstallationTokensMintedMetric.Inc()
+
+	githubClient, err := NewGithubClient(token.GetToken(), baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	githubClient.appId = appId
+	githubClient.appPrivateKeyPem = privateKeyPem
+
+	expiresAt := c.now().Add(installationTokenDefaultLifetime)
+	if token.ExpiresAt != nil {
+		expiresAt = *token.ExpiresAt
+	}
+
+	c.mutex.Lock()
+	c.removeExpiredInstallationClients()
+	c.installationClients[key] = &cachedInstallationClient{client: githubClient, expiresAt: expiresAt}
+	c.mutex.Unlock()
+
+	client := *githubClient
+	return &client, nil
+}
+
+// removeExpiredInstallationClients evicts clients with expired tokens, so the cache doesn't grow with every installation ever used.
+// Must be called with the mutex locked.
+func (c *appClientCache) removeExpiredInstallationClients() {
+	now := c.now()
+	for key, cached := range c.installationClients {
+		if !now.Before(cached.expiresAt) {
+			delete(c.installationClients, key)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+const testAppId = 12345
+
+func generateTestPrivateKey(t *testing.T) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+}
+
+// newTestAppServer returns GitHub Enterprise server with the application installed into owner/repository.
+// The returned map counts created installation tokens by installation id.
+func newTestAppServer(t *testing.T, tokenLifetime time.Duration) (*httptest.Server, map[int64]int) {
+	mintedTokens := map[int64]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/owner/repository/installation", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJson(t, w, map[string]interface{}{"id": 1})
+	})
+	mux.HandleFunc("/api/v3/repos/owner/other-repository/installation", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		writeTestJson(t, w, map[string]interface{}{"message": "Not Found"})
+	})
+	mux.HandleFunc("/api/v3/app", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJson(t, w, map[string]interface{}{"id": testAppId, "slug": "test-app"})
+	})
+	mux.HandleFunc("/api/v3/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJson(t, w, map[string]interface{}{
+			"total_count":  1,
+			"repositories": []map[string]interface{}{{"name": "repository", "full_name": "owner/repository"}},
+		})
+	})
+	mux.HandleFunc("/api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJson(t, w, []map[string]interface{}{{"id": 2}})
+	})
+	for _, installationId := range []int64{1, 2} {
+		installationId := installationId
+		mux.HandleFunc(fmt.Sprintf("/api/v3/app/installations/%d/access_tokens", installationId), func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			mintedTokens[installationId]++
+			writeTestJson(t, w, map[string]interface{}{
+				"token":      fmt.Sprintf("token-%d-%d", installationId, mintedTokens[installationId]),
+				"expires_at": time.Now().Add(tokenLifetime).Format(time.RFC3339),
+			})
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, mintedTokens
+}
+
+func writeTestJson(t *testing.T, w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resetAppClientCache(t *testing.T) {
+	appClients = newAppClientCache()
+	t.Cleanup(func() { appClients = newAppClientCache() })
+}
+
+func TestNewGithubClientByAppCachesInstallationToken(t *testing.T) {
+	resetAppClientCache(t)
+	server, mintedTokens := newTestAppServer(t, time.Hour)
+	privateKey := generateTestPrivateKey(t)
+	ctx := context.Background()
+	metricValue := testutil.ToFloat64(InstallationTokensMintedMetric)
+
+	client, err := NewGithubClientByApp(ctx, testAppId, privateKey, "https://github.example.com/owner/repository", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.isAppConfigured() {
+		t.Errorf("expected application to be configured in the client")
+	}
+	client.EnableForkMode("forks")
+
+	sameClient, err := NewGithubClientByApp(ctx, testAppId, privateKey, "https://github.example.com/owner/repository", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mintedTokens[1] != 1 {
+		t.Errorf("expected the installation token to be created once, created %d times", mintedTokens[1])
+	}
+	if sameClient.client != client.client {
+		t.Errorf("expected go-github client to be shared")
+	}
+	if sameClient.isForkModeEnabled {
+		t.Errorf("configuration of a returned client must not affect the cached client")
+	}
+	if minted := testutil.ToFloat64(InstallationTokensMintedMetric) - metricValue; minted != 1 {
+		t.Errorf("expected 1 minted token in metrics, got %v", minted)
+	}
+
+	// Rotated private key should not reuse the client of the old key
+	if _, err := NewGithubClientByApp(ctx, testAppId, generateTestPrivateKey(t), "https://github.example.com/owner/repository", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if mintedTokens[1] != 2 {
+		t.Errorf("expected new installation token for the new private key")
+	}
+}
+
+func TestNewGithubClientByAppRenewsExpiringToken(t *testing.T) {
+	resetAppClientCache(t)
+	server, mintedTokens := newTestAppServer(t, installationTokenRenewalMargin+time.Minute)
+	privateKey := generateTestPrivateKey(t)
+	ctx := context.Background()
+
+	if _, err := NewGithubClientByApp(ctx, testAppId, privateKey, "https://github.example.com/owner/repository", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGithubClientByApp(ctx, testAppId, privateKey, "https://github.example.com/owner/repository", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if mintedTokens[1] != 1 {
+		t.Errorf("expected the token to be reused before renewal time")
+	}
+
+	appClients.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := NewGithubClientByApp(ctx, testAppId, privateKey, "https://github.example.com/owner/repository", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if mintedTokens[1] != 2 {
+		t.Errorf("expected the token to be renewed shortly before expiration")
+	}
+}
+
+func TestNewGithubClientByAppNotInstalled(t *testing.T) {
+	resetAppClientCache(t)
+	server, mintedTokens := newTestAppServer(t, time.Hour)
+
+	_, err := NewGithubClientByApp(context.Background(), testAppId, generateTestPrivateKey(t), "https://github.example.com/owner/other-repository", server.URL)
+	boErr, ok := err.(*boerrors.BuildOpError)
+	if !ok || boErr.GetErrorId() != int(boerrors.EGitHubAppNotInstalled) {
+		t.Errorf("expected application not installed error, got: %v", err)
+	}
+	if len(mintedTokens) != 0 {
+		t.Errorf("no token should be created")
+	}
+}
+
+func TestNewGithubClientForSimpleBuildByAppCachesInstallationToken(t *testing.T) {
+	resetAppClientCache(t)
+	server, mintedTokens := newTestAppServer(t, time.Hour)
+	privateKey := generateTestPrivateKey(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := NewGithubClientForSimpleBuildByApp(context.Background(), testAppId, privateKey, server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mintedTokens[2] != 1 {
+		t.Errorf("expected the installation token to be created once, created %d times", mintedTokens[2])
+	}
+}
+
+func TestGetAppInstallationsFromEnterpriseInstance(t *testing.T) {
+	resetAppClientCache(t)
+	server, mintedTokens := newTestAppServer(t, time.Hour)
+
+	installations, slug, err := GetAppInstallations(context.Background(), fmt.Sprint(testAppId), generateTestPrivateKey(t), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slug != "test-app" {
+		t.Errorf("expected test-app slug, got %s", slug)
+	}
+	if len(installations) != 1 || installations[0].ID != 2 || mintedTokens[2] != 1 {
+		t.Fatalf("expected installation with new token, got %v", installations)
+	}
+	if installations[0].ApiUrl != server.URL+"/api/v3/" {
+		t.Errorf("expected installation API URL of the enterprise instance, got %s", installations[0].ApiUrl)
+	}
+	if len(installations[0].Repositories) != 1 || installations[0].Repositories[0].GetFullName() != "owner/repository" {
+		t.Errorf("expected repositories of the installation to be listed from the enterprise instance")
+	}
+}
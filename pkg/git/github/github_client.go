@@ -19,17 +19,21 @@ package github
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"path/filepath"
 	"strings"
 
 	"github.com/google/go-github/v45/github"
 	"golang.org/x/oauth2"
 
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
 )
 
 // Allow mocking for tests
-var NewGithubClient func(accessToken string) *GithubClient = newGithubClient
+var NewGithubClient func(accessToken, baseUrl string) (*GithubClient, error) = newGithubClient
 
 const (
 	// Allowed values are 'json' and 'form' according to the doc: https://docs.github.com/en/rest/webhooks/repos#create-a-repository-webhook
@@ -41,13 +45,59 @@ var (
 )
 
 var _ gp.GitProviderClient = (*GithubClient)(nil)
+var _ gp.ContextBinder = (*GithubClient)(nil)
 
 type GithubClient struct {
 	ctx    context.Context
 	client *github.Client
+	// baseUrl is GitHub Enterprise instance URL or empty string for github.com
+	baseUrl string
 
 	appId            int64
 	appPrivateKeyPem []byte
+
+	// commitSigner signs created commits, if set
+	commitSigner commitsigning.Signer
+	// isAppCommitSigningEnabled makes GitHub sign created commits on behalf of the application
+	isAppCommitSigningEnabled bool
+
+	// isForkModeEnabled makes merge request branches to be pushed into a fork of the target repository
+	isForkModeEnabled bool
+	// forkOrganization is the organization to create forks in, the token owner account is used if empty
+	forkOrganization string
+	// forkOwner is the resolved owner of the forks
+	forkOwner string
+}
+
+// WithContext returns a shallow copy of the client which makes all its requests within the given context.
+func (g *GithubClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	return g.withContext(ctx), nil
+}
+
+func (g *GithubClient) withContext(ctx context.Context) *GithubClient {
+	client := *g
+	client.ctx = ctx
+	return &client
+}
+
+// SetCommitSigner makes the client sign the commits it creates with the given signer.
+func (g *GithubClient) SetCommitSigner(signer commitsigning.Signer) {
+	g.commitSigner = signer
+}
+
+// EnableAppCommitSigning makes GitHub sign the commits created by the configured application.
+// Such commits have the application as the author, so custom author of merge requests is ignored.
+func (g *GithubClient) EnableAppCommitSigning() {
+	g.isAppCommitSigningEnabled = true
+}
+
+// EnableForkMode makes the client push merge request branches into a fork of the target repository
+// and open cross-repository pull requests. The fork is created in the given organization
+// or in the token owner account if the organization is empty.
+func (g *GithubClient) EnableForkMode(organization string) {
+	g.isForkModeEnabled = true
+	g.forkOrganization = organization
+	g.forkOwner = organization
 }
 
 // EnsurePaCMergeRequest creates or updates existing Pipelines as Code configuration proposal merge request
@@ -73,31 +123,39 @@ func (g *GithubClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestD
 		return "", nil
 	}
 
+	// In fork mode the branch with a proposal is pushed into the fork
+	branchOwner := owner
+	if g.isForkModeEnabled {
+		if branchOwner, err = g.ensureFork(owner, repository); err != nil {
+			return "", err
+		}
+	}
+
 	// Check if branch with a proposal exists
-	branchExists, err := g.branchExist(owner, repository, d.BranchName)
+	branchExists, err := g.branchExist(branchOwner, repository, d.BranchName)
 	if err != nil {
 		return "", err
 	}
 
 	if branchExists {
-		upToDate, err := g.filesUpToDate(owner, repository, d.BranchName, d.Files)
+		upToDate, err := g.filesUpToDate(branchOwner, repository, d.BranchName, d.Files)
 		if err != nil {
 			return "", err
 		}
 		if !upToDate {
 			// Update branch
-			branchRef, err := g.getBranch(owner, repository, d.BranchName)
+			branchRef, err := g.getBranch(branchOwner, repository, d.BranchName)
 			if err != nil {
 				return "", err
 			}
 
-			err = g.addCommitToBranch(owner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
+			err = g.addCommitToBranch(branchOwner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
 			if err != nil {
 				return "", err
 			}
 		}
 
-		pr, err := g.findPullRequestByBranchesWithinRepository(owner, repository, d.BranchName, d.BaseBranchName)
+		pr, err := g.findPullRequestByBranches(owner, repository, branchOwner, d.BranchName, d.BaseBranchName)
 		if err != nil {
 			return "", err
 		}
@@ -105,13 +163,13 @@ func (g *GithubClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestD
 			return *pr.HTMLURL, nil
 		}
 
-		prUrl, err := g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+		prUrl, err := g.createPullRequest(owner, repository, branchOwner, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 		if err != nil {
 			if strings.Contains(err.Error(), "No commits between") {
 				// This could happen when a PR was created and merged, but PR branch was not deleted. Then main was updated.
 				// Current branch has correct configuration, but it's not possible to create a PR,
 				// because current branch reference is included into main branch.
-				if _, err := g.deleteBranch(owner, repository, d.BranchName); err != nil {
+				if _, err := g.deleteBranch(branchOwner, repository, d.BranchName); err != nil {
 					return "", err
 				}
 				return g.EnsurePaCMergeRequest(repoUrl, d)
@@ -121,17 +179,17 @@ func (g *GithubClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestD
 
 	} else {
 		// Create branch, commit and pull request
-		branchRef, err := g.createBranch(owner, repository, d.BranchName, d.BaseBranchName)
+		branchRef, err := g.createMergeRequestBranch(owner, repository, branchOwner, d.BranchName, d.BaseBranchName)
 		if err != nil {
 			return "", err
 		}
 
-		err = g.addCommitToBranch(owner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
+		err = g.addCommitToBranch(branchOwner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
 		if err != nil {
 			return "", err
 		}
 
-		return g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+		return g.createPullRequest(owner, repository, branchOwner, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 	}
 }
 
@@ -159,41 +217,54 @@ func (g *GithubClient) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestDat
 
 	// Need to create PR that deletes PaC configuration of the component
 
+	// In fork mode the branch with a proposal is pushed into the fork
+	branchOwner := owner
+	if g.isForkModeEnabled {
+		if branchOwner, err = g.ensureFork(owner, repository); err != nil {
+			return "", err
+		}
+	}
+
 	// Delete old branch, if any
-	if _, err := g.deleteBranch(owner, repository, d.BranchName); err != nil {
+	if _, err := g.deleteBranch(branchOwner, repository, d.BranchName); err != nil {
 		return "", err
 	}
 
 	// Create branch, commit and pull request
-	branchRef, err := g.createBranch(owner, repository, d.BranchName, d.BaseBranchName)
+	branchRef, err := g.createMergeRequestBranch(owner, repository, branchOwner, d.BranchName, d.BaseBranchName)
 	if err != nil {
 		return "", err
 	}
 
-	err = g.addDeleteCommitToBranch(owner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
+	err = g.addDeleteCommitToBranch(branchOwner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, branchRef)
 	if err != nil {
 		return "", err
 	}
 
-	return g.createPullRequestWithinRepository(owner, repository, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+	return g.createPullRequest(owner, repository, branchOwner, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 }
 
 // FindUnmergedPaCMergeRequest finds out the unmerged merge request that is opened during the component onboarding
 // An onboarding merge request fulfills both:
 // 1) opened based on the base branch which is determined by the Revision or is the default branch of component repository
-// 2) opened from head ref: owner:appstudio-{component.Name}
+// 2) opened from head ref: owner:appstudio-{component.Name}, where owner is the fork owner in fork mode
 // If no onboarding merge request is found, nil is returned.
 func (g *GithubClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
 	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
 
+	branchOwner, err := g.getMergeRequestBranchOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := &github.PullRequestListOptions{
-		Head: fmt.Sprintf("%s:%s", owner, d.BranchName),
+		Head: fmt.Sprintf("%s:%s", branchOwner, d.BranchName),
 		Base: d.BaseBranchName,
 		// Opened pull request is searched by default by GitHub API.
 	}
-	pullRequests, resp, err := g.client.PullRequests.List(context.Background(), owner, repository, opts)
+	pullRequests, resp, err := g.client.PullRequests.List(g.ctx, owner, repository, opts)
 	if err != nil {
-		return nil, refineGitHostingServiceError(resp.Response, err)
+		return nil, refineGitHostingServiceError(resp, err)
 	}
 	if len(pullRequests) == 0 {
 		return nil, nil
@@ -207,6 +278,164 @@ func (g *GithubClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRe
 	}, nil
 }
 
+// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch, if the pull request branch is behind the base branch.
+// The branch is force updated, so the pull request stays open.
+func (g *GithubClient) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return g.refreshPaCMergeRequest(repoUrl, d, false)
+}
+
+// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal pull request
+// from the current top of the base branch with the given files, even if the branch is up to date.
+func (g *GithubClient) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	webUrl, _, err = g.refreshPaCMergeRequest(repoUrl, d, true)
+	return webUrl, err
+}
+
+// refreshPaCMergeRequest recreates the branch of the open proposal pull request if it's behind the base branch or force is set.
+func (g *GithubClient) refreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData, force bool) (webUrl string, refreshed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return "", false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	branchOwner, err := g.getMergeRequestBranchOwner(owner)
+	if err != nil {
+		return "", false, err
+	}
+	pr, err := g.findPullRequestByBranches(owner, repository, branchOwner, d.BranchName, d.BaseBranchName)
+	if err != nil || pr == nil {
+		return "", false, err
+	}
+
+	if !force {
+		isBehind, err := g.isBranchBehind(owner, repository, branchOwner, d.BranchName, d.BaseBranchName)
+		if err != nil || !isBehind {
+			return pr.GetHTMLURL(), false, err
+		}
+	}
+
+	if err := g.resetBranchToBaseWithFiles(owner, repository, branchOwner, d); err != nil {
+		return "", false, err
+	}
+	return pr.GetHTMLURL(), true, nil
+}
+
+// GetMergeRequestStatus returns current state of the pull request with the given web URL in the given repository
+func (g *GithubClient) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	number, err := gp.GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl)
+	if err != nil {
+		return nil, err
+	}
+	pr, resp, err := g.client.PullRequests.Get(g.ctx, owner, repository, number)
+	if err != nil {
+		if resp == nil {
+			return nil, err
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+
+	status := &gp.MergeRequestStatus{
+		State:     gp.MergeRequestStateOpen,
+		Mergeable: pr.Mergeable,
+		CreatedAt: pr.CreatedAt,
+		MergedAt:  pr.MergedAt,
+	}
+	if pr.GetState() == "closed" {
+		if pr.GetMerged() {
+			status.State = gp.MergeRequestStateMerged
+		} else {
+			status.State = gp.MergeRequestStateClosed
+		}
+		return status, nil
+	}
+
+	branchOwner := pr.GetHead().GetUser().GetLogin()
+	if branchOwner == "" {
+		branchOwner = owner
+	}
+	if status.IsBehind, err = g.isBranchBehind(owner, repository, branchOwner, pr.GetHead().GetRef(), pr.GetBase().GetRef()); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch
+func (g *GithubClient) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	upToDate, err := g.filesUpToDate(owner, repository, d.BaseBranchName, d.Files)
+	if err != nil || upToDate {
+		return false, err
+	}
+
+	baseBranchRef, err := g.getBranch(owner, repository, d.BaseBranchName)
+	if err != nil {
+		return false, err
+	}
+	if err := g.addCommitToBranch(owner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, d.Files, baseBranchRef); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch
+func (g *GithubClient) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(owner, repository)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := g.filesExistInDirectory(owner, repository, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil || len(files) == 0 {
+		return false, err
+	}
+
+	baseBranchRef, err := g.getBranch(owner, repository, d.BaseBranchName)
+	if err != nil {
+		return false, err
+	}
+	if err := g.addDeleteCommitToBranch(owner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, files, baseBranchRef); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// IsBranchProtected returns true if the given branch has protection rules.
+// The rules are not evaluated, so a protected branch might still accept pushes of the token owner.
+func (g *GithubClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	branch, resp, err := g.client.Repositories.GetBranch(g.ctx, owner, repository, branchName, true)
+	if err != nil {
+		return false, refineGitHostingServiceError(resp, err)
+	}
+	return branch.GetProtected(), nil
+}
+
 // SetupPaCWebhook creates Pipelines as Code webhook in the given repository
 func (g *GithubClient) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
 	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
@@ -296,10 +525,15 @@ func (g *GithubClient) GetDefaultBranch(repoUrl string) (string, error) {
 	return g.getDefaultBranch(owner, repository)
 }
 
-// DeleteBranch deletes given branch from repository
+// DeleteBranch deletes given branch from repository.
+// In fork mode the branch is deleted from the fork, as only merge request branches are deleted.
 func (g *GithubClient) DeleteBranch(repoUrl, branchName string) (bool, error) {
 	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
-	return g.deleteBranch(owner, repository, branchName)
+	branchOwner, err := g.getMergeRequestBranchOwner(owner)
+	if err != nil {
+		return false, err
+	}
+	return g.deleteBranch(branchOwner, repository, branchName)
 }
 
 // GetBranchSha returns SHA of top commit in the given branch
@@ -329,6 +563,7 @@ func (g *GithubClient) GetBranchSha(repoUrl, branchName string) (string, error)
 
 // IsFileExist check whether given file exists in the given branch of the reposiotry.
 // If branch is empty string, default branch is used.
+// In fork mode, the branch is looked up in the fork if the repository doesn't have it.
 func (g *GithubClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
 	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
 
@@ -338,6 +573,12 @@ func (g *GithubClient) IsFileExist(repoUrl, branchName, filePath string) (bool,
 		if err != nil {
 			return false, err
 		}
+	} else {
+		var err error
+		owner, err = g.resolveBranchOwner(owner, repository, branchName)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	directory := filepath.Dir(filePath)
@@ -348,6 +589,29 @@ func (g *GithubClient) IsFileExist(repoUrl, branchName, filePath string) (bool,
 	return len(files) > 0, nil
 }
 
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (g *GithubClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
+
+	opts := &github.RepositoryContentGetOptions{
+		Ref: revision,
+	}
+	fileContentReader, resp, err := g.client.Repositories.DownloadContents(g.ctx, owner, repository, filePath, opts)
+	if err != nil {
+		// It's not clear when it returns 404 or 200 with the error message. Check both.
+		if (resp != nil && resp.StatusCode == 404) || strings.Contains(err.Error(), "no file named") {
+			return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+		}
+		if resp == nil {
+			return nil, err
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	defer fileContentReader.Close()
+	return io.ReadAll(fileContentReader)
+}
+
 // IsRepositoryPublic returns true if the repository could be accessed without authentication
 func (g *GithubClient) IsRepositoryPublic(repoUrl string) (bool, error) {
 	owner, repository := getOwnerAndRepoFromUrl(repoUrl)
@@ -373,16 +637,37 @@ func (g *GithubClient) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string
 	return fmt.Sprintf("%s/%s/%s?rev=%s", gitProviderHost, owner, repository, sha)
 }
 
-func newGithubClient(accessToken string) *GithubClient {
-	gh := &GithubClient{}
+// newGithubClient creates GitHub client for the given instance.
+// If base URL is empty, github.com is used.
+func newGithubClient(accessToken, baseUrl string) (*GithubClient, error) {
+	gh := &GithubClient{baseUrl: baseUrl}
 	gh.ctx = context.TODO()
 
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
 	tc := oauth2.NewClient(gh.ctx, ts)
+	tc.Transport = ratelimit.NewTransport(tc.Transport)
+
+	client, err := newGithubApiClient(tc, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	gh.client = client
 
-	gh.client = github.NewClient(tc)
+	return gh, nil
+}
 
-	return gh
+// newGithubApiClient creates go-github client that uses given HTTP client.
+// If base URL is not empty, the client is configured for the GitHub Enterprise instance,
+// API path is appended to the base URL if missing.
+func newGithubApiClient(httpClient *http.Client, baseUrl string) (*github.Client, error) {
+	if baseUrl == "" {
+		return github.NewClient(httpClient), nil
+	}
+	client, err := github.NewEnterpriseClient(baseUrl, baseUrl, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub Enterprise client for %s: %w", baseUrl, err)
+	}
+	return client, nil
 }
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const testRepoUrl = "https://github.example.com/owner/repository"
+
+func newTestGithubClient(t *testing.T, handler http.HandlerFunc) gp.GitProviderClientWithContext {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewGithubClient("token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gp.NewClientWithContext(client)
+}
+
+func TestGetDefaultBranchRateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	client := newTestGithubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	})
+
+	_, err := client.GetDefaultBranch(context.Background(), testRepoUrl)
+	if err == nil {
+		t.Fatal("expected rate limit error")
+	}
+	if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter < 50*time.Minute || requeueAfter > time.Hour {
+		t.Errorf("expected requeue after the rate limit reset, got %s: %v", requeueAfter, err)
+	}
+}
+
+func TestGetDefaultBranchTimeout(t *testing.T) {
+	client := newTestGithubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Simulate hung git host
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetDefaultBranch(ctx, testRepoUrl)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got: %v", err)
+	}
+}
+
+func TestEnsureForkNotReady(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repos/forks/repository"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name": "repository", "fork": true, "default_branch": "main",
+				"parent": {"full_name": "owner/repository"}, "created_at": "` + time.Now().UTC().Format(time.RFC3339) + `"}`))
+		default:
+			// Git data of the fork is not available yet
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewGithubClient("token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.EnableForkMode("forks")
+
+	_, err = client.ensureFork("owner", "repository")
+	if err == nil {
+		t.Fatal("expected fork not ready error")
+	}
+	if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter != forkReadyRetryDelay {
+		t.Errorf("expected retry after %s, got %s: %v", forkReadyRetryDelay, requeueAfter, err)
+	}
+}
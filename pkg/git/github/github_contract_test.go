@@ -0,0 +1,39 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/fake"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovidertest"
+)
+
+func TestGithubClientContract(t *testing.T) {
+	gitprovidertest.RunContractTests(t, func(t *testing.T, provider *fake.GitProvider) gp.GitProviderClient {
+		server := httptest.NewServer(fake.NewGithubHandler(provider))
+		t.Cleanup(server.Close)
+
+		client, err := NewGithubClient("token", server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+const (
+	// forkReadyRetryDelay is the delay before retry of an operation which waits for a just created fork
+	forkReadyRetryDelay = 30 * time.Second
+	// forkReadyCheckPeriod is the time after fork creation, during which the fork data might not be available yet
+	forkReadyCheckPeriod = 10 * time.Minute
+)
+
+// getForkOwner returns the account to keep forks of target repositories in.
+func (g *GithubClient) getForkOwner() (string, error) {
+	if g.forkOwner != "" {
+		return g.forkOwner, nil
+	}
+	user, resp, err := g.client.Users.Get(g.ctx, "")
+	if err != nil {
+		if resp == nil {
+			return "", err
+		}
+		return "", refineGitHostingServiceError(resp, err)
+	}
+	g.forkOwner = user.GetLogin()
+	return g.forkOwner, nil
+}
+
+// getMergeRequestBranchOwner returns owner of the repository which holds merge request branches.
+// In fork mode it's the fork owner, otherwise the owner of the target repository.
+// Forks always have the same name as the target repository.
+func (g *GithubClient) getMergeRequestBranchOwner(owner string) (string, error) {
+	if !g.isForkModeEnabled {
+		return owner, nil
+	}
+	return g.getForkOwner()
+}
+
+// ensureFork creates fork of the given repository, if it doesn't exist yet.
+// Returns owner of the fork, which is the owner of the given repository if the fork owner owns it.
+func (g *GithubClient) ensureFork(owner, repository string) (string, error) {
+	forkOwner, err := g.getForkOwner()
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(forkOwner, owner) {
+		// It's not possible to fork own repository, nor it is needed
+		return owner, nil
+	}
+
+	fork, err := g.getRepositoryInfo(forkOwner, repository)
+	if err != nil {
+		return "", err
+	}
+	if fork != nil {
+		if !fork.GetFork() || !strings.EqualFold(fork.GetParent().GetFullName(), owner+"/"+repository) {
+			return "", boerrors.NewBuildOpError(boerrors.EForkRepositoryConflict,
+				fmt.Errorf("repository %s/%s is not a fork of %s/%s", forkOwner, repository, owner, repository))
+		}
+	} else {
+		opts := &github.RepositoryCreateForkOptions{Organization: g.forkOrganization}
+		if _, resp, err := g.client.Repositories.CreateFork(g.ctx, owner, repository, opts); err != nil {
+			// The fork is created asynchronously and GitHub responds with 202 Accepted
+			if _, isAccepted := err.(*github.AcceptedError); !isAccepted {
+				if resp == nil {
+					return "", err
+				}
+				return "", refineGitHostingServiceError(resp, err)
+			}
+		}
+		if fork, err = g.getRepositoryInfo(forkOwner, repository); err != nil {
+			return "", err
+		}
+	}
+	if err := g.checkForkReady(forkOwner, repository, fork); err != nil {
+		return "", err
+	}
+	return forkOwner, nil
+}
+
+// checkForkReady checks whether git data of the recently created fork is available.
+// GitHub creates forks asynchronously. Instead of blocking the caller until the fork is ready,
+// transient error is returned, so the operation is retried later.
+func (g *GithubClient) checkForkReady(forkOwner, repository string, fork *github.Repository) error {
+	if fork != nil {
+		if time.Since(fork.GetCreatedAt().Time) > forkReadyCheckPeriod {
+			return nil
+		}
+		if branchExists, err := g.branchExist(forkOwner, repository, fork.GetDefaultBranch()); err == nil && branchExists {
+			return nil
+		}
+	}
+	return boerrors.NewTransientBuildOpError(fmt.Errorf("fork %s/%s is not ready yet", forkOwner, repository), forkReadyRetryDelay)
+}
+
+// createMergeRequestBranch creates the merge request branch from the base branch of the target repository.
+// In fork mode the branch is created in the fork right from the latest commit of the target repository base branch,
+// as forks share git objects with their parent repository. This way the fork doesn't need to be synced.
+func (g *GithubClient) createMergeRequestBranch(owner, repository, branchOwner, branch, baseBranch string) (*github.Reference, error) {
+	if branchOwner == owner {
+		return g.createBranch(owner, repository, branch, baseBranch)
+	}
+
+	baseBranchRef, err := g.getBranch(owner, repository, baseBranch)
+	if err != nil {
+		return nil, err
+	}
+	newBranchRef := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: baseBranchRef.Object.SHA},
+	}
+	ref, resp, err := g.client.Git.CreateRef(g.ctx, branchOwner, repository, newBranchRef)
+	return ref, refineGitHostingServiceError(resp, err)
+}
+
+// resolveBranchOwner returns owner of the repository the given branch should be looked up in.
+// In fork mode merge request branches live in the fork, so a branch that is missing
+// in the target repository is looked up in the fork.
+func (g *GithubClient) resolveBranchOwner(owner, repository, branch string) (string, error) {
+	if !g.isForkModeEnabled {
+		return owner, nil
+	}
+	branchExists, err := g.branchExist(owner, repository, branch)
+	if err != nil || branchExists {
+		return owner, err
+	}
+	return g.getForkOwner()
+}
@@ -18,6 +18,7 @@ package github
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/google/go-github/v45/github"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 )
 
@@ -40,13 +42,21 @@ func getOwnerAndRepoFromUrl(repoUrl string) (owner string, repository string) {
 // refineGitHostingServiceError generates expected permanent error from GitHub response.
 // If no one is detected, the original error will be returned.
 // RefineGitHostingServiceError should be called just after every GitHub API call.
-func refineGitHostingServiceError(response *http.Response, originErr error) error {
-	// go-github APIs do not return a http.Response object if the error is not related to an HTTP request.
-	if response == nil {
+func refineGitHostingServiceError(resp *github.Response, originErr error) error {
+	// go-github APIs do not return a response object if the error is not related to an HTTP request,
+	// for example if the request deadline is exceeded or the rate limited request is not repeated.
+	if resp == nil || resp.Response == nil {
 		return originErr
 	}
-	if _, ok := originErr.(*github.RateLimitError); ok {
-		return boerrors.NewBuildOpError(boerrors.EGitHubReachRateLimit, originErr)
+	response := resp.Response
+	// Rate limits are reset after a while, so retry the operation later
+	var rateLimitErr *github.RateLimitError
+	if errors.As(originErr, &rateLimitErr) {
+		return boerrors.NewTransientBuildOpError(originErr, time.Until(rateLimitErr.Rate.Reset.Time))
+	}
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(originErr, &abuseRateLimitErr) {
+		return boerrors.NewTransientBuildOpError(originErr, abuseRateLimitErr.GetRetryAfter())
 	}
 	switch response.StatusCode {
 	case http.StatusUnauthorized:
@@ -67,11 +77,33 @@ func refineGitHostingServiceError(response *http.Response, originErr error) erro
 	}
 }
 
+// refinePushRejectedError converts error of a branch update, which GitHub refused
+// because of branch protection or repository rules, into push rejected error.
+func refinePushRejectedError(err error) error {
+	var errResponse *github.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.Response == nil {
+		return err
+	}
+	message := strings.ToLower(errResponse.Message)
+	switch errResponse.Response.StatusCode {
+	case http.StatusForbidden:
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		if strings.Contains(message, "protected branch") || strings.Contains(message, "rule violation") {
+			return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+		}
+	}
+	return err
+}
+
 func (g *GithubClient) branchExist(owner, repository, branch string) (bool, error) {
 	_, resp, err := g.client.Git.GetRef(g.ctx, owner, repository, "refs/heads/"+branch)
 	if err == nil {
 		return true, nil
 	}
+	if resp == nil {
+		return false, err
+	}
 	switch resp.StatusCode {
 	case 401:
 		return false, boerrors.NewBuildOpError(boerrors.EGitHubTokenUnauthorized, err)
@@ -84,7 +116,7 @@ func (g *GithubClient) branchExist(owner, repository, branch string) (bool, erro
 
 func (g *GithubClient) getBranch(owner, repository, branch string) (*github.Reference, error) {
 	ref, resp, err := g.client.Git.GetRef(g.ctx, owner, repository, "refs/heads/"+branch)
-	return ref, refineGitHostingServiceError(resp.Response, err)
+	return ref, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) createBranch(owner, repository, branch, baseBranch string) (*github.Reference, error) {
@@ -97,17 +129,17 @@ func (g *GithubClient) createBranch(owner, repository, branch, baseBranch string
 		Object: &github.GitObject{SHA: baseBranchRef.Object.SHA},
 	}
 	ref, resp, err := g.client.Git.CreateRef(g.ctx, owner, repository, newBranchRef)
-	return ref, refineGitHostingServiceError(resp.Response, err)
+	return ref, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) deleteBranch(owner, repository, branch string) (bool, error) {
 	resp, err := g.client.Git.DeleteRef(g.ctx, owner, repository, "refs/heads/"+branch)
 	if err != nil {
-		if resp.Response.StatusCode == 422 {
+		if resp != nil && resp.StatusCode == 422 {
 			// The given branch doesn't exist
 			return false, nil
 		}
-		return false, refineGitHostingServiceError(resp.Response, err)
+		return false, refineGitHostingServiceError(resp, err)
 	}
 	return true, nil
 }
@@ -115,7 +147,7 @@ func (g *GithubClient) deleteBranch(owner, repository, branch string) (bool, err
 func (g *GithubClient) getDefaultBranch(owner, repository string) (string, error) {
 	repositoryInfo, resp, err := g.client.Repositories.Get(g.ctx, owner, repository)
 	if err != nil {
-		return "", refineGitHostingServiceError(resp.Response, err)
+		return "", refineGitHostingServiceError(resp, err)
 	}
 	if repositoryInfo == nil {
 		return "", fmt.Errorf("repository info is empty in GitHub API response")
@@ -132,12 +164,12 @@ func (g *GithubClient) filesUpToDate(owner, repository, branch string, files []g
 		fileContentReader, resp, err := g.client.Repositories.DownloadContents(g.ctx, owner, repository, file.FullPath, opts)
 		if err != nil {
 			// It's not clear when it returns 404 or 200 with the error message. Check both.
-			if resp.StatusCode == 404 || strings.Contains(err.Error(), "no file named") {
+			if (resp != nil && resp.StatusCode == 404) || strings.Contains(err.Error(), "no file named") {
 				// Given file not found
 				return false, nil
 			}
 
-			return false, refineGitHostingServiceError(resp.Response, err)
+			return false, refineGitHostingServiceError(resp, err)
 		}
 		fileContent, err := io.ReadAll(fileContentReader)
 		if err != nil {
@@ -161,6 +193,9 @@ func (g *GithubClient) filesExistInDirectory(owner, repository, branch, director
 	}
 	_, dirContent, resp, err := g.client.Repositories.GetContents(g.ctx, owner, repository, directoryPath, opts)
 	if err != nil {
+		if resp == nil {
+			return existingFiles, err
+		}
 		switch resp.StatusCode {
 		case 401:
 			return existingFiles, boerrors.NewBuildOpError(boerrors.EGitHubTokenUnauthorized, err)
@@ -193,7 +228,7 @@ func (g *GithubClient) createTree(owner, repository string, baseRef *github.Refe
 	}
 
 	tree, resp, err := g.client.Git.CreateTree(g.ctx, owner, repository, *baseRef.Object.SHA, entries)
-	return tree, refineGitHostingServiceError(resp.Response, err)
+	return tree, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) deleteFromTree(owner, repository string, baseRef *github.Reference, files []gp.RepositoryFile) (tree *github.Tree, err error) {
@@ -208,14 +243,14 @@ func (g *GithubClient) deleteFromTree(owner, repository string, baseRef *github.
 	}
 
 	tree, resp, err := g.client.Git.CreateTree(g.ctx, owner, repository, *baseRef.Object.SHA, entries)
-	return tree, refineGitHostingServiceError(resp.Response, err)
+	return tree, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) addCommitToBranch(owner, repository, authorName, authorEmail, commitMessage string, files []gp.RepositoryFile, ref *github.Reference) error {
 	// Get the parent commit to attach the commit to.
 	parent, resp, err := g.client.Repositories.GetCommit(g.ctx, owner, repository, *ref.Object.SHA, nil)
 	if err != nil {
-		return refineGitHostingServiceError(resp.Response, err)
+		return refineGitHostingServiceError(resp, err)
 	}
 	// This is not always populated, but is needed.
 	parent.Commit.SHA = parent.SHA
@@ -226,18 +261,15 @@ func (g *GithubClient) addCommitToBranch(owner, repository, authorName, authorEm
 	}
 
 	// Create the commit using the tree.
-	date := time.Now()
-	author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
-	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}}
-	newCommit, resp, err := g.client.Git.CreateCommit(g.ctx, owner, repository, commit)
+	newCommit, err := g.createCommit(owner, repository, authorName, authorEmail, commitMessage, tree, parent.Commit)
 	if err != nil {
-		return refineGitHostingServiceError(resp.Response, err)
+		return err
 	}
 
 	// Attach the created commit to the given branch.
 	ref.Object.SHA = newCommit.SHA
 	_, resp, err = g.client.Git.UpdateRef(g.ctx, owner, repository, ref, false)
-	return refineGitHostingServiceError(resp.Response, err)
+	return refineGitHostingServiceError(resp, err)
 }
 
 // Creates commit into specified branch that deletes given files.
@@ -245,7 +277,7 @@ func (g *GithubClient) addDeleteCommitToBranch(owner, repository, authorName, au
 	// Get the parent commit to attach the commit to.
 	parent, resp, err := g.client.Repositories.GetCommit(g.ctx, owner, repository, *ref.Object.SHA, nil)
 	if err != nil {
-		return refineGitHostingServiceError(resp.Response, err)
+		return refineGitHostingServiceError(resp, err)
 	}
 	// This is not always populated, but needed.
 	parent.Commit.SHA = parent.SHA
@@ -256,31 +288,101 @@ func (g *GithubClient) addDeleteCommitToBranch(owner, repository, authorName, au
 	}
 
 	// Create the commit using the tree.
-	date := time.Now()
-	author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
-	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}}
-	newCommit, resp, err := g.client.Git.CreateCommit(g.ctx, owner, repository, commit)
+	newCommit, err := g.createCommit(owner, repository, authorName, authorEmail, commitMessage, tree, parent.Commit)
 	if err != nil {
-		return refineGitHostingServiceError(resp.Response, err)
+		return err
 	}
 
 	// Attach the created commit to the given branch.
 	ref.Object.SHA = newCommit.SHA
 	_, resp, err = g.client.Git.UpdateRef(g.ctx, owner, repository, ref, false)
-	return refineGitHostingServiceError(resp.Response, err)
+	return refineGitHostingServiceError(resp, err)
 }
 
-// findPullRequestByBranchesWithinRepository searches for a PR within repository by current and target (base) branch.
-func (g *GithubClient) findPullRequestByBranchesWithinRepository(owner, repository, branchName, baseBranchName string) (*github.PullRequest, error) {
+// createCommit creates commit object with the given tree and parent.
+// The commit is signed if the client is configured so.
+func (g *GithubClient) createCommit(owner, repository, authorName, authorEmail, commitMessage string, tree *github.Tree, parent *github.Commit) (*github.Commit, error) {
+	commit := &github.Commit{Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent}}
+	if !g.isAppCommitSigningEnabled {
+		// GitHub signs only commits without custom author, so set it only if the application doesn't sign the commit.
+		// Dates are truncated to seconds as it's the precision of git, otherwise the signature won't match.
+		date := time.Now().UTC().Truncate(time.Second)
+		author := &github.CommitAuthor{Date: &date, Name: &authorName, Email: &authorEmail}
+		commit.Author = author
+		commit.Committer = author
+
+		if g.commitSigner != nil {
+			identity := commitsigning.CommitIdentity{Name: authorName, Email: authorEmail, When: date}
+			payload := commitsigning.CommitPayload(tree.GetSHA(), []string{parent.GetSHA()}, identity, identity, commitMessage)
+			signature, err := g.commitSigner.Sign(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign commit: %w", err)
+			}
+			commit.Verification = &github.SignatureVerification{Signature: &signature}
+		}
+	}
+
+	newCommit, resp, err := g.client.Git.CreateCommit(g.ctx, owner, repository, commit)
+	if err != nil {
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	return newCommit, nil
+}
+
+// isBranchBehind checks whether the base branch has commits which are not in the given branch.
+// The branch belongs to the repository of the given branch owner, which is the repository itself or its fork.
+func (g *GithubClient) isBranchBehind(owner, repository, branchOwner, branchName, baseBranchName string) (bool, error) {
+	comparison, resp, err := g.client.Repositories.CompareCommits(g.ctx, owner, repository, baseBranchName, branchOwner+":"+branchName, nil)
+	if err != nil {
+		return false, refineGitHostingServiceError(resp, err)
+	}
+	return comparison.GetBehindBy() > 0, nil
+}
+
+// resetBranchToBaseWithFiles replaces content of the merge request branch with single commit
+// which adds the given files on top of the latest commit of the base branch.
+func (g *GithubClient) resetBranchToBaseWithFiles(owner, repository, branchOwner string, d *gp.MergeRequestData) error {
+	baseBranchRef, err := g.getBranch(owner, repository, d.BaseBranchName)
+	if err != nil {
+		return err
+	}
+	parent, resp, err := g.client.Repositories.GetCommit(g.ctx, owner, repository, baseBranchRef.GetObject().GetSHA(), nil)
+	if err != nil {
+		return refineGitHostingServiceError(resp, err)
+	}
+	// This is not always populated, but is needed.
+	parent.Commit.SHA = parent.SHA
+
+	// In fork mode the objects of the target repository are available in the fork too
+	tree, err := g.createTree(branchOwner, repository, baseBranchRef, d.Files)
+	if err != nil {
+		return err
+	}
+	newCommit, err := g.createCommit(branchOwner, repository, d.AuthorName, d.AuthorEmail, d.CommitMessage, tree, parent.Commit)
+	if err != nil {
+		return err
+	}
+
+	branchRef := &github.Reference{
+		Ref:    github.String("refs/heads/" + d.BranchName),
+		Object: &github.GitObject{SHA: newCommit.SHA},
+	}
+	_, resp, err = g.client.Git.UpdateRef(g.ctx, branchOwner, repository, branchRef, true)
+	return refineGitHostingServiceError(resp, err)
+}
+
+// findPullRequestByBranches searches for a PR in the repository by current and target (base) branch.
+// The current branch belongs to the repository of the given head owner, which is the repository itself or its fork.
+func (g *GithubClient) findPullRequestByBranches(owner, repository, headOwner, branchName, baseBranchName string) (*github.PullRequest, error) {
 	opts := &github.PullRequestListOptions{
 		State:       "open",
 		Base:        baseBranchName,
-		Head:        owner + ":" + branchName,
+		Head:        headOwner + ":" + branchName,
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 	prs, resp, err := g.client.PullRequests.List(g.ctx, owner, repository, opts)
 	if err != nil {
-		return nil, refineGitHostingServiceError(resp.Response, err)
+		return nil, refineGitHostingServiceError(resp, err)
 	}
 	switch len(prs) {
 	case 0:
@@ -292,22 +394,24 @@ func (g *GithubClient) findPullRequestByBranchesWithinRepository(owner, reposito
 	}
 }
 
-// createPullRequestWithinRepository create a new pull request into the same repository.
+// createPullRequest create a new pull request into the repository.
+// The pull request branch belongs to the repository of the given head owner, which is the repository itself or its fork.
 // Returns url to the created pull request.
-func (g *GithubClient) createPullRequestWithinRepository(owner, repository, branchName, baseBranchName, prTitle, prText string) (string, error) {
-	branch := fmt.Sprintf("%s:%s", owner, branchName)
+func (g *GithubClient) createPullRequest(owner, repository, headOwner, branchName, baseBranchName, prTitle, prText string) (string, error) {
+	branch := fmt.Sprintf("%s:%s", headOwner, branchName)
 
 	newPRData := &github.NewPullRequest{
-		Title:               &prTitle,
-		Head:                &branch,
-		Base:                &baseBranchName,
-		Body:                &prText,
-		MaintainerCanModify: github.Bool(true),
+		Title: &prTitle,
+		Head:  &branch,
+		Base:  &baseBranchName,
+		Body:  &prText,
+		// GitHub doesn't allow maintainers to modify pull requests from forks owned by organizations
+		MaintainerCanModify: github.Bool(headOwner == owner || g.forkOrganization == ""),
 	}
 
 	pr, resp, err := g.client.PullRequests.Create(g.ctx, owner, repository, newPRData)
 	if err != nil {
-		return "", refineGitHostingServiceError(resp.Response, err)
+		return "", refineGitHostingServiceError(resp, err)
 	}
 
 	return pr.GetHTMLURL(), nil
@@ -319,7 +423,7 @@ func (g *GithubClient) getWebhookByTargetUrl(owner, repository, webhookTargetUrl
 	listOpts := &github.ListOptions{PerPage: 100}
 	webhooks, resp, err := g.client.Repositories.ListHooks(g.ctx, owner, repository, listOpts)
 	if err != nil {
-		return nil, refineGitHostingServiceError(resp.Response, err)
+		return nil, refineGitHostingServiceError(resp, err)
 	}
 
 	for _, webhook := range webhooks {
@@ -333,17 +437,17 @@ func (g *GithubClient) getWebhookByTargetUrl(owner, repository, webhookTargetUrl
 
 func (g *GithubClient) createWebhook(owner, repository string, webhook *github.Hook) (*github.Hook, error) {
 	webhook, resp, err := g.client.Repositories.CreateHook(g.ctx, owner, repository, webhook)
-	return webhook, refineGitHostingServiceError(resp.Response, err)
+	return webhook, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) updateWebhook(owner, repository string, webhook *github.Hook) (*github.Hook, error) {
 	webhook, resp, err := g.client.Repositories.EditHook(g.ctx, owner, repository, *webhook.ID, webhook)
-	return webhook, refineGitHostingServiceError(resp.Response, err)
+	return webhook, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GithubClient) deleteWebhook(owner, repository string, webhookId int64) error {
 	resp, err := g.client.Repositories.DeleteHook(g.ctx, owner, repository, webhookId)
-	if err != nil {
+	if err != nil && resp != nil {
 		switch resp.StatusCode {
 		case 401:
 			return boerrors.NewBuildOpError(boerrors.EGitHubTokenUnauthorized, err)
@@ -360,7 +464,7 @@ func (g *GithubClient) deleteWebhook(owner, repository string, webhookId int64)
 func (g *GithubClient) getRepositoryInfo(owner, repository string) (*github.Repository, error) {
 	repo, resp, err := g.client.Repositories.Get(g.ctx, owner, repository)
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			return nil, nil
 		}
 		return nil, err
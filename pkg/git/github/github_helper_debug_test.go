@@ -17,6 +17,7 @@ limitations under the License.
 package github
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -57,7 +58,7 @@ func createClient(clientType githubClientCreationWay) *GithubClient {
 			return nil
 		}
 		owner, _ := getOwnerAndRepoFromUrl(repoUrl)
-		ghclient, err := NewGithubClientByApp(githubAppId, []byte(githubAppPrivateKey), owner)
+		ghclient, err := NewGithubClientByApp(context.Background(), githubAppId, []byte(githubAppPrivateKey), owner, "")
 		if err != nil {
 			fmt.Printf("error: %v", err)
 		}
@@ -69,14 +70,18 @@ func createClient(clientType githubClientCreationWay) *GithubClient {
 			fmt.Printf("Cannot read private key file by path: %s", githubAppPrivateKeyPath)
 			return nil
 		}
-		ghclient, err := newGithubClientForSimpleBuildByApp(githubAppId, []byte(githubAppPrivateKey))
+		ghclient, err := newGithubClientForSimpleBuildByApp(context.Background(), githubAppId, []byte(githubAppPrivateKey), "")
 		if err != nil {
 			fmt.Printf("error: %v", err)
 		}
 		return ghclient
 
 	case githubToken:
-		return NewGithubClient(accessToken)
+		ghclient, err := NewGithubClient(accessToken, "")
+		if err != nil {
+			fmt.Printf("error: %v", err)
+		}
+		return ghclient
 	default:
 		return nil
 	}
@@ -211,7 +216,7 @@ func TestIsAppInstalledIntoRepository(t *testing.T) {
 		return
 	}
 
-	installed, err := ghclient.IsAppInstalledIntoRepository(repoUrl)
+	installed, err := ghclient.IsAppInstalledIntoRepository(context.Background(), repoUrl)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -17,22 +17,63 @@ limitations under the License.
 package gitlab
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"strings"
 
 	"github.com/xanzy/go-gitlab"
 
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
 	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+	"github.com/redhat-appstudio/build-service/pkg/git/ratelimit"
 )
 
 // Allow mocking for tests
-var NewGitlabClient func(accessToken string) (*GitlabClient, error) = newGitlabClient
+var NewGitlabClient func(accessToken, baseUrl string) (*GitlabClient, error) = newGitlabClient
 
 var _ gp.GitProviderClient = (*GitlabClient)(nil)
+var _ gp.ContextBinder = (*GitlabClient)(nil)
 
 type GitlabClient struct {
+	ctx    context.Context
 	client *gitlab.Client
+	// accessToken is needed to push signed commits via git protocol
+	accessToken string
+
+	// commitSigner signs created commits, if set
+	commitSigner commitsigning.Signer
+
+	// isForkModeEnabled makes merge request branches to be pushed into a fork of the target project
+	isForkModeEnabled bool
+	// forkNamespace is the group or user namespace to create forks in, the token owner namespace is used if empty
+	forkNamespace string
+}
+
+// WithContext returns a shallow copy of the client which makes all its requests within the given context.
+func (g *GitlabClient) WithContext(ctx context.Context) (gp.GitProviderClient, error) {
+	client := *g
+	client.ctx = ctx
+	return &client, nil
+}
+
+// requestContext returns the request option which makes GitLab API request within the client context.
+func (g *GitlabClient) requestContext() gitlab.RequestOptionFunc {
+	return gitlab.WithContext(g.ctx)
+}
+
+// SetCommitSigner makes the client sign the commits it creates with the given signer.
+func (g *GitlabClient) SetCommitSigner(signer commitsigning.Signer) {
+	g.commitSigner = signer
+}
+
+// EnableForkMode makes the client push merge request branches into a fork of the target project
+// and open cross-project merge requests. The fork is created in the given namespace
+// or in the token owner namespace if the namespace is empty.
+func (g *GitlabClient) EnableForkMode(namespace string) {
+	g.isForkModeEnabled = true
+	g.forkNamespace = namespace
 }
 
 // EnsurePaCMergeRequest creates or updates existing (if needed) Pipelines as Code configuration proposal merge request.
@@ -59,24 +100,36 @@ func (g *GitlabClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestD
 		return "", nil
 	}
 
-	mrBranchExists, err := g.branchExist(projectPath, d.BranchName)
+	// In fork mode the branch with a proposal is pushed into the fork
+	var fork *gitlab.Project
+	if g.isForkModeEnabled {
+		if fork, err = g.ensureFork(projectPath); err != nil {
+			return "", err
+		}
+	}
+	branchProjectPath := projectPath
+	if fork != nil {
+		branchProjectPath = fork.PathWithNamespace
+	}
+
+	mrBranchExists, err := g.branchExist(branchProjectPath, d.BranchName)
 	if err != nil {
 		return "", err
 	}
 
 	if mrBranchExists {
-		mrBranchUpToDate, err := g.filesUpToDate(projectPath, d.BranchName, d.Files)
+		mrBranchUpToDate, err := g.filesUpToDate(branchProjectPath, d.BranchName, d.Files)
 		if err != nil {
 			return "", err
 		}
 		if !mrBranchUpToDate {
-			err := g.commitFilesIntoBranch(projectPath, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+			err := g.commitFilesIntoBranch(branchProjectPath, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
 			if err != nil {
 				return "", err
 			}
 		}
 
-		mr, err := g.findMergeRequestByBranches(projectPath, d.BranchName, d.BaseBranchName)
+		mr, err := g.findMergeRequestByBranches(projectPath, fork, d.BranchName, d.BaseBranchName)
 		if err != nil {
 			return "", err
 		}
@@ -85,34 +138,45 @@ func (g *GitlabClient) EnsurePaCMergeRequest(repoUrl string, d *gp.MergeRequestD
 			return mr.WebURL, nil
 		}
 
-		diffExists, err := g.diffNotEmpty(projectPath, d.BranchName, d.BaseBranchName)
-		if err != nil {
-			return "", err
+		// Base branch of the fork might be outdated, so the branches cannot be compared within the fork.
+		// Recreate the fork branch instead to make sure it's based on the latest base branch.
+		diffExists := false
+		if fork == nil {
+			if diffExists, err = g.diffNotEmpty(projectPath, d.BranchName, d.BaseBranchName); err != nil {
+				return "", err
+			}
 		}
 		if !diffExists {
 			// This situation occurs if an MR was merged but the branch was not deleted and main is changed after the merge.
 			// Despite the fact that there is actual diff between branches, git treats it as no diff,
 			// because the branch is already "included" in main.
-			if _, err := g.deleteBranch(projectPath, d.BranchName); err != nil {
+			if _, err := g.deleteBranch(branchProjectPath, d.BranchName); err != nil {
 				return "", err
 			}
 			return g.EnsurePaCMergeRequest(repoUrl, d)
 		}
 
-		return g.createMergeRequestWithinRepository(projectPath, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+		return g.createMergeRequest(projectPath, fork, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 	} else {
 		// Need to create branch and MR with Pipelines as Code configuration
-		err = g.createBranch(projectPath, d.BranchName, d.BaseBranchName)
-		if err != nil {
-			return "", err
-		}
+		if fork != nil {
+			err = g.commitFilesIntoNewForkBranch(fork, d.BranchName, d.BaseBranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files, false)
+			if err != nil {
+				return "", err
+			}
+		} else {
+			err = g.createBranch(projectPath, d.BranchName, d.BaseBranchName)
+			if err != nil {
+				return "", err
+			}
 
-		err = g.commitFilesIntoBranch(projectPath, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
-		if err != nil {
-			return "", err
+			err = g.commitFilesIntoBranch(projectPath, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+			if err != nil {
+				return "", err
+			}
 		}
 
-		return g.createMergeRequestWithinRepository(projectPath, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+		return g.createMergeRequest(projectPath, fork, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 	}
 }
 
@@ -142,37 +206,73 @@ func (g *GitlabClient) UndoPaCMergeRequest(repoUrl string, d *gp.MergeRequestDat
 
 	// Need to create MR that deletes PaC configuration of the component
 
-	// Delete old branch, if any
-	if _, err := g.deleteBranch(projectPath, d.BranchName); err != nil {
-		return "", err
+	// In fork mode the branch with a proposal is pushed into the fork
+	var fork *gitlab.Project
+	if g.isForkModeEnabled {
+		if fork, err = g.ensureFork(projectPath); err != nil {
+			return "", err
+		}
 	}
 
-	// Create branch, commit and pull request
-	if err := g.createBranch(projectPath, d.BranchName, d.BaseBranchName); err != nil {
-		return "", err
-	}
+	if fork != nil {
+		// Delete old branch, if any
+		if _, err := g.deleteBranch(fork.PathWithNamespace, d.BranchName); err != nil {
+			return "", err
+		}
 
-	err = g.addDeleteCommitToBranch(projectPath, d.BranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files)
-	if err != nil {
-		return "", err
+		err = g.commitFilesIntoNewForkBranch(fork, d.BranchName, d.BaseBranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, files, true)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Delete old branch, if any
+		if _, err := g.deleteBranch(projectPath, d.BranchName); err != nil {
+			return "", err
+		}
+
+		// Create branch, commit and pull request
+		if err := g.createBranch(projectPath, d.BranchName, d.BaseBranchName); err != nil {
+			return "", err
+		}
+
+		err = g.addDeleteCommitToBranch(projectPath, d.BranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	return g.createMergeRequestWithinRepository(projectPath, d.BranchName, d.BaseBranchName, d.Title, d.Text)
+	return g.createMergeRequest(projectPath, fork, d.BranchName, d.BaseBranchName, d.Title, d.Text)
 }
 
-// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal merge request
+// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal merge request.
+// In fork mode, only merge requests from the fork are considered.
 func (g *GitlabClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (*gp.MergeRequest, error) {
 	projectPath := getProjectPathFromRepoUrl(repoUrl)
 
+	branchProjectPath, err := g.getMergeRequestBranchProjectPath(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	var fork *gitlab.Project
+	if branchProjectPath != projectPath {
+		if fork, err = g.getProjectInfo(branchProjectPath); err != nil || fork == nil {
+			// No fork, no merge requests from it
+			return nil, err
+		}
+	}
+
 	opts := &gitlab.ListProjectMergeRequestsOptions{
 		State:          gitlab.String("opened"),
 		AuthorUsername: gitlab.String(d.AuthorName),
-		SourceBranch:   gitlab.String(d.BaseBranchName),
-		TargetBranch:   gitlab.String(d.BranchName),
+		SourceBranch:   gitlab.String(d.BranchName),
+		TargetBranch:   gitlab.String(d.BaseBranchName),
 	}
-	mrs, resp, err := g.client.MergeRequests.ListProjectMergeRequests(projectPath, opts)
+	mrs, resp, err := g.client.MergeRequests.ListProjectMergeRequests(projectPath, opts, g.requestContext())
 	if err != nil {
-		return nil, refineGitHostingServiceError(resp.Response, err)
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+	if fork != nil {
+		mrs = filterMergeRequestsBySourceProject(mrs, fork.ID)
 	}
 	if len(mrs) == 0 {
 		return nil, nil
@@ -186,6 +286,161 @@ func (g *GitlabClient) FindUnmergedPaCMergeRequest(repoUrl string, d *gp.MergeRe
 	}, nil
 }
 
+// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+// from the current top of the base branch, if the merge request branch is behind the base branch.
+func (g *GitlabClient) RefreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, refreshed bool, err error) {
+	return g.refreshPaCMergeRequest(repoUrl, d, false)
+}
+
+// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+// from the current top of the base branch with the given files, even if the branch is up to date.
+func (g *GitlabClient) ResetPaCMergeRequest(repoUrl string, d *gp.MergeRequestData) (webUrl string, err error) {
+	webUrl, _, err = g.refreshPaCMergeRequest(repoUrl, d, true)
+	return webUrl, err
+}
+
+// refreshPaCMergeRequest recreates the branch of the open proposal merge request if it's behind the base branch or force is set.
+func (g *GitlabClient) refreshPaCMergeRequest(repoUrl string, d *gp.MergeRequestData, force bool) (webUrl string, refreshed bool, err error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(projectPath)
+		if err != nil {
+			return "", false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	branchProjectPath, err := g.getMergeRequestBranchProjectPath(projectPath)
+	if err != nil {
+		return "", false, err
+	}
+	var fork *gitlab.Project
+	if branchProjectPath != projectPath {
+		if fork, err = g.getProjectInfo(branchProjectPath); err != nil || fork == nil {
+			// No fork, no merge requests from it
+			return "", false, err
+		}
+	}
+
+	mr, err := g.findMergeRequestByBranches(projectPath, fork, d.BranchName, d.BaseBranchName)
+	if err != nil || mr == nil {
+		return "", false, err
+	}
+
+	if !force {
+		isBehind, err := g.isMergeRequestBehind(projectPath, mr.IID)
+		if err != nil || !isBehind {
+			return mr.WebURL, false, err
+		}
+	}
+
+	if err := g.resetBranchToBaseWithFiles(projectPath, fork, d); err != nil {
+		return "", false, err
+	}
+	return mr.WebURL, true, nil
+}
+
+// GetMergeRequestStatus returns current state of the merge request with the given web URL in the given repository
+func (g *GitlabClient) GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*gp.MergeRequestStatus, error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	iid, err := gp.GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl)
+	if err != nil {
+		return nil, err
+	}
+	opts := &gitlab.GetMergeRequestsOptions{IncludeDivergedCommitsCount: gitlab.Bool(true)}
+	mr, resp, err := g.client.MergeRequests.GetMergeRequest(projectPath, iid, opts, g.requestContext())
+	if err != nil {
+		if resp == nil {
+			return nil, err
+		}
+		return nil, refineGitHostingServiceError(resp, err)
+	}
+
+	status := &gp.MergeRequestStatus{
+		CreatedAt: mr.CreatedAt,
+		MergedAt:  mr.MergedAt,
+	}
+	switch mr.State {
+	case "merged":
+		status.State = gp.MergeRequestStateMerged
+	case "closed":
+		status.State = gp.MergeRequestStateClosed
+	default:
+		status.State = gp.MergeRequestStateOpen
+		status.IsBehind = mr.DivergedCommitsCount > 0 || mr.HasConflicts
+		switch mr.DetailedMergeStatus {
+		case "", "unchecked", "checking", "preparing":
+			// Not computed yet
+		default:
+			mergeable := !mr.HasConflicts
+			status.Mergeable = &mergeable
+		}
+	}
+	return status, nil
+}
+
+// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch
+func (g *GitlabClient) CommitPaCConfiguration(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(projectPath)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	upToDate, err := g.filesUpToDate(projectPath, d.BaseBranchName, d.Files)
+	if err != nil || upToDate {
+		return false, err
+	}
+
+	if err := g.commitFilesIntoBranch(projectPath, d.BaseBranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch
+func (g *GitlabClient) CommitPaCConfigurationRemoval(repoUrl string, d *gp.MergeRequestData) (committed bool, err error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	// Fallback to the default branch if base branch is not set
+	if d.BaseBranchName == "" {
+		baseBranch, err := g.getDefaultBranch(projectPath)
+		if err != nil {
+			return false, err
+		}
+		d.BaseBranchName = baseBranch
+	}
+
+	files, err := g.filesExistInDirectory(projectPath, d.BaseBranchName, ".tekton", d.Files)
+	if err != nil || len(files) == 0 {
+		return false, err
+	}
+
+	if err := g.addDeleteCommitToBranch(projectPath, d.BaseBranchName, d.AuthorName, d.AuthorEmail, d.CommitMessage, files); err != nil {
+		return false, refinePushRejectedError(err)
+	}
+	return true, nil
+}
+
+// IsBranchProtected returns true if the given branch is protected and the token owner is not allowed to push into it
+func (g *GitlabClient) IsBranchProtected(repoUrl, branchName string) (bool, error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	branch, err := g.getBranch(projectPath, branchName)
+	if err != nil {
+		return false, err
+	}
+	return branch.Protected && !branch.CanPush, nil
+}
+
 // SetupPaCWebhook creates Pipelines as Code webhook in the given repository
 func (g *GitlabClient) SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error {
 	projectPath := getProjectPathFromRepoUrl(repoUrl)
@@ -227,10 +482,15 @@ func (g *GitlabClient) GetDefaultBranch(repoUrl string) (string, error) {
 	return g.getDefaultBranch(projectPath)
 }
 
-// DeleteBranch deletes given branch from repository
+// DeleteBranch deletes given branch from repository.
+// In fork mode the branch is deleted from the fork, as only merge request branches are deleted.
 func (g *GitlabClient) DeleteBranch(repoUrl, branchName string) (bool, error) {
 	projectPath := getProjectPathFromRepoUrl(repoUrl)
-	return g.deleteBranch(projectPath, branchName)
+	branchProjectPath, err := g.getMergeRequestBranchProjectPath(projectPath)
+	if err != nil {
+		return false, err
+	}
+	return g.deleteBranch(branchProjectPath, branchName)
 }
 
 // GetBranchSha returns SHA of top commit in the given branch
@@ -250,6 +510,7 @@ func (g *GitlabClient) GetBranchSha(repoUrl, branchName string) (string, error)
 
 // IsFileExist check whether given file exists in the given branch of the reposiotry.
 // If branch is empty string, default branch is used.
+// In fork mode, the branch is looked up in the fork if the repository doesn't have it.
 func (g *GitlabClient) IsFileExist(repoUrl, branchName, filePath string) (bool, error) {
 	projectPath := getProjectPathFromRepoUrl(repoUrl)
 
@@ -259,6 +520,12 @@ func (g *GitlabClient) IsFileExist(repoUrl, branchName, filePath string) (bool,
 		if err != nil {
 			return false, err
 		}
+	} else {
+		var err error
+		projectPath, err = g.resolveBranchProjectPath(projectPath, branchName)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	directory := filepath.Dir(filePath)
@@ -269,6 +536,32 @@ func (g *GitlabClient) IsFileExist(repoUrl, branchName, filePath string) (bool,
 	return len(files) > 0, nil
 }
 
+// DownloadFileContent returns content of the given file at the given revision of the repository.
+// If revision is empty string, default branch is used.
+func (g *GitlabClient) DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error) {
+	projectPath := getProjectPathFromRepoUrl(repoUrl)
+
+	if revision == "" {
+		var err error
+		revision, err = g.getDefaultBranch(projectPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts := &gitlab.GetRawFileOptions{
+		Ref: &revision,
+	}
+	fileContent, resp, err := g.client.RepositoryFiles.GetRawFile(projectPath, filePath, opts, g.requestContext())
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil, fmt.Errorf("file %s not found in %s at %q revision", filePath, repoUrl, revision)
+		}
+		return nil, err
+	}
+	return fileContent, nil
+}
+
 // IsRepositoryPublic returns true if the repository could be accessed without authentication
 func (g *GitlabClient) IsRepositoryPublic(repoUrl string) (bool, error) {
 	projectPath := getProjectPathFromRepoUrl(repoUrl)
@@ -299,13 +592,25 @@ func (g *GitlabClient) GetConfiguredGitAppName() (string, string, error) {
 	return "", "", fmt.Errorf("GitLab does not support applications")
 }
 
-func newGitlabClient(accessToken string) (*GitlabClient, error) {
+// newGitlabClient creates GitLab client for the given instance.
+// If base URL is empty, gitlab.com is used.
+func newGitlabClient(accessToken, baseUrl string) (*GitlabClient, error) {
 	glc := &GitlabClient{}
-	c, err := gitlab.NewClient(accessToken)
+	glc.ctx = context.TODO()
+
+	opts := []gitlab.ClientOptionFunc{
+		gitlab.WithHTTPClient(&http.Client{Transport: ratelimit.NewTransport(nil)}),
+	}
+	if baseUrl != "" {
+		// The client appends API version path if it is missing in the given URL
+		opts = append(opts, gitlab.WithBaseURL(baseUrl))
+	}
+	c, err := gitlab.NewClient(accessToken, opts...)
 	if err != nil {
 		return nil, err
 	}
 	glc.client = c
+	glc.accessToken = accessToken
 
 	return glc, nil
 }
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const testRepoUrl = "https://gitlab.example.com/namespace/project"
+
+func newTestGitlabClient(t *testing.T, handler http.HandlerFunc) gp.GitProviderClientWithContext {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewGitlabClient("token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gp.NewClientWithContext(client)
+}
+
+func TestDeleteBranchRateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	client := newTestGitlabClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message": "Retry later"}`))
+	})
+
+	_, err := client.DeleteBranch(context.Background(), testRepoUrl, "branch")
+	if err == nil {
+		t.Fatal("expected rate limit error")
+	}
+	if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter < 50*time.Minute || requeueAfter > time.Hour {
+		t.Errorf("expected requeue after the rate limit reset, got %s: %v", requeueAfter, err)
+	}
+}
+
+func TestDeleteBranchTimeout(t *testing.T) {
+	client := newTestGitlabClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Simulate hung git host
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.DeleteBranch(ctx, testRepoUrl, "branch")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got: %v", err)
+	}
+}
+
+func TestCheckForkReady(t *testing.T) {
+	tests := []struct {
+		importStatus     string
+		wantErr          bool
+		wantRequeueAfter time.Duration
+	}{
+		{importStatus: "finished"},
+		{importStatus: ""},
+		{importStatus: "failed", wantErr: true},
+		{importStatus: "started", wantErr: true, wantRequeueAfter: forkReadyRetryDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.importStatus, func(t *testing.T) {
+			err := checkForkReady(&gitlab.Project{PathWithNamespace: "forks/project", ImportStatus: tt.importStatus})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkForkReady() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter != tt.wantRequeueAfter {
+				t.Errorf("expected retry after %s, got %s", tt.wantRequeueAfter, requeueAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// forkReadyRetryDelay is the delay before retry of an operation which waits for a just created fork
+const forkReadyRetryDelay = 30 * time.Second
+
+// getForkNamespace returns the namespace to keep forks of target projects in.
+func (g *GitlabClient) getForkNamespace() (string, error) {
+	if g.forkNamespace != "" {
+		return g.forkNamespace, nil
+	}
+	user, resp, err := g.client.Users.CurrentUser(g.requestContext())
+	if err != nil {
+		if resp == nil {
+			return "", err
+		}
+		return "", refineGitHostingServiceError(resp, err)
+	}
+	g.forkNamespace = user.Username
+	return g.forkNamespace, nil
+}
+
+// getMergeRequestBranchProjectPath returns path of the project which holds merge request branches.
+// In fork mode it's the fork, otherwise the target project itself.
+// Forks always have the same path as the target project, but in the fork namespace.
+func (g *GitlabClient) getMergeRequestBranchProjectPath(projectPath string) (string, error) {
+	if !g.isForkModeEnabled {
+		return projectPath, nil
+	}
+	namespace, err := g.getForkNamespace()
+	if err != nil {
+		return "", err
+	}
+	return namespace + "/" + path.Base(projectPath), nil
+}
+
+// ensureFork creates fork of the given project, if it doesn't exist yet.
+// Returns nil if the fork namespace owns the project, so it cannot and need not be forked.
+func (g *GitlabClient) ensureFork(projectPath string) (*gitlab.Project, error) {
+	forkPath, err := g.getMergeRequestBranchProjectPath(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if forkPath == projectPath {
+		return nil, nil
+	}
+
+	project, err := g.getProjectInfo(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project %s not found", projectPath)
+	}
+
+	fork, err := g.getProjectInfo(forkPath)
+	if err != nil {
+		return nil, err
+	}
+	if fork != nil {
+		if fork.ForkedFromProject == nil || fork.ForkedFromProject.ID != project.ID {
+			return nil, boerrors.NewBuildOpError(boerrors.EForkRepositoryConflict,
+				fmt.Errorf("project %s is not a fork of %s", forkPath, projectPath))
+		}
+	} else {
+		namespace, forkProjectPath := path.Dir(forkPath), path.Base(forkPath)
+		opts := &gitlab.ForkProjectOptions{
+			NamespacePath: &namespace,
+			Path:          &forkProjectPath,
+		}
+		var resp *gitlab.Response
+		fork, resp, err = g.client.Projects.ForkProject(project.ID, opts, g.requestContext())
+		if err != nil {
+			if resp == nil {
+				return nil, err
+			}
+			return nil, refineGitHostingServiceError(resp, err)
+		}
+	}
+	if err := checkForkReady(fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// checkForkReady checks whether the fork project repository is imported.
+// GitLab imports forks asynchronously. Instead of blocking the caller until the fork is ready,
+// transient error is returned, so the operation is retried later.
+func checkForkReady(fork *gitlab.Project) error {
+	switch fork.ImportStatus {
+	case "", "none", "finished":
+		return nil
+	case "failed":
+		return fmt.Errorf("failed to fork into %s: %s", fork.PathWithNamespace, fork.ImportError)
+	}
+	return boerrors.NewTransientBuildOpError(fmt.Errorf("fork %s is not ready yet", fork.PathWithNamespace), forkReadyRetryDelay)
+}
+
+// commitFilesIntoNewForkBranch creates the branch in the fork from the latest commit of the parent project base branch
+// and commits the given changes into it. GitLab does both in single request, so the fork doesn't need to be synced.
+// If isDelete is true, the given files are deleted, otherwise created or updated.
+func (g *GitlabClient) commitFilesIntoNewForkBranch(fork *gitlab.Project, branchName, baseBranchName, commitMessage, authorName, authorEmail string, files []gp.RepositoryFile, isDelete bool) error {
+	parentProjectId := fork.ForkedFromProject.ID
+
+	var actions []*gitlab.CommitActionOptions
+	if isDelete {
+		actions = getDeleteFileActions(files)
+	} else {
+		var err error
+		if actions, err = g.getUpsertFileActions(parentProjectId, baseBranchName, files); err != nil {
+			return err
+		}
+	}
+
+	parentProject := strconv.Itoa(parentProjectId)
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        &branchName,
+		StartBranch:   &baseBranchName,
+		StartProject:  &parentProject,
+		CommitMessage: &commitMessage,
+		AuthorName:    &authorName,
+		AuthorEmail:   &authorEmail,
+		Actions:       actions,
+	}
+	_, resp, err := g.client.Commits.CreateCommit(fork.ID, opts, g.requestContext())
+	if err != nil && resp != nil {
+		return refineGitHostingServiceError(resp, err)
+	}
+	return err
+}
+
+// resolveBranchProjectPath returns path of the project the given branch should be looked up in.
+// In fork mode merge request branches live in the fork, so a branch that is missing
+// in the target project is looked up in the fork.
+func (g *GitlabClient) resolveBranchProjectPath(projectPath, branchName string) (string, error) {
+	if !g.isForkModeEnabled {
+		return projectPath, nil
+	}
+	branchExists, err := g.branchExist(projectPath, branchName)
+	if err != nil || branchExists {
+		return projectPath, err
+	}
+	return g.getMergeRequestBranchProjectPath(projectPath)
+}
@@ -18,8 +18,10 @@ package gitlab
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/xanzy/go-gitlab"
@@ -40,12 +42,13 @@ func getProjectPathFromRepoUrl(repoUrl string) string {
 // refineGitHostingServiceError generates expected permanent error from GitHub response.
 // If no one is detected, the original error will be returned.
 // refineGitHostingServiceError should be called just after every GitHub API call.
-func refineGitHostingServiceError(response *http.Response, originErr error) error {
-	// go-gitlab APIs do not return a http.Response object if the error is not related to an HTTP request.
-	if response == nil {
+func refineGitHostingServiceError(resp *gitlab.Response, originErr error) error {
+	// go-gitlab APIs do not return a response object if the error is not related to an HTTP request,
+	// for example if the request deadline is exceeded or the rate limited request is not repeated.
+	if resp == nil || resp.Response == nil {
 		return originErr
 	}
-	switch response.StatusCode {
+	switch resp.StatusCode {
 	case 401:
 		return boerrors.NewBuildOpError(boerrors.EGitLabTokenUnauthorized, originErr)
 	case 403:
@@ -55,10 +58,24 @@ func refineGitHostingServiceError(response *http.Response, originErr error) erro
 	}
 }
 
+// refinePushRejectedError converts error of a commit, which GitLab refused because the branch is protected,
+// into push rejected error. Both API commits and git pushes of signed commits are recognized.
+func refinePushRejectedError(err error) error {
+	var errResponse *gitlab.ErrorResponse
+	if errors.As(err, &errResponse) && errResponse.Response != nil && errResponse.Response.StatusCode == http.StatusForbidden {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	}
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "protected branch") || strings.Contains(message, "pre-receive hook declined") {
+		return boerrors.NewBuildOpError(boerrors.EGitBranchPushRejected, err)
+	}
+	return err
+}
+
 func (g *GitlabClient) getBranch(projectPath, branchName string) (*gitlab.Branch, error) {
-	branch, resp, err := g.client.Branches.GetBranch(projectPath, branchName)
+	branch, resp, err := g.client.Branches.GetBranch(projectPath, branchName, g.requestContext())
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			return nil, nil
 		}
 		return nil, err
@@ -67,9 +84,9 @@ func (g *GitlabClient) getBranch(projectPath, branchName string) (*gitlab.Branch
 }
 
 func (g *GitlabClient) branchExist(projectPath, branchName string) (bool, error) {
-	_, resp, err := g.client.Branches.GetBranch(projectPath, branchName)
+	_, resp, err := g.client.Branches.GetBranch(projectPath, branchName, g.requestContext())
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			return false, nil
 		}
 		return false, err
@@ -82,23 +99,23 @@ func (g *GitlabClient) createBranch(projectPath, branchName, baseBranchName stri
 		Branch: &branchName,
 		Ref:    &baseBranchName,
 	}
-	_, _, err := g.client.Branches.CreateBranch(projectPath, opts)
+	_, _, err := g.client.Branches.CreateBranch(projectPath, opts, g.requestContext())
 	return err
 }
 
 func (g *GitlabClient) deleteBranch(projectPath, branch string) (bool, error) {
-	if resp, err := g.client.Branches.DeleteBranch(projectPath, branch); err != nil {
-		if resp.Response.StatusCode == 404 {
+	if resp, err := g.client.Branches.DeleteBranch(projectPath, branch, g.requestContext()); err != nil {
+		if resp != nil && resp.StatusCode == 404 {
 			// The given branch doesn't exist
 			return false, nil
 		}
-		return false, refineGitHostingServiceError(resp.Response, err)
+		return false, refineGitHostingServiceError(resp, err)
 	}
 	return true, nil
 }
 
 func (g *GitlabClient) getDefaultBranch(projectPath string) (string, error) {
-	projectInfo, _, err := g.client.Projects.GetProject(projectPath, nil)
+	projectInfo, _, err := g.client.Projects.GetProject(projectPath, nil, g.requestContext())
 	if err != nil {
 		return "", err
 	}
@@ -113,9 +130,9 @@ func (g *GitlabClient) filesUpToDate(projectPath, branchName string, files []gp.
 		opts := &gitlab.GetRawFileOptions{
 			Ref: &branchName,
 		}
-		fileContent, resp, err := g.client.RepositoryFiles.GetRawFile(projectPath, file.FullPath, opts)
+		fileContent, resp, err := g.client.RepositoryFiles.GetRawFile(projectPath, file.FullPath, opts, g.requestContext())
 		if err != nil {
-			if resp.StatusCode != 404 {
+			if resp == nil || resp.StatusCode != 404 {
 				return false, err
 			}
 			return false, nil
@@ -137,9 +154,9 @@ func (g *GitlabClient) filesExistInDirectory(projectPath, branchName, directoryP
 		Path:        &directoryPath,
 		ListOptions: gitlab.ListOptions{PerPage: 100},
 	}
-	dirContent, resp, err := g.client.Repositories.ListTree(projectPath, opts)
+	dirContent, resp, err := g.client.Repositories.ListTree(projectPath, opts, g.requestContext())
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			return existingFiles, nil
 		}
 		return existingFiles, err
@@ -158,6 +175,29 @@ func (g *GitlabClient) filesExistInDirectory(projectPath, branchName, directoryP
 }
 
 func (g *GitlabClient) commitFilesIntoBranch(projectPath, branchName, commitMessage, authorName, authorEmail string, files []gp.RepositoryFile) error {
+	if g.commitSigner != nil {
+		return g.pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail, files, false)
+	}
+
+	actions, err := g.getUpsertFileActions(projectPath, branchName, files)
+	if err != nil {
+		return err
+	}
+
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        &branchName,
+		CommitMessage: &commitMessage,
+		AuthorName:    &authorName,
+		AuthorEmail:   &authorEmail,
+		Actions:       actions,
+	}
+	_, _, err = g.client.Commits.CreateCommit(projectPath, opts, g.requestContext())
+	return err
+}
+
+// getUpsertFileActions returns commit actions which create or update the given files
+// depending on whether they exist in the given branch of the project.
+func (g *GitlabClient) getUpsertFileActions(pid interface{}, branchName string, files []gp.RepositoryFile) ([]*gitlab.CommitActionOptions, error) {
 	actions := []*gitlab.CommitActionOptions{}
 	for _, file := range files {
 		filePath := file.FullPath
@@ -166,10 +206,10 @@ func (g *GitlabClient) commitFilesIntoBranch(projectPath, branchName, commitMess
 
 		// Detect file action: update or create
 		opts := &gitlab.GetRawFileOptions{Ref: &branchName}
-		_, resp, err := g.client.RepositoryFiles.GetRawFile(projectPath, file.FullPath, opts)
+		_, resp, err := g.client.RepositoryFiles.GetRawFile(pid, file.FullPath, opts, g.requestContext())
 		if err != nil {
-			if resp.StatusCode != 404 {
-				return err
+			if resp == nil || resp.StatusCode != 404 {
+				return nil, err
 			}
 			fileAction = gitlab.FileCreate
 		} else {
@@ -184,20 +224,11 @@ func (g *GitlabClient) commitFilesIntoBranch(projectPath, branchName, commitMess
 
 		actions = append(actions, action)
 	}
-
-	opts := &gitlab.CreateCommitOptions{
-		Branch:        &branchName,
-		CommitMessage: &commitMessage,
-		AuthorName:    &authorName,
-		AuthorEmail:   &authorEmail,
-		Actions:       actions,
-	}
-	_, _, err := g.client.Commits.CreateCommit(projectPath, opts)
-	return err
+	return actions, nil
 }
 
-// Creates commit into specified branch that deletes given files.
-func (g *GitlabClient) addDeleteCommitToBranch(projectPath, branchName, authorName, authorEmail, commitMessage string, files []gp.RepositoryFile) error {
+// getDeleteFileActions returns commit actions which delete the given files.
+func getDeleteFileActions(files []gp.RepositoryFile) []*gitlab.CommitActionOptions {
 	actions := []*gitlab.CommitActionOptions{}
 	fileActionType := gitlab.FileDelete
 	for _, file := range files {
@@ -207,15 +238,23 @@ func (g *GitlabClient) addDeleteCommitToBranch(projectPath, branchName, authorNa
 			FilePath: &filePath,
 		})
 	}
+	return actions
+}
+
+// Creates commit into specified branch that deletes given files.
+func (g *GitlabClient) addDeleteCommitToBranch(projectPath, branchName, authorName, authorEmail, commitMessage string, files []gp.RepositoryFile) error {
+	if g.commitSigner != nil {
+		return g.pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail, files, true)
+	}
 
 	opts := &gitlab.CreateCommitOptions{
 		Branch:        &branchName,
 		CommitMessage: &commitMessage,
 		AuthorName:    &authorName,
 		AuthorEmail:   &authorEmail,
-		Actions:       actions,
+		Actions:       getDeleteFileActions(files),
 	}
-	_, _, err := g.client.Commits.CreateCommit(projectPath, opts)
+	_, _, err := g.client.Commits.CreateCommit(projectPath, opts, g.requestContext())
 	return err
 }
 
@@ -226,27 +265,93 @@ func (g *GitlabClient) diffNotEmpty(projectPath, branchName, baseBranchName stri
 		To:       &branchName,
 		Straight: &straight,
 	}
-	cmpres, _, err := g.client.Repositories.Compare(projectPath, opts)
+	cmpres, _, err := g.client.Repositories.Compare(projectPath, opts, g.requestContext())
 	if err != nil {
 		return false, err
 	}
 	return len(cmpres.Diffs) > 0, nil
 }
 
-func (g *GitlabClient) findMergeRequestByBranches(projectPath, branch, targetBranch string) (*gitlab.MergeRequest, error) {
+// isMergeRequestBehind checks whether the target branch has commits which are not in the merge request source branch
+// or the merge request has conflicts.
+func (g *GitlabClient) isMergeRequestBehind(projectPath string, mergeRequestIid int) (bool, error) {
+	opts := &gitlab.GetMergeRequestsOptions{IncludeDivergedCommitsCount: gitlab.Bool(true)}
+	mr, resp, err := g.client.MergeRequests.GetMergeRequest(projectPath, mergeRequestIid, opts, g.requestContext())
+	if err != nil {
+		if resp == nil {
+			return false, err
+		}
+		return false, refineGitHostingServiceError(resp, err)
+	}
+	return mr.DivergedCommitsCount > 0 || mr.HasConflicts, nil
+}
+
+// resetBranchToBaseWithFiles replaces content of the merge request branch with single commit
+// which adds the given files on top of the latest commit of the base branch.
+// If fork is not nil, the merge request branch is in the fork.
+func (g *GitlabClient) resetBranchToBaseWithFiles(projectPath string, fork *gitlab.Project, d *gp.MergeRequestData) error {
+	if g.commitSigner != nil {
+		// Signed commits are pushed via git protocol, so recreate the branch instead of force push.
+		// GitLab keeps the merge request open while its source branch doesn't exist.
+		// Note, signing is not supported in fork mode.
+		if _, err := g.deleteBranch(projectPath, d.BranchName); err != nil {
+			return err
+		}
+		if err := g.createBranch(projectPath, d.BranchName, d.BaseBranchName); err != nil {
+			return err
+		}
+		return g.commitFilesIntoBranch(projectPath, d.BranchName, d.CommitMessage, d.AuthorName, d.AuthorEmail, d.Files)
+	}
+
+	actions, err := g.getUpsertFileActions(projectPath, d.BaseBranchName, d.Files)
+	if err != nil {
+		return err
+	}
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        &d.BranchName,
+		StartBranch:   &d.BaseBranchName,
+		CommitMessage: &d.CommitMessage,
+		AuthorName:    &d.AuthorName,
+		AuthorEmail:   &d.AuthorEmail,
+		Actions:       actions,
+		// Overwrite the branch with the new commit based on the start branch
+		Force: gitlab.Bool(true),
+	}
+	branchProjectPath := projectPath
+	if fork != nil {
+		startProject := strconv.Itoa(fork.ForkedFromProject.ID)
+		opts.StartProject = &startProject
+		branchProjectPath = fork.PathWithNamespace
+	}
+	_, resp, err := g.client.Commits.CreateCommit(branchProjectPath, opts, g.requestContext())
+	if err != nil && resp != nil {
+		return refineGitHostingServiceError(resp, err)
+	}
+	return err
+}
+
+// findMergeRequestByBranches searches for opened merge request in the project by source and target branch.
+// If fork is not nil, only merge requests from the fork are considered.
+func (g *GitlabClient) findMergeRequestByBranches(projectPath string, fork *gitlab.Project, branch, targetBranch string) (*gitlab.MergeRequest, error) {
 	openedState := "opened"
-	viewType := "simple"
 	opts := &gitlab.ListProjectMergeRequestsOptions{
 		State:        &openedState,
 		SourceBranch: &branch,
 		TargetBranch: &targetBranch,
-		View:         &viewType,
 		ListOptions:  gitlab.ListOptions{PerPage: 100},
 	}
-	mrs, _, err := g.client.MergeRequests.ListProjectMergeRequests(projectPath, opts)
+	if fork == nil {
+		// Simple view doesn't contain source project, so it can be used only within the project
+		viewType := "simple"
+		opts.View = &viewType
+	}
+	mrs, _, err := g.client.MergeRequests.ListProjectMergeRequests(projectPath, opts, g.requestContext())
 	if err != nil {
 		return nil, err
 	}
+	if fork != nil {
+		mrs = filterMergeRequestsBySourceProject(mrs, fork.ID)
+	}
 	switch len(mrs) {
 	case 0:
 		return nil, nil
@@ -257,14 +362,32 @@ func (g *GitlabClient) findMergeRequestByBranches(projectPath, branch, targetBra
 	}
 }
 
-func (g *GitlabClient) createMergeRequestWithinRepository(projectPath, branchName, baseBranchName, mrTitle, mrText string) (string, error) {
+func filterMergeRequestsBySourceProject(mrs []*gitlab.MergeRequest, sourceProjectId int) []*gitlab.MergeRequest {
+	var filtered []*gitlab.MergeRequest
+	for _, mr := range mrs {
+		if mr.SourceProjectID == sourceProjectId {
+			filtered = append(filtered, mr)
+		}
+	}
+	return filtered
+}
+
+// createMergeRequest creates merge request into the project.
+// If fork is not nil, the merge request is created from the branch of the fork.
+func (g *GitlabClient) createMergeRequest(projectPath string, fork *gitlab.Project, branchName, baseBranchName, mrTitle, mrText string) (string, error) {
 	opts := &gitlab.CreateMergeRequestOptions{
 		SourceBranch: &branchName,
 		TargetBranch: &baseBranchName,
 		Title:        &mrTitle,
 		Description:  &mrText,
 	}
-	mr, _, err := g.client.MergeRequests.CreateMergeRequest(projectPath, opts)
+	var pid interface{} = projectPath
+	if fork != nil {
+		// Cross project merge request is created in the source project
+		pid = fork.ID
+		opts.TargetProjectID = &fork.ForkedFromProject.ID
+	}
+	mr, _, err := g.client.MergeRequests.CreateMergeRequest(pid, opts, g.requestContext())
 	if err != nil {
 		return "", err
 	}
@@ -273,9 +396,9 @@ func (g *GitlabClient) createMergeRequestWithinRepository(projectPath, branchNam
 
 func (g *GitlabClient) getWebhookByTargetUrl(projectPath, webhookTargetUrl string) (*gitlab.ProjectHook, error) {
 	opts := &gitlab.ListProjectHooksOptions{PerPage: 100}
-	webhooks, resp, err := g.client.Projects.ListProjectHooks(projectPath, opts)
+	webhooks, resp, err := g.client.Projects.ListProjectHooks(projectPath, opts, g.requestContext())
 	if err != nil {
-		return nil, refineGitHostingServiceError(resp.Response, err)
+		return nil, refineGitHostingServiceError(resp, err)
 	}
 	for _, webhook := range webhooks {
 		if webhook.URL == webhookTargetUrl {
@@ -288,22 +411,22 @@ func (g *GitlabClient) getWebhookByTargetUrl(projectPath, webhookTargetUrl strin
 
 func (g *GitlabClient) createPaCWebhook(projectPath, webhookTargetUrl, webhookSecret string) (*gitlab.ProjectHook, error) {
 	opts := getPaCWebhookOpts(webhookTargetUrl, webhookSecret)
-	hook, resp, err := g.client.Projects.AddProjectHook(projectPath, opts)
-	return hook, refineGitHostingServiceError(resp.Response, err)
+	hook, resp, err := g.client.Projects.AddProjectHook(projectPath, opts, g.requestContext())
+	return hook, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GitlabClient) updatePaCWebhook(projectPath string, webhookId int, webhookTargetUrl, webhookSecret string) (*gitlab.ProjectHook, error) {
 	opts := gitlab.EditProjectHookOptions(*getPaCWebhookOpts(webhookTargetUrl, webhookSecret))
-	hook, resp, err := g.client.Projects.EditProjectHook(projectPath, webhookId, &opts)
-	return hook, refineGitHostingServiceError(resp.Response, err)
+	hook, resp, err := g.client.Projects.EditProjectHook(projectPath, webhookId, &opts, g.requestContext())
+	return hook, refineGitHostingServiceError(resp, err)
 }
 
 func (g *GitlabClient) deleteWebhook(projectPath string, webhookId int) error {
-	resp, err := g.client.Projects.DeleteProjectHook(projectPath, webhookId)
-	if resp.StatusCode == 404 {
+	resp, err := g.client.Projects.DeleteProjectHook(projectPath, webhookId, g.requestContext())
+	if resp != nil && resp.StatusCode == 404 {
 		return nil
 	}
-	return refineGitHostingServiceError(resp.Response, err)
+	return refineGitHostingServiceError(resp, err)
 }
 
 func getPaCWebhookOpts(webhookTargetUrl, webhookSecret string) *gitlab.AddProjectHookOptions {
@@ -325,9 +448,9 @@ func getPaCWebhookOpts(webhookTargetUrl, webhookSecret string) *gitlab.AddProjec
 
 // IsRepositoryPublic returns true if the repository could be accessed without authentication
 func (g *GitlabClient) getProjectInfo(projectPath string) (*gitlab.Project, error) {
-	project, resp, err := g.client.Projects.GetProject(projectPath, &gitlab.GetProjectOptions{})
+	project, resp, err := g.client.Projects.GetProject(projectPath, &gitlab.GetProjectOptions{}, g.requestContext())
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			return nil, nil
 		}
 		return nil, err
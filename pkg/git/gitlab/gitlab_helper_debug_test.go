@@ -38,7 +38,7 @@ func TestEnsurePaCMergeRequest(t *testing.T) {
 		return
 	}
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,7 +75,7 @@ func TestUndoPaCMergeRequest(t *testing.T) {
 		return
 	}
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -112,7 +112,7 @@ func TestSetupPaCWebhook(t *testing.T) {
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 	webhookSecretString := "d01b38971dad59514298d763f288392c08221043"
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -130,7 +130,7 @@ func TestDeletePaCWebhook(t *testing.T) {
 
 	targetWebhookUrl := "https://pac.route.my-cluster.net"
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -146,7 +146,7 @@ func TestIsRepositoryPublic(t *testing.T) {
 		return
 	}
 
-	glclient, err := NewGitlabClient(accessToken)
+	glclient, err := NewGitlabClient(accessToken, "")
 	if err != nil {
 		t.Fatal(err)
 	}
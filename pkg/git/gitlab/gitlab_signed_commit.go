@@ -0,0 +1,241 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+// pushSignedCommit creates signed commit with the given changes on top of the given branch and pushes it.
+// GitLab API doesn't accept commit signatures, so the commit is created locally in memory
+// from a shallow clone of the branch and then pushed via git protocol.
+// The clone has no working tree, the commit tree is built directly from the git objects,
+// so the repository content is not checked out in memory.
+// If isDelete is true, the given files are deleted, otherwise created or updated.
+func (g *GitlabClient) pushSignedCommit(projectPath, branchName, commitMessage, authorName, authorEmail string, files []gp.RepositoryFile, isDelete bool) error {
+	project, err := g.getProjectInfo(projectPath)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return fmt.Errorf("project %s not found", projectPath)
+	}
+
+	auth := &githttp.BasicAuth{Username: "oauth2", Password: g.accessToken}
+	branchRef := plumbing.NewBranchReferenceName(branchName)
+	repository, err := git.CloneContext(g.ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           project.HTTPURLToRepo,
+		Auth:          auth,
+		ReferenceName: branchRef,
+		SingleBranch:  true,
+		NoCheckout:    true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone %s branch of %s: %w", branchName, projectPath, err)
+	}
+	head, err := repository.Reference(branchRef, true)
+	if err != nil {
+		return err
+	}
+	parent, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	treeHash := parent.TreeHash
+	for _, file := range files {
+		var blobHash *plumbing.Hash
+		if !isDelete {
+			hash, err := storeBlob(repository.Storer, file.Content)
+			if err != nil {
+				return err
+			}
+			blobHash = &hash
+		}
+		if treeHash, err = updateTree(repository.Storer, treeHash, strings.Split(file.FullPath, "/"), blobHash); err != nil {
+			return fmt.Errorf("failed to update %s: %w", file.FullPath, err)
+		}
+	}
+
+	// Git stores time with seconds precision, truncate it to sign exactly the stored data
+	author := object.Signature{Name: authorName, Email: authorEmail, When: time.Now().UTC().Truncate(time.Second)}
+	commit := &object.Commit{
+		Author:       author,
+		Committer:    author,
+		Message:      commitMessage,
+		TreeHash:     treeHash,
+		ParentHashes: []plumbing.Hash{parent.Hash},
+	}
+	signedCommitHash, err := storeSignedCommit(repository.Storer, commit, g.commitSigner)
+	if err != nil {
+		return err
+	}
+	if err := repository.Storer.SetReference(plumbing.NewHashReference(branchRef, signedCommitHash)); err != nil {
+		return err
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", branchRef, branchRef))
+	if err := repository.PushContext(g.ctx, &git.PushOptions{RefSpecs: []gitconfig.RefSpec{refSpec}, Auth: auth}); err != nil {
+		return fmt.Errorf("failed to push signed commit into %s branch of %s: %w", branchName, projectPath, err)
+	}
+	return nil
+}
+
+// storeBlob saves the given content as a blob object. Returns hash of the blob.
+func storeBlob(objectStorer storer.EncodedObjectStorer, content []byte) (plumbing.Hash, error) {
+	blob := objectStorer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	writer, err := blob.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return objectStorer.SetEncodedObject(blob)
+}
+
+// updateTree saves copy of the tree with the given hash, in which the file at the given path points to the given blob.
+// If the blob hash is nil, the file is deleted and directories left empty are deleted too.
+// Zero tree hash means an empty tree. Returns hash of the new tree, zero if the new tree is empty.
+func updateTree(objectStorer storer.EncodedObjectStorer, treeHash plumbing.Hash, path []string, blobHash *plumbing.Hash) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	if !treeHash.IsZero() {
+		var err error
+		if tree, err = object.GetTree(objectStorer, treeHash); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	name := path[0]
+	entryIndex := -1
+	for i, entry := range tree.Entries {
+		if entry.Name == name {
+			entryIndex = i
+			break
+		}
+	}
+
+	newEntry := object.TreeEntry{Name: name}
+	if len(path) == 1 {
+		if blobHash == nil {
+			if entryIndex == -1 || !tree.Entries[entryIndex].Mode.IsFile() {
+				return plumbing.ZeroHash, fmt.Errorf("file not found")
+			}
+		} else {
+			newEntry.Mode = filemode.Regular
+			newEntry.Hash = *blobHash
+		}
+	} else {
+		subtreeHash := plumbing.ZeroHash
+		if entryIndex != -1 {
+			if tree.Entries[entryIndex].Mode != filemode.Dir {
+				return plumbing.ZeroHash, fmt.Errorf("%s is not a directory", name)
+			}
+			subtreeHash = tree.Entries[entryIndex].Hash
+		}
+		newSubtreeHash, err := updateTree(objectStorer, subtreeHash, path[1:], blobHash)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if !newSubtreeHash.IsZero() {
+			newEntry.Mode = filemode.Dir
+			newEntry.Hash = newSubtreeHash
+		}
+	}
+
+	entries := make([]object.TreeEntry, 0, len(tree.Entries)+1)
+	for i, entry := range tree.Entries {
+		if i != entryIndex {
+			entries = append(entries, entry)
+		}
+	}
+	if !newEntry.Hash.IsZero() {
+		entries = append(entries, newEntry)
+	}
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	// Git requires tree entries ordered by name, where directory names are compared with trailing slash
+	sort.Slice(entries, func(i, j int) bool {
+		return treeEntrySortName(entries[i]) < treeEntrySortName(entries[j])
+	})
+
+	newTree := objectStorer.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(newTree); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return objectStorer.SetEncodedObject(newTree)
+}
+
+func treeEntrySortName(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
+
+// storeSignedCommit signs the given commit and saves it. Returns hash of the signed commit.
+func storeSignedCommit(objectStorer storer.EncodedObjectStorer, commit *object.Commit, signer commitsigning.Signer) (plumbing.Hash, error) {
+	payload := objectStorer.NewEncodedObject()
+	if err := commit.EncodeWithoutSignature(payload); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	payloadReader, err := payload.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer payloadReader.Close()
+	payloadContent, err := io.ReadAll(payloadReader)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signature, err := signer.Sign(payloadContent)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to sign commit: %w", err)
+	}
+	commit.PGPSignature = signature
+
+	signedCommit := objectStorer.NewEncodedObject()
+	if err := commit.Encode(signedCommit); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return objectStorer.SetEncodedObject(signedCommit)
+}
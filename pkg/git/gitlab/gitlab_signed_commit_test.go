@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// readTreeFiles returns content of all files in the given tree by their paths.
+func readTreeFiles(t *testing.T, storage *memory.Storage, treeHash plumbing.Hash) map[string]string {
+	t.Helper()
+	tree, err := object.GetTree(storage, treeHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		files[file.Name] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func updateTestTree(t *testing.T, storage *memory.Storage, treeHash plumbing.Hash, path, content string, isDelete bool) plumbing.Hash {
+	t.Helper()
+	var blobHash *plumbing.Hash
+	if !isDelete {
+		hash, err := storeBlob(storage, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		blobHash = &hash
+	}
+	newTreeHash, err := updateTree(storage, treeHash, strings.Split(path, "/"), blobHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTreeHash
+}
+
+func TestUpdateTree(t *testing.T) {
+	storage := memory.NewStorage()
+
+	treeHash := updateTestTree(t, storage, plumbing.ZeroHash, "README.md", "readme", false)
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton/push.yaml", "push", false)
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton/pull-request.yaml", "pull request", false)
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton-old", "file", false)
+	if files := readTreeFiles(t, storage, treeHash); len(files) != 4 || files[".tekton/push.yaml"] != "push" ||
+		files[".tekton/pull-request.yaml"] != "pull request" || files["README.md"] != "readme" {
+		t.Errorf("unexpected files: %v", files)
+	}
+
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton/push.yaml", "updated push", false)
+	if files := readTreeFiles(t, storage, treeHash); len(files) != 4 || files[".tekton/push.yaml"] != "updated push" {
+		t.Errorf("unexpected files after update: %v", files)
+	}
+
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton/push.yaml", "", true)
+	treeHash = updateTestTree(t, storage, treeHash, ".tekton/pull-request.yaml", "", true)
+	tree, err := object.GetTree(storage, treeHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Entries) != 2 || tree.Entries[0].Name != ".tekton-old" || tree.Entries[1].Name != "README.md" {
+		t.Errorf("empty directory should be deleted, got entries: %v", tree.Entries)
+	}
+
+	if _, err := updateTree(storage, treeHash, []string{"missing.yaml"}, nil); err == nil {
+		t.Errorf("expected error on deletion of missing file")
+	}
+}
@@ -16,10 +16,22 @@ limitations under the License.
 
 package gitprovider
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+)
 
 const (
 	PipelinesAsCodeWebhhokInsecureSslEnvVar = "PAC_WEBHOOK_INSECURE_SSL"
+
+	// ForkMergeRequestsSecretKey is the Pipelines as Code secret field which, if set to true,
+	// makes merge request branches to be pushed into a fork of the target repository
+	// instead of the repository itself. Useful when the token is allowed to fork, but not to push.
+	ForkMergeRequestsSecretKey = "fork-merge-requests"
+	// ForkNamespaceSecretKey is the Pipelines as Code secret field with the organization (GitHub)
+	// or the group (GitLab) to create forks in. The token owner account is used if not set.
+	ForkNamespaceSecretKey = "fork-namespace"
 )
 
 func IsInsecureSSL() bool {
@@ -33,3 +45,10 @@ func IsInsecureSSL() bool {
 	}
 	return false
 }
+
+// IsForkModeEnabled checks whether merge requests should be created from a fork
+// according to the given Pipelines as Code secret data.
+func IsForkModeEnabled(config map[string][]byte) bool {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(string(config[ForkMergeRequestsSecretKey])))
+	return enabled
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitprovider
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultCallTimeout limits duration of a single git provider client call,
+// so a hung git hosting service doesn't block the caller until its own context is done.
+const DefaultCallTimeout = 2 * time.Minute
+
+// GitProviderClientWithContext is the context aware variant of GitProviderClient.
+// Each call is aborted when the given context is done or DefaultCallTimeout elapses, whichever happens first.
+// See GitProviderClient for description of the methods.
+type GitProviderClientWithContext interface {
+	EnsurePaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (webUrl string, err error)
+	UndoPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (webUrl string, err error)
+	FindUnmergedPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (*MergeRequest, error)
+	RefreshPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (webUrl string, refreshed bool, err error)
+	ResetPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (webUrl string, err error)
+	CommitPaCConfiguration(ctx context.Context, repoUrl string, data *MergeRequestData) (committed bool, err error)
+	CommitPaCConfigurationRemoval(ctx context.Context, repoUrl string, data *MergeRequestData) (committed bool, err error)
+	IsBranchProtected(ctx context.Context, repoUrl, branchName string) (bool, error)
+	GetMergeRequestStatus(ctx context.Context, repoUrl, mergeRequestWebUrl string) (*MergeRequestStatus, error)
+	SetupPaCWebhook(ctx context.Context, repoUrl, webhookUrl, webhookSecret string) error
+	DeletePaCWebhook(ctx context.Context, repoUrl, webhookUrl string) error
+	GetDefaultBranch(ctx context.Context, repoUrl string) (string, error)
+	DeleteBranch(ctx context.Context, repoUrl, branchName string) (bool, error)
+	GetBranchSha(ctx context.Context, repoUrl, branchName string) (string, error)
+	IsFileExist(ctx context.Context, repoUrl, branchName, filePath string) (bool, error)
+	DownloadFileContent(ctx context.Context, repoUrl, revision, filePath string) ([]byte, error)
+	IsRepositoryPublic(ctx context.Context, repoUrl string) (bool, error)
+	// GetBrowseRepositoryAtShaLink doesn't make any requests, so it doesn't need a context.
+	GetBrowseRepositoryAtShaLink(repoUrl, sha string) string
+	GetConfiguredGitAppName(ctx context.Context) (string, string, error)
+}
+
+// ContextBinder is implemented by git provider clients which are able to make their requests within a context.
+type ContextBinder interface {
+	// WithContext returns a shallow copy of the client which makes all its requests within the given context.
+	WithContext(ctx context.Context) (GitProviderClient, error)
+}
+
+// NewClientWithContext adapts the given client to GitProviderClientWithContext.
+// If the client doesn't implement ContextBinder, the context is checked only before each call,
+// an ongoing call cannot be interrupted.
+func NewClientWithContext(client GitProviderClient) GitProviderClientWithContext {
+	return &clientWithContext{client: client}
+}
+
+var _ GitProviderClientWithContext = (*clientWithContext)(nil)
+
+type clientWithContext struct {
+	client GitProviderClient
+}
+
+// bind returns the wrapped client bound to a call context derived from the given one.
+// The returned cancel function must be called when the call is finished.
+func (c *clientWithContext) bind(ctx context.Context) (GitProviderClient, context.CancelFunc, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	callCtx, cancel := context.WithTimeout(ctx, DefaultCallTimeout)
+	if binder, ok := c.client.(ContextBinder); ok {
+		client, err := binder.WithContext(callCtx)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		return client, cancel, nil
+	}
+	return c.client, cancel, nil
+}
+
+func (c *clientWithContext) EnsurePaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return client.EnsurePaCMergeRequest(repoUrl, data)
+}
+
+func (c *clientWithContext) UndoPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return client.UndoPaCMergeRequest(repoUrl, data)
+}
+
+func (c *clientWithContext) FindUnmergedPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (*MergeRequest, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return client.FindUnmergedPaCMergeRequest(repoUrl, data)
+}
+
+func (c *clientWithContext) RefreshPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (string, bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer cancel()
+	return client.RefreshPaCMergeRequest(repoUrl, data)
+}
+
+func (c *clientWithContext) ResetPaCMergeRequest(ctx context.Context, repoUrl string, data *MergeRequestData) (string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return client.ResetPaCMergeRequest(repoUrl, data)
+}
+
+func (c *clientWithContext) CommitPaCConfiguration(ctx context.Context, repoUrl string, data *MergeRequestData) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.CommitPaCConfiguration(repoUrl, data)
+}
+
+func (c *clientWithContext) CommitPaCConfigurationRemoval(ctx context.Context, repoUrl string, data *MergeRequestData) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.CommitPaCConfigurationRemoval(repoUrl, data)
+}
+
+func (c *clientWithContext) IsBranchProtected(ctx context.Context, repoUrl, branchName string) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.IsBranchProtected(repoUrl, branchName)
+}
+
+func (c *clientWithContext) GetMergeRequestStatus(ctx context.Context, repoUrl, mergeRequestWebUrl string) (*MergeRequestStatus, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return client.GetMergeRequestStatus(repoUrl, mergeRequestWebUrl)
+}
+
+func (c *clientWithContext) SetupPaCWebhook(ctx context.Context, repoUrl, webhookUrl, webhookSecret string) error {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return client.SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret)
+}
+
+func (c *clientWithContext) DeletePaCWebhook(ctx context.Context, repoUrl, webhookUrl string) error {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return client.DeletePaCWebhook(repoUrl, webhookUrl)
+}
+
+func (c *clientWithContext) GetDefaultBranch(ctx context.Context, repoUrl string) (string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return client.GetDefaultBranch(repoUrl)
+}
+
+func (c *clientWithContext) DeleteBranch(ctx context.Context, repoUrl, branchName string) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.DeleteBranch(repoUrl, branchName)
+}
+
+func (c *clientWithContext) GetBranchSha(ctx context.Context, repoUrl, branchName string) (string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	return client.GetBranchSha(repoUrl, branchName)
+}
+
+func (c *clientWithContext) IsFileExist(ctx context.Context, repoUrl, branchName, filePath string) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.IsFileExist(repoUrl, branchName, filePath)
+}
+
+func (c *clientWithContext) DownloadFileContent(ctx context.Context, repoUrl, revision, filePath string) ([]byte, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return client.DownloadFileContent(repoUrl, revision, filePath)
+}
+
+func (c *clientWithContext) IsRepositoryPublic(ctx context.Context, repoUrl string) (bool, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	return client.IsRepositoryPublic(repoUrl)
+}
+
+func (c *clientWithContext) GetBrowseRepositoryAtShaLink(repoUrl, sha string) string {
+	return c.client.GetBrowseRepositoryAtShaLink(repoUrl, sha)
+}
+
+func (c *clientWithContext) GetConfiguredGitAppName(ctx context.Context) (string, string, error) {
+	client, cancel, err := c.bind(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer cancel()
+	return client.GetConfiguredGitAppName()
+}
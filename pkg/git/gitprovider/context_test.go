@@ -0,0 +1,134 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testClient is a git provider client which records the context it was bound to.
+// Only GetDefaultBranch is implemented, other methods panic via the nil embedded interface.
+type testClient struct {
+	GitProviderClient
+	boundCtx context.Context
+}
+
+func (c *testClient) WithContext(ctx context.Context) (GitProviderClient, error) {
+	c.boundCtx = ctx
+	return c, nil
+}
+
+func (c *testClient) GetDefaultBranch(repoUrl string) (string, error) {
+	return "main", nil
+}
+
+// unbindableClient is a git provider client which fails to bind to a context.
+type unbindableClient struct {
+	GitProviderClient
+}
+
+func (c *unbindableClient) WithContext(ctx context.Context) (GitProviderClient, error) {
+	return nil, errors.New("cannot bind client")
+}
+
+// clientWithoutContext is a git provider client which doesn't support contexts.
+type clientWithoutContext struct {
+	GitProviderClient
+	calls int
+}
+
+func (c *clientWithoutContext) GetDefaultBranch(repoUrl string) (string, error) {
+	c.calls++
+	return "main", nil
+}
+
+func TestNewClientWithContextBindsCallDeadline(t *testing.T) {
+	tests := []struct {
+		name            string
+		timeout         time.Duration
+		maxCallDuration time.Duration
+	}{
+		{name: "should limit call without deadline", maxCallDuration: DefaultCallTimeout},
+		{name: "should keep earlier deadline", timeout: time.Minute, maxCallDuration: time.Minute},
+		{name: "should limit call with later deadline", timeout: time.Hour, maxCallDuration: DefaultCallTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			wrappedClient := &testClient{}
+			client := NewClientWithContext(wrappedClient)
+
+			branch, err := client.GetDefaultBranch(ctx, "https://githost.com/org/repository")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if branch != "main" {
+				t.Errorf("unexpected default branch: %s", branch)
+			}
+			if wrappedClient.boundCtx == nil {
+				t.Fatal("the client wasn't bound to the call context")
+			}
+			deadline, ok := wrappedClient.boundCtx.Deadline()
+			if !ok {
+				t.Fatal("the call context has no deadline")
+			}
+			if callDuration := time.Until(deadline); callDuration > tt.maxCallDuration || callDuration < tt.maxCallDuration-time.Minute/2 {
+				t.Errorf("unexpected call deadline in %v", callDuration)
+			}
+			if wrappedClient.boundCtx.Err() == nil {
+				t.Errorf("the call context must be released after the call")
+			}
+		})
+	}
+}
+
+func TestNewClientWithContextStopsOnDoneContext(t *testing.T) {
+	wrappedClient := &clientWithoutContext{}
+	client := NewClientWithContext(wrappedClient)
+
+	if _, err := client.GetDefaultBranch(context.Background(), "https://githost.com/org/repository"); err != nil {
+		t.Fatal(err)
+	}
+	if wrappedClient.calls != 1 {
+		t.Errorf("expected the wrapped client to be called")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetDefaultBranch(ctx, "https://githost.com/org/repository"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got: %v", err)
+	}
+	if wrappedClient.calls != 1 {
+		t.Errorf("the wrapped client must not be called with done context")
+	}
+}
+
+func TestNewClientWithContextReturnsBindError(t *testing.T) {
+	client := NewClientWithContext(&unbindableClient{})
+
+	if _, err := client.GetDefaultBranch(context.Background(), "https://githost.com/org/repository"); err == nil || err.Error() != "cannot bind client" {
+		t.Errorf("expected bind error, got: %v", err)
+	}
+}
@@ -16,7 +16,13 @@ limitations under the License.
 
 package gitprovider
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
 
 type GitProviderClient interface {
 	// EnsurePaCMergeRequest creates or updates existing (if needed) Pipelines as Code configuration proposal merge request.
@@ -32,6 +38,35 @@ type GitProviderClient interface {
 	// FindUnmergedPaCMergeRequest searches for existing Pipelines as Code configuration proposal merge request
 	FindUnmergedPaCMergeRequest(repoUrl string, data *MergeRequestData) (*MergeRequest, error)
 
+	// RefreshPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+	// from the current top of the base branch with the given files, if the branch is behind the base branch.
+	// Note, a branch which conflicts with the base branch is always behind it.
+	// Returns the merge request web URL and true if the branch was recreated.
+	// If there is no error and web URL is empty, it means that there is no open proposal merge request to refresh.
+	RefreshPaCMergeRequest(repoUrl string, data *MergeRequestData) (webUrl string, refreshed bool, err error)
+
+	// ResetPaCMergeRequest recreates the branch of the open Pipelines as Code configuration proposal merge request
+	// from the current top of the base branch with exactly the given files, even if the branch is up to date.
+	// The merge request is kept, unless the git provider doesn't allow force update of branches.
+	// Returns the merge request web URL, which is empty if there is no open proposal merge request.
+	ResetPaCMergeRequest(repoUrl string, data *MergeRequestData) (webUrl string, err error)
+
+	// CommitPaCConfiguration commits Pipelines as Code configuration files directly into the base branch, without a merge request.
+	// Returns false if the base branch is already up to date.
+	// Returns EGitBranchPushRejected build operation error if the git provider rejects the push.
+	CommitPaCConfiguration(repoUrl string, data *MergeRequestData) (committed bool, err error)
+
+	// CommitPaCConfigurationRemoval deletes Pipelines as Code configuration files directly from the base branch, without a merge request.
+	// Returns false if there is nothing to delete.
+	// Returns EGitBranchPushRejected build operation error if the git provider rejects the push.
+	CommitPaCConfigurationRemoval(repoUrl string, data *MergeRequestData) (committed bool, err error)
+
+	// IsBranchProtected returns true if direct pushes into the given branch are restricted.
+	IsBranchProtected(repoUrl, branchName string) (bool, error)
+
+	// GetMergeRequestStatus returns current state of the merge request with the given web URL in the given repository.
+	GetMergeRequestStatus(repoUrl, mergeRequestWebUrl string) (*MergeRequestStatus, error)
+
 	// SetupPaCWebhook creates Pipelines as Code webhook in the given repository
 	SetupPaCWebhook(repoUrl, webhookUrl, webhookSecret string) error
 
@@ -51,6 +86,10 @@ type GitProviderClient interface {
 	// IsFileExist check whether given file exists in the given branch of the reposiotry
 	IsFileExist(repoUrl, branchName, filePath string) (bool, error)
 
+	// DownloadFileContent returns content of the given file at the given revision of the repository.
+	// Revision could be a branch, tag or commit SHA. If revision is empty string, default branch is used.
+	DownloadFileContent(repoUrl, revision, filePath string) ([]byte, error)
+
 	// IsRepositoryPublic returns true if the repository could be accessed without authentication
 	IsRepositoryPublic(repoUrl string) (bool, error)
 
@@ -84,3 +123,36 @@ type MergeRequest struct {
 	WebUrl    string
 	Title     string
 }
+
+const (
+	MergeRequestStateOpen   = "open"
+	MergeRequestStateMerged = "merged"
+	// MergeRequestStateClosed means that the merge request was closed without merge.
+	MergeRequestStateClosed = "closed"
+)
+
+type MergeRequestStatus struct {
+	// State is one of: open, merged, closed.
+	State string
+	// Mergeable shows whether the open merge request can be merged without conflicts.
+	// Nil if the git provider hasn't computed it yet or doesn't provide it.
+	Mergeable *bool
+	// IsBehind shows whether the base branch has commits which are not in the open merge request branch.
+	IsBehind  bool
+	CreatedAt *time.Time
+	MergedAt  *time.Time
+}
+
+// GetMergeRequestNumberFromWebUrl returns number of the merge request within its repository,
+// which is the last element of the merge request web URL for all supported git providers.
+func GetMergeRequestNumberFromWebUrl(mergeRequestWebUrl string) (int, error) {
+	webUrl, err := url.Parse(mergeRequestWebUrl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid merge request URL %s: %w", mergeRequestWebUrl, err)
+	}
+	number, err := strconv.Atoi(path.Base(webUrl.Path))
+	if err != nil {
+		return 0, fmt.Errorf("merge request URL %s doesn't end with the merge request number", mergeRequestWebUrl)
+	}
+	return number, nil
+}
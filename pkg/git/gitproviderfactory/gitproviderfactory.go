@@ -17,18 +17,29 @@ limitations under the License.
 package gitproviderfactory
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/redhat-appstudio/application-service/gitops"
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/azuredevops"
+	"github.com/redhat-appstudio/build-service/pkg/git/bitbucket"
+	"github.com/redhat-appstudio/build-service/pkg/git/commitsigning"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitea"
 	"github.com/redhat-appstudio/build-service/pkg/git/github"
 	"github.com/redhat-appstudio/build-service/pkg/git/gitlab"
 	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 )
 
-var CreateGitClient func(gitClientConfig GitClientConfig) (gitprovider.GitProviderClient, error) = createGitClient
+var CreateGitClient func(ctx context.Context, gitClientConfig GitClientConfig) (gitprovider.GitProviderClientWithContext, error) = createGitClient
+
+const (
+	// bitbucketUsernameKey is the Pipelines as Code secret field with the name of Bitbucket user
+	// the app password from the token field belongs to.
+	bitbucketUsernameKey = "username"
+)
 
 type GitClientConfig struct {
 	// PacSecretData are the content of Pipelines as Code secret
@@ -45,10 +56,77 @@ type GitClientConfig struct {
 	// Ignored for clients created directly via a token.
 	// Only for simple builds must be set to false.
 	IsAppInstallationExpected bool
+	// ApiBaseUrl is the API endpoint of self-hosted git provider instance, e.g. GitHub Enterprise or self-hosted GitLab.
+	// Empty string means the public instance of the git provider.
+	ApiBaseUrl string
 }
 
-// createGitClient creates new git provider client for the requested config
-func createGitClient(gitClientConfig GitClientConfig) (gitprovider.GitProviderClient, error) {
+// createGitClient creates new git provider client for the requested config.
+// Each call of the client is bound to the context passed into the call.
+// The given context limits only the client creation, which might make requests to the git provider.
+func createGitClient(ctx context.Context, gitClientConfig GitClientConfig) (gitprovider.GitProviderClientWithContext, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitprovider.DefaultCallTimeout)
+	defer cancel()
+
+	client, err := createConfiguredGitClient(ctx, gitClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return gitprovider.NewClientWithContext(client), nil
+}
+
+// createConfiguredGitClient creates new git provider client for the requested config
+// and configures signing of the commits it creates and the repository to push merge request branches into.
+func createConfiguredGitClient(ctx context.Context, gitClientConfig GitClientConfig) (gitprovider.GitProviderClient, error) {
+	client, err := createUnsignedGitClient(ctx, gitClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	config := gitClientConfig.PacSecretData
+	commitSigner, err := commitsigning.NewSignerFromSecret(config)
+	if err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid, fmt.Errorf("failed to create git client: %w", err))
+	}
+	switch c := client.(type) {
+	case *github.GithubClient:
+		if commitSigner != nil {
+			c.SetCommitSigner(commitSigner)
+		} else if commitsigning.IsGitHubAppSigningEnabled(gitClientConfig.GitProvider, config) {
+			c.EnableAppCommitSigning()
+		}
+	case *gitlab.GitlabClient:
+		if commitSigner != nil {
+			c.SetCommitSigner(commitSigner)
+		}
+	default:
+		if commitSigner != nil {
+			return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+				fmt.Errorf("failed to create git client: commit signing is not supported for %s", gitClientConfig.GitProvider))
+		}
+	}
+
+	if gitprovider.IsForkModeEnabled(config) {
+		forkNamespace := strings.TrimSpace(string(config[gitprovider.ForkNamespaceSecretKey]))
+		switch c := client.(type) {
+		case *github.GithubClient:
+			c.EnableForkMode(forkNamespace)
+		case *gitlab.GitlabClient:
+			if commitSigner != nil {
+				// Signed commits are pushed via git protocol which cannot create the branch from the parent project
+				return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+					fmt.Errorf("failed to create git client: commit signing is not supported for merge requests from forks in GitLab"))
+			}
+			c.EnableForkMode(forkNamespace)
+		default:
+			return nil, boerrors.NewBuildOpError(boerrors.EPaCSecretInvalid,
+				fmt.Errorf("failed to create git client: merge requests from forks are not supported for %s", gitClientConfig.GitProvider))
+		}
+	}
+	return client, nil
+}
+
+func createUnsignedGitClient(ctx context.Context, gitClientConfig GitClientConfig) (gitprovider.GitProviderClient, error) {
 	gitProvider := gitClientConfig.GitProvider
 	config := gitClientConfig.PacSecretData
 
@@ -61,7 +139,7 @@ func createGitClient(gitClientConfig GitClientConfig) (gitprovider.GitProviderCl
 	switch gitProvider {
 	case "github":
 		if !isAppUsed {
-			return github.NewGithubClient(accessToken), nil
+			return github.NewGithubClient(accessToken, gitClientConfig.ApiBaseUrl)
 		}
 
 		githubAppIdStr := string(config[gitops.PipelinesAsCode_githubAppIdKey])
@@ -76,25 +154,12 @@ func createGitClient(gitClientConfig GitClientConfig) (gitprovider.GitProviderCl
 		if gitClientConfig.IsAppInstallationExpected {
 			// It's required that the configured Pipelines as Code application is installed into user's account
 			// and enabled for the given repository.
-
-			githubClient, err := github.NewGithubClientByApp(githubAppId, privateKey, gitClientConfig.RepoUrl)
-			if err != nil {
-				return nil, err
-			}
-
-			// Check if the application is installed into target repository
-			appInstalled, err := githubClient.IsAppInstalledIntoRepository(gitClientConfig.RepoUrl)
-			if err != nil {
-				return nil, err
-			}
-			if !appInstalled {
-				return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled,
-					fmt.Errorf("failed to create git client: GitHub Application is not installed into the repository"))
-			}
-			return githubClient, nil
+			// The client is created from the installation of the repository, so it fails if the application
+			// is not installed into the repository.
+			return github.NewGithubClientByApp(ctx, githubAppId, privateKey, gitClientConfig.RepoUrl, gitClientConfig.ApiBaseUrl)
 		} else {
 			// For simple builds we need to query repositories where configured Pipelines as Code application is not installed.
-			githubClient, err := github.NewGithubClientForSimpleBuildByApp(githubAppId, privateKey)
+			githubClient, err := github.NewGithubClientForSimpleBuildByApp(ctx, githubAppId, privateKey, gitClientConfig.ApiBaseUrl)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create GitHub client for simple build: %w", err)
 			}
@@ -105,10 +170,20 @@ func createGitClient(gitClientConfig GitClientConfig) (gitprovider.GitProviderCl
 		if isAppUsed {
 			return nil, fmt.Errorf("GitLab does not have applications")
 		}
-		return gitlab.NewGitlabClient(accessToken)
+		return gitlab.NewGitlabClient(accessToken, gitClientConfig.ApiBaseUrl)
 
 	case "bitbucket":
-		return nil, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, fmt.Errorf("git provider %s is not supported", gitProvider))
+		username := strings.TrimSpace(string(config[bitbucketUsernameKey]))
+		return bitbucket.NewBitbucketClient(username, accessToken)
+
+	case "gitea":
+		// Gitea is always self-hosted, so the instance URL must be known
+		return gitea.NewGiteaClient(accessToken, gitClientConfig.ApiBaseUrl)
+
+	case "azure-devops":
+		// Organization and project are part of the repository URL, so only self-hosted server URL is needed
+		return azuredevops.NewAzureDevOpsClient(accessToken, gitClientConfig.ApiBaseUrl)
+
 	default:
 		return nil, boerrors.NewBuildOpError(boerrors.EUnknownGitProvider, fmt.Errorf("git provider %s is not supported", gitProvider))
 	}
@@ -17,30 +17,48 @@ limitations under the License.
 package gitproviderfactory
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/redhat-appstudio/application-service/gitops"
 
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/azuredevops"
+	"github.com/redhat-appstudio/build-service/pkg/git/bitbucket"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitea"
 	"github.com/redhat-appstudio/build-service/pkg/git/github"
 	"github.com/redhat-appstudio/build-service/pkg/git/gitlab"
+	"github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
 )
 
 func TestGetContainerImageRepository(t *testing.T) {
 	denyAllConstructors := func() {
-		github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
+		github.NewGithubClientByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*github.GithubClient, error) {
 			t.Errorf("should not be invoked")
 			return nil, nil
 		}
-		github.NewGithubClientForSimpleBuildByApp = func(appId int64, privateKeyPem []byte) (*github.GithubClient, error) {
+		github.NewGithubClientForSimpleBuildByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string) (*github.GithubClient, error) {
 			t.Errorf("should not be invoked")
 			return nil, nil
 		}
-		github.NewGithubClient = func(accessToken string) *github.GithubClient {
+		github.NewGithubClient = func(accessToken, baseUrl string) (*github.GithubClient, error) {
 			t.Errorf("should not be invoked")
-			return nil
+			return nil, nil
+		}
+		gitlab.NewGitlabClient = func(accessToken, baseUrl string) (*gitlab.GitlabClient, error) {
+			t.Errorf("should not be invoked")
+			return nil, nil
+		}
+		bitbucket.NewBitbucketClient = func(username, appPassword string) (*bitbucket.BitbucketClient, error) {
+			t.Errorf("should not be invoked")
+			return nil, nil
+		}
+		gitea.NewGiteaClient = func(accessToken, baseUrl string) (*gitea.GiteaClient, error) {
+			t.Errorf("should not be invoked")
+			return nil, nil
 		}
-		gitlab.NewGitlabClient = func(accessToken string) (*gitlab.GitlabClient, error) {
+		azuredevops.NewAzureDevOpsClient = func(accessToken, baseUrl string) (*azuredevops.AzureDevOpsClient, error) {
 			t.Errorf("should not be invoked")
 			return nil, nil
 		}
@@ -66,7 +84,7 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
-				github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
+				github.NewGithubClientByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*github.GithubClient, error) {
 					return &github.GithubClient{}, nil
 				}
 			},
@@ -84,11 +102,8 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
-				github.IsAppInstalledIntoRepository = func(ghclient *github.GithubClient, repoUrl string) (bool, error) {
-					return false, nil
-				}
-				github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
-					return &github.GithubClient{}, nil
+				github.NewGithubClientByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*github.GithubClient, error) {
+					return nil, boerrors.NewBuildOpError(boerrors.EGitHubAppNotInstalled, fmt.Errorf("installation not found"))
 				}
 			},
 			expectError: true,
@@ -105,7 +120,7 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
-				github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
+				github.NewGithubClientByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*github.GithubClient, error) {
 					return &github.GithubClient{}, nil
 				}
 			},
@@ -123,7 +138,7 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
-				github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
+				github.NewGithubClientByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, repoUrl, baseUrl string) (*github.GithubClient, error) {
 					return nil, fmt.Errorf("wrong key")
 				}
 			},
@@ -141,10 +156,7 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: false,
 			},
 			allowConstructors: func() {
-				github.IsAppInstalledIntoRepository = func(ghclient *github.GithubClient, repoUrl string) (bool, error) {
-					return false, nil
-				}
-				github.NewGithubClientForSimpleBuildByApp = func(appId int64, privateKeyPem []byte) (*github.GithubClient, error) {
+				github.NewGithubClientForSimpleBuildByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string) (*github.GithubClient, error) {
 					return &github.GithubClient{}, nil
 				}
 			},
@@ -162,78 +174,184 @@ func TestGetContainerImageRepository(t *testing.T) {
 				IsAppInstallationExpected: false,
 			},
 			allowConstructors: func() {
-				github.NewGithubClientForSimpleBuildByApp = func(appId int64, privateKeyPem []byte) (*github.GithubClient, error) {
+				github.NewGithubClientForSimpleBuildByApp = func(ctx context.Context, appId int64, privateKeyPem []byte, baseUrl string) (*github.GithubClient, error) {
 					return nil, fmt.Errorf("wrong key")
 				}
 			},
 			expectError: true,
 		},
 		{
-			name: "should create GitHub client, but fail when check if the application is installed into target repository fails",
+			name: "should create GitHub client from token",
 			gitClientConfig: GitClientConfig{
 				PacSecretData: map[string][]byte{
-					gitops.PipelinesAsCode_githubAppIdKey:   []byte("12345"),
-					gitops.PipelinesAsCode_githubPrivateKey: []byte("private key"),
+					"github_token": []byte("token"),
 				},
 				GitProvider:               "github",
 				RepoUrl:                   repoUrl,
-				IsAppInstallationExpected: true,
+				IsAppInstallationExpected: false,
 			},
 			allowConstructors: func() {
-				github.IsAppInstalledIntoRepository = func(ghclient *github.GithubClient, repoUrl string) (bool, error) {
-					return false, fmt.Errorf("application check failed")
-				}
-				github.NewGithubClientByApp = func(appId int64, privateKeyPem []byte, repoUrl string) (*github.GithubClient, error) {
+				github.NewGithubClient = func(accessToken, baseUrl string) (*github.GithubClient, error) {
 					return &github.GithubClient{}, nil
 				}
 			},
-			expectError: true,
+			expectError: false,
 		},
 		{
-			name: "should create GitHub client from token",
+			name: "should create GitLab client from token",
+			gitClientConfig: GitClientConfig{
+				PacSecretData: map[string][]byte{
+					"gitlab_token": []byte("token"),
+				},
+				GitProvider:               "gitlab",
+				RepoUrl:                   repoUrl,
+				IsAppInstallationExpected: true,
+			},
+			allowConstructors: func() {
+				gitlab.NewGitlabClient = func(accessToken, baseUrl string) (*gitlab.GitlabClient, error) {
+					return &gitlab.GitlabClient{}, nil
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "should create GitHub Enterprise client from token",
 			gitClientConfig: GitClientConfig{
 				PacSecretData: map[string][]byte{
 					"github_token": []byte("token"),
 				},
 				GitProvider:               "github",
-				RepoUrl:                   repoUrl,
+				RepoUrl:                   "https://github.example.com/org/repository",
 				IsAppInstallationExpected: false,
+				ApiBaseUrl:                "https://github.example.com",
 			},
 			allowConstructors: func() {
-				github.NewGithubClient = func(accessToken string) *github.GithubClient {
-					return &github.GithubClient{}
+				github.NewGithubClient = func(accessToken, baseUrl string) (*github.GithubClient, error) {
+					if baseUrl != "https://github.example.com" {
+						t.Errorf("unexpected GitHub API base URL: %s", baseUrl)
+					}
+					return &github.GithubClient{}, nil
 				}
 			},
 			expectError: false,
 		},
 		{
-			name: "should create GitLab client from token",
+			name: "should create self-hosted GitLab client from token",
 			gitClientConfig: GitClientConfig{
 				PacSecretData: map[string][]byte{
 					"gitlab_token": []byte("token"),
 				},
 				GitProvider:               "gitlab",
+				RepoUrl:                   "https://gitlab.example.com/org/repository",
+				IsAppInstallationExpected: true,
+				ApiBaseUrl:                "https://gitlab.example.com/api/v4",
+			},
+			allowConstructors: func() {
+				gitlab.NewGitlabClient = func(accessToken, baseUrl string) (*gitlab.GitlabClient, error) {
+					if baseUrl != "https://gitlab.example.com/api/v4" {
+						t.Errorf("unexpected GitLab API base URL: %s", baseUrl)
+					}
+					return &gitlab.GitlabClient{}, nil
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "should create BitBucket client from username and app password",
+			gitClientConfig: GitClientConfig{
+				PacSecretData: map[string][]byte{
+					"bitbucket.token": []byte("token"),
+					"username":        []byte("user"),
+				},
+				GitProvider:               "bitbucket",
 				RepoUrl:                   repoUrl,
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
-				gitlab.NewGitlabClient = func(accessToken string) (*gitlab.GitlabClient, error) {
+				bitbucket.NewBitbucketClient = func(username, appPassword string) (*bitbucket.BitbucketClient, error) {
+					if username != "user" || appPassword != "token" {
+						t.Errorf("unexpected Bitbucket credentials: %s %s", username, appPassword)
+					}
+					return &bitbucket.BitbucketClient{}, nil
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "should create Gitea client from token",
+			gitClientConfig: GitClientConfig{
+				PacSecretData: map[string][]byte{
+					"gitea.token": []byte("token"),
+				},
+				GitProvider:               "gitea",
+				RepoUrl:                   "https://gitea.example.com/org/repository",
+				IsAppInstallationExpected: true,
+				ApiBaseUrl:                "https://gitea.example.com",
+			},
+			allowConstructors: func() {
+				gitea.NewGiteaClient = func(accessToken, baseUrl string) (*gitea.GiteaClient, error) {
+					if accessToken != "token" || baseUrl != "https://gitea.example.com" {
+						t.Errorf("unexpected Gitea client parameters: %s %s", accessToken, baseUrl)
+					}
+					return &gitea.GiteaClient{}, nil
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "should create Azure DevOps client from personal access token",
+			gitClientConfig: GitClientConfig{
+				PacSecretData: map[string][]byte{
+					"azure-devops.token": []byte("pat"),
+				},
+				GitProvider:               "azure-devops",
+				RepoUrl:                   "https://dev.azure.com/org/project/_git/repository",
+				IsAppInstallationExpected: true,
+			},
+			allowConstructors: func() {
+				azuredevops.NewAzureDevOpsClient = func(accessToken, baseUrl string) (*azuredevops.AzureDevOpsClient, error) {
+					if accessToken != "pat" || baseUrl != "" {
+						t.Errorf("unexpected Azure DevOps client parameters: %s %s", accessToken, baseUrl)
+					}
+					return &azuredevops.AzureDevOpsClient{}, nil
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "should create GitLab client which creates merge requests from forks",
+			gitClientConfig: GitClientConfig{
+				PacSecretData: map[string][]byte{
+					"gitlab_token":                         []byte("token"),
+					gitprovider.ForkMergeRequestsSecretKey: []byte("true"),
+				},
+				GitProvider:               "gitlab",
+				RepoUrl:                   repoUrl,
+				IsAppInstallationExpected: true,
+			},
+			allowConstructors: func() {
+				gitlab.NewGitlabClient = func(accessToken, baseUrl string) (*gitlab.GitlabClient, error) {
 					return &gitlab.GitlabClient{}, nil
 				}
 			},
 			expectError: false,
 		},
 		{
-			name: "should not create BitBucket client",
+			name: "should not create Bitbucket client which creates merge requests from forks",
 			gitClientConfig: GitClientConfig{
 				PacSecretData: map[string][]byte{
-					"bitbucket_token": []byte("token"),
+					"bitbucket.token":                      []byte("token"),
+					"username":                             []byte("user"),
+					gitprovider.ForkMergeRequestsSecretKey: []byte("true"),
 				},
 				GitProvider:               "bitbucket",
 				RepoUrl:                   repoUrl,
 				IsAppInstallationExpected: true,
 			},
 			allowConstructors: func() {
+				bitbucket.NewBitbucketClient = func(username, appPassword string) (*bitbucket.BitbucketClient, error) {
+					return &bitbucket.BitbucketClient{}, nil
+				}
 			},
 			expectError: true,
 		},
@@ -254,14 +372,16 @@ func TestGetContainerImageRepository(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			// Listing all repositories of the installation is expensive, the installation lookup is enough
 			github.IsAppInstalledIntoRepository = func(ghclient *github.GithubClient, repoUrl string) (bool, error) {
-				return true, nil
+				t.Errorf("should not be invoked")
+				return false, nil
 			}
 
 			denyAllConstructors()
 			tt.allowConstructors()
 
-			gitClient, err := createGitClient(tt.gitClientConfig)
+			gitClient, err := createGitClient(context.Background(), tt.gitClientConfig)
 
 			if err != nil {
 				if !tt.expectError {
@@ -0,0 +1,733 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitprovidertest provides contract tests, which every git provider client implementation must pass.
+// The tests are provider agnostic: the repository state is prepared and verified via fake git provider,
+// which the tested client operates on directly or via an HTTP stand-in of the git provider API.
+package gitprovidertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	"github.com/redhat-appstudio/build-service/pkg/git/fake"
+	gp "github.com/redhat-appstudio/build-service/pkg/git/gitprovider"
+)
+
+const (
+	repoUrl           = "https://git.example.com/devfile-samples/devfile-sample-python-basic"
+	defaultBranch     = "main"
+	pacBranch         = "appstudio-devfile-sample-python-basic"
+	pullRequestFile   = ".tekton/devfile-sample-python-basic-pull-request.yaml"
+	pushFile          = ".tekton/devfile-sample-python-basic-push.yaml"
+	sourceFile        = "main.py"
+	webhookTargetUrl  = "https://pac.example.com/webhook"
+	mergeRequestTitle = "Red Hat Trusted App Pipeline update devfile-sample-python-basic"
+)
+
+// NewClientFunc returns client of the tested implementation, which operates on the repositories of the given fake git provider.
+type NewClientFunc func(t *testing.T, provider *fake.GitProvider) gp.GitProviderClient
+
+// Options describes deviations from the common behavior, which the contract allows to the tested implementation.
+type Options struct {
+	// MergeRequestRecreatedOnRefresh should be set if the git provider doesn't allow to rewrite merge request branch,
+	// so the implementation closes the outdated merge request and opens a new one on refresh and reset.
+	MergeRequestRecreatedOnRefresh bool
+}
+
+type contractTest struct {
+	name string
+	test func(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider)
+}
+
+// RunContractTests runs the contract tests as subtests of the given test.
+// Each subtest gets new fake git provider with a repository, which has only source file in its default branch.
+// The clients are called via context adapter, as the controllers do.
+func RunContractTests(t *testing.T, newClient NewClientFunc) {
+	RunContractTestsWithOptions(t, newClient, Options{})
+}
+
+// RunContractTestsWithOptions runs the contract tests as RunContractTests does, taking into account the allowed deviations.
+func RunContractTestsWithOptions(t *testing.T, newClient NewClientFunc, options Options) {
+	tests := append(repositoryContractTests(), []contractTest{
+		{name: "IsBranchProtected should return branch protection", test: testIsBranchProtected},
+		{name: "DeleteBranch should delete existing branch only", test: testDeleteBranch},
+		{name: "EnsurePaCMergeRequest should create and update merge request", test: testEnsurePaCMergeRequest},
+		{name: "EnsurePaCMergeRequest should do nothing if base branch is up to date", test: testEnsurePaCMergeRequestNotNeeded},
+		{name: "FindUnmergedPaCMergeRequest should find open merge request only", test: testFindUnmergedPaCMergeRequest},
+		{name: "GetMergeRequestStatus should return merge request state", test: testGetMergeRequestStatus},
+		{name: "RefreshPaCMergeRequest should rebase outdated merge request", test: func(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+			testRefreshPaCMergeRequest(t, client, provider, options)
+		}},
+		{name: "ResetPaCMergeRequest should replace merge request files", test: func(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+			testResetPaCMergeRequest(t, client, provider, options)
+		}},
+		{name: "UndoPaCMergeRequest should create configuration removal merge request", test: testUndoPaCMergeRequest},
+		{name: "CommitPaCConfiguration should commit into base branch", test: testCommitPaCConfiguration},
+		{name: "CommitPaCConfiguration should report rejected push", test: testCommitPaCConfigurationIntoProtectedBranch},
+		{name: "CommitPaCConfigurationRemoval should delete configuration from base branch", test: testCommitPaCConfigurationRemoval},
+		{name: "SetupPaCWebhook and DeletePaCWebhook should manage webhook", test: testPaCWebhook},
+	}...)
+	runContractTests(t, newClient, tests)
+}
+
+// RunRepositoryContractTests runs only the contract tests of read only repository operations.
+// It is meant for implementations which are used for builds without Pipelines as Code only.
+func RunRepositoryContractTests(t *testing.T, newClient NewClientFunc) {
+	runContractTests(t, newClient, repositoryContractTests())
+}
+
+func repositoryContractTests() []contractTest {
+	return []contractTest{
+		{name: "GetDefaultBranch should return default branch", test: testGetDefaultBranch},
+		{name: "GetBranchSha should return top commit of the branch", test: testGetBranchSha},
+		{name: "IsFileExist should check file in the branch", test: testIsFileExist},
+		{name: "DownloadFileContent should return file content at the revision", test: testDownloadFileContent},
+		{name: "IsRepositoryPublic should return repository visibility", test: testIsRepositoryPublic},
+	}
+}
+
+func runContractTests(t *testing.T, newClient NewClientFunc, tests []contractTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := fake.NewGitProvider()
+			provider.AddRepository(repoUrl, defaultBranch, []gp.RepositoryFile{{FullPath: sourceFile, Content: []byte("print('Hello')\n")}})
+			client := gp.NewClientWithContext(newClient(t, provider))
+			tt.test(t, client, provider)
+		})
+	}
+}
+
+// newMergeRequestData returns Pipelines as Code configuration merge request data with the given pipeline definition.
+// Base branch is not set, so the default branch is used.
+func newMergeRequestData(pipeline string) *gp.MergeRequestData {
+	return &gp.MergeRequestData{
+		CommitMessage: "Red Hat Trusted App Pipeline update",
+		BranchName:    pacBranch,
+		Title:         mergeRequestTitle,
+		Text:          "Pipelines as Code configuration proposal",
+		// GitLab client searches merge requests by the author
+		AuthorName:  fake.TokenOwner,
+		AuthorEmail: "build-service@example.com",
+		Files: []gp.RepositoryFile{
+			{FullPath: pullRequestFile, Content: []byte("pull request " + pipeline)},
+			{FullPath: pushFile, Content: []byte("push " + pipeline)},
+		},
+	}
+}
+
+func getCommit(t *testing.T, provider *fake.GitProvider, revision string) *fake.Commit {
+	t.Helper()
+	c, err := provider.GetCommit(repoUrl, revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// hasAncestor checks whether the given commit is a descendant of the ancestor commit.
+// Some providers commit the files one by one, so the ancestor isn't necessarily the direct parent.
+func hasAncestor(t *testing.T, provider *fake.GitProvider, c *fake.Commit, ancestorSha string) bool {
+	t.Helper()
+	visited := map[string]bool{}
+	queue := c.ParentShas
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if sha == ancestorSha {
+			return true
+		}
+		if visited[sha] {
+			continue
+		}
+		visited[sha] = true
+		queue = append(queue, getCommit(t, provider, sha).ParentShas...)
+	}
+	return false
+}
+
+func getMergeRequests(t *testing.T, provider *fake.GitProvider) []fake.MergeRequest {
+	t.Helper()
+	mergeRequests, err := provider.GetMergeRequests(repoUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mergeRequests
+}
+
+// assertFiles checks that the commit has the given files with the same content.
+func assertFiles(t *testing.T, c *fake.Commit, files []gp.RepositoryFile) {
+	t.Helper()
+	for _, file := range files {
+		content, exists := c.Files[file.FullPath]
+		if !exists {
+			t.Errorf("file %s is missing in commit %s", file.FullPath, c.Sha)
+		} else if !bytes.Equal(content, file.Content) {
+			t.Errorf("unexpected content of %s in commit %s: %s", file.FullPath, c.Sha, content)
+		}
+	}
+}
+
+func assertNoFiles(t *testing.T, c *fake.Commit, files []gp.RepositoryFile) {
+	t.Helper()
+	for _, file := range files {
+		if _, exists := c.Files[file.FullPath]; exists {
+			t.Errorf("file %s should not exist in commit %s", file.FullPath, c.Sha)
+		}
+	}
+}
+
+func testGetDefaultBranch(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	branch, err := client.GetDefaultBranch(context.Background(), repoUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != defaultBranch {
+		t.Errorf("expected %s default branch, got %s", defaultBranch, branch)
+	}
+}
+
+func testGetBranchSha(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	sha, err := client.GetBranchSha(ctx, repoUrl, defaultBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectedSha := getCommit(t, provider, defaultBranch).Sha; sha != expectedSha {
+		t.Errorf("expected %s SHA, got %s", expectedSha, sha)
+	}
+
+	newSha, err := provider.PushFiles(repoUrl, defaultBranch, []gp.RepositoryFile{{FullPath: "README.md", Content: []byte("readme")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha, err = client.GetBranchSha(ctx, repoUrl, defaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	if sha != newSha {
+		t.Errorf("expected %s SHA after push, got %s", newSha, sha)
+	}
+}
+
+func testIsFileExist(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	if _, err := provider.PushFiles(repoUrl, "feature", []gp.RepositoryFile{{FullPath: pushFile, Content: []byte("push")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		branchName string
+		filePath   string
+		exists     bool
+	}{
+		{branchName: "", filePath: sourceFile, exists: true},
+		{branchName: defaultBranch, filePath: sourceFile, exists: true},
+		{branchName: defaultBranch, filePath: pushFile, exists: false},
+		{branchName: "feature", filePath: pushFile, exists: true},
+		{branchName: "feature", filePath: pullRequestFile, exists: false},
+	}
+	for _, tt := range tests {
+		exists, err := client.IsFileExist(ctx, repoUrl, tt.branchName, tt.filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tt.exists {
+			t.Errorf("expected existence of %s in %q branch to be %t", tt.filePath, tt.branchName, tt.exists)
+		}
+	}
+}
+
+func testDownloadFileContent(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	oldSha := getCommit(t, provider, defaultBranch).Sha
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, []gp.RepositoryFile{{FullPath: sourceFile, Content: []byte("print('Bye')\n")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		revision string
+		content  string
+	}{
+		{revision: "", content: "print('Bye')\n"},
+		{revision: defaultBranch, content: "print('Bye')\n"},
+		{revision: oldSha, content: "print('Hello')\n"},
+	}
+	for _, tt := range tests {
+		content, err := client.DownloadFileContent(ctx, repoUrl, tt.revision, sourceFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tt.content {
+			t.Errorf("unexpected content at %q revision: %s", tt.revision, content)
+		}
+	}
+
+	if _, err := client.DownloadFileContent(ctx, repoUrl, defaultBranch, pushFile); err == nil {
+		t.Errorf("expected error on download of missing file")
+	}
+}
+
+func testIsRepositoryPublic(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	for _, public := range []bool{true, false} {
+		if err := provider.SetRepositoryPublic(repoUrl, public); err != nil {
+			t.Fatal(err)
+		}
+		isPublic, err := client.IsRepositoryPublic(ctx, repoUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isPublic != public {
+			t.Errorf("expected repository public to be %t", public)
+		}
+	}
+}
+
+func testIsBranchProtected(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	for _, protected := range []bool{false, true} {
+		if err := provider.SetBranchProtected(repoUrl, defaultBranch, protected); err != nil {
+			t.Fatal(err)
+		}
+		isProtected, err := client.IsBranchProtected(ctx, repoUrl, defaultBranch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isProtected != protected {
+			t.Errorf("expected branch protected to be %t", protected)
+		}
+	}
+}
+
+func testDeleteBranch(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	if _, err := provider.PushFiles(repoUrl, pacBranch, []gp.RepositoryFile{{FullPath: pushFile, Content: []byte("push")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := client.DeleteBranch(ctx, repoUrl, pacBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Errorf("expected existing branch to be deleted")
+	}
+	if _, err := provider.GetCommit(repoUrl, pacBranch); err == nil {
+		t.Errorf("the branch still exists")
+	}
+
+	if deleted, err = client.DeleteBranch(ctx, repoUrl, pacBranch); err != nil {
+		t.Fatal(err)
+	}
+	if deleted {
+		t.Errorf("missing branch should not be reported as deleted")
+	}
+}
+
+func testEnsurePaCMergeRequest(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	baseSha := getCommit(t, provider, defaultBranch).Sha
+	d := newMergeRequestData("v1")
+
+	webUrl, err := client.EnsurePaCMergeRequest(ctx, repoUrl, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl == "" {
+		t.Fatal("expected merge request web URL")
+	}
+	mergeRequests := getMergeRequests(t, provider)
+	if len(mergeRequests) != 1 {
+		t.Fatalf("expected 1 merge request, got %d", len(mergeRequests))
+	}
+	mr := mergeRequests[0]
+	if mr.WebUrl != webUrl || mr.Title != mergeRequestTitle || mr.SourceBranch != pacBranch || mr.TargetBranch != defaultBranch {
+		t.Errorf("unexpected merge request: %+v", mr)
+	}
+	branchCommit := getCommit(t, provider, pacBranch)
+	assertFiles(t, branchCommit, d.Files)
+	if branchCommit.AuthorName != d.AuthorName || branchCommit.AuthorEmail != d.AuthorEmail {
+		t.Errorf("unexpected commit author: %s <%s>", branchCommit.AuthorName, branchCommit.AuthorEmail)
+	}
+	if getCommit(t, provider, defaultBranch).Sha != baseSha {
+		t.Errorf("base branch must not be changed")
+	}
+
+	// Repeated call should not change anything
+	if webUrl, err = client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != mr.WebUrl {
+		t.Errorf("expected the existing merge request %s, got %s", mr.WebUrl, webUrl)
+	}
+	if getCommit(t, provider, pacBranch).Sha != branchCommit.Sha {
+		t.Errorf("up to date merge request branch must not be changed")
+	}
+
+	// New configuration should update the existing merge request
+	d = newMergeRequestData("v2")
+	if webUrl, err = client.EnsurePaCMergeRequest(ctx, repoUrl, d); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != mr.WebUrl {
+		t.Errorf("expected the existing merge request %s, got %s", mr.WebUrl, webUrl)
+	}
+	if len(getMergeRequests(t, provider)) != 1 {
+		t.Errorf("no new merge request should be created")
+	}
+	assertFiles(t, getCommit(t, provider, pacBranch), d.Files)
+}
+
+func testEnsurePaCMergeRequestNotNeeded(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	d := newMergeRequestData("v1")
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, d.Files); err != nil {
+		t.Fatal(err)
+	}
+
+	webUrl, err := client.EnsurePaCMergeRequest(context.Background(), repoUrl, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != "" {
+		t.Errorf("expected no merge request, got %s", webUrl)
+	}
+	if len(getMergeRequests(t, provider)) != 0 {
+		t.Errorf("no merge request should be created")
+	}
+}
+
+func testFindUnmergedPaCMergeRequest(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	findData := newMergeRequestData("v1")
+	findData.BaseBranchName = defaultBranch
+
+	mr, err := client.FindUnmergedPaCMergeRequest(ctx, repoUrl, findData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr != nil {
+		t.Fatalf("expected no merge request, got %+v", mr)
+	}
+
+	if _, err := client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if mr, err = client.FindUnmergedPaCMergeRequest(ctx, repoUrl, findData); err != nil {
+		t.Fatal(err)
+	}
+	if mr == nil {
+		t.Fatal("expected the open merge request to be found")
+	}
+	if mr.Title != mergeRequestTitle || mr.WebUrl == "" || mr.CreatedAt == nil {
+		t.Errorf("unexpected merge request: %+v", mr)
+	}
+
+	if err := provider.MergeMergeRequest(repoUrl, getMergeRequests(t, provider)[0].Number); err != nil {
+		t.Fatal(err)
+	}
+	if mr, err = client.FindUnmergedPaCMergeRequest(ctx, repoUrl, findData); err != nil {
+		t.Fatal(err)
+	}
+	if mr != nil {
+		t.Errorf("merged merge request should not be found")
+	}
+}
+
+func testGetMergeRequestStatus(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	assertState := func(webUrl, expectedState string) *gp.MergeRequestStatus {
+		t.Helper()
+		status, err := client.GetMergeRequestStatus(ctx, repoUrl, webUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State != expectedState {
+			t.Errorf("expected %s merge request, got %s", expectedState, status.State)
+		}
+		return status
+	}
+
+	webUrl, err := client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := assertState(webUrl, gp.MergeRequestStateOpen); status.CreatedAt == nil || status.MergedAt != nil || status.IsBehind {
+		t.Errorf("unexpected open merge request status: %+v", status)
+	}
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, []gp.RepositoryFile{{FullPath: pushFile, Content: []byte("update")}}); err != nil {
+		t.Fatal(err)
+	}
+	if status := assertState(webUrl, gp.MergeRequestStateOpen); !status.IsBehind {
+		t.Errorf("merge request should be behind the updated base branch")
+	}
+	if err := provider.MergeMergeRequest(repoUrl, getMergeRequests(t, provider)[0].Number); err != nil {
+		t.Fatal(err)
+	}
+	if status := assertState(webUrl, gp.MergeRequestStateMerged); status.MergedAt == nil {
+		t.Errorf("merged merge request should have merge time")
+	}
+
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, []gp.RepositoryFile{{FullPath: pushFile, Content: []byte("custom")}}); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl, err = client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.CloseMergeRequest(repoUrl, getMergeRequests(t, provider)[1].Number); err != nil {
+		t.Fatal(err)
+	}
+	assertState(webUrl, gp.MergeRequestStateClosed)
+}
+
+// assertRefreshedMergeRequest checks that the refreshed merge request is the only open one.
+// It's the original merge request, unless the implementation is allowed to recreate it.
+func assertRefreshedMergeRequest(t *testing.T, provider *fake.GitProvider, options Options, originalWebUrl, webUrl string) {
+	t.Helper()
+	mergeRequests := getMergeRequests(t, provider)
+	if !options.MergeRequestRecreatedOnRefresh {
+		if webUrl != originalWebUrl {
+			t.Errorf("expected the existing merge request %s, got %s", originalWebUrl, webUrl)
+		}
+		if len(mergeRequests) != 1 || mergeRequests[0].State != gp.MergeRequestStateOpen {
+			t.Errorf("the merge request should stay open")
+		}
+		return
+	}
+	if len(mergeRequests) != 2 || mergeRequests[0].WebUrl != originalWebUrl || mergeRequests[0].State != gp.MergeRequestStateClosed ||
+		mergeRequests[1].WebUrl != webUrl || mergeRequests[1].State != gp.MergeRequestStateOpen {
+		t.Errorf("the original merge request should be closed in favor of the new one %s: %+v", webUrl, mergeRequests)
+	}
+}
+
+func testRefreshPaCMergeRequest(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider, options Options) {
+	ctx := context.Background()
+
+	webUrl, refreshed, err := client.RefreshPaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != "" || refreshed {
+		t.Errorf("nothing should be refreshed without merge request")
+	}
+
+	mrWebUrl, err := client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl, refreshed, err = client.RefreshPaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != mrWebUrl || refreshed {
+		t.Errorf("up to date merge request should not be refreshed")
+	}
+
+	sourceUpdate := []gp.RepositoryFile{{FullPath: sourceFile, Content: []byte("print('Bye')\n")}}
+	baseSha, err := provider.PushFiles(repoUrl, defaultBranch, sourceUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newMergeRequestData("v2")
+	if webUrl, refreshed, err = client.RefreshPaCMergeRequest(ctx, repoUrl, d); err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed {
+		t.Errorf("outdated merge request should be refreshed")
+	}
+	branchCommit := getCommit(t, provider, pacBranch)
+	if !hasAncestor(t, provider, branchCommit, baseSha) {
+		t.Errorf("merge request branch should be based on the top of the base branch, parents: %v", branchCommit.ParentShas)
+	}
+	assertFiles(t, branchCommit, append(sourceUpdate, d.Files...))
+	assertRefreshedMergeRequest(t, provider, options, mrWebUrl, webUrl)
+}
+
+func testResetPaCMergeRequest(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider, options Options) {
+	ctx := context.Background()
+
+	webUrl, err := client.ResetPaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != "" {
+		t.Errorf("nothing should be reset without merge request")
+	}
+
+	mrWebUrl, err := client.EnsurePaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newMergeRequestData("v2")
+	d.Files = d.Files[:1]
+	if webUrl, err = client.ResetPaCMergeRequest(ctx, repoUrl, d); err != nil {
+		t.Fatal(err)
+	}
+	branchCommit := getCommit(t, provider, pacBranch)
+	assertFiles(t, branchCommit, d.Files)
+	if _, exists := branchCommit.Files[pushFile]; exists {
+		t.Errorf("file %s should be removed from the merge request", pushFile)
+	}
+	assertRefreshedMergeRequest(t, provider, options, mrWebUrl, webUrl)
+}
+
+func testUndoPaCMergeRequest(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	d := newMergeRequestData("v1")
+
+	webUrl, err := client.UndoPaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webUrl != "" {
+		t.Errorf("expected no merge request without configuration, got %s", webUrl)
+	}
+
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, d.Files); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl, err = client.UndoPaCMergeRequest(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if webUrl == "" {
+		t.Fatal("expected merge request web URL")
+	}
+	mergeRequests := getMergeRequests(t, provider)
+	if len(mergeRequests) != 1 || mergeRequests[0].WebUrl != webUrl || mergeRequests[0].SourceBranch != pacBranch {
+		t.Errorf("unexpected merge requests: %+v", mergeRequests)
+	}
+	branchCommit := getCommit(t, provider, pacBranch)
+	assertNoFiles(t, branchCommit, d.Files)
+	if _, exists := branchCommit.Files[sourceFile]; !exists {
+		t.Errorf("other files must be kept")
+	}
+	assertFiles(t, getCommit(t, provider, defaultBranch), d.Files)
+}
+
+func testCommitPaCConfiguration(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	d := newMergeRequestData("v1")
+
+	committed, err := client.CommitPaCConfiguration(ctx, repoUrl, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !committed {
+		t.Errorf("expected configuration to be committed")
+	}
+	baseCommit := getCommit(t, provider, defaultBranch)
+	assertFiles(t, baseCommit, d.Files)
+	if baseCommit.AuthorName != d.AuthorName {
+		t.Errorf("unexpected commit author: %s", baseCommit.AuthorName)
+	}
+	if len(getMergeRequests(t, provider)) != 0 {
+		t.Errorf("no merge request should be created")
+	}
+
+	if committed, err = client.CommitPaCConfiguration(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if committed {
+		t.Errorf("up to date configuration should not be committed")
+	}
+}
+
+func testCommitPaCConfigurationIntoProtectedBranch(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	if err := provider.SetBranchProtected(repoUrl, defaultBranch, true); err != nil {
+		t.Fatal(err)
+	}
+	baseSha := getCommit(t, provider, defaultBranch).Sha
+
+	_, err := client.CommitPaCConfiguration(context.Background(), repoUrl, newMergeRequestData("v1"))
+	var boErr *boerrors.BuildOpError
+	if !errors.As(err, &boErr) || boErr.GetErrorId() != int(boerrors.EGitBranchPushRejected) {
+		t.Errorf("expected push rejected error, got: %v", err)
+	}
+	if getCommit(t, provider, defaultBranch).Sha != baseSha {
+		t.Errorf("protected branch must not be changed")
+	}
+}
+
+func testCommitPaCConfigurationRemoval(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	d := newMergeRequestData("v1")
+
+	committed, err := client.CommitPaCConfigurationRemoval(ctx, repoUrl, newMergeRequestData("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed {
+		t.Errorf("nothing should be committed without configuration")
+	}
+
+	if _, err := provider.PushFiles(repoUrl, defaultBranch, d.Files); err != nil {
+		t.Fatal(err)
+	}
+	if committed, err = client.CommitPaCConfigurationRemoval(ctx, repoUrl, newMergeRequestData("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if !committed {
+		t.Errorf("expected configuration removal to be committed")
+	}
+	baseCommit := getCommit(t, provider, defaultBranch)
+	assertNoFiles(t, baseCommit, d.Files)
+	if _, exists := baseCommit.Files[sourceFile]; !exists {
+		t.Errorf("other files must be kept")
+	}
+}
+
+func testPaCWebhook(t *testing.T, client gp.GitProviderClientWithContext, provider *fake.GitProvider) {
+	ctx := context.Background()
+	getWebhooks := func() []fake.Webhook {
+		t.Helper()
+		webhooks, err := provider.GetWebhooks(repoUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return webhooks
+	}
+
+	if err := client.SetupPaCWebhook(ctx, repoUrl, webhookTargetUrl, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if webhooks := getWebhooks(); len(webhooks) != 1 || webhooks[0].Url != webhookTargetUrl || webhooks[0].Secret != "secret" {
+		t.Fatalf("unexpected webhooks: %+v", webhooks)
+	}
+
+	// Existing webhook should get the new secret
+	if err := client.SetupPaCWebhook(ctx, repoUrl, webhookTargetUrl, "new-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if webhooks := getWebhooks(); len(webhooks) != 1 || webhooks[0].Secret != "new-secret" {
+		t.Fatalf("unexpected webhooks after update: %+v", webhooks)
+	}
+
+	if err := client.SetupPaCWebhook(ctx, repoUrl, "https://other.example.com/webhook", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeletePaCWebhook(ctx, repoUrl, webhookTargetUrl); err != nil {
+		t.Fatal(err)
+	}
+	if webhooks := getWebhooks(); len(webhooks) != 1 || webhooks[0].Url == webhookTargetUrl {
+		t.Errorf("unexpected webhooks after deletion: %+v", webhooks)
+	}
+
+	// Deletion of missing webhook is not an error
+	if err := client.DeletePaCWebhook(ctx, repoUrl, webhookTargetUrl); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ratelimit provides HTTP transport for git provider API clients
+// which waits for the git provider rate limit reset instead of failing the request.
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+const (
+	// maxRetries is the number of retries of a rate limited request.
+	maxRetries = 3
+	// maxRetryDelay is the longest wait for rate limit reset within a request.
+	// If the rate limit resets later, the operation should be retried by the caller.
+	maxRetryDelay = 10 * time.Second
+	// minRetryDelay compensates rounding of reset time to seconds and clock skew.
+	minRetryDelay = time.Second
+	// defaultRetryDelay is used when the git provider doesn't say when the rate limit resets.
+	// GitHub recommends to wait at least one minute in case of secondary rate limits.
+	defaultRetryDelay = time.Minute
+
+	// secondaryRateLimitBodyLimit limits the response body size inspected for secondary rate limit message.
+	secondaryRateLimitBodyLimit = 64 * 1024
+)
+
+// Allow mocking for tests
+var wait func(ctx context.Context, delay time.Duration) error = waitWithContext
+var now func() time.Time = time.Now
+
+// NewTransport returns HTTP transport which recognizes rate limited responses of GitHub and GitLab APIs.
+// Rate limited idempotent requests are repeated, if the rate limit resets soon enough.
+// Otherwise, transient build operation error with the delay until the rate limit reset is returned.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		delay, isRateLimited := getRetryDelay(resp)
+		if !isRateLimited {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if attempt >= maxRetries || delay > maxRetryDelay || !isRetriable(req) {
+			return nil, boerrors.NewTransientBuildOpError(fmt.Errorf("git provider rate limit exceeded, retry in %s", delay), delay)
+		}
+		if err := wait(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getRetryDelay returns true if the response shows that a rate limit is hit
+// and the delay after which the request could be repeated.
+func getRetryDelay(resp *http.Response) (time.Duration, bool) {
+	// GitHub responds with 403 in case of rate limits
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	// GitHub secondary rate limits and GitLab set it
+	if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return delay, true
+	}
+	// GitHub uses X-RateLimit-* headers, GitLab uses RateLimit-* headers
+	for _, headerPrefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if resp.Header.Get(headerPrefix+"Remaining") != "0" {
+			continue
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get(headerPrefix+"Reset"), 10, 64); err == nil {
+			return normalizeDelay(time.Unix(reset, 0).Sub(now())), true
+		}
+		return defaultRetryDelay, true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+		return defaultRetryDelay, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses value of Retry-After header, which is either delay in seconds or HTTP date.
+func parseRetryAfter(retryAfter string) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil {
+		return normalizeDelay(time.Duration(seconds) * time.Second), true
+	}
+	if retryTime, err := http.ParseTime(retryAfter); err == nil {
+		return normalizeDelay(retryTime.Sub(now())), true
+	}
+	return 0, false
+}
+
+func normalizeDelay(delay time.Duration) time.Duration {
+	if delay < minRetryDelay {
+		return minRetryDelay
+	}
+	return delay
+}
+
+// isSecondaryRateLimit detects GitHub secondary rate limit response without Retry-After header.
+// Such responses are distinguishable by the error message only, so the body is read and restored.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, secondaryRateLimitBodyLimit))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+// isRetriable returns true if the request could be safely repeated.
+func isRetriable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// rewindRequest returns copy of the request with unread body.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	newReq := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		newReq.Body = body
+	}
+	return newReq, nil
+}
+
+func waitWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+var testNow = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// mockTime makes the transport use fixed current time and records waits instead of sleeping.
+func mockTime(t *testing.T) *[]time.Duration {
+	waits := []time.Duration{}
+	now = func() time.Time { return testNow }
+	wait = func(ctx context.Context, delay time.Duration) error {
+		waits = append(waits, delay)
+		return ctx.Err()
+	}
+	t.Cleanup(func() {
+		now = time.Now
+		wait = waitWithContext
+	})
+	return &waits
+}
+
+func TestGetRetryDelay(t *testing.T) {
+	mockTime(t)
+	resetIn := func(delay time.Duration) string {
+		return strconv.FormatInt(testNow.Add(delay).Unix(), 10)
+	}
+
+	tests := []struct {
+		name          string
+		statusCode    int
+		headers       map[string]string
+		body          string
+		isRateLimited bool
+		delay         time.Duration
+	}{
+		{
+			name:          "should not detect rate limit in successful response",
+			statusCode:    http.StatusOK,
+			headers:       map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": resetIn(time.Minute)},
+			isRateLimited: false,
+		},
+		{
+			name:          "should detect GitHub primary rate limit",
+			statusCode:    http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": resetIn(5 * time.Minute)},
+			isRateLimited: true,
+			delay:         5 * time.Minute,
+		},
+		{
+			name:          "should detect GitHub secondary rate limit with Retry-After header",
+			statusCode:    http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "4000", "Retry-After": "30"},
+			isRateLimited: true,
+			delay:         30 * time.Second,
+		},
+		{
+			name:          "should detect GitHub secondary rate limit without Retry-After header",
+			statusCode:    http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "4000"},
+			body:          `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`,
+			isRateLimited: true,
+			delay:         defaultRetryDelay,
+		},
+		{
+			name:          "should not detect rate limit in GitHub permission error",
+			statusCode:    http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "4000"},
+			body:          `{"message": "Resource not accessible by integration"}`,
+			isRateLimited: false,
+		},
+		{
+			name:          "should detect GitLab rate limit",
+			statusCode:    http.StatusTooManyRequests,
+			headers:       map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": resetIn(20 * time.Second)},
+			isRateLimited: true,
+			delay:         20 * time.Second,
+		},
+		{
+			name:          "should use Retry-After date",
+			statusCode:    http.StatusTooManyRequests,
+			headers:       map[string]string{"Retry-After": testNow.Add(2 * time.Minute).Format(http.TimeFormat)},
+			isRateLimited: true,
+			delay:         2 * time.Minute,
+		},
+		{
+			name:          "should use default delay if reset time is unknown",
+			statusCode:    http.StatusTooManyRequests,
+			isRateLimited: true,
+			delay:         defaultRetryDelay,
+		},
+		{
+			name:          "should not return delay shorter than minimal",
+			statusCode:    http.StatusForbidden,
+			headers:       map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": resetIn(-time.Minute)},
+			isRateLimited: true,
+			delay:         minRetryDelay,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			for name, value := range tt.headers {
+				resp.Header.Set(name, value)
+			}
+
+			delay, isRateLimited := getRetryDelay(resp)
+			if isRateLimited != tt.isRateLimited {
+				t.Fatalf("expected rate limited: %t", tt.isRateLimited)
+			}
+			if delay != tt.delay {
+				t.Errorf("expected delay %v, got %v", tt.delay, delay)
+			}
+			// The body must be available for the client
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("response body is changed: %s", string(body))
+			}
+		})
+	}
+}
+
+// newRateLimitedServer returns server which responds with GitLab rate limit error the given number of times
+// and then returns the request body.
+func newRateLimitedServer(t *testing.T, rateLimitedResponses int, retryAfter string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= rateLimitedResponses {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTransportRetriesRateLimitedRequest(t *testing.T) {
+	waits := mockTime(t)
+	server, requests := newRateLimitedServer(t, 2, "3")
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if string(body) != "content" {
+		t.Errorf("request body is not resent on retry, got: %s", string(body))
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 requests, got %d", *requests)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 3*time.Second || (*waits)[1] != 3*time.Second {
+		t.Errorf("unexpected waits: %v", *waits)
+	}
+}
+
+func TestTransportReturnsTransientError(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		rateLimitedResponses int
+		retryAfter           string
+		expectedRequests     int
+		expectedDelay        time.Duration
+	}{
+		{
+			name:                 "should not wait for distant rate limit reset",
+			method:               http.MethodGet,
+			rateLimitedResponses: 1,
+			retryAfter:           "600",
+			expectedRequests:     1,
+			expectedDelay:        10 * time.Minute,
+		},
+		{
+			name:                 "should not retry not idempotent request",
+			method:               http.MethodPost,
+			rateLimitedResponses: 1,
+			retryAfter:           "5",
+			expectedRequests:     1,
+			expectedDelay:        5 * time.Second,
+		},
+		{
+			name:                 "should give up after max retries",
+			method:               http.MethodGet,
+			rateLimitedResponses: maxRetries + 1,
+			retryAfter:           "5",
+			expectedRequests:     maxRetries + 1,
+			expectedDelay:        5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTime(t)
+			server, requests := newRateLimitedServer(t, tt.rateLimitedResponses, tt.retryAfter)
+			client := &http.Client{Transport: NewTransport(nil)}
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("expected rate limit error")
+			}
+			var boErr *boerrors.BuildOpError
+			if !errors.As(err, &boErr) || boErr.IsPersistent() {
+				t.Errorf("expected transient build operation error, got: %v", err)
+			}
+			if requeueAfter := boerrors.GetRequeueAfter(err); requeueAfter != tt.expectedDelay {
+				t.Errorf("expected %v requeue delay, got %v", tt.expectedDelay, requeueAfter)
+			}
+			if *requests != tt.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tt.expectedRequests, *requests)
+			}
+		})
+	}
+}
+
+func TestTransportStopsWaitingOnDoneContext(t *testing.T) {
+	server, requests := newRateLimitedServer(t, 1, "5")
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got: %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("expected 1 request, got %d", *requests)
+	}
+}
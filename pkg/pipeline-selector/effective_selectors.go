@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"sort"
+
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+)
+
+// EffectivePipelineSelector is an item of the merged pipeline selectors list.
+type EffectivePipelineSelector struct {
+	// Namespace and name of the BuildPipelineSelector object the item comes from.
+	Namespace string
+	Name      string
+	// Index of the item in the selectors list of the object.
+	Index int
+	// PinBundleDigest is copied from the BuildPipelineSelector object the item comes from.
+	PinBundleDigest bool
+
+	Selector *buildappstudiov1alpha1.PipelineSelector
+}
+
+// GetEffectivePipelineSelectors merges given BuildPipelineSelectors into single ordered list of items
+// according to the priority and mode of each BuildPipelineSelector.
+// The selectors must be ordered from the most specific (application) to the least specific (global) one.
+func GetEffectivePipelineSelectors(selectors []buildappstudiov1alpha1.BuildPipelineSelector) []EffectivePipelineSelector {
+	// Merge from the least specific selector by default
+	mergeOrder := make([]int, len(selectors))
+	for i := range selectors {
+		mergeOrder[i] = len(selectors) - 1 - i
+	}
+	sort.SliceStable(mergeOrder, func(i, j int) bool {
+		return selectors[mergeOrder[i]].Spec.Priority < selectors[mergeOrder[j]].Spec.Priority
+	})
+
+	var effectiveSelectors []EffectivePipelineSelector
+	for _, selectorIndex := range mergeOrder {
+		buildPipelineSelector := &selectors[selectorIndex]
+
+		items := make([]EffectivePipelineSelector, 0, len(buildPipelineSelector.Spec.Selectors))
+		for i := range buildPipelineSelector.Spec.Selectors {
+			items = append(items, EffectivePipelineSelector{
+				Namespace:       buildPipelineSelector.Namespace,
+				Name:            buildPipelineSelector.Name,
+				Index:           i,
+				PinBundleDigest: buildPipelineSelector.Spec.PinBundleDigest,
+				Selector:        &buildPipelineSelector.Spec.Selectors[i],
+			})
+		}
+
+		if buildPipelineSelector.Spec.Mode == buildappstudiov1alpha1.BuildPipelineSelectorMergeModeReplace {
+			effectiveSelectors = items
+			continue
+		}
+
+		newItems := items
+		if buildPipelineSelector.Spec.OverrideByName {
+			newItems = nil
+			for _, item := range items {
+				if overriddenItemIndex := findEffectivePipelineSelector(effectiveSelectors, item.Selector.Name); overriddenItemIndex != -1 {
+					effectiveSelectors[overriddenItemIndex] = item
+				} else {
+					newItems = append(newItems, item)
+				}
+			}
+		}
+		if buildPipelineSelector.Spec.Mode == buildappstudiov1alpha1.BuildPipelineSelectorMergeModeAppend {
+			effectiveSelectors = append(effectiveSelectors, newItems...)
+		} else {
+			effectiveSelectors = append(newItems, effectiveSelectors...)
+		}
+	}
+	return effectiveSelectors
+}
+
+// findEffectivePipelineSelector returns index of the item with the given name or -1 if there is no such item.
+// Unnamed items never match.
+func findEffectivePipelineSelector(effectiveSelectors []EffectivePipelineSelector, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i := range effectiveSelectors {
+		if effectiveSelectors[i].Selector.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"reflect"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetEffectivePipelineSelectors(t *testing.T) {
+	newSelector := func(namespace, name string, priority int32, mode buildappstudiov1alpha1.BuildPipelineSelectorMergeMode, itemNames ...string) buildappstudiov1alpha1.BuildPipelineSelector {
+		selector := buildappstudiov1alpha1.BuildPipelineSelector{
+			ObjectMeta: v1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Priority: priority,
+				Mode:     mode,
+			},
+		}
+		for _, itemName := range itemNames {
+			selector.Spec.Selectors = append(selector.Spec.Selectors, buildappstudiov1alpha1.PipelineSelector{
+				Name:        itemName,
+				PipelineRef: newBundleResolverPipelineRef("my-bundle", name+"-"+itemName),
+			})
+		}
+		return selector
+	}
+	withOverrideByName := func(selector buildappstudiov1alpha1.BuildPipelineSelector) buildappstudiov1alpha1.BuildPipelineSelector {
+		selector.Spec.OverrideByName = true
+		return selector
+	}
+	// Returns items of the effective list in form of <selector name>/<item name>
+	describe := func(effectiveSelectors []EffectivePipelineSelector) []string {
+		var items []string
+		for _, effectiveSelector := range effectiveSelectors {
+			items = append(items, effectiveSelector.Name+"/"+effectiveSelector.Selector.Name)
+		}
+		return items
+	}
+
+	tests := []struct {
+		name      string
+		selectors []buildappstudiov1alpha1.BuildPipelineSelector
+		want      []string
+	}{
+		{
+			name: "should keep the most specific items first by default",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "app", 0, "", "java"),
+				newSelector("ns", "namespace", 0, "", "python"),
+				newSelector("build-service", "global", 0, "", "nodejs", "docker"),
+			},
+			want: []string{"app/java", "namespace/python", "global/nodejs", "global/docker"},
+		},
+		{
+			name: "should put appended items after the less specific ones",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "namespace", 0, buildappstudiov1alpha1.BuildPipelineSelectorMergeModeAppend, "fallback"),
+				newSelector("build-service", "global", 0, "", "nodejs", "docker"),
+			},
+			want: []string{"global/nodejs", "global/docker", "namespace/fallback"},
+		},
+		{
+			name: "should drop less specific items in replace mode",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "app", 0, "", "java"),
+				newSelector("ns", "namespace", 0, buildappstudiov1alpha1.BuildPipelineSelectorMergeModeReplace, "python"),
+				newSelector("build-service", "global", 0, "", "nodejs", "docker"),
+			},
+			want: []string{"app/java", "namespace/python"},
+		},
+		{
+			name: "should concatenate items with the same name by default",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "namespace", 0, "", "new", "docker"),
+				newSelector("build-service", "global", 0, "", "nodejs", "docker", "", "fallback"),
+			},
+			want: []string{"namespace/new", "namespace/docker", "global/nodejs", "global/docker", "global/", "global/fallback"},
+		},
+		{
+			name: "should override item with the same name in place if requested",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				withOverrideByName(newSelector("ns", "namespace", 0, "", "new", "docker")),
+				newSelector("build-service", "global", 0, "", "nodejs", "docker", "", "fallback"),
+			},
+			want: []string{"namespace/new", "global/nodejs", "namespace/docker", "global/", "global/fallback"},
+		},
+		{
+			name: "should not override unnamed items",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				withOverrideByName(newSelector("ns", "namespace", 0, buildappstudiov1alpha1.BuildPipelineSelectorMergeModeAppend, "")),
+				newSelector("build-service", "global", 0, "", ""),
+			},
+			want: []string{"global/", "namespace/"},
+		},
+		{
+			name: "should merge selector with higher priority last",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "app", 0, "", "java"),
+				newSelector("ns", "namespace", 0, "", "python"),
+				newSelector("build-service", "global", 10, buildappstudiov1alpha1.BuildPipelineSelectorMergeModePrepend, "mandatory"),
+			},
+			want: []string{"global/mandatory", "app/java", "namespace/python"},
+		},
+		{
+			name: "should not allow lower priority selector to replace higher priority items",
+			selectors: []buildappstudiov1alpha1.BuildPipelineSelector{
+				newSelector("ns", "app", -1, buildappstudiov1alpha1.BuildPipelineSelectorMergeModeReplace, "java"),
+				newSelector("build-service", "global", 0, "", "nodejs"),
+			},
+			want: []string{"global/nodejs", "app/java"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effectiveSelectors := GetEffectivePipelineSelectors(tt.selectors)
+			if got := describe(effectiveSelectors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetEffectivePipelineSelectors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePipelineSelectorsWithOverride(t *testing.T) {
+	component := getComponentWithDevfile(`
+        schemaVersion: 2.2.0
+        metadata:
+            name: devfile-nodejs
+            language: nodejs
+    `)
+	selectors := []buildappstudiov1alpha1.BuildPipelineSelector{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "test-namespace"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				PinBundleDigest: true,
+				OverrideByName:  true,
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "NodeJS",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "custom-nodejs-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs"},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "build-service"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "Java",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "java-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "java"},
+					},
+					{
+						Name:           "NodeJS",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "nodejs-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs"},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := EvaluatePipelineSelectors(component, selectors)
+	if err != nil {
+		t.Fatalf("EvaluatePipelineSelectors(): unexpected error: %v", err)
+	}
+	wantPipelineRef := selectors[0].Spec.Selectors[0].PipelineRef.AsPipelineRef()
+	if !reflect.DeepEqual(result.PipelineRef, wantPipelineRef) {
+		t.Errorf("EvaluatePipelineSelectors(): pipelineRef got: %v, want: %v", result.PipelineRef, wantPipelineRef)
+	}
+	wantMatchedSelector := &MatchedSelector{Namespace: "test-namespace", Name: "build-pipeline-selector", Index: 0, ItemName: "NodeJS", PinBundleDigest: true}
+	if !reflect.DeepEqual(result.Matched, wantMatchedSelector) {
+		t.Errorf("EvaluatePipelineSelectors(): matched selector got: %v, want: %v", result.Matched, wantMatchedSelector)
+	}
+	wantTrace := []buildappstudiov1alpha1.PipelineSelectorTrace{
+		{
+			Namespace: "build-service",
+			Name:      "build-pipeline-selector",
+			Items: []buildappstudiov1alpha1.PipelineSelectorItemTrace{
+				{Index: 0, Name: "Java", FailedCondition: `language "nodejs" is not one of "java"`},
+			},
+		},
+		{
+			Namespace: "test-namespace",
+			Name:      "build-pipeline-selector",
+			Items: []buildappstudiov1alpha1.PipelineSelectorItemTrace{
+				{Index: 0, Name: "NodeJS", Matched: true},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result.Trace, wantTrace) {
+		t.Errorf("EvaluatePipelineSelectors(): trace got: %v, want: %v", result.Trace, wantTrace)
+	}
+}
+
+func TestEvaluatePipelineSelectorsWithLegacyConfiguration(t *testing.T) {
+	component := getComponentWithDevfile(`
+        schemaVersion: 2.2.0
+        metadata:
+            name: devfile-nodejs
+            language: nodejs
+    `)
+	// Neither mode nor priority is set, so the items are just concatenated even if their names are the same
+	selectors := []buildappstudiov1alpha1.BuildPipelineSelector{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "test-namespace"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "NodeJS",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "custom-nodejs-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs", ProjectType: "quarkus"},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "build-service"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "NodeJS",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "nodejs-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs"},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := EvaluatePipelineSelectors(component, selectors)
+	if err != nil {
+		t.Fatalf("EvaluatePipelineSelectors(): unexpected error: %v", err)
+	}
+	wantPipelineRef := selectors[1].Spec.Selectors[0].PipelineRef.AsPipelineRef()
+	if !reflect.DeepEqual(result.PipelineRef, wantPipelineRef) {
+		t.Errorf("EvaluatePipelineSelectors(): pipelineRef got: %v, want: %v", result.PipelineRef, wantPipelineRef)
+	}
+	wantMatchedSelector := &MatchedSelector{Namespace: "build-service", Name: "build-pipeline-selector", Index: 0, ItemName: "NodeJS"}
+	if !reflect.DeepEqual(result.Matched, wantMatchedSelector) {
+		t.Errorf("EvaluatePipelineSelectors(): matched selector got: %v, want: %v", result.Matched, wantMatchedSelector)
+	}
+}
+
+func getComponentWithDevfile(devfile string) *appstudiov1alpha1.Component {
+	return &appstudiov1alpha1.Component{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-component",
+			Namespace: "test-namespace",
+		},
+		Status: appstudiov1alpha1.ComponentStatus{
+			Devfile: devfile,
+		},
+	}
+}
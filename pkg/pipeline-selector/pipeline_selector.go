@@ -17,6 +17,8 @@ limitations under the License.
 package pipelineselector
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
@@ -27,21 +29,62 @@ import (
 	"github.com/redhat-appstudio/build-service/pkg/boerrors"
 )
 
+// MatchedSelector describes the pipeline selector item the build pipeline was selected by.
+type MatchedSelector struct {
+	// Namespace and name of the BuildPipelineSelector object.
+	Namespace string
+	Name      string
+	// Index of the matched item in the selectors list of the object.
+	Index int
+	// Name of the matched item, if set.
+	ItemName string
+	// PinBundleDigest shows whether the BuildPipelineSelector requests pinning of the pipeline bundle to digest.
+	PinBundleDigest bool
+}
+
 // SelectPipelineForComponent evaluates given list of pipeline selectors against specified component
 // to find the build pipeline for the component.
 // The first match is returned.
 func SelectPipelineForComponent(component *appstudiov1alpha1.Component, selectors []buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param, error) {
-	selectionParameters, err := getPipelineSelectionParametersForComponent(component)
+	pipelineRef, pipelineParams, _, err := SelectPipelineForComponentWithMatch(component, selectors)
+	return pipelineRef, pipelineParams, err
+}
+
+// SelectPipelineForComponentWithMatch does the same as SelectPipelineForComponent,
+// but also returns the pipeline selector item which matched the component.
+func SelectPipelineForComponentWithMatch(component *appstudiov1alpha1.Component, selectors []buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param, *MatchedSelector, error) {
+	result, err := EvaluatePipelineSelectors(component, selectors)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+	return result.PipelineRef, result.PipelineParams, result.Matched, nil
+}
 
-	for i := range selectors {
-		if buildPipelineRef, buildPipelineAdditionalParams := findMatchingPipeline(selectionParameters, &selectors[i]); buildPipelineRef != nil {
-			return buildPipelineRef, buildPipelineAdditionalParams, nil
-		}
+// SelectionResult describes the result of the build pipeline selection for a component.
+type SelectionResult struct {
+	PipelineRef    *tektonapi.PipelineRef
+	PipelineParams []tektonapi.Param
+	// Matched is nil if no pipeline selector item matches the component.
+	Matched *MatchedSelector
+	// Trace shows how each of the evaluated selectors matched the component.
+	Trace []buildappstudiov1alpha1.PipelineSelectorTrace
+}
+
+// EvaluatePipelineSelectors evaluates given list of pipeline selectors against specified component
+// and records how each of the selector items matched the component.
+// The selectors are merged into effective list first, see GetEffectivePipelineSelectors.
+// Evaluation stops at the first matching selector item.
+func EvaluatePipelineSelectors(component *appstudiov1alpha1.Component, selectors []buildappstudiov1alpha1.BuildPipelineSelector) (*SelectionResult, error) {
+	selectionParameters, err := getPipelineSelectionParametersForComponent(component)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil, nil
+	expressionVariables, err := getWhenExpressionVariables(component, selectionParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return findMatchingPipeline(selectionParameters, expressionVariables, GetEffectivePipelineSelectors(selectors)), nil
 }
 
 // getPipelineSelectionParametersForComponent returns build parameters of the given component
@@ -82,50 +125,116 @@ func getPipelineSelectionParametersForComponent(component *appstudiov1alpha1.Com
 	return parameters, nil
 }
 
-// findMatchingPipeline evaluates given selectors chain against component parameters.
-// The first match is returned.
-func findMatchingPipeline(selectionParameters *buildappstudiov1alpha1.WhenCondition, selectors *buildappstudiov1alpha1.BuildPipelineSelector) (*tektonapi.PipelineRef, []tektonapi.Param) {
-	for _, pipelineSelector := range selectors.Spec.Selectors {
-		if pipelineConditionsMatchComponentParameters(&pipelineSelector.WhenConditions, selectionParameters) {
-			var pipelineParams []tektonapi.Param
-			for _, param := range pipelineSelector.PipelineParams {
-				pipelineParams = append(pipelineParams, tektonapi.Param{
-					Name:  param.Name,
-					Value: *tektonapi.NewStructuredValues(param.Value),
-				})
-			}
-			return pipelineSelector.PipelineRef.AsPipelineRef(), pipelineParams
+// findMatchingPipeline evaluates given effective selectors chain against component parameters.
+// The first match is returned together with the trace of the evaluated selector items.
+// Consecutive items of the same BuildPipelineSelector are grouped in the trace.
+// Expression variables are used to evaluate CEL expression of the when conditions, if any.
+func findMatchingPipeline(selectionParameters *buildappstudiov1alpha1.WhenCondition, expressionVariables map[string]interface{}, effectiveSelectors []EffectivePipelineSelector) *SelectionResult {
+	result := &SelectionResult{}
+	for _, effectiveSelector := range effectiveSelectors {
+		pipelineSelector := effectiveSelector.Selector
+		failedCondition := getFailedCondition(&pipelineSelector.WhenConditions, selectionParameters, expressionVariables)
+
+		if len(result.Trace) == 0 ||
+			result.Trace[len(result.Trace)-1].Namespace != effectiveSelector.Namespace ||
+			result.Trace[len(result.Trace)-1].Name != effectiveSelector.Name {
+			result.Trace = append(result.Trace, buildappstudiov1alpha1.PipelineSelectorTrace{
+				Namespace: effectiveSelector.Namespace,
+				Name:      effectiveSelector.Name,
+			})
+		}
+		selectorTrace := &result.Trace[len(result.Trace)-1]
+		selectorTrace.Items = append(selectorTrace.Items, buildappstudiov1alpha1.PipelineSelectorItemTrace{
+			Index:           effectiveSelector.Index,
+			Name:            pipelineSelector.Name,
+			Matched:         failedCondition == "",
+			FailedCondition: failedCondition,
+		})
+		if failedCondition != "" {
+			continue
+		}
+
+		var pipelineParams []tektonapi.Param
+		for _, param := range pipelineSelector.PipelineParams {
+			pipelineParams = append(pipelineParams, tektonapi.Param{
+				Name:  param.Name,
+				Value: *tektonapi.NewStructuredValues(param.Value),
+			})
+		}
+		result.PipelineRef = pipelineSelector.PipelineRef.AsPipelineRef()
+		result.PipelineParams = pipelineParams
+		result.Matched = &MatchedSelector{
+			Namespace:       effectiveSelector.Namespace,
+			Name:            effectiveSelector.Name,
+			Index:           effectiveSelector.Index,
+			ItemName:        pipelineSelector.Name,
+			PinBundleDigest: effectiveSelector.PinBundleDigest,
+		}
+		return result
+	}
+	return result
+}
+
+// getFailedCondition returns description of the first pipeline condition not satisfied by the component
+// or empty string if the component matches all the conditions, including CEL expression.
+// An expression which cannot be evaluated doesn't match, so a broken selector item doesn't break the whole selection.
+func getFailedCondition(pipeline, component *buildappstudiov1alpha1.WhenCondition, expressionVariables map[string]interface{}) string {
+	if failedCondition := getFailedComponentParametersCondition(pipeline, component); failedCondition != "" {
+		return failedCondition
+	}
+	if pipeline.Expression != "" {
+		matches, err := evaluateWhenExpression(pipeline.Expression, expressionVariables)
+		if err != nil {
+			return err.Error()
+		}
+		if !matches {
+			return fmt.Sprintf("expression %q is false", pipeline.Expression)
 		}
 	}
-	return nil, nil
+	return ""
 }
 
-// pipelineConditionsMatchComponentParameters evaluates given pipeline selector against component parameters.
-// In other words, checks if given pipeline can build the component (according to what the pipeline conditions say).
-func pipelineConditionsMatchComponentParameters(pipeline, component *buildappstudiov1alpha1.WhenCondition) bool {
+// getFailedComponentParametersCondition returns description of the first pipeline condition
+// not satisfied by the component parameters or empty string if all the conditions are satisfied.
+func getFailedComponentParametersCondition(pipeline, component *buildappstudiov1alpha1.WhenCondition) string {
 	if pipeline.Language != "" && !pipelineMatchesComponentCondition(pipeline.Language, component.Language) {
-		return false
+		return fmt.Sprintf("language %q is not one of %q", component.Language, pipeline.Language)
 	}
 	if pipeline.ProjectType != "" && !pipelineMatchesComponentCondition(pipeline.ProjectType, component.ProjectType) {
-		return false
+		return fmt.Sprintf("projectType %q is not one of %q", component.ProjectType, pipeline.ProjectType)
 	}
 
 	if pipeline.DockerfileRequired != nil && *pipeline.DockerfileRequired != *component.DockerfileRequired {
-		return false
+		if *pipeline.DockerfileRequired {
+			return "dockerfile is required, but the component has none"
+		}
+		return "dockerfile is not expected, but the component has one"
 	}
 
 	if pipeline.ComponentName != "" && !pipelineMatchesComponentCondition(pipeline.ComponentName, component.ComponentName) {
-		return false
+		return fmt.Sprintf("componentName %q is not one of %q", component.ComponentName, pipeline.ComponentName)
 	}
 
-	if len(pipeline.Labels) != 0 && !pipelineMatchesComponentLabels(pipeline.Labels, component.Labels) {
-		return false
+	if len(pipeline.Labels) != 0 {
+		if labelName, ok := getFailedComponentLabel(pipeline.Labels, component.Labels); !ok {
+			return getFailedLabelDescription("label", labelName, pipeline.Labels, component.Labels)
+		}
 	}
-	if len(pipeline.Annotations) != 0 && !pipelineMatchesComponentLabels(pipeline.Annotations, component.Annotations) {
-		return false
+	if len(pipeline.Annotations) != 0 {
+		if annotationName, ok := getFailedComponentLabel(pipeline.Annotations, component.Annotations); !ok {
+			return getFailedLabelDescription("annotation", annotationName, pipeline.Annotations, component.Annotations)
+		}
 	}
 
-	return true
+	return ""
+}
+
+func getFailedLabelDescription(kind, name string, pipelineLabels, componentLabels map[string]string) string {
+	componentLabelValue, componentLabelExists := componentLabels[name]
+	if !componentLabelExists {
+		return fmt.Sprintf("%s %q is missing", kind, name)
+	}
+	return fmt.Sprintf("%s %q value %q is not one of %q", kind, name, componentLabelValue, pipelineLabels[name])
 }
 
 // pipelineMatchesComponentCondition checks if component condition is covered by the pipeline conditions.
@@ -144,7 +253,7 @@ func pipelineMatchesComponentCondition(pipelineConditions, componentCondition st
 	return false
 }
 
-// pipelineMatchesComponentLabels checks if given pipeline supports build of the the component by looking at labels.
+// getFailedComponentLabel returns name of a pipeline label not satisfied by the component labels, if any.
 // For example, component labels are:
 //
 //	appstudio/builder: maven
@@ -154,17 +263,24 @@ func pipelineMatchesComponentCondition(pipelineConditions, componentCondition st
 //
 //	appstudio/builder: maven,gradle
 //
-// The result is true.
-func pipelineMatchesComponentLabels(pipelineLabels, componentLabels map[string]string) bool {
-	for labelName, labelSupportedValues := range pipelineLabels {
+// The labels match.
+// Labels are checked in sorted order to make the result stable.
+func getFailedComponentLabel(pipelineLabels, componentLabels map[string]string) (string, bool) {
+	labelNames := make([]string, 0, len(pipelineLabels))
+	for labelName := range pipelineLabels {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
+
+	for _, labelName := range labelNames {
 		if componentLabelValue, componentLabelExists := componentLabels[labelName]; componentLabelExists {
-			if !pipelineMatchesComponentCondition(labelSupportedValues, componentLabelValue) {
-				return false
+			if !pipelineMatchesComponentCondition(pipelineLabels[labelName], componentLabelValue) {
+				return labelName, false
 			}
 		} else {
-			return false
+			return labelName, false
 		}
 	}
 
-	return true
+	return "", true
 }
@@ -396,6 +396,80 @@ func TestSelectPipelineForComponent(t *testing.T) {
 	}
 }
 
+func TestSelectPipelineForComponentWithMatch(t *testing.T) {
+	component := &appstudiov1alpha1.Component{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-component",
+			Namespace: "test-namespace",
+		},
+		Status: appstudiov1alpha1.ComponentStatus{
+			Devfile: `
+                schemaVersion: 2.2.0
+                metadata:
+                    name: devfile-nodejs
+                    language: nodejs
+            `,
+		},
+	}
+	selectors := []buildappstudiov1alpha1.BuildPipelineSelector{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "test-application", Namespace: "test-namespace"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "Java",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "java-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "java"},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "build-service"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "Python",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "python-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "python"},
+					},
+					{
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "nodejs-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs"},
+					},
+				},
+			},
+		},
+	}
+
+	pipelineRef, _, matchedSelector, err := SelectPipelineForComponentWithMatch(component, selectors)
+	if err != nil {
+		t.Fatalf("SelectPipelineForComponentWithMatch(): unexpected error: %s", err.Error())
+	}
+	wantPipelineRef := selectors[1].Spec.Selectors[1].PipelineRef.AsPipelineRef()
+	if !reflect.DeepEqual(pipelineRef, wantPipelineRef) {
+		t.Errorf("SelectPipelineForComponentWithMatch(): pipelineRef got: %v, want: %v", pipelineRef, wantPipelineRef)
+	}
+	wantMatchedSelector := &MatchedSelector{Namespace: "build-service", Name: "build-pipeline-selector", Index: 1, ItemName: ""}
+	if !reflect.DeepEqual(matchedSelector, wantMatchedSelector) {
+		t.Errorf("SelectPipelineForComponentWithMatch(): matched selector got: %v, want: %v", matchedSelector, wantMatchedSelector)
+	}
+
+	component.Status.Devfile = `
+        schemaVersion: 2.2.0
+        metadata:
+            name: devfile-go
+            language: go
+    `
+	pipelineRef, _, matchedSelector, err = SelectPipelineForComponentWithMatch(component, selectors)
+	if err != nil {
+		t.Fatalf("SelectPipelineForComponentWithMatch(): unexpected error: %s", err.Error())
+	}
+	if pipelineRef != nil || matchedSelector != nil {
+		t.Errorf("SelectPipelineForComponentWithMatch(): expected no match, got: %v, %v", pipelineRef, matchedSelector)
+	}
+}
+
 func TestGetPipelineSelectionParametersForComponent(t *testing.T) {
 	getComponent := func(devfileYaml string) appstudiov1alpha1.Component {
 		return appstudiov1alpha1.Component{
@@ -977,7 +1051,9 @@ func TestFindMatchingPipeline(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pipelineRef, pipelineParams := findMatchingPipeline(&tt.componentConditions, &tt.pipelinesChain)
+			effectiveSelectors := GetEffectivePipelineSelectors([]buildappstudiov1alpha1.BuildPipelineSelector{tt.pipelinesChain})
+			result := findMatchingPipeline(&tt.componentConditions, nil, effectiveSelectors)
+			pipelineRef, pipelineParams := result.PipelineRef, result.PipelineParams
 
 			if !reflect.DeepEqual(pipelineRef, tt.wantPipelineRef) {
 				t.Errorf("findMatchingPipeline(): pipelineRef got: %v, want: %v", pipelineRef, tt.wantPipelineRef)
@@ -989,7 +1065,7 @@ func TestFindMatchingPipeline(t *testing.T) {
 	}
 }
 
-func TestPipelineConditionsMatchComponentParameters(t *testing.T) {
+func TestGetFailedComponentParametersCondition(t *testing.T) {
 	getSampleConditions := func() buildappstudiov1alpha1.WhenCondition {
 		return buildappstudiov1alpha1.WhenCondition{
 			Language:           "java",
@@ -1189,9 +1265,9 @@ func TestPipelineConditionsMatchComponentParameters(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matches := pipelineConditionsMatchComponentParameters(&tt.pipelineConditions, &tt.componentConditions)
-			if matches != tt.wantMatch {
-				t.Errorf("pipelineConditionsMatchComponentParameters(%v, %v): got: %t, want: %t", tt.pipelineConditions, tt.componentConditions, matches, tt.wantMatch)
+			failedCondition := getFailedComponentParametersCondition(&tt.pipelineConditions, &tt.componentConditions)
+			if matches := failedCondition == ""; matches != tt.wantMatch {
+				t.Errorf("getFailedComponentParametersCondition(%v, %v): got: %q, want match: %t", tt.pipelineConditions, tt.componentConditions, failedCondition, tt.wantMatch)
 			}
 		})
 	}
@@ -1275,7 +1351,7 @@ func TestPipelineMatchesComponentCondition(t *testing.T) {
 	}
 }
 
-func TestPipelineMatchesComponentLabels(t *testing.T) {
+func TestGetFailedComponentLabel(t *testing.T) {
 	tests := []struct {
 		name            string
 		componentLabels map[string]string
@@ -1435,9 +1511,9 @@ func TestPipelineMatchesComponentLabels(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matches := pipelineMatchesComponentLabels(tt.pipelineLabels, tt.componentLabels)
+			_, matches := getFailedComponentLabel(tt.pipelineLabels, tt.componentLabels)
 			if matches != tt.wantMatch {
-				t.Errorf("pipelineMatchesComponentCondition(%s, %s): got: %v, want: %v", tt.pipelineLabels, tt.componentLabels, matches, tt.wantMatch)
+				t.Errorf("getFailedComponentLabel(%s, %s): got: %v, want: %v", tt.pipelineLabels, tt.componentLabels, matches, tt.wantMatch)
 			}
 		})
 	}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"fmt"
+	"strings"
+
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+)
+
+// FormatSelectionTrace returns one line human readable description of the pipeline selection trace, e.g.:
+// ns/app: not found; ns/build-pipeline-selector: #0 (Java): language "nodejs" is not one of "java", #1: matched
+func FormatSelectionTrace(trace []buildappstudiov1alpha1.PipelineSelectorTrace) string {
+	selectorDescriptions := make([]string, 0, len(trace))
+	for _, selectorTrace := range trace {
+		selectorDescriptions = append(selectorDescriptions, formatSelectorTrace(&selectorTrace))
+	}
+	return strings.Join(selectorDescriptions, "; ")
+}
+
+func formatSelectorTrace(selectorTrace *buildappstudiov1alpha1.PipelineSelectorTrace) string {
+	selectorName := selectorTrace.Namespace + "/" + selectorTrace.Name
+	if selectorTrace.NotFound {
+		return selectorName + ": not found"
+	}
+	if len(selectorTrace.Items) == 0 {
+		return selectorName + ": no selectors"
+	}
+
+	itemDescriptions := make([]string, 0, len(selectorTrace.Items))
+	for _, itemTrace := range selectorTrace.Items {
+		itemName := fmt.Sprintf("#%d", itemTrace.Index)
+		if itemTrace.Name != "" {
+			itemName = fmt.Sprintf("#%d (%s)", itemTrace.Index, itemTrace.Name)
+		}
+		if itemTrace.Matched {
+			itemDescriptions = append(itemDescriptions, itemName+": matched")
+		} else {
+			itemDescriptions = append(itemDescriptions, itemName+": "+itemTrace.FailedCondition)
+		}
+	}
+	return selectorName + ": " + strings.Join(itemDescriptions, ", ")
+}
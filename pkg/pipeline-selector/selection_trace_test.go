@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"reflect"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEvaluatePipelineSelectorsTrace(t *testing.T) {
+	component := &appstudiov1alpha1.Component{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-component",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{"builder": "npm"},
+		},
+		Status: appstudiov1alpha1.ComponentStatus{
+			Devfile: `
+                schemaVersion: 2.2.0
+                metadata:
+                    name: devfile-nodejs
+                    language: nodejs
+            `,
+		},
+	}
+	selectors := []buildappstudiov1alpha1.BuildPipelineSelector{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "test-application", Namespace: "test-namespace"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "Java",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "java-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "java,kotlin"},
+					},
+					{
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "docker-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{DockerfileRequired: getBoolPtr(true)},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "build-service"},
+			Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+				Selectors: []buildappstudiov1alpha1.PipelineSelector{
+					{
+						Name:           "Yarn",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "yarn-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Labels: map[string]string{"builder": "yarn"}},
+					},
+					{
+						Name:           "Annotated",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "annotated-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Annotations: map[string]string{"build": "true"}},
+					},
+					{
+						Name:           "Expression",
+						PipelineRef:    newBundleResolverPipelineRef("my-bundle", "expression-build-pipeline"),
+						WhenConditions: buildappstudiov1alpha1.WhenCondition{Expression: "componentName.startsWith('web-')"},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := EvaluatePipelineSelectors(component, selectors)
+	if err != nil {
+		t.Fatalf("EvaluatePipelineSelectors(): unexpected error: %v", err)
+	}
+	if result.PipelineRef != nil || result.Matched != nil {
+		t.Errorf("EvaluatePipelineSelectors(): expected no match, got: %v, %v", result.PipelineRef, result.Matched)
+	}
+	wantTrace := []buildappstudiov1alpha1.PipelineSelectorTrace{
+		{
+			Namespace: "test-namespace",
+			Name:      "test-application",
+			Items: []buildappstudiov1alpha1.PipelineSelectorItemTrace{
+				{Index: 0, Name: "Java", FailedCondition: `language "nodejs" is not one of "java,kotlin"`},
+				{Index: 1, FailedCondition: "dockerfile is required, but the component has none"},
+			},
+		},
+		{
+			Namespace: "build-service",
+			Name:      "build-pipeline-selector",
+			Items: []buildappstudiov1alpha1.PipelineSelectorItemTrace{
+				{Index: 0, Name: "Yarn", FailedCondition: `label "builder" value "npm" is not one of "yarn"`},
+				{Index: 1, Name: "Annotated", FailedCondition: `annotation "build" is missing`},
+				{Index: 2, Name: "Expression", FailedCondition: `expression "componentName.startsWith('web-')" is false`},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result.Trace, wantTrace) {
+		t.Errorf("EvaluatePipelineSelectors(): trace got: %v, want: %v", result.Trace, wantTrace)
+	}
+
+	component.Name = "web-component"
+	result, err = EvaluatePipelineSelectors(component, selectors)
+	if err != nil {
+		t.Fatalf("EvaluatePipelineSelectors(): unexpected error: %v", err)
+	}
+	if result.Matched == nil || result.Matched.Index != 2 || result.Matched.ItemName != "Expression" {
+		t.Errorf("EvaluatePipelineSelectors(): unexpected match: %v", result.Matched)
+	}
+	if lastItem := result.Trace[1].Items[2]; !lastItem.Matched || lastItem.FailedCondition != "" {
+		t.Errorf("EvaluatePipelineSelectors(): expected the last item to be matched, got: %v", lastItem)
+	}
+}
+
+func TestFormatSelectionTrace(t *testing.T) {
+	trace := []buildappstudiov1alpha1.PipelineSelectorTrace{
+		{Namespace: "test-namespace", Name: "test-application", NotFound: true},
+		{Namespace: "test-namespace", Name: "build-pipeline-selector"},
+		{
+			Namespace: "build-service",
+			Name:      "build-pipeline-selector",
+			Items: []buildappstudiov1alpha1.PipelineSelectorItemTrace{
+				{Index: 0, Name: "Java", FailedCondition: `language "nodejs" is not one of "java"`},
+				{Index: 1, Matched: true},
+			},
+		},
+	}
+
+	want := `test-namespace/test-application: not found; test-namespace/build-pipeline-selector: no selectors; ` +
+		`build-service/build-pipeline-selector: #0 (Java): language "nodejs" is not one of "java", #1: matched`
+	if got := FormatSelectionTrace(trace); got != want {
+		t.Errorf("FormatSelectionTrace() = %s, want %s", got, want)
+	}
+}
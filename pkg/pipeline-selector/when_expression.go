@@ -0,0 +1,183 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+)
+
+const (
+	// whenExpressionCostLimit limits evaluation cost of a when condition expression,
+	// so an expensive expression written by a tenant cannot stall pipeline selection.
+	whenExpressionCostLimit = 100000
+	// maxCachedWhenExpressions limits number of compiled expressions kept in memory.
+	maxCachedWhenExpressions = 1000
+)
+
+var (
+	whenExpressionEnv     *cel.Env
+	whenExpressionEnvErr  error
+	whenExpressionEnvOnce sync.Once
+
+	// whenExpressionPrograms caches compiled when condition expressions, including the compilation errors.
+	whenExpressionPrograms      = map[string]*compiledWhenExpression{}
+	whenExpressionProgramsMutex sync.Mutex
+)
+
+type compiledWhenExpression struct {
+	program cel.Program
+	err     error
+}
+
+// getWhenExpressionEnv returns CEL environment with all variables available in when condition expressions.
+func getWhenExpressionEnv() (*cel.Env, error) {
+	whenExpressionEnvOnce.Do(func() {
+		whenExpressionEnv, whenExpressionEnvErr = cel.NewEnv(
+			cel.Variable("language", cel.StringType),
+			cel.Variable("projectType", cel.StringType),
+			cel.Variable("dockerfile", cel.BoolType),
+			cel.Variable("componentName", cel.StringType),
+			cel.Variable("application", cel.StringType),
+			cel.Variable("annotations", cel.MapType(cel.StringType, cel.StringType)),
+			cel.Variable("labels", cel.MapType(cel.StringType, cel.StringType)),
+			cel.Variable("gitUrl", cel.StringType),
+			cel.Variable("gitHost", cel.StringType),
+			cel.Variable("gitRevision", cel.StringType),
+			cel.Variable("contextDir", cel.StringType),
+			cel.Variable("component", cel.MapType(cel.StringType, cel.DynType)),
+		)
+	})
+	return whenExpressionEnv, whenExpressionEnvErr
+}
+
+// CompileWhenExpression checks that the given when condition expression is a valid CEL expression
+// which evaluates to boolean and returns the compiled program.
+func CompileWhenExpression(expression string) (cel.Program, error) {
+	env, err := getWhenExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EInvalidPipelineSelectorExpression,
+			fmt.Errorf("failed to compile expression %q: %w", expression, issues.Err()))
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, boerrors.NewBuildOpError(boerrors.EInvalidPipelineSelectorExpression,
+			fmt.Errorf("expression %q must evaluate to bool, but evaluates to %s", expression, ast.OutputType()))
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(whenExpressionCostLimit))
+	if err != nil {
+		return nil, boerrors.NewBuildOpError(boerrors.EInvalidPipelineSelectorExpression,
+			fmt.Errorf("failed to create program for expression %q: %w", expression, err))
+	}
+	return program, nil
+}
+
+// getWhenExpressionProgram returns compiled program of the given when condition expression.
+// Expressions are compiled once, the same selectors are evaluated for every Component.
+func getWhenExpressionProgram(expression string) (cel.Program, error) {
+	whenExpressionProgramsMutex.Lock()
+	defer whenExpressionProgramsMutex.Unlock()
+
+	if compiled, ok := whenExpressionPrograms[expression]; ok {
+		return compiled.program, compiled.err
+	}
+	if len(whenExpressionPrograms) >= maxCachedWhenExpressions {
+		// Expressions of removed selectors are never evaluated again, start over
+		whenExpressionPrograms = map[string]*compiledWhenExpression{}
+	}
+	program, err := CompileWhenExpression(expression)
+	whenExpressionPrograms[expression] = &compiledWhenExpression{program: program, err: err}
+	return program, err
+}
+
+// evaluateWhenExpression evaluates given when condition expression using given variables.
+// Evaluation fails if the expression exceeds the cost limit.
+func evaluateWhenExpression(expression string, variables map[string]interface{}) (bool, error) {
+	program, err := getWhenExpressionProgram(expression)
+	if err != nil {
+		return false, err
+	}
+
+	result, _, err := program.Eval(variables)
+	if err != nil {
+		return false, boerrors.NewBuildOpError(boerrors.EInvalidPipelineSelectorExpression,
+			fmt.Errorf("failed to evaluate expression %q: %w", expression, err))
+	}
+	matches, ok := result.Value().(bool)
+	if !ok {
+		return false, boerrors.NewBuildOpError(boerrors.EInvalidPipelineSelectorExpression,
+			fmt.Errorf("expression %q evaluated to non bool value: %v", expression, result.Value()))
+	}
+	return matches, nil
+}
+
+// getWhenExpressionVariables returns variables available in when condition expressions for the given component.
+func getWhenExpressionVariables(component *appstudiov1alpha1.Component, parameters *buildappstudiov1alpha1.WhenCondition) (map[string]interface{}, error) {
+	componentObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(component)
+	if err != nil {
+		return nil, err
+	}
+
+	var gitUrl, gitHost, gitRevision, contextDir string
+	if gitSource := component.Spec.Source.GitSource; gitSource != nil {
+		gitUrl = gitSource.URL
+		gitRevision = gitSource.Revision
+		contextDir = gitSource.Context
+		if u, err := url.Parse(gitSource.URL); err == nil {
+			gitHost = u.Hostname()
+		}
+	}
+
+	dockerfile := parameters.DockerfileRequired != nil && *parameters.DockerfileRequired
+
+	annotations := parameters.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	labels := parameters.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"language":      parameters.Language,
+		"projectType":   parameters.ProjectType,
+		"dockerfile":    dockerfile,
+		"componentName": parameters.ComponentName,
+		"application":   component.Spec.Application,
+		"annotations":   annotations,
+		"labels":        labels,
+		"gitUrl":        gitUrl,
+		"gitHost":       gitHost,
+		"gitRevision":   gitRevision,
+		"contextDir":    contextDir,
+		"component":     componentObject,
+	}, nil
+}
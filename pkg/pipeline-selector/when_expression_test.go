@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelineselector
+
+import (
+	"strings"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	buildappstudiov1alpha1 "github.com/redhat-appstudio/build-service/api/v1alpha1"
+	"github.com/redhat-appstudio/build-service/pkg/boerrors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompileWhenExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{
+			name:       "should accept boolean expression",
+			expression: "componentName.matches('^web-') && !('skip-build' in labels) && language != 'java'",
+		},
+		{
+			name:       "should accept expression on the whole component",
+			expression: "component.spec.application == 'my-app'",
+		},
+		{
+			name:       "should reject expression with syntax error",
+			expression: "componentName ==",
+			wantErr:    true,
+		},
+		{
+			name:       "should reject expression with unknown variable",
+			expression: "unknown == 'value'",
+			wantErr:    true,
+		},
+		{
+			name:       "should reject non boolean expression",
+			expression: "componentName + '-suffix'",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWhenExpression(tt.expression)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("CompileWhenExpression(): unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("CompileWhenExpression(): expected error")
+			}
+			if boErr, ok := err.(*boerrors.BuildOpError); !ok || boErr.GetErrorId() != int(boerrors.EInvalidPipelineSelectorExpression) {
+				t.Errorf("CompileWhenExpression(): expected EInvalidPipelineSelectorExpression error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSelectPipelineForComponentWithExpression(t *testing.T) {
+	getComponent := func(name string, labels map[string]string) *appstudiov1alpha1.Component {
+		return &appstudiov1alpha1.Component{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-namespace",
+				Labels:    labels,
+			},
+			Spec: appstudiov1alpha1.ComponentSpec{
+				Application: "test-application",
+				Source: appstudiov1alpha1.ComponentSource{
+					ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+						GitSource: &appstudiov1alpha1.GitSource{
+							URL: "https://gitlab.example.com/org/repository",
+						},
+					},
+				},
+			},
+			Status: appstudiov1alpha1.ComponentStatus{
+				Devfile: `
+                    schemaVersion: 2.2.0
+                    metadata:
+                        name: devfile-nodejs
+                        language: nodejs
+                `,
+			},
+		}
+	}
+	getSelectors := func(expression string) []buildappstudiov1alpha1.BuildPipelineSelector {
+		return []buildappstudiov1alpha1.BuildPipelineSelector{
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "build-pipeline-selector", Namespace: "build-service"},
+				Spec: buildappstudiov1alpha1.BuildPipelineSelectorSpec{
+					Selectors: []buildappstudiov1alpha1.PipelineSelector{
+						{
+							Name:           "expression",
+							PipelineRef:    newBundleResolverPipelineRef("my-bundle", "expression-pipeline"),
+							WhenConditions: buildappstudiov1alpha1.WhenCondition{Language: "nodejs", Expression: expression},
+						},
+						{
+							Name:        "fallback",
+							PipelineRef: newBundleResolverPipelineRef("my-bundle", "fallback-pipeline"),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		component     *appstudiov1alpha1.Component
+		expression    string
+		wantItemIndex int
+		// wantFailedCondition is a part of the failed condition of the expression item in the trace
+		wantFailedCondition string
+	}{
+		{
+			name:          "should match component name by regex",
+			component:     getComponent("web-frontend", nil),
+			expression:    "componentName.matches('^web-')",
+			wantItemIndex: 0,
+		},
+		{
+			name:          "should not match component name by regex",
+			component:     getComponent("backend", nil),
+			expression:    "componentName.matches('^web-')",
+			wantItemIndex: 1,
+		},
+		{
+			name:          "should check label absence",
+			component:     getComponent("web-frontend", map[string]string{"skip-build": "true"}),
+			expression:    "componentName.matches('^web-') && !('skip-build' in labels) && language != 'java'",
+			wantItemIndex: 1,
+		},
+		{
+			name:          "should match git host",
+			component:     getComponent("backend", nil),
+			expression:    "gitHost == 'gitlab.example.com' && application == 'test-application'",
+			wantItemIndex: 0,
+		},
+		{
+			name:          "should use AND with other conditions",
+			component:     getComponent("backend", nil),
+			expression:    "!dockerfile",
+			wantItemIndex: 0,
+		},
+		{
+			name:                "should not match invalid expression",
+			component:           getComponent("backend", nil),
+			expression:          "componentName ==",
+			wantItemIndex:       1,
+			wantFailedCondition: "failed to compile expression",
+		},
+		{
+			name:                "should not match on evaluation error",
+			component:           getComponent("backend", nil),
+			expression:          "labels['missing'] == 'value'",
+			wantItemIndex:       1,
+			wantFailedCondition: "no such key: missing",
+		},
+		{
+			name:                "should not match expression exceeding cost limit",
+			component:           getComponent("backend", nil),
+			expression:          "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(a, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(b, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(c, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(d, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(e, a + b + c + d + e > 0)))))",
+			wantItemIndex:       1,
+			wantFailedCondition: "cost limit exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EvaluatePipelineSelectors(tt.component, getSelectors(tt.expression))
+			if err != nil {
+				t.Fatalf("EvaluatePipelineSelectors(): unexpected error: %v", err)
+			}
+			if result.Matched == nil || result.Matched.Index != tt.wantItemIndex {
+				t.Errorf("EvaluatePipelineSelectors(): matched selector got: %v, want item index: %d", result.Matched, tt.wantItemIndex)
+			}
+			if tt.wantFailedCondition != "" {
+				if failedCondition := result.Trace[0].Items[0].FailedCondition; !strings.Contains(failedCondition, tt.wantFailedCondition) {
+					t.Errorf("EvaluatePipelineSelectors(): failed condition got: %q, want: %q", failedCondition, tt.wantFailedCondition)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWhenExpressionProgramCachesPrograms(t *testing.T) {
+	expression := "componentName == 'cached'"
+	program, err := getWhenExpressionProgram(expression)
+	if err != nil {
+		t.Fatalf("getWhenExpressionProgram(): unexpected error: %v", err)
+	}
+	cachedProgram, err := getWhenExpressionProgram(expression)
+	if err != nil {
+		t.Fatalf("getWhenExpressionProgram(): unexpected error: %v", err)
+	}
+	if program != cachedProgram {
+		t.Errorf("getWhenExpressionProgram(): expected cached program to be returned")
+	}
+}